@@ -2,20 +2,34 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database/fallback"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database/memory"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database/mysql"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database/postgres"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database/redis"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database/sqlite"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database/traced"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/http"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/metrics"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/mqtt"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/service"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/spool"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/tracing"
 )
 
+// tracingShutdownTimeout es el tiempo máximo para exportar los spans pendientes al apagar
+const tracingShutdownTimeout = 5 * time.Second
+
 func main() {
 	// Cargar configuración
 	cfg, err := config.Load()
@@ -25,52 +39,185 @@ func main() {
 	}
 
 	// Inicializar logger
-	log, err := logger.New(
-		cfg.Logging.LogDir,
-		cfg.Logging.AppLogFile,
-		cfg.Logging.InvalidLogFile,
-		cfg.Logging.DeviceLogDir,
-	)
+	log, err := logger.New(&cfg.Logging)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error al inicializar el logger: %v\n", err)
 		os.Exit(1)
 	}
+	defer log.Close()
 
 	log.Info("Iniciando Servidor de Endpoint de Telemetría...")
 	log.Info("Configuración cargada exitosamente")
 
-	// Inicializar conexión MySQL
-	log.Info("Conectando a la base de datos MySQL...")
-	mysqlConn, err := mysql.NewConnection(&cfg.MySQL)
+	// Inicializar trazas OpenTelemetry (exportador OTLP configurado por OTEL_EXPORTER_OTLP_*)
+	shutdownTracing, err := tracing.Setup(context.Background(), &cfg.Tracing)
 	if err != nil {
-		log.Error("Error al conectar a MySQL: %v", err)
+		log.Error("Error al inicializar las trazas: %v", err)
 		os.Exit(1)
 	}
-	defer mysqlConn.Close()
-	log.Info("Conexión MySQL establecida")
+	if cfg.Tracing.Enabled {
+		log.Info("Trazas OpenTelemetry habilitadas (servicio: %s, muestreo: %.2f)", cfg.Tracing.ServiceName, cfg.Tracing.SampleRatio)
+	}
 
-	// Inicializar repositorio MySQL
-	repo := mysql.NewRepository(mysqlConn)
+	// Inicializar repositorio según el motor configurado en DB_DRIVER
+	var repo database.Repository
+	switch cfg.Database.Driver {
+	case config.DatabaseDriverPostgres:
+		log.Info("Conectando a la base de datos PostgreSQL...")
+		postgresConn, err := postgres.NewConnection(&cfg.Postgres)
+		if err != nil {
+			log.Error("Error al conectar a PostgreSQL: %v", err)
+			os.Exit(1)
+		}
+		repo = postgres.NewRepository(postgresConn)
+		metrics.RegisterDB(postgresConn.GetDB(), "postgres")
+		log.Info("Conexión PostgreSQL establecida")
+	case config.DatabaseDriverSQLite:
+		log.Info("Abriendo base de datos SQLite...")
+		sqliteConn, err := sqlite.NewConnection(&cfg.SQLite)
+		if err != nil {
+			log.Error("Error al abrir la base de datos SQLite: %v", err)
+			os.Exit(1)
+		}
+		repo = sqlite.NewRepository(sqliteConn)
+		metrics.RegisterDB(sqliteConn.GetDB(), "sqlite")
+		log.Info("Base de datos SQLite abierta en %s", cfg.SQLite.Path)
+	default:
+		log.Info("Conectando a la base de datos MySQL...")
+		mysqlConn, err := mysql.NewConnection(&cfg.MySQL)
+		if err != nil {
+			log.Error("Error al conectar a MySQL: %v", err)
+			os.Exit(1)
+		}
+		if !mysqlConn.ConsecutiveIDs() {
+			log.Warning("auto_increment_increment distinto de 1: las mediciones se insertarán de a una fila")
+		}
+		repo = mysql.NewRepository(mysqlConn)
+		metrics.RegisterDB(mysqlConn.GetDB(), "mysql")
+		log.Info("Conexión MySQL establecida")
+	}
+	defer repo.Close()
+	if cfg.Tracing.Enabled {
+		repo = traced.NewRepository(repo, cfg.Database.Driver)
+	}
 
-	// Inicializar conexión Redis
-	log.Info("Conectando a Redis...")
-	redisConn, err := redis.NewConnection(&cfg.Redis)
-	if err != nil {
-		log.Error("Error al conectar a Redis: %v", err)
-		os.Exit(1)
+	// Inicializar caché según el motor configurado en CACHE_DRIVER
+	var cache database.Cache
+	switch cfg.Cache.Driver {
+	case config.CacheDriverMemory:
+		cache = memory.NewCache(cfg.Cache.TTL, cfg.Cache.MaxEntries)
+		log.Info("Caché en memoria inicializado")
+	default:
+		// Redis con respaldo en memoria: si no está disponible el servicio opera en modo degradado
+		log.Info("Conectando a Redis...")
+		cache = fallback.NewCache(func() (database.Cache, error) {
+			redisConn, err := redis.NewConnection(&cfg.Redis)
+			if err != nil {
+				return nil, err
+			}
+			log.Info("Conexión Redis establecida")
+			metrics.SetRedisClient(redisConn.GetClient())
+			return redis.NewCache(redisConn), nil
+		}, memory.NewCache(cfg.Cache.TTL, cfg.Cache.MaxEntries), cfg.Cache.RetryInterval, log)
+	}
+	defer cache.Close()
+	if cfg.Tracing.Enabled {
+		cache = traced.NewCache(cache, cfg.Cache.Driver)
 	}
-	defer redisConn.Close()
-	log.Info("Conexión Redis establecida")
 
-	// Inicializar caché Redis
-	cache := redis.NewCache(redisConn)
+	// Inicializar verificaciones de salud de las dependencias
+	healthService := service.NewHealthService(&cfg.Health, log)
+	healthService.AddCheck(config.HealthComponentDatabase, repo.Ping)
+	healthService.AddCheck(config.HealthComponentCache, func(ctx context.Context) error {
+		err := cache.Ping(ctx)
+		if errors.Is(err, fallback.ErrPrimaryUnavailable) {
+			return fmt.Errorf("redis no disponible, caché en memoria (%v): %w", err, service.ErrDegraded)
+		}
+		return err
+	})
+
+	// Inicializar servicio de webhooks y su despachador de entregas
+	webhookService := service.NewWebhookService(repo, &cfg.Webhook, log)
+	webhookService.Start()
+
+	// Abrir spool local de mediciones para cuando la base de datos no esté disponible
+	var measurementSpool *spool.Spool
+	if cfg.Spool.Enabled {
+		measurementSpool, err = spool.Open(&cfg.Spool)
+		if err != nil {
+			log.Error("Error al abrir el spool de mediciones: %v", err)
+			os.Exit(1)
+		}
+		defer measurementSpool.Close()
+	}
+
+	// Iniciar escritura por lotes de mediciones (INSERT de múltiples filas)
+	var measurementWriter *service.MeasurementWriter
+	if cfg.Writer.Enabled {
+		measurementWriter = service.NewMeasurementWriter(repo, &cfg.Writer, log)
+		measurementWriter.Start()
+
+		// Cada worker de la cola de ingesta espera su medición, por lo que un lote nunca supera INGEST_WORKERS
+		if cfg.Ingest.Async && cfg.Writer.BatchSize > cfg.Ingest.Workers {
+			log.Warning("WRITER_BATCH_SIZE (%d) supera INGEST_WORKERS (%d): los lotes no superarán %d mediciones",
+				cfg.Writer.BatchSize, cfg.Ingest.Workers, cfg.Ingest.Workers)
+		}
+	}
 
 	// Inicializar servicio de telemetría
-	telemetryService := service.NewTelemetryService(repo, cache, cache, log)
+	telemetryService := service.NewTelemetryService(repo, cache, &cfg.Telemetry, &cfg.Spool, measurementSpool, measurementWriter, webhookService, log)
 	log.Info("Servicio de telemetría inicializado")
 
+	// Inicializar servicio de geocercas, evaluado en cada medición
+	geofenceService := service.NewGeofenceService(repo, cache, &cfg.Geofence, webhookService, log)
+	telemetryService.AddObserver(geofenceService)
+
+	// Inicializar servicio de alertas, evaluado en cada medición
+	alertService := service.NewAlertService(repo, cache, &cfg.Alert, webhookService, log)
+	telemetryService.AddObserver(alertService)
+
+	// Inicializar stream de mediciones en vivo
+	var streamHub *service.StreamHub
+	if cfg.Stream.Enabled {
+		streamHub = service.NewStreamHub(cache, &cfg.Stream, log)
+		streamHub.Start()
+		telemetryService.AddObserver(streamHub)
+	}
+
+	// Iniciar reenvío del spool una vez registrados los observadores
+	telemetryService.Start()
+
+	// Inicializar deduplicación de mensajes repetidos
+	dedupService := service.NewDedupService(cache, &cfg.Dedup, log)
+	if cfg.Dedup.Enabled {
+		log.Info("Deduplicación de mensajes habilitada (ventana: %s)", cfg.Dedup.Window)
+	}
+
+	// Iniciar cola de ingesta asíncrona entre HTTP/MQTT y el servicio de telemetría
+	var ingestPipeline *service.IngestPipeline
+	if cfg.Ingest.Async {
+		ingestPipeline = service.NewIngestPipeline(telemetryService, dedupService, &cfg.Ingest, log)
+		ingestPipeline.Start()
+	}
+
+	// Inicializar servicio de autenticación de dispositivos
+	authService := service.NewAuthService(repo, cache, &cfg.Auth, log)
+	if cfg.Auth.Enabled {
+		log.Info("Autenticación de dispositivos habilitada (header: %s)", cfg.Auth.Header)
+	}
+
+	// Iniciar watchdog de dispositivos fuera de línea
+	var watchdog *service.OfflineWatchdog
+	if cfg.Watchdog.Enabled {
+		watchdog = service.NewOfflineWatchdog(repo, &cfg.Watchdog, webhookService, log)
+		watchdog.Start()
+	}
+
+	// Inicializar servicio de administración de dispositivos
+	deviceService := service.NewDeviceService(repo, cache, log)
+
 	// Inicializar servidor HTTP
-	httpServer := http.NewServer(&cfg.Server, &cfg.RateLimit, telemetryService, log)
+	httpServer := http.NewServer(&cfg.Server, &cfg.RateLimit, &cfg.Auth, &cfg.Admin, &cfg.Stream, &cfg.Metrics, &cfg.Payload, telemetryService, ingestPipeline, dedupService, authService, deviceService, geofenceService, alertService, webhookService, streamHub, healthService, log)
 
 	// Iniciar servidor HTTP en una goroutine
 	go func() {
@@ -84,27 +231,27 @@ func main() {
 	var mqttClient *mqtt.Client
 	if cfg.MQTT.Enabled {
 		log.Info("MQTT está habilitado, inicializando cliente MQTT...")
-		mqttClient, err = mqtt.NewClient(&cfg.MQTT, log)
+		// Crear manejador MQTT
+		mqttHandler := mqtt.NewHandler(telemetryService, ingestPipeline, &cfg.Ingest, dedupService, authService, &cfg.Auth, &cfg.Payload, log)
+
+		mqttClient, err = mqtt.NewClient(&cfg.MQTT, mqttHandler.HandleMessage, log)
 		if err != nil {
 			log.Error("Error al crear cliente MQTT: %v", err)
 			os.Exit(1)
 		}
 
-		// Conectar al broker MQTT
+		// Conectar al broker MQTT y suscribirse al topic
 		if err := mqttClient.Connect(); err != nil {
 			log.Error("Error al conectar al broker MQTT: %v", err)
 			os.Exit(1)
 		}
 
-		// Crear manejador MQTT
-		mqttHandler := mqtt.NewHandler(telemetryService, log)
-
-		// Suscribirse al topic
-		if err := mqttClient.Subscribe(mqttHandler.HandleMessage); err != nil {
-			log.Error("Error al suscribirse al topic MQTT: %v", err)
-			mqttClient.Disconnect()
-			os.Exit(1)
-		}
+		healthService.AddCheck(config.HealthComponentMQTT, func(ctx context.Context) error {
+			if !mqttClient.IsConnected() {
+				return fmt.Errorf("cliente MQTT desconectado del broker")
+			}
+			return nil
+		})
 
 		log.Info("Cliente MQTT iniciado y suscrito al topic")
 	} else {
@@ -118,6 +265,11 @@ func main() {
 
 	log.Info("Señal de apagado recibida, apagando ordenadamente...")
 
+	// Cerrar las conexiones del stream para que el servidor pueda apagarse
+	if streamHub != nil {
+		streamHub.Stop()
+	}
+
 	// Apagar servidor HTTP
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
@@ -131,5 +283,34 @@ func main() {
 		mqttClient.Disconnect()
 	}
 
+	// Procesar las mediciones encoladas antes de detener el resto de los servicios
+	if ingestPipeline != nil {
+		ingestPipeline.Stop()
+	}
+
+	// Detener reenvío del spool; las mediciones pendientes quedan en disco
+	telemetryService.Stop()
+
+	// Escribir el último lote de mediciones una vez detenidos sus productores
+	if measurementWriter != nil {
+		measurementWriter.Stop()
+	}
+
+	// Detener watchdog
+	if watchdog != nil {
+		watchdog.Stop()
+	}
+
+	// Detener despachador de webhooks; las entregas pendientes quedan en la cola
+	webhookService.Stop()
+
+	// Exportar los spans pendientes antes de salir. El contexto del apagado HTTP
+	// puede haber expirado mientras se vaciaban las colas, por lo que se usa uno propio
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancelTracing()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		log.Error("Error al apagar las trazas: %v", err)
+	}
+
 	log.Info("Apagado del servidor completado")
 }
@@ -4,19 +4,52 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Config -> almacena toda la configuración de la aplicación
 type Config struct {
 	Server    ServerConfig
+	Database  DatabaseConfig
 	MySQL     MySQLConfig
+	Postgres  PostgresConfig
+	SQLite    SQLiteConfig
+	Cache     CacheConfig
 	Redis     RedisConfig
 	MQTT      MQTTConfig
 	RateLimit RateLimitConfig
 	Logging   LoggingConfig
+	Telemetry TelemetryConfig
+	Auth      AuthConfig
+	Admin     AdminConfig
+	Watchdog  WatchdogConfig
+	Geofence  GeofenceConfig
+	Alert     AlertConfig
+	Webhook   WebhookConfig
+	Stream    StreamConfig
+	Spool     SpoolConfig
+	Ingest    IngestConfig
+	Writer    WriterConfig
+	Metrics   MetricsConfig
+	Health    HealthConfig
+	Tracing   TracingConfig
+	Dedup     DedupConfig
+	Payload   PayloadConfig
 }
 
+// Configuración del motor de base de datos
+type DatabaseConfig struct {
+	Driver string // "mysql", "postgres" o "sqlite"
+}
+
+// Motores de base de datos soportados
+const (
+	DatabaseDriverMySQL    = "mysql"
+	DatabaseDriverPostgres = "postgres"
+	DatabaseDriverSQLite   = "sqlite"
+)
+
 // Configuración de Base de Datos MySQL
 type MySQLConfig struct {
 	Host            string
@@ -29,6 +62,39 @@ type MySQLConfig struct {
 	ConnMaxLifetime time.Duration
 }
 
+// Configuración de Base de Datos PostgreSQL (o TimescaleDB)
+type PostgresConfig struct {
+	Host            string
+	Port            string
+	User            string
+	Password        string
+	Database        string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// Configuración de Base de Datos SQLite embebida
+type SQLiteConfig struct {
+	Path        string        // Ruta del archivo de base de datos
+	BusyTimeout time.Duration // Espera máxima ante la base de datos bloqueada
+}
+
+// Configuración del caché de dispositivos
+type CacheConfig struct {
+	Driver        string        // "redis" (con respaldo en memoria) o "memory" (en proceso, sin servidor externo)
+	TTL           time.Duration // Duración de las entradas del caché en memoria
+	MaxEntries    int           // Máximo de entradas del caché en memoria (LRU)
+	RetryInterval time.Duration // Intervalo de verificación de Redis para volver a usarlo tras una caída
+}
+
+// Implementaciones de caché soportadas
+const (
+	CacheDriverRedis  = "redis"
+	CacheDriverMemory = "memory"
+)
+
 // Configuración de Redis
 type RedisConfig struct {
 	Host         string
@@ -59,23 +125,182 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration
 	WriteTimeout    time.Duration
 	ShutdownTimeout time.Duration
+	BatchMaxItems   int
+	BatchMaxSizeMB  int // Tamaño máximo del cuerpo de un lote
 }
 
 // Configuración de Rate Limiting
 type RateLimitConfig struct {
 	RequestsPerSecond float64
 	BurstSize         int
-	RequestDelay      time.Duration
+}
+
+// Configuración del procesamiento de telemetría
+type TelemetryConfig struct {
+	MaxClockSkew    time.Duration // Adelanto máximo permitido del reloj del dispositivo
+	MaxBackfillAge  time.Duration // Antigüedad máxima de lecturas atrasadas (0 = sin límite)
+	ClockSkewPolicy string        // "reject" rechaza la lectura, "flag" la almacena con la fecha de recepción
+
+	UnknownDeviceWindow time.Duration // Ventana en la que se notifica una sola vez cada identificador desconocido
+	UnknownDeviceRate   float64       // Identificadores desconocidos por segundo registrados y notificados (0 = solo en el log)
+}
+
+// Políticas ante desfase de reloj del dispositivo
+const (
+	ClockSkewPolicyReject = "reject"
+	ClockSkewPolicyFlag   = "flag"
+)
+
+// Configuración de autenticación de dispositivos
+type AuthConfig struct {
+	Enabled              bool
+	Header               string        // Header con el token; también se acepta "Authorization: Bearer <token>"
+	SignatureWindow      time.Duration // Ventana de validez de timestamp y nonce en solicitudes firmadas
+	MQTTRequireSignature bool          // Rechazar mensajes MQTT sin firma HMAC
+	RejectionRecordRate  float64       // Rechazos por segundo registrados en la tabla de errores (0 = solo en el log)
+}
+
+// Configuración de la API de administración
+type AdminConfig struct {
+	Token     string // Token requerido en "Authorization: Bearer"; vacío deshabilita la API
+	ReadToken string // Token de solo lectura para las consultas de /devices (también se acepta Token)
+}
+
+// Configuración del watchdog de dispositivos fuera de línea
+type WatchdogConfig struct {
+	Enabled  bool
+	Interval time.Duration
+}
+
+// Configuración de geocercas
+type GeofenceConfig struct {
+	CacheTTL time.Duration // Duración del caché local de geocercas por dispositivo
+}
+
+// Configuración de reglas de alerta
+type AlertConfig struct {
+	CacheTTL time.Duration // Duración del caché local de reglas por dispositivo
+}
+
+// Configuración de webhooks salientes
+type WebhookConfig struct {
+	Enabled      bool          // Habilita el envío de notificaciones
+	PollInterval time.Duration // Intervalo de revisión de la cola de entregas
+	Timeout      time.Duration // Timeout de cada solicitud HTTP
+	MaxAttempts  int           // Intentos antes de marcar la entrega como fallida
+	BackoffBase  time.Duration // Espera tras el primer intento fallido (se duplica en cada intento)
+	BackoffMax   time.Duration // Espera máxima entre intentos
+	BatchSize    int           // Entregas procesadas por revisión
+	CacheTTL     time.Duration // Duración del caché local de webhooks
+}
+
+// Configuración del stream de telemetría en vivo (SSE / WebSocket)
+type StreamConfig struct {
+	Enabled        bool
+	Token          string        // Token requerido para suscribirse (obligatorio con el stream habilitado)
+	Heartbeat      time.Duration // Intervalo de keep-alive hacia los clientes
+	BufferSize     int           // Mensajes en espera por cliente antes de descartar
+	AllowedOrigins []string      // Orígenes de navegador permitidos para WebSocket, además del propio
+}
+
+// Configuración del spool local de mediciones (base de datos no disponible)
+type SpoolConfig struct {
+	Enabled       bool
+	Dir           string        // Directorio de los segmentos del spool
+	SegmentSizeMB int           // Tamaño de cada segmento antes de crear uno nuevo
+	MaxSizeMB     int           // Tamaño máximo total del spool (0 = sin límite)
+	Sync          bool          // Sincronizar a disco (fsync) cada medición almacenada
+	RetryInterval time.Duration // Intervalo de verificación de la base de datos para reenviar
+}
+
+// Configuración de la cola de ingesta asíncrona
+type IngestConfig struct {
+	Async        bool          // Procesar las mediciones en segundo plano (HTTP responde 202 al encolar, salvo los lotes)
+	Workers      int           // Cantidad de workers que procesan la cola
+	QueueSize    int           // Capacidad de la cola; llena, HTTP responde 429
+	MQTTWait     time.Duration // Espera máxima de un mensaje MQTT por espacio en la cola
+	DrainTimeout time.Duration // Tiempo máximo para procesar la cola al apagar
+}
+
+// Configuración de la escritura por lotes de mediciones
+type WriterConfig struct {
+	Enabled       bool
+	BatchSize     int           // Mediciones máximas por INSERT; acotado en la práctica por las escrituras concurrentes
+	FlushInterval time.Duration // Espera máxima de una medición antes de escribir el lote
+}
+
+// Configuración del endpoint de métricas Prometheus
+type MetricsConfig struct {
+	Enabled bool
+	Path    string // Ruta del endpoint de métricas
+	Token   string // Token requerido en "Authorization: Bearer"; vacío permite acceso sin token
+}
+
+// Componentes verificados por /health/ready
+const (
+	HealthComponentDatabase = "database"
+	HealthComponentCache    = "cache"
+	HealthComponentMQTT     = "mqtt"
+)
+
+// Configuración de las verificaciones de salud
+type HealthConfig struct {
+	Timeout  time.Duration // Tiempo máximo de verificación de cada componente
+	Critical []string      // Componentes que, caídos, marcan el servicio como no listo
+}
+
+// Configuración de trazas OpenTelemetry. El destino del exportador OTLP/HTTP se
+// configura con las variables estándar OTEL_EXPORTER_OTLP_*
+type TracingConfig struct {
+	Enabled     bool
+	ServiceName string  // Nombre del servicio en las trazas (OTEL_SERVICE_NAME tiene prioridad)
+	SampleRatio float64 // Fracción de trazas nuevas registradas (0 a 1)
+}
+
+// Configuración de la deduplicación de mensajes repetidos
+type DedupConfig struct {
+	Enabled bool
+	Window  time.Duration // Tiempo durante el que se reconoce un mensaje repetido
+}
+
+// Configuración de la decodificación de payloads comprimidos y binarios
+type PayloadConfig struct {
+	MaxBodyMB         int // Tamaño máximo de una medición tal como se envía (comprimida o no)
+	MaxDecompressedMB int // Tamaño máximo de un payload descomprimido
 }
 
 // Configuración de Logging
 type LoggingConfig struct {
-	LogDir          string
-	AppLogFile      string
-	InvalidLogFile  string
-	DeviceLogDir    string
+	LogDir         string
+	AppLogFile     string
+	InvalidLogFile string
+	DeviceLogDir   string
+	Level          string // Nivel mínimo: "debug", "info", "warning" o "error"
+	Format         string // "json" o "text" (clave=valor)
+
+	// Rotación y retención de todos los archivos de log
+	MaxSizeMB      int           // Tamaño que provoca la rotación de un archivo
+	RotateInterval time.Duration // Rotación periódica, alineada a UTC (0 = solo por tamaño)
+	MaxBackups     int           // Archivos rotados conservados por log (0 = sin límite)
+	MaxAgeDays     int           // Antigüedad máxima de los archivos rotados (0 = sin límite)
+	Compress       bool          // Comprimir con gzip los archivos rotados
+
+	// Archivos de log por dispositivo abiertos simultáneamente
+	DeviceMaxOpen     int           // Máximo de archivos abiertos; se cierran los menos usados
+	DeviceIdleTimeout time.Duration // Tiempo sin escrituras tras el que se cierra un archivo
 }
 
+// Niveles y formatos de logging admitidos
+const (
+	LogLevelDebug   = "debug"
+	LogLevelInfo    = "info"
+	LogLevelWarning = "warning"
+	LogLevelError   = "error"
+
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
 // Load -> carga la configuración desde variables de entorno
 func Load() (*Config, error) {
 	cfg := &Config{
@@ -84,6 +309,11 @@ func Load() (*Config, error) {
 			ReadTimeout:     getDurationEnv("SERVER_READ_TIMEOUT", 15*time.Second),
 			WriteTimeout:    getDurationEnv("SERVER_WRITE_TIMEOUT", 15*time.Second),
 			ShutdownTimeout: getDurationEnv("SERVER_SHUTDOWN_TIMEOUT", 10*time.Second),
+			BatchMaxItems:   getIntEnv("SERVER_BATCH_MAX_ITEMS", 500),
+			BatchMaxSizeMB:  getIntEnv("SERVER_BATCH_MAX_SIZE_MB", 5),
+		},
+		Database: DatabaseConfig{
+			Driver: getEnv("DB_DRIVER", DatabaseDriverMySQL),
 		},
 		MySQL: MySQLConfig{
 			Host:            getEnv("MYSQL_HOST", "localhost"),
@@ -95,6 +325,27 @@ func Load() (*Config, error) {
 			MaxIdleConns:    getIntEnv("MYSQL_MAX_IDLE_CONNS", 5),
 			ConnMaxLifetime: getDurationEnv("MYSQL_CONN_MAX_LIFETIME", 5*time.Minute),
 		},
+		Postgres: PostgresConfig{
+			Host:            getEnv("POSTGRES_HOST", "localhost"),
+			Port:            getEnv("POSTGRES_PORT", "5432"),
+			User:            getEnv("POSTGRES_USER", "postgres"),
+			Password:        getEnv("POSTGRES_PASSWORD", ""),
+			Database:        getEnv("POSTGRES_DATABASE", "telemetria"),
+			SSLMode:         getEnv("POSTGRES_SSLMODE", "disable"),
+			MaxOpenConns:    getIntEnv("POSTGRES_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    getIntEnv("POSTGRES_MAX_IDLE_CONNS", 5),
+			ConnMaxLifetime: getDurationEnv("POSTGRES_CONN_MAX_LIFETIME", 5*time.Minute),
+		},
+		SQLite: SQLiteConfig{
+			Path:        getEnv("SQLITE_PATH", "./data/telemetria.db"),
+			BusyTimeout: getDurationEnv("SQLITE_BUSY_TIMEOUT", 5*time.Second),
+		},
+		Cache: CacheConfig{
+			Driver:        getEnv("CACHE_DRIVER", CacheDriverRedis),
+			TTL:           getDurationEnv("CACHE_TTL", 24*time.Hour),
+			MaxEntries:    getIntEnv("CACHE_MAX_ENTRIES", 10000),
+			RetryInterval: getDurationEnv("CACHE_RETRY_INTERVAL", 10*time.Second),
+		},
 		Redis: RedisConfig{
 			Host:         getEnv("REDIS_HOST", "localhost"),
 			Port:         getEnv("REDIS_PORT", "6379"),
@@ -118,13 +369,111 @@ func Load() (*Config, error) {
 		RateLimit: RateLimitConfig{
 			RequestsPerSecond: getFloat64Env("RATE_LIMIT_RPS", 100.0),
 			BurstSize:         getIntEnv("RATE_LIMIT_BURST", 200),
-			RequestDelay:      getDurationEnv("REQUEST_DELAY", 10*time.Millisecond),
 		},
 		Logging: LoggingConfig{
 			LogDir:         getEnv("LOG_DIR", "./logs"),
 			AppLogFile:     getEnv("APP_LOG_FILE", "app.log"),
 			InvalidLogFile: getEnv("INVALID_LOG_FILE", "invalid_requests.log"),
 			DeviceLogDir:   getEnv("DEVICE_LOG_DIR", "./logs/devices"),
+			Level:          strings.ToLower(getEnv("LOG_LEVEL", LogLevelInfo)),
+			Format:         strings.ToLower(getEnv("LOG_FORMAT", LogFormatJSON)),
+
+			MaxSizeMB:      getIntEnv("LOG_MAX_SIZE_MB", 100),
+			RotateInterval: getDurationEnv("LOG_ROTATE_INTERVAL", 24*time.Hour),
+			MaxBackups:     getIntEnv("LOG_MAX_BACKUPS", 10),
+			MaxAgeDays:     getIntEnv("LOG_MAX_AGE_DAYS", 30),
+			Compress:       getBoolEnv("LOG_COMPRESS", true),
+
+			DeviceMaxOpen:     getIntEnv("LOG_DEVICE_MAX_OPEN", 256),
+			DeviceIdleTimeout: getDurationEnv("LOG_DEVICE_IDLE_TIMEOUT", 5*time.Minute),
+		},
+		Telemetry: TelemetryConfig{
+			MaxClockSkew:    getDurationEnv("TELEMETRY_MAX_CLOCK_SKEW", 5*time.Minute),
+			MaxBackfillAge:  getDurationEnv("TELEMETRY_MAX_BACKFILL_AGE", 7*24*time.Hour),
+			ClockSkewPolicy: getEnv("TELEMETRY_CLOCK_SKEW_POLICY", ClockSkewPolicyFlag),
+
+			UnknownDeviceWindow: getDurationEnv("TELEMETRY_UNKNOWN_DEVICE_WINDOW", time.Hour),
+			UnknownDeviceRate:   getFloat64Env("TELEMETRY_UNKNOWN_DEVICE_RATE", 1),
+		},
+		Auth: AuthConfig{
+			Enabled:              getBoolEnv("AUTH_ENABLED", false),
+			Header:               getEnv("AUTH_HEADER", "X-API-Key"),
+			SignatureWindow:      getDurationEnv("AUTH_SIGNATURE_WINDOW", 5*time.Minute),
+			MQTTRequireSignature: getBoolEnv("MQTT_REQUIRE_SIGNATURE", false),
+			RejectionRecordRate:  getFloat64Env("AUTH_REJECTION_RECORD_RATE", 1),
+		},
+		Admin: AdminConfig{
+			Token:     getEnv("ADMIN_TOKEN", ""),
+			ReadToken: getEnv("READ_TOKEN", ""),
+		},
+		Watchdog: WatchdogConfig{
+			Enabled:  getBoolEnv("WATCHDOG_ENABLED", true),
+			Interval: getDurationEnv("WATCHDOG_INTERVAL", time.Minute),
+		},
+		Geofence: GeofenceConfig{
+			CacheTTL: getDurationEnv("GEOFENCE_CACHE_TTL", time.Minute),
+		},
+		Alert: AlertConfig{
+			CacheTTL: getDurationEnv("ALERT_CACHE_TTL", time.Minute),
+		},
+		Webhook: WebhookConfig{
+			Enabled:      getBoolEnv("WEBHOOK_ENABLED", true),
+			PollInterval: getDurationEnv("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+			Timeout:      getDurationEnv("WEBHOOK_TIMEOUT", 10*time.Second),
+			MaxAttempts:  getIntEnv("WEBHOOK_MAX_ATTEMPTS", 10),
+			BackoffBase:  getDurationEnv("WEBHOOK_BACKOFF_BASE", 30*time.Second),
+			BackoffMax:   getDurationEnv("WEBHOOK_BACKOFF_MAX", time.Hour),
+			BatchSize:    getIntEnv("WEBHOOK_BATCH_SIZE", 50),
+			CacheTTL:     getDurationEnv("WEBHOOK_CACHE_TTL", time.Minute),
+		},
+		Stream: StreamConfig{
+			Enabled:        getBoolEnv("STREAM_ENABLED", false),
+			Token:          getEnv("STREAM_TOKEN", ""),
+			Heartbeat:      getDurationEnv("STREAM_HEARTBEAT", 15*time.Second),
+			BufferSize:     getIntEnv("STREAM_BUFFER_SIZE", 64),
+			AllowedOrigins: getListEnv("STREAM_ALLOWED_ORIGINS", nil),
+		},
+		Spool: SpoolConfig{
+			Enabled:       getBoolEnv("SPOOL_ENABLED", true),
+			Dir:           getEnv("SPOOL_DIR", "./data/spool"),
+			SegmentSizeMB: getIntEnv("SPOOL_SEGMENT_SIZE_MB", 16),
+			MaxSizeMB:     getIntEnv("SPOOL_MAX_SIZE_MB", 1024),
+			Sync:          getBoolEnv("SPOOL_SYNC", true),
+			RetryInterval: getDurationEnv("SPOOL_RETRY_INTERVAL", 5*time.Second),
+		},
+		Ingest: IngestConfig{
+			Async:        getBoolEnv("INGEST_ASYNC", false),
+			Workers:      getIntEnv("INGEST_WORKERS", 64),
+			QueueSize:    getIntEnv("INGEST_QUEUE_SIZE", 10000),
+			MQTTWait:     getDurationEnv("INGEST_MQTT_WAIT", 5*time.Second),
+			DrainTimeout: getDurationEnv("INGEST_DRAIN_TIMEOUT", 30*time.Second),
+		},
+		Writer: WriterConfig{
+			Enabled:       getBoolEnv("WRITER_BATCH_ENABLED", true),
+			BatchSize:     getIntEnv("WRITER_BATCH_SIZE", 64),
+			FlushInterval: getDurationEnv("WRITER_FLUSH_INTERVAL", 50*time.Millisecond),
+		},
+		Metrics: MetricsConfig{
+			Enabled: getBoolEnv("METRICS_ENABLED", true),
+			Path:    getEnv("METRICS_PATH", "/metrics"),
+			Token:   getEnv("METRICS_TOKEN", ""),
+		},
+		Health: HealthConfig{
+			Timeout:  getDurationEnv("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+			Critical: getListEnv("HEALTH_CRITICAL", []string{HealthComponentDatabase}),
+		},
+		Tracing: TracingConfig{
+			Enabled:     getBoolEnv("TRACING_ENABLED", false),
+			ServiceName: getEnv("TRACING_SERVICE_NAME", "telemetria-endpoint"),
+			SampleRatio: getFloat64Env("TRACING_SAMPLE_RATIO", 1.0),
+		},
+		Dedup: DedupConfig{
+			Enabled: getBoolEnv("DEDUP_ENABLED", true),
+			Window:  getDurationEnv("DEDUP_WINDOW", 10*time.Minute),
+		},
+		Payload: PayloadConfig{
+			MaxBodyMB:         getIntEnv("PAYLOAD_MAX_BODY_MB", 1),
+			MaxDecompressedMB: getIntEnv("PAYLOAD_MAX_DECOMPRESSED_MB", 10),
 		},
 	}
 
@@ -138,18 +487,145 @@ func Load() (*Config, error) {
 
 // Validate valida la configuración
 func (c *Config) Validate() error {
-	if c.MySQL.Host == "" {
-		return fmt.Errorf("MYSQL_HOST es requerido")
+	if c.Server.BatchMaxItems < 1 || c.Server.BatchMaxSizeMB < 1 {
+		return fmt.Errorf("SERVER_BATCH_MAX_ITEMS y SERVER_BATCH_MAX_SIZE_MB deben ser mayores a cero")
+	}
+	switch c.Database.Driver {
+	case DatabaseDriverMySQL:
+		if c.MySQL.Host == "" {
+			return fmt.Errorf("MYSQL_HOST es requerido")
+		}
+		if c.MySQL.Database == "" {
+			return fmt.Errorf("MYSQL_DATABASE es requerido")
+		}
+	case DatabaseDriverPostgres:
+		if c.Postgres.Host == "" {
+			return fmt.Errorf("POSTGRES_HOST es requerido")
+		}
+		if c.Postgres.Database == "" {
+			return fmt.Errorf("POSTGRES_DATABASE es requerido")
+		}
+	case DatabaseDriverSQLite:
+		if c.SQLite.Path == "" {
+			return fmt.Errorf("SQLITE_PATH es requerido")
+		}
+	default:
+		return fmt.Errorf("DB_DRIVER debe ser %q, %q o %q", DatabaseDriverMySQL, DatabaseDriverPostgres, DatabaseDriverSQLite)
+	}
+	switch c.Cache.Driver {
+	case CacheDriverRedis:
+		if c.Redis.Host == "" {
+			return fmt.Errorf("REDIS_HOST es requerido")
+		}
+		if c.Cache.RetryInterval <= 0 {
+			return fmt.Errorf("CACHE_RETRY_INTERVAL debe ser mayor a cero")
+		}
+	case CacheDriverMemory:
+	default:
+		return fmt.Errorf("CACHE_DRIVER debe ser %q o %q", CacheDriverRedis, CacheDriverMemory)
 	}
-	if c.MySQL.Database == "" {
-		return fmt.Errorf("MYSQL_DATABASE es requerido")
+	// El caché en memoria se usa en ambos modos (como respaldo de Redis o como caché principal)
+	if c.Cache.TTL <= 0 {
+		return fmt.Errorf("CACHE_TTL debe ser mayor a cero")
 	}
-	if c.Redis.Host == "" {
-		return fmt.Errorf("REDIS_HOST es requerido")
+	if c.Cache.MaxEntries <= 0 {
+		return fmt.Errorf("CACHE_MAX_ENTRIES debe ser mayor a cero")
 	}
 	if c.MQTT.Enabled && c.MQTT.BrokerURL == "" {
 		return fmt.Errorf("MQTT_BROKER_URL es requerido cuando MQTT está habilitado")
 	}
+	if c.Telemetry.UnknownDeviceWindow <= 0 {
+		return fmt.Errorf("TELEMETRY_UNKNOWN_DEVICE_WINDOW debe ser mayor a cero")
+	}
+	if c.Telemetry.UnknownDeviceRate < 0 {
+		return fmt.Errorf("TELEMETRY_UNKNOWN_DEVICE_RATE no puede ser negativo")
+	}
+	if c.Auth.RejectionRecordRate < 0 {
+		return fmt.Errorf("AUTH_REJECTION_RECORD_RATE no puede ser negativo")
+	}
+	if c.Watchdog.Enabled && c.Watchdog.Interval <= 0 {
+		return fmt.Errorf("WATCHDOG_INTERVAL debe ser mayor a cero")
+	}
+	if c.Webhook.Enabled && (c.Webhook.PollInterval <= 0 || c.Webhook.MaxAttempts < 1 || c.Webhook.BatchSize < 1) {
+		return fmt.Errorf("WEBHOOK_POLL_INTERVAL, WEBHOOK_MAX_ATTEMPTS y WEBHOOK_BATCH_SIZE deben ser mayores a cero")
+	}
+	if c.Stream.Enabled && c.Stream.Token == "" {
+		return fmt.Errorf("STREAM_TOKEN es requerido cuando el stream está habilitado")
+	}
+	if c.Stream.Enabled && (c.Stream.Heartbeat <= 0 || c.Stream.BufferSize < 1) {
+		return fmt.Errorf("STREAM_HEARTBEAT y STREAM_BUFFER_SIZE deben ser mayores a cero")
+	}
+	if c.Spool.Enabled {
+		if c.Spool.Dir == "" {
+			return fmt.Errorf("SPOOL_DIR es requerido cuando el spool está habilitado")
+		}
+		if c.Spool.SegmentSizeMB <= 0 || c.Spool.RetryInterval <= 0 {
+			return fmt.Errorf("SPOOL_SEGMENT_SIZE_MB y SPOOL_RETRY_INTERVAL deben ser mayores a cero")
+		}
+		if c.Spool.MaxSizeMB < 0 {
+			return fmt.Errorf("SPOOL_MAX_SIZE_MB no puede ser negativo")
+		}
+	}
+	if c.Ingest.Async && (c.Ingest.Workers < 1 || c.Ingest.QueueSize < 1) {
+		return fmt.Errorf("INGEST_WORKERS e INGEST_QUEUE_SIZE deben ser mayores a cero")
+	}
+	if c.Writer.Enabled && (c.Writer.BatchSize < 1 || c.Writer.FlushInterval <= 0) {
+		return fmt.Errorf("WRITER_BATCH_SIZE y WRITER_FLUSH_INTERVAL deben ser mayores a cero")
+	}
+	if c.Metrics.Enabled && !strings.HasPrefix(c.Metrics.Path, "/") {
+		return fmt.Errorf("METRICS_PATH debe comenzar con /")
+	}
+	if c.Health.Timeout <= 0 {
+		return fmt.Errorf("HEALTH_CHECK_TIMEOUT debe ser mayor a cero")
+	}
+	if c.Dedup.Enabled && c.Dedup.Window <= 0 {
+		return fmt.Errorf("DEDUP_WINDOW debe ser mayor a cero")
+	}
+	if c.Payload.MaxBodyMB <= 0 {
+		return fmt.Errorf("PAYLOAD_MAX_BODY_MB debe ser mayor a cero")
+	}
+	if c.Payload.MaxDecompressedMB <= 0 {
+		return fmt.Errorf("PAYLOAD_MAX_DECOMPRESSED_MB debe ser mayor a cero")
+	}
+	switch c.Logging.Level {
+	case LogLevelDebug, LogLevelInfo, LogLevelWarning, LogLevelError:
+	default:
+		return fmt.Errorf("LOG_LEVEL debe ser %q, %q, %q o %q", LogLevelDebug, LogLevelInfo, LogLevelWarning, LogLevelError)
+	}
+	switch c.Logging.Format {
+	case LogFormatJSON, LogFormatText:
+	default:
+		return fmt.Errorf("LOG_FORMAT debe ser %q o %q", LogFormatJSON, LogFormatText)
+	}
+	if c.Logging.MaxSizeMB <= 0 {
+		return fmt.Errorf("LOG_MAX_SIZE_MB debe ser mayor a cero")
+	}
+	if c.Logging.RotateInterval < 0 {
+		return fmt.Errorf("LOG_ROTATE_INTERVAL no puede ser negativo")
+	}
+	if c.Logging.MaxBackups < 0 || c.Logging.MaxAgeDays < 0 {
+		return fmt.Errorf("LOG_MAX_BACKUPS y LOG_MAX_AGE_DAYS no pueden ser negativos")
+	}
+	if c.Logging.DeviceMaxOpen <= 0 {
+		return fmt.Errorf("LOG_DEVICE_MAX_OPEN debe ser mayor a cero")
+	}
+	if c.Logging.DeviceIdleTimeout <= 0 {
+		return fmt.Errorf("LOG_DEVICE_IDLE_TIMEOUT debe ser mayor a cero")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("TRACING_SAMPLE_RATIO debe estar entre 0 y 1")
+	}
+	for _, component := range c.Health.Critical {
+		switch component {
+		case HealthComponentDatabase, HealthComponentCache, HealthComponentMQTT:
+		default:
+			return fmt.Errorf("HEALTH_CRITICAL contiene un componente desconocido: %q (válidos: %s, %s, %s)",
+				component, HealthComponentDatabase, HealthComponentCache, HealthComponentMQTT)
+		}
+	}
+	if c.Telemetry.ClockSkewPolicy != ClockSkewPolicyReject && c.Telemetry.ClockSkewPolicy != ClockSkewPolicyFlag {
+		return fmt.Errorf("TELEMETRY_CLOCK_SKEW_POLICY debe ser %q o %q", ClockSkewPolicyReject, ClockSkewPolicyFlag)
+	}
 	return nil
 }
 
@@ -213,3 +689,20 @@ func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+// getListEnv lee una lista separada por comas; una variable definida pero vacía
+// retorna una lista vacía
+func getListEnv(key string, defaultValue []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,540 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database/memory"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// pingTimeout es el tiempo máximo de cada verificación del caché principal
+const pingTimeout = 5 * time.Second
+
+// ErrPrimaryUnavailable indica que el caché principal aún no se ha podido conectar
+var ErrPrimaryUnavailable = errors.New("caché principal no disponible")
+
+// ConnectFunc establece la conexión con el caché principal (Redis)
+type ConnectFunc func() (database.Cache, error)
+
+// Cache implementa la interfaz database.Cache sobre un caché principal (Redis)
+// con respaldo en memoria. Mientras el principal no está disponible las
+// operaciones se atienden en memoria (modo degradado) y se vuelve al principal
+// cuando se recupera. Publicar y suscribirse no tienen respaldo: retornan error
+// mientras el principal no se ha conectado, y el stream entrega localmente
+type Cache struct {
+	connect   ConnectFunc
+	secondary *memory.Cache
+	interval  time.Duration
+	logger    *logger.Logger
+
+	mu       sync.RWMutex
+	primary  database.Cache
+	degraded bool
+	dirty    map[string]struct{}      // Dispositivos modificados en memoria durante el modo degradado
+	syncing  map[string]chan struct{} // Dispositivos pendientes de sincronizar tras volver al principal
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewCache crea el caché con respaldo e intenta conectar el caché principal.
+// Si la conexión falla el servicio inicia en modo degradado y se reintenta cada interval
+func NewCache(connect ConnectFunc, secondary *memory.Cache, interval time.Duration, log *logger.Logger) *Cache {
+	c := &Cache{
+		connect:   connect,
+		secondary: secondary,
+		interval:  interval,
+		logger:    log,
+		degraded:  true,
+		dirty:     make(map[string]struct{}),
+		syncing:   make(map[string]chan struct{}),
+		stop:      make(chan struct{}),
+	}
+
+	if primary, err := connect(); err != nil {
+		c.logger.Warning("Caché principal no disponible, iniciando con caché en memoria: %v", err)
+	} else {
+		c.primary = primary
+		c.degraded = false
+	}
+
+	c.wg.Add(1)
+	go c.monitor()
+
+	return c
+}
+
+// Degraded indica si las operaciones se están atendiendo con el caché en memoria
+func (c *Cache) Degraded() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.degraded
+}
+
+// GetDevice obtiene un dispositivo del caché por identificador
+func (c *Cache) GetDevice(ctx context.Context, identifier string) (*models.Device, error) {
+	var device *models.Device
+	err := c.read(identifier, func(cache database.Cache) error {
+		var err error
+		device, err = cache.GetDevice(ctx, identifier)
+		return err
+	})
+	return device, err
+}
+
+// SetDevice almacena un dispositivo en caché
+func (c *Cache) SetDevice(ctx context.Context, device *models.Device) error {
+	return c.write(device.Identificador, func(cache database.Cache) error {
+		return cache.SetDevice(ctx, device)
+	})
+}
+
+// DeleteDevice elimina un dispositivo del caché
+func (c *Cache) DeleteDevice(ctx context.Context, identifier string) error {
+	return c.write(identifier, func(cache database.Cache) error {
+		return cache.DeleteDevice(ctx, identifier)
+	})
+}
+
+// UpdateDeviceLocation actualiza solo los campos de ubicación del dispositivo en caché
+func (c *Cache) UpdateDeviceLocation(ctx context.Context, identifier string, latitud, longitud float64, timestamp time.Time) error {
+	return c.write(identifier, func(cache database.Cache) error {
+		return cache.UpdateDeviceLocation(ctx, identifier, latitud, longitud, timestamp)
+	})
+}
+
+// GetDeviceCredentials obtiene las credenciales de un dispositivo desde el caché
+func (c *Cache) GetDeviceCredentials(ctx context.Context, identifier string) ([]models.DeviceCredential, bool, error) {
+	var credentials []models.DeviceCredential
+	var found bool
+	err := c.read(identifier, func(cache database.Cache) error {
+		var err error
+		credentials, found, err = cache.GetDeviceCredentials(ctx, identifier)
+		return err
+	})
+	return credentials, found, err
+}
+
+// SetDeviceCredentials almacena las credenciales de un dispositivo en caché
+func (c *Cache) SetDeviceCredentials(ctx context.Context, identifier string, credentials []models.DeviceCredential) error {
+	return c.write(identifier, func(cache database.Cache) error {
+		return cache.SetDeviceCredentials(ctx, identifier, credentials)
+	})
+}
+
+// DeleteDeviceCredentials elimina las credenciales de un dispositivo del caché
+func (c *Cache) DeleteDeviceCredentials(ctx context.Context, identifier string) error {
+	return c.write(identifier, func(cache database.Cache) error {
+		return cache.DeleteDeviceCredentials(ctx, identifier)
+	})
+}
+
+// GetDeviceSensors obtiene el catálogo de sensores de un dispositivo desde el caché
+func (c *Cache) GetDeviceSensors(ctx context.Context, identifier string) ([]models.SensorDefinition, bool, error) {
+	var sensors []models.SensorDefinition
+	var found bool
+	err := c.read(identifier, func(cache database.Cache) error {
+		var err error
+		sensors, found, err = cache.GetDeviceSensors(ctx, identifier)
+		return err
+	})
+	return sensors, found, err
+}
+
+// SetDeviceSensors almacena el catálogo de sensores de un dispositivo en caché
+func (c *Cache) SetDeviceSensors(ctx context.Context, identifier string, sensors []models.SensorDefinition) error {
+	return c.write(identifier, func(cache database.Cache) error {
+		return cache.SetDeviceSensors(ctx, identifier, sensors)
+	})
+}
+
+// DeleteDeviceSensors elimina el catálogo de sensores de un dispositivo del caché
+func (c *Cache) DeleteDeviceSensors(ctx context.Context, identifier string) error {
+	return c.write(identifier, func(cache database.Cache) error {
+		return cache.DeleteDeviceSensors(ctx, identifier)
+	})
+}
+
+// GetGeofenceStates obtiene la permanencia del dispositivo en cada geocerca en la que está dentro
+func (c *Cache) GetGeofenceStates(ctx context.Context, identifier string) (map[uint]models.GeofenceState, error) {
+	var states map[uint]models.GeofenceState
+	err := c.read(identifier, func(cache database.Cache) error {
+		var err error
+		states, err = cache.GetGeofenceStates(ctx, identifier)
+		return err
+	})
+	return states, err
+}
+
+// SetGeofenceState almacena la permanencia del dispositivo en una geocerca
+func (c *Cache) SetGeofenceState(ctx context.Context, identifier string, geofenceID uint, state *models.GeofenceState) error {
+	return c.write(identifier, func(cache database.Cache) error {
+		return cache.SetGeofenceState(ctx, identifier, geofenceID, state)
+	})
+}
+
+// DeleteGeofenceState elimina la permanencia del dispositivo en una geocerca
+func (c *Cache) DeleteGeofenceState(ctx context.Context, identifier string, geofenceID uint) error {
+	return c.write(identifier, func(cache database.Cache) error {
+		return cache.DeleteGeofenceState(ctx, identifier, geofenceID)
+	})
+}
+
+// GetAlertStates obtiene el estado de cada regla de alerta evaluada para el dispositivo
+func (c *Cache) GetAlertStates(ctx context.Context, identifier string) (map[uint]models.AlertState, error) {
+	var states map[uint]models.AlertState
+	err := c.read(identifier, func(cache database.Cache) error {
+		var err error
+		states, err = cache.GetAlertStates(ctx, identifier)
+		return err
+	})
+	return states, err
+}
+
+// SetAlertState almacena el estado de una regla de alerta para el dispositivo
+func (c *Cache) SetAlertState(ctx context.Context, identifier string, ruleID uint, state *models.AlertState) error {
+	return c.write(identifier, func(cache database.Cache) error {
+		return cache.SetAlertState(ctx, identifier, ruleID, state)
+	})
+}
+
+// DeleteAlertState elimina el estado de una regla de alerta para el dispositivo
+func (c *Cache) DeleteAlertState(ctx context.Context, identifier string, ruleID uint) error {
+	return c.write(identifier, func(cache database.Cache) error {
+		return cache.DeleteAlertState(ctx, identifier, ruleID)
+	})
+}
+
+// Publish publica un mensaje en el caché principal
+func (c *Cache) Publish(ctx context.Context, channel string, payload []byte) error {
+	primary := c.getPrimary()
+	if primary == nil {
+		return ErrPrimaryUnavailable
+	}
+	return primary.Publish(ctx, channel, payload)
+}
+
+// Subscribe se suscribe a un canal del caché principal
+func (c *Cache) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	primary := c.getPrimary()
+	if primary == nil {
+		return nil, ErrPrimaryUnavailable
+	}
+	return primary.Subscribe(ctx, channel)
+}
+
+// RegisterNonce registra un nonce de solicitud firmada.
+// Retorna false si el nonce ya existía (solicitud repetida)
+func (c *Cache) RegisterNonce(ctx context.Context, identifier, nonce string, ttl time.Duration) (bool, error) {
+	var registered bool
+	err := c.read(identifier, func(cache database.Cache) error {
+		var err error
+		registered, err = cache.RegisterNonce(ctx, identifier, nonce, ttl)
+		return err
+	})
+	return registered, err
+}
+
+// ClaimMessage reserva el ID de un mensaje. Si ya existía retorna el resultado
+// guardado, vacío mientras el mensaje original se procesa
+func (c *Cache) ClaimMessage(ctx context.Context, identifier, messageID string, ttl time.Duration) (bool, string, error) {
+	var (
+		claimed bool
+		result  string
+	)
+	err := c.read(identifier, func(cache database.Cache) error {
+		var err error
+		claimed, result, err = cache.ClaimMessage(ctx, identifier, messageID, ttl)
+		return err
+	})
+	return claimed, result, err
+}
+
+// CompleteMessage guarda el resultado de un mensaje procesado
+func (c *Cache) CompleteMessage(ctx context.Context, identifier, messageID, result string, ttl time.Duration) error {
+	return c.read(identifier, func(cache database.Cache) error {
+		return cache.CompleteMessage(ctx, identifier, messageID, result, ttl)
+	})
+}
+
+// ReleaseMessage elimina la reserva de un mensaje
+func (c *Cache) ReleaseMessage(ctx context.Context, identifier, messageID string) error {
+	return c.read(identifier, func(cache database.Cache) error {
+		return cache.ReleaseMessage(ctx, identifier, messageID)
+	})
+}
+
+// Ping verifica el caché principal. Si no está disponible retorna un error que
+// envuelve ErrPrimaryUnavailable, aunque las operaciones se sigan atendiendo en memoria
+func (c *Cache) Ping(ctx context.Context) error {
+	primary := c.getPrimary()
+	if primary == nil {
+		return ErrPrimaryUnavailable
+	}
+	if err := primary.Ping(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrPrimaryUnavailable, err)
+	}
+	return nil
+}
+
+// Close detiene la verificación del caché principal y cierra ambos cachés
+func (c *Cache) Close() error {
+	close(c.stop)
+	c.wg.Wait()
+
+	c.secondary.Close()
+	if primary := c.getPrimary(); primary != nil {
+		return primary.Close()
+	}
+	return nil
+}
+
+// read ejecuta una operación sobre un dispositivo en el caché activo. Si el
+// principal falla se pasa a modo degradado y la operación se repite en memoria
+func (c *Cache) read(identifier string, op func(cache database.Cache) error) error {
+	if primary := c.active(identifier); primary != nil {
+		err := op(primary)
+		if err == nil {
+			return nil
+		}
+		c.degrade(err)
+	}
+
+	return op(c.secondary)
+}
+
+// write ejecuta una operación de escritura como read y, si se atiende en
+// memoria, registra el dispositivo para sincronizarlo al volver al principal
+func (c *Cache) write(identifier string, op func(cache database.Cache) error) error {
+	if primary := c.active(identifier); primary != nil {
+		err := op(primary)
+		if err == nil {
+			return nil
+		}
+		c.degrade(err)
+	}
+
+	c.mu.Lock()
+	c.dirty[identifier] = struct{}{}
+	c.mu.Unlock()
+
+	return op(c.secondary)
+}
+
+// active retorna el caché principal si no se está en modo degradado. Si el
+// dispositivo aún se está sincronizando se espera a que termine, para no leer ni
+// escribir estados que la sincronización reemplazaría
+func (c *Cache) active(identifier string) database.Cache {
+	for {
+		c.mu.RLock()
+		if c.degraded {
+			c.mu.RUnlock()
+			return nil
+		}
+		synced, pending := c.syncing[identifier]
+		primary := c.primary
+		c.mu.RUnlock()
+
+		if !pending {
+			return primary
+		}
+		<-synced
+	}
+}
+
+// getPrimary retorna el caché principal, o nil si aún no se ha conectado
+func (c *Cache) getPrimary() database.Cache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.primary
+}
+
+// degrade pasa a modo degradado. El caché en memoria se vacía para no usar
+// datos de una caída anterior, salvo durante una sincronización: en ese caso
+// conserva los dispositivos que aún no se copiaron al principal
+func (c *Cache) degrade(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.degraded {
+		return
+	}
+
+	c.degraded = true
+	if len(c.syncing) == 0 {
+		c.secondary.Clear()
+	}
+	c.logger.Warning("Caché principal no disponible, usando caché en memoria: %v", err)
+}
+
+// monitor verifica periódicamente el caché principal: lo conecta si no se pudo
+// al iniciar, detecta caídas y vuelve a usarlo cuando se recupera
+func (c *Cache) monitor() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.check()
+		}
+	}
+}
+
+// check verifica el caché principal y actualiza el modo de operación
+func (c *Cache) check() {
+	primary := c.getPrimary()
+	if primary == nil {
+		connected, err := c.connect()
+		if err != nil {
+			return
+		}
+
+		c.mu.Lock()
+		c.primary = connected
+		c.mu.Unlock()
+		primary = connected
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	if err := primary.Ping(ctx); err != nil {
+		c.degrade(err)
+		return
+	}
+
+	if c.Degraded() {
+		c.resync(primary)
+	}
+}
+
+// resync vuelve al caché principal y le copia los dispositivos modificados en
+// memoria durante el modo degradado. El cambio se hace primero y bajo el lock,
+// para que un flujo constante de escrituras no impida volver: las operaciones
+// sobre un dispositivo pendiente esperan a que se sincronice, y cada dispositivo
+// sincronizado se elimina de la memoria. Si el principal falla durante la
+// sincronización se vuelve al modo degradado con los dispositivos restantes
+func (c *Cache) resync(primary database.Cache) {
+	c.mu.Lock()
+	pending := make([]string, 0, len(c.dirty))
+	for identifier := range c.dirty {
+		pending = append(pending, identifier)
+		c.syncing[identifier] = make(chan struct{})
+	}
+	c.dirty = make(map[string]struct{})
+	c.degraded = false
+	c.mu.Unlock()
+
+	c.logger.Info("Caché principal disponible nuevamente, sincronizando %d dispositivos", len(pending))
+
+	for i, identifier := range pending {
+		ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+		err := c.resyncDevice(ctx, primary, identifier)
+		cancel()
+
+		// Si otra operación detectó una caída, el dispositivo se conserva en memoria
+		if err == nil && c.Degraded() {
+			err = ErrPrimaryUnavailable
+		}
+		if err != nil {
+			c.logger.Warning("Error al sincronizar el caché principal, se reintentará: %v", err)
+			c.abortResync(pending[i:])
+			return
+		}
+
+		c.secondary.Forget(identifier)
+		c.mu.Lock()
+		close(c.syncing[identifier])
+		delete(c.syncing, identifier)
+		c.mu.Unlock()
+	}
+}
+
+// abortResync vuelve al modo degradado con los dispositivos que no se alcanzaron
+// a sincronizar, que siguen en memoria para el próximo intento
+func (c *Cache) abortResync(identifiers []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.degraded = true
+	for _, identifier := range identifiers {
+		c.dirty[identifier] = struct{}{}
+		close(c.syncing[identifier])
+		delete(c.syncing, identifier)
+	}
+}
+
+// resyncDevice sincroniza en el caché principal un dispositivo modificado en
+// memoria: se eliminan del principal el dispositivo, sus credenciales y sensores
+// (se recargan desde la base de datos) y se copian los estados de geocercas y
+// alertas calculados durante el modo degradado
+func (c *Cache) resyncDevice(ctx context.Context, primary database.Cache, identifier string) error {
+	if err := primary.DeleteDevice(ctx, identifier); err != nil {
+		return err
+	}
+	if err := primary.DeleteDeviceCredentials(ctx, identifier); err != nil {
+		return err
+	}
+	if err := primary.DeleteDeviceSensors(ctx, identifier); err != nil {
+		return err
+	}
+
+	// Estados de geocercas
+	geofenceStates, err := c.secondary.GetGeofenceStates(ctx, identifier)
+	if err != nil {
+		return err
+	}
+	staleGeofences, err := primary.GetGeofenceStates(ctx, identifier)
+	if err != nil {
+		return err
+	}
+	for geofenceID := range staleGeofences {
+		if _, ok := geofenceStates[geofenceID]; !ok {
+			if err := primary.DeleteGeofenceState(ctx, identifier, geofenceID); err != nil {
+				return err
+			}
+		}
+	}
+	for geofenceID, state := range geofenceStates {
+		state := state
+		if err := primary.SetGeofenceState(ctx, identifier, geofenceID, &state); err != nil {
+			return err
+		}
+	}
+
+	// Estados de reglas de alerta
+	alertStates, err := c.secondary.GetAlertStates(ctx, identifier)
+	if err != nil {
+		return err
+	}
+	staleAlerts, err := primary.GetAlertStates(ctx, identifier)
+	if err != nil {
+		return err
+	}
+	for ruleID := range staleAlerts {
+		if _, ok := alertStates[ruleID]; !ok {
+			if err := primary.DeleteAlertState(ctx, identifier, ruleID); err != nil {
+				return err
+			}
+		}
+	}
+	for ruleID, state := range alertStates {
+		state := state
+		if err := primary.SetAlertState(ctx, identifier, ruleID, &state); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
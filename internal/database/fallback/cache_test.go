@@ -0,0 +1,173 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database/memory"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+var errDown = errors.New("redis caído")
+
+// flakyCache es un caché principal en memoria que puede simular caídas
+type flakyCache struct {
+	database.Cache
+	down       atomic.Bool // Ping y escrituras de dispositivos fallan
+	failResync atomic.Bool // La sincronización falla al eliminar dispositivos
+}
+
+func (f *flakyCache) Ping(ctx context.Context) error {
+	if f.down.Load() {
+		return errDown
+	}
+	return nil
+}
+
+func (f *flakyCache) SetDevice(ctx context.Context, device *models.Device) error {
+	if f.down.Load() {
+		return errDown
+	}
+	return f.Cache.SetDevice(ctx, device)
+}
+
+func (f *flakyCache) SetGeofenceState(ctx context.Context, identifier string, geofenceID uint, state *models.GeofenceState) error {
+	if f.down.Load() {
+		return errDown
+	}
+	return f.Cache.SetGeofenceState(ctx, identifier, geofenceID, state)
+}
+
+func (f *flakyCache) DeleteDevice(ctx context.Context, identifier string) error {
+	if f.down.Load() || f.failResync.Load() {
+		return errDown
+	}
+	return f.Cache.DeleteDevice(ctx, identifier)
+}
+
+// newTestCache crea un caché con respaldo cuyo principal comienza caído, con un
+// dispositivo y un estado de geocerca escritos en memoria
+func newTestCache(t *testing.T) (*Cache, *flakyCache, *memory.Cache) {
+	t.Helper()
+
+	dir := t.TempDir()
+	log, err := logger.New(&config.LoggingConfig{
+		LogDir:            dir,
+		AppLogFile:        "app.log",
+		InvalidLogFile:    "invalid_requests.log",
+		DeviceLogDir:      filepath.Join(dir, "devices"),
+		Level:             config.LogLevelDebug,
+		Format:            config.LogFormatJSON,
+		MaxSizeMB:         10,
+		DeviceMaxOpen:     4,
+		DeviceIdleTimeout: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("error al crear logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	primary := &flakyCache{Cache: memory.NewCache(time.Minute, 100)}
+	secondary := memory.NewCache(time.Minute, 100)
+	c := NewCache(func() (database.Cache, error) { return primary, nil }, secondary, time.Hour, log)
+	t.Cleanup(func() { c.Close() })
+
+	ctx := context.Background()
+	primary.down.Store(true)
+	if err := c.SetDevice(ctx, &models.Device{IDTelemetria: 1, Identificador: "D1"}); err != nil {
+		t.Fatalf("error al escribir en memoria: %v", err)
+	}
+	if !c.Degraded() {
+		t.Fatal("se esperaba modo degradado tras la caída del principal")
+	}
+	if err := c.SetGeofenceState(ctx, "D1", 7, &models.GeofenceState{}); err != nil {
+		t.Fatalf("error al escribir estado de geocerca: %v", err)
+	}
+
+	return c, primary, secondary
+}
+
+func TestPingReportsPrimaryStatus(t *testing.T) {
+	c, primary, _ := newTestCache(t)
+
+	if err := c.Ping(context.Background()); !errors.Is(err, ErrPrimaryUnavailable) {
+		t.Fatalf("con el principal caído: se obtuvo %v, se esperaba %v", err, ErrPrimaryUnavailable)
+	}
+
+	primary.down.Store(false)
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("con el principal disponible: error inesperado %v", err)
+	}
+}
+
+func TestResyncUnderSteadyWrites(t *testing.T) {
+	c, primary, secondary := newTestCache(t)
+	ctx := context.Background()
+	primary.down.Store(false)
+
+	// Escrituras continuas del mismo dispositivo durante la verificación
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				c.UpdateDeviceLocation(ctx, "D1", -33.45, -70.66, time.Now())
+			}
+		}
+	}()
+
+	c.check()
+	close(stop)
+	wg.Wait()
+
+	if c.Degraded() {
+		t.Fatal("el caché debe volver al principal aunque haya escrituras constantes")
+	}
+
+	// El estado calculado en memoria se copió al principal y se eliminó de la memoria
+	states, err := primary.GetGeofenceStates(ctx, "D1")
+	if err != nil || len(states) != 1 {
+		t.Fatalf("estado de geocerca en el principal: %v (error %v)", states, err)
+	}
+	if states, _ := secondary.GetGeofenceStates(ctx, "D1"); len(states) != 0 {
+		t.Errorf("el dispositivo sincronizado sigue en memoria: %v", states)
+	}
+}
+
+func TestResyncFailureKeepsDevicesInMemory(t *testing.T) {
+	c, primary, secondary := newTestCache(t)
+	ctx := context.Background()
+	primary.down.Store(false)
+	primary.failResync.Store(true)
+
+	c.check()
+	if !c.Degraded() {
+		t.Fatal("se esperaba volver al modo degradado si la sincronización falla")
+	}
+	if states, _ := secondary.GetGeofenceStates(ctx, "D1"); len(states) != 1 {
+		t.Fatalf("el estado en memoria se perdió tras la sincronización fallida: %v", states)
+	}
+
+	// El siguiente intento sincroniza el dispositivo pendiente
+	primary.failResync.Store(false)
+	c.check()
+	if c.Degraded() {
+		t.Fatal("se esperaba volver al principal en el segundo intento")
+	}
+	if states, _ := primary.GetGeofenceStates(ctx, "D1"); len(states) != 1 {
+		t.Errorf("estado de geocerca en el principal: %v", states)
+	}
+}
@@ -2,20 +2,95 @@ package database
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
 )
 
+// Errores comunes retornados por las implementaciones de Repository
+var (
+	// ErrDuplicateKey indica que el registro viola una restricción de unicidad
+	ErrDuplicateKey = errors.New("registro duplicado")
+
+	// ErrInvalidReference indica que el registro referencia a otro inexistente
+	ErrInvalidReference = errors.New("referencia inexistente")
+)
+
 // Repository define la interfaz para operaciones de base de datos
 // Esta abstracción permite una fácil migración a otras bases de datos SQL
 type Repository interface {
 	// Operaciones de dispositivos
 	GetDeviceByIdentifier(ctx context.Context, identifier string) (*models.Device, error)
 	UpdateDeviceConnection(ctx context.Context, deviceID uint, timestamp time.Time) error
+	ListDevices(ctx context.Context) ([]models.Device, error)
+	CreateDevice(ctx context.Context, device *models.Device) error
+	UpdateDevice(ctx context.Context, device *models.Device) error
+	SetDeviceActive(ctx context.Context, deviceID uint, active bool) error
+	DeleteDevice(ctx context.Context, deviceID uint) error
+
+	// Operaciones de estado fuera de línea
+	ListOverdueDevices(ctx context.Context, now time.Time) ([]models.Device, error)
+	ListOfflineDevices(ctx context.Context) ([]models.Device, error)
+	MarkDeviceOffline(ctx context.Context, deviceID uint, timestamp, cutoff time.Time) (bool, error)
+	MarkDeviceOnline(ctx context.Context, deviceID uint) (bool, error)
+
+	// Operaciones de credenciales de dispositivos
+	GetDeviceCredentials(ctx context.Context, identifier string) ([]models.DeviceCredential, error)
+
+	// Operaciones del catálogo de sensores
+	GetDeviceSensors(ctx context.Context, deviceID uint) ([]models.SensorDefinition, error)
+
+	// Operaciones de grupos de dispositivos
+	ListGroups(ctx context.Context) ([]models.DeviceGroup, error)
+	CreateGroup(ctx context.Context, group *models.DeviceGroup) error
+	DeleteGroup(ctx context.Context, groupID uint) (bool, error)
+
+	// Operaciones de geocercas
+	ListGeofences(ctx context.Context) ([]models.Geofence, error)
+	GetGeofence(ctx context.Context, geofenceID uint) (*models.Geofence, error)
+	GetDeviceGeofences(ctx context.Context, deviceID uint, groupID *uint) ([]models.Geofence, error)
+	CreateGeofence(ctx context.Context, geofence *models.Geofence) error
+	UpdateGeofence(ctx context.Context, geofence *models.Geofence) (bool, error)
+	DeleteGeofence(ctx context.Context, geofenceID uint) (bool, error)
+	AssignGeofence(ctx context.Context, assignment *models.GeofenceAssignment) error
+	UnassignGeofence(ctx context.Context, assignment *models.GeofenceAssignment) (bool, error)
+
+	// Operaciones de reglas de alerta
+	ListAlertRules(ctx context.Context) ([]models.AlertRule, error)
+	GetAlertRule(ctx context.Context, ruleID uint) (*models.AlertRule, error)
+	GetDeviceAlertRules(ctx context.Context, deviceID uint, groupID *uint) ([]models.AlertRule, error)
+	CreateAlertRule(ctx context.Context, rule *models.AlertRule) error
+	UpdateAlertRule(ctx context.Context, rule *models.AlertRule) (bool, error)
+	DeleteAlertRule(ctx context.Context, ruleID uint) (bool, error)
+
+	// Operaciones del historial de alertas
+	InsertAlert(ctx context.Context, alert *models.Alert) error
+	GetOpenAlert(ctx context.Context, ruleID, deviceID uint) (*models.Alert, error)
+	ResolveAlert(ctx context.Context, alertID uint64, timestamp time.Time, value float64) error
+	ListAlerts(ctx context.Context, query *models.AlertQuery) ([]models.Alert, error)
+
+	// Operaciones de webhooks
+	ListWebhooks(ctx context.Context) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, webhookID uint) (*models.Webhook, error)
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	UpdateWebhook(ctx context.Context, webhook *models.Webhook) (bool, error)
+	DeleteWebhook(ctx context.Context, webhookID uint) (bool, error)
+
+	// Operaciones de la cola persistente de entregas de webhooks
+	EnqueueDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, query *models.DeliveryQuery) ([]models.WebhookDelivery, error)
+
+	// Operaciones de eventos
+	InsertEvent(ctx context.Context, event *models.Event) error
+	ListEvents(ctx context.Context, query *models.EventQuery) ([]models.Event, error)
 
 	// Operaciones de mediciones
 	InsertMeasurement(ctx context.Context, measurement *models.Measurement) error
+	InsertMeasurements(ctx context.Context, measurements []*models.Measurement) error
+	ListMeasurements(ctx context.Context, query *models.MeasurementQuery) ([]models.Measurement, error)
 
 	// Operaciones de errores
 	InsertError(ctx context.Context, errorRecord *models.ErrorRecord) error
@@ -33,6 +108,43 @@ type Cache interface {
 	GetDevice(ctx context.Context, identifier string) (*models.Device, error)
 	SetDevice(ctx context.Context, device *models.Device) error
 	DeleteDevice(ctx context.Context, identifier string) error
+	UpdateDeviceLocation(ctx context.Context, identifier string, latitud, longitud float64, timestamp time.Time) error
+
+	// Operaciones de caché de credenciales
+	GetDeviceCredentials(ctx context.Context, identifier string) ([]models.DeviceCredential, bool, error)
+	SetDeviceCredentials(ctx context.Context, identifier string, credentials []models.DeviceCredential) error
+	DeleteDeviceCredentials(ctx context.Context, identifier string) error
+
+	// Operaciones de caché del catálogo de sensores
+	GetDeviceSensors(ctx context.Context, identifier string) ([]models.SensorDefinition, bool, error)
+	SetDeviceSensors(ctx context.Context, identifier string, sensors []models.SensorDefinition) error
+	DeleteDeviceSensors(ctx context.Context, identifier string) error
+
+	// Operaciones de estado de geocercas por dispositivo
+	GetGeofenceStates(ctx context.Context, identifier string) (map[uint]models.GeofenceState, error)
+	SetGeofenceState(ctx context.Context, identifier string, geofenceID uint, state *models.GeofenceState) error
+	DeleteGeofenceState(ctx context.Context, identifier string, geofenceID uint) error
+
+	// Operaciones de estado de reglas de alerta por dispositivo
+	GetAlertStates(ctx context.Context, identifier string) (map[uint]models.AlertState, error)
+	SetAlertState(ctx context.Context, identifier string, ruleID uint, state *models.AlertState) error
+	DeleteAlertState(ctx context.Context, identifier string, ruleID uint) error
+
+	// Publicación y suscripción de mensajes entre instancias. El canal retornado
+	// por Subscribe se cierra al cancelar el contexto
+	Publish(ctx context.Context, channel string, payload []byte) error
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, error)
+
+	// Registro de nonces de solicitudes firmadas; retorna false si el nonce ya fue usado
+	RegisterNonce(ctx context.Context, identifier, nonce string, ttl time.Duration) (bool, error)
+
+	// Deduplicación de mensajes. ClaimMessage reserva el ID de un mensaje y retorna
+	// true si es la primera vez que se recibe; si no, retorna el resultado guardado
+	// con CompleteMessage ("" mientras el original se procesa). ReleaseMessage libera
+	// la reserva de un mensaje que no se pudo procesar para permitir su reintento
+	ClaimMessage(ctx context.Context, identifier, messageID string, ttl time.Duration) (bool, string, error)
+	CompleteMessage(ctx context.Context, identifier, messageID, result string, ttl time.Duration) error
+	ReleaseMessage(ctx context.Context, identifier, messageID string) error
 
 	// Verificación de salud
 	Ping(ctx context.Context) error
@@ -0,0 +1,517 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// cleanupInterval es el intervalo de eliminación de entradas expiradas
+const cleanupInterval = time.Minute
+
+// subscriberBuffer es la cantidad de mensajes en espera por suscriptor
+const subscriberBuffer = 256
+
+// Cache implementa la interfaz database.Cache en memoria del proceso, para
+// instalaciones de un solo equipo sin Redis o como respaldo cuando Redis no está
+// disponible. Las entradas expiran según el TTL configurado y, al superar el
+// máximo de entradas, se descartan las usadas hace más tiempo (LRU). Los mensajes
+// publicados solo llegan a suscriptores del mismo proceso
+type Cache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu          sync.Mutex
+	entries     map[string]*list.Element
+	lru         *list.List           // Frente: entrada usada más recientemente
+	nonces      map[string]time.Time // Fuera del LRU para no debilitar la protección contra repeticiones
+	messages    map[string]message   // Fuera del LRU por el mismo motivo
+	subscribers map[string]map[chan []byte]struct{}
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// entry es un valor almacenado en caché con su fecha de expiración
+type entry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// message es el resultado de un mensaje reservado para deduplicación
+type message struct {
+	result  string
+	expires time.Time
+}
+
+// NewCache crea un nuevo caché en memoria e inicia la limpieza periódica de entradas expiradas
+func NewCache(ttl time.Duration, maxEntries int) *Cache {
+	c := &Cache{
+		ttl:         ttl,
+		maxEntries:  maxEntries,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		nonces:      make(map[string]time.Time),
+		messages:    make(map[string]message),
+		subscribers: make(map[string]map[chan []byte]struct{}),
+		stop:        make(chan struct{}),
+	}
+
+	go c.cleanup()
+	return c
+}
+
+// GetDevice obtiene un dispositivo del caché por identificador
+func (c *Cache) GetDevice(ctx context.Context, identifier string) (*models.Device, error) {
+	value, ok := c.get(deviceKey(identifier))
+	if !ok {
+		return nil, nil // Dispositivo no está en caché
+	}
+
+	device := copyDevice(value.(*models.Device))
+	return device, nil
+}
+
+// SetDevice almacena un dispositivo en caché
+func (c *Cache) SetDevice(ctx context.Context, device *models.Device) error {
+	c.set(deviceKey(device.Identificador), copyDevice(device), c.ttl)
+	return nil
+}
+
+// DeleteDevice elimina un dispositivo del caché
+func (c *Cache) DeleteDevice(ctx context.Context, identifier string) error {
+	c.delete(deviceKey(identifier))
+	return nil
+}
+
+// UpdateDeviceLocation actualiza solo los campos de ubicación del dispositivo en caché.
+// Si el dispositivo no está en caché no hace nada; se cargará desde la base de datos
+func (c *Cache) UpdateDeviceLocation(ctx context.Context, identifier string, latitud, longitud float64, timestamp time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.lookup(deviceKey(identifier), time.Now())
+	if e == nil {
+		return nil
+	}
+
+	device := e.value.(*models.Device)
+	device.Latitud = &latitud
+	device.Longitud = &longitud
+	device.UltimaConexion = timestamp
+	e.expires = time.Now().Add(c.ttl)
+
+	return nil
+}
+
+// GetDeviceCredentials obtiene las credenciales de un dispositivo desde el caché.
+// El segundo valor indica si la entrada existía (una lista vacía también se cachea)
+func (c *Cache) GetDeviceCredentials(ctx context.Context, identifier string) ([]models.DeviceCredential, bool, error) {
+	value, ok := c.get(credentialsKey(identifier))
+	if !ok {
+		return nil, false, nil // Credenciales no están en caché
+	}
+
+	credentials := value.([]models.DeviceCredential)
+	return append([]models.DeviceCredential(nil), credentials...), true, nil
+}
+
+// SetDeviceCredentials almacena las credenciales de un dispositivo en caché
+func (c *Cache) SetDeviceCredentials(ctx context.Context, identifier string, credentials []models.DeviceCredential) error {
+	c.set(credentialsKey(identifier), append([]models.DeviceCredential(nil), credentials...), c.ttl)
+	return nil
+}
+
+// DeleteDeviceCredentials elimina las credenciales de un dispositivo del caché
+func (c *Cache) DeleteDeviceCredentials(ctx context.Context, identifier string) error {
+	c.delete(credentialsKey(identifier))
+	return nil
+}
+
+// GetDeviceSensors obtiene el catálogo de sensores de un dispositivo desde el caché.
+// El segundo valor indica si la entrada existía (un catálogo vacío también se cachea)
+func (c *Cache) GetDeviceSensors(ctx context.Context, identifier string) ([]models.SensorDefinition, bool, error) {
+	value, ok := c.get(sensorsKey(identifier))
+	if !ok {
+		return nil, false, nil // Catálogo no está en caché
+	}
+
+	sensors := value.([]models.SensorDefinition)
+	return append([]models.SensorDefinition(nil), sensors...), true, nil
+}
+
+// SetDeviceSensors almacena el catálogo de sensores de un dispositivo en caché
+func (c *Cache) SetDeviceSensors(ctx context.Context, identifier string, sensors []models.SensorDefinition) error {
+	c.set(sensorsKey(identifier), append([]models.SensorDefinition(nil), sensors...), c.ttl)
+	return nil
+}
+
+// DeleteDeviceSensors elimina el catálogo de sensores de un dispositivo del caché
+func (c *Cache) DeleteDeviceSensors(ctx context.Context, identifier string) error {
+	c.delete(sensorsKey(identifier))
+	return nil
+}
+
+// GetGeofenceStates obtiene la permanencia del dispositivo en cada geocerca en la que está dentro
+func (c *Cache) GetGeofenceStates(ctx context.Context, identifier string) (map[uint]models.GeofenceState, error) {
+	states := make(map[uint]models.GeofenceState)
+	if value, ok := c.get(geofencesKey(identifier)); ok {
+		for id, state := range value.(map[uint]models.GeofenceState) {
+			states[id] = state
+		}
+	}
+
+	return states, nil
+}
+
+// SetGeofenceState almacena la permanencia del dispositivo en una geocerca
+func (c *Cache) SetGeofenceState(ctx context.Context, identifier string, geofenceID uint, state *models.GeofenceState) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	states := c.hash(geofencesKey(identifier), func() interface{} { return make(map[uint]models.GeofenceState) })
+	states.(map[uint]models.GeofenceState)[geofenceID] = *state
+
+	return nil
+}
+
+// DeleteGeofenceState elimina la permanencia del dispositivo en una geocerca
+func (c *Cache) DeleteGeofenceState(ctx context.Context, identifier string, geofenceID uint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e := c.lookup(geofencesKey(identifier), time.Now()); e != nil {
+		delete(e.value.(map[uint]models.GeofenceState), geofenceID)
+	}
+
+	return nil
+}
+
+// GetAlertStates obtiene el estado de cada regla de alerta evaluada para el dispositivo
+func (c *Cache) GetAlertStates(ctx context.Context, identifier string) (map[uint]models.AlertState, error) {
+	states := make(map[uint]models.AlertState)
+	if value, ok := c.get(alertsKey(identifier)); ok {
+		for id, state := range value.(map[uint]models.AlertState) {
+			states[id] = state
+		}
+	}
+
+	return states, nil
+}
+
+// SetAlertState almacena el estado de una regla de alerta para el dispositivo
+func (c *Cache) SetAlertState(ctx context.Context, identifier string, ruleID uint, state *models.AlertState) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	states := c.hash(alertsKey(identifier), func() interface{} { return make(map[uint]models.AlertState) })
+	states.(map[uint]models.AlertState)[ruleID] = *state
+
+	return nil
+}
+
+// DeleteAlertState elimina el estado de una regla de alerta para el dispositivo
+func (c *Cache) DeleteAlertState(ctx context.Context, identifier string, ruleID uint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e := c.lookup(alertsKey(identifier), time.Now()); e != nil {
+		delete(e.value.(map[uint]models.AlertState), ruleID)
+	}
+
+	return nil
+}
+
+// Publish entrega un mensaje a los suscriptores del canal en este proceso.
+// Los suscriptores cuyo buffer está lleno pierden el mensaje
+func (c *Cache) Publish(ctx context.Context, channel string, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for ch := range c.subscribers[channel] {
+		select {
+		case ch <- append([]byte(nil), payload...):
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Subscribe se suscribe a un canal en este proceso; el canal retornado se
+// cierra al cancelar el contexto o al cerrar el caché
+func (c *Cache) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	ch := make(chan []byte, subscriberBuffer)
+
+	c.mu.Lock()
+	if c.subscribers[channel] == nil {
+		c.subscribers[channel] = make(map[chan []byte]struct{})
+	}
+	c.subscribers[channel][ch] = struct{}{}
+	c.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.stop:
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.subscribers[channel][ch]; ok {
+			delete(c.subscribers[channel], ch)
+			close(ch)
+		}
+	}()
+
+	return ch, nil
+}
+
+// RegisterNonce registra un nonce de solicitud firmada.
+// Retorna false si el nonce ya existía (solicitud repetida)
+func (c *Cache) RegisterNonce(ctx context.Context, identifier, nonce string, ttl time.Duration) (bool, error) {
+	key := fmt.Sprintf("nonce:%s:%s", identifier, nonce)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if expires, ok := c.nonces[key]; ok && !now.After(expires) {
+		return false, nil
+	}
+	c.nonces[key] = now.Add(ttl)
+
+	return true, nil
+}
+
+// ClaimMessage reserva el ID de un mensaje. Si ya existía retorna el resultado
+// guardado, vacío mientras el mensaje original se procesa
+func (c *Cache) ClaimMessage(ctx context.Context, identifier, messageID string, ttl time.Duration) (bool, string, error) {
+	key := fmt.Sprintf("message:%s:%s", identifier, messageID)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if m, ok := c.messages[key]; ok && !now.After(m.expires) {
+		return false, m.result, nil
+	}
+	c.messages[key] = message{expires: now.Add(ttl)}
+
+	return true, "", nil
+}
+
+// CompleteMessage guarda el resultado de un mensaje procesado
+func (c *Cache) CompleteMessage(ctx context.Context, identifier, messageID, result string, ttl time.Duration) error {
+	key := fmt.Sprintf("message:%s:%s", identifier, messageID)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.messages[key] = message{result: result, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// ReleaseMessage elimina la reserva de un mensaje
+func (c *Cache) ReleaseMessage(ctx context.Context, identifier, messageID string) error {
+	key := fmt.Sprintf("message:%s:%s", identifier, messageID)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.messages, key)
+	return nil
+}
+
+// Ping verifica el caché; en memoria siempre está disponible
+func (c *Cache) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Clear elimina todas las entradas del caché, excepto los nonces y mensajes registrados
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// Forget elimina del caché el dispositivo, sus credenciales, sensores y estados
+// de geocercas y alertas
+func (c *Cache) Forget(identifier string) {
+	for _, key := range []string{
+		deviceKey(identifier),
+		credentialsKey(identifier),
+		sensorsKey(identifier),
+		geofencesKey(identifier),
+		alertsKey(identifier),
+	} {
+		c.delete(key)
+	}
+}
+
+// Close detiene la limpieza periódica y cierra los canales de los suscriptores
+func (c *Cache) Close() error {
+	c.once.Do(func() { close(c.stop) })
+	return nil
+}
+
+// cleanup elimina periódicamente las entradas expiradas hasta que se cierre el caché
+func (c *Cache) cleanup() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case now := <-ticker.C:
+			c.mu.Lock()
+			for _, element := range c.entries {
+				if element.Value.(*entry).expired(now) {
+					c.remove(element)
+				}
+			}
+			for key, expires := range c.nonces {
+				if now.After(expires) {
+					delete(c.nonces, key)
+				}
+			}
+			for key, m := range c.messages {
+				if now.After(m.expires) {
+					delete(c.messages, key)
+				}
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+// get obtiene una entrada vigente del caché
+func (c *Cache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.lookup(key, time.Now())
+	if e == nil {
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+// set almacena una entrada en caché con la duración indicada
+func (c *Cache) set(key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store(key, value, time.Now().Add(ttl))
+}
+
+// delete elimina una entrada del caché
+func (c *Cache) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+}
+
+// hash obtiene (o crea con newValue) una entrada de tipo mapa y refresca su
+// expiración, al igual que un hash de Redis. Debe llamarse con el mutex tomado
+func (c *Cache) hash(key string, newValue func() interface{}) interface{} {
+	now := time.Now()
+
+	e := c.lookup(key, now)
+	if e == nil {
+		e = c.store(key, newValue(), now)
+	}
+	e.expires = now.Add(c.ttl)
+
+	return e.value
+}
+
+// lookup obtiene una entrada vigente y la marca como usada recientemente.
+// Las entradas expiradas se eliminan. Debe llamarse con el mutex tomado
+func (c *Cache) lookup(key string, now time.Time) *entry {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	e := element.Value.(*entry)
+	if e.expired(now) {
+		c.remove(element)
+		return nil
+	}
+
+	c.lru.MoveToFront(element)
+	return e
+}
+
+// store almacena una entrada como la usada más recientemente, descartando las
+// menos usadas si se supera el máximo. Debe llamarse con el mutex tomado
+func (c *Cache) store(key string, value interface{}, expires time.Time) *entry {
+	if element, ok := c.entries[key]; ok {
+		e := element.Value.(*entry)
+		e.value = value
+		e.expires = expires
+		c.lru.MoveToFront(element)
+		return e
+	}
+
+	e := &entry{key: key, value: value, expires: expires}
+	c.entries[key] = c.lru.PushFront(e)
+
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+
+	return e
+}
+
+// remove elimina una entrada del caché. Debe llamarse con el mutex tomado
+func (c *Cache) remove(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*entry).key)
+}
+
+// expired indica si la entrada ya expiró
+func (e *entry) expired(now time.Time) bool {
+	return now.After(e.expires)
+}
+
+// copyDevice copia un dispositivo, incluidos sus campos de ubicación, para que
+// el valor en caché no se comparta con quien lo almacena o lo consulta
+func copyDevice(device *models.Device) *models.Device {
+	copied := *device
+	if device.Latitud != nil {
+		lat := *device.Latitud
+		copied.Latitud = &lat
+	}
+	if device.Longitud != nil {
+		lon := *device.Longitud
+		copied.Longitud = &lon
+	}
+	if device.IDGrupo != nil {
+		groupID := *device.IDGrupo
+		copied.IDGrupo = &groupID
+	}
+	return &copied
+}
+
+// Claves de caché, equivalentes a las usadas en Redis
+func deviceKey(identifier string) string { return fmt.Sprintf("device:%s", identifier) }
+func credentialsKey(identifier string) string {
+	return fmt.Sprintf("device:%s:credentials", identifier)
+}
+func sensorsKey(identifier string) string   { return fmt.Sprintf("device:%s:sensors", identifier) }
+func geofencesKey(identifier string) string { return fmt.Sprintf("device:%s:geofences", identifier) }
+func alertsKey(identifier string) string    { return fmt.Sprintf("device:%s:alerts", identifier) }
@@ -13,6 +13,11 @@ import (
 // Connection representa una conexión a la base de datos MySQL
 type Connection struct {
 	db *sql.DB
+
+	// autoIncrementIncrement es el valor de @@auto_increment_increment del
+	// servidor. Con un valor distinto de 1 (p. ej. en Galera o en replicación
+	// multi-fuente) los IDs de un INSERT de múltiples filas no son consecutivos
+	autoIncrementIncrement int64
 }
 
 // NewConnection crea una nueva conexión MySQL
@@ -43,7 +48,12 @@ func NewConnection(cfg *config.MySQLConfig) (*Connection, error) {
 		return nil, fmt.Errorf("error al hacer ping a la base de datos: %w", err)
 	}
 
-	return &Connection{db: db}, nil
+	var increment int64
+	if err := db.QueryRowContext(ctx, "SELECT @@auto_increment_increment").Scan(&increment); err != nil {
+		return nil, fmt.Errorf("error al consultar auto_increment_increment: %w", err)
+	}
+
+	return &Connection{db: db, autoIncrementIncrement: increment}, nil
 }
 
 // GetDB retorna la conexión subyacente a la base de datos
@@ -51,6 +61,12 @@ func (c *Connection) GetDB() *sql.DB {
 	return c.db
 }
 
+// ConsecutiveIDs indica si el servidor asigna IDs consecutivos a las filas de
+// un mismo INSERT, es decir, si @@auto_increment_increment es 1
+func (c *Connection) ConsecutiveIDs() bool {
+	return c.autoIncrementIncrement == 1
+}
+
 // Ping verifica si la conexión a la base de datos está activa
 func (c *Connection) Ping(ctx context.Context) error {
 	return c.db.PingContext(ctx)
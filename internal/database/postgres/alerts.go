@@ -0,0 +1,314 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// alertRuleColumns son las columnas leídas por queryAlertRules
+const alertRuleColumns = `
+	r.idRegla, r.Nombre, r.idTelemetria, e.Identificador, r.idGrupo, r.Sensor, r.Operador,
+	r.Umbral, r.UmbralMax, r.Histeresis, r.Duracion, r.Activo
+`
+
+// ListAlertRules obtiene todas las reglas de alerta
+func (r *Repository) ListAlertRules(ctx context.Context) ([]models.AlertRule, error) {
+	query := `
+		SELECT ` + alertRuleColumns + `
+		FROM alertas_reglas r
+		LEFT JOIN equipos_telemetria e ON e.idTelemetria = r.idTelemetria
+		ORDER BY r.idRegla
+	`
+
+	return r.queryAlertRules(ctx, query)
+}
+
+// GetAlertRule obtiene una regla de alerta por ID
+func (r *Repository) GetAlertRule(ctx context.Context, ruleID uint) (*models.AlertRule, error) {
+	query := `
+		SELECT ` + alertRuleColumns + `
+		FROM alertas_reglas r
+		LEFT JOIN equipos_telemetria e ON e.idTelemetria = r.idTelemetria
+		WHERE r.idRegla = $1
+	`
+
+	rules, err := r.queryAlertRules(ctx, query, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil // Regla no encontrada
+	}
+
+	return &rules[0], nil
+}
+
+// GetDeviceAlertRules obtiene las reglas de alerta activas de un dispositivo o de su grupo
+func (r *Repository) GetDeviceAlertRules(ctx context.Context, deviceID uint, groupID *uint) ([]models.AlertRule, error) {
+	query := `
+		SELECT ` + alertRuleColumns + `
+		FROM alertas_reglas r
+		LEFT JOIN equipos_telemetria e ON e.idTelemetria = r.idTelemetria
+		WHERE r.Activo = TRUE
+		  AND (r.idTelemetria = $1 OR (r.idGrupo IS NOT NULL AND r.idGrupo = $2))
+		ORDER BY r.idRegla
+	`
+
+	return r.queryAlertRules(ctx, query, deviceID, groupID)
+}
+
+// CreateAlertRule inserta una nueva regla de alerta
+func (r *Repository) CreateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	query := `
+		INSERT INTO alertas_reglas
+		(Nombre, idTelemetria, idGrupo, Sensor, Operador, Umbral, UmbralMax, Histeresis, Duracion, Activo)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING idRegla
+	`
+
+	err := r.conn.GetDB().QueryRowContext(ctx, query,
+		rule.Nombre,
+		rule.IDTelemetria,
+		rule.IDGrupo,
+		rule.Sensor,
+		rule.Operador,
+		rule.Umbral,
+		rule.UmbralMax,
+		rule.Histeresis,
+		rule.Duracion,
+		rule.Activo,
+	).Scan(&rule.IDRegla)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return database.ErrInvalidReference
+		}
+		return fmt.Errorf("error al insertar regla de alerta: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateAlertRule actualiza una regla de alerta
+func (r *Repository) UpdateAlertRule(ctx context.Context, rule *models.AlertRule) (bool, error) {
+	query := `
+		UPDATE alertas_reglas
+		SET Nombre = $1, idTelemetria = $2, idGrupo = $3, Sensor = $4, Operador = $5, Umbral = $6,
+		    UmbralMax = $7, Histeresis = $8, Duracion = $9, Activo = $10
+		WHERE idRegla = $11
+	`
+
+	updated, err := r.execAffected(ctx, query,
+		rule.Nombre,
+		rule.IDTelemetria,
+		rule.IDGrupo,
+		rule.Sensor,
+		rule.Operador,
+		rule.Umbral,
+		rule.UmbralMax,
+		rule.Histeresis,
+		rule.Duracion,
+		rule.Activo,
+		rule.IDRegla,
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return false, database.ErrInvalidReference
+		}
+		return false, fmt.Errorf("error al actualizar regla de alerta: %w", err)
+	}
+
+	return updated, nil
+}
+
+// DeleteAlertRule elimina una regla de alerta y su historial
+func (r *Repository) DeleteAlertRule(ctx context.Context, ruleID uint) (bool, error) {
+	query := `
+		DELETE FROM alertas_reglas
+		WHERE idRegla = $1
+	`
+
+	return r.execAffected(ctx, query, ruleID)
+}
+
+// InsertAlert registra una alerta activada
+func (r *Repository) InsertAlert(ctx context.Context, alert *models.Alert) error {
+	query := `
+		INSERT INTO equipos_telemetria_alertas
+		(idRegla, idTelemetria, Sensor, Valor, FechaActivacion, Descripcion)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING idAlerta
+	`
+
+	err := r.conn.GetDB().QueryRowContext(ctx, query,
+		alert.IDRegla,
+		alert.IDTelemetria,
+		alert.Sensor,
+		alert.Valor,
+		alert.FechaActivacion,
+		alert.Descripcion,
+	).Scan(&alert.IDAlerta)
+	if err != nil {
+		return fmt.Errorf("error al insertar alerta: %w", err)
+	}
+
+	return nil
+}
+
+// GetOpenAlert obtiene la alerta sin resolver de una regla para un dispositivo
+func (r *Repository) GetOpenAlert(ctx context.Context, ruleID, deviceID uint) (*models.Alert, error) {
+	query := `
+		SELECT idAlerta, idRegla, idTelemetria, Sensor, Valor, FechaActivacion, FechaResolucion, ValorResolucion, Descripcion
+		FROM equipos_telemetria_alertas
+		WHERE idRegla = $1 AND idTelemetria = $2 AND FechaResolucion IS NULL
+		ORDER BY idAlerta DESC
+		LIMIT 1
+	`
+
+	alerts, err := r.queryAlerts(ctx, query, ruleID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(alerts) == 0 {
+		return nil, nil // Sin alerta abierta
+	}
+
+	return &alerts[0], nil
+}
+
+// ResolveAlert marca una alerta como resuelta
+func (r *Repository) ResolveAlert(ctx context.Context, alertID uint64, timestamp time.Time, value float64) error {
+	query := `
+		UPDATE equipos_telemetria_alertas
+		SET FechaResolucion = $1, ValorResolucion = $2
+		WHERE idAlerta = $3 AND FechaResolucion IS NULL
+	`
+
+	if _, err := r.conn.GetDB().ExecContext(ctx, query, timestamp, value, alertID); err != nil {
+		return fmt.Errorf("error al resolver alerta: %w", err)
+	}
+
+	return nil
+}
+
+// ListAlerts consulta el historial de alertas de un dispositivo, de la más reciente a la más antigua
+func (r *Repository) ListAlerts(ctx context.Context, q *models.AlertQuery) ([]models.Alert, error) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"idTelemetria = " + arg(q.IDTelemetria)}
+
+	switch q.Estado {
+	case models.AlertStatusActive:
+		conditions = append(conditions, "FechaResolucion IS NULL")
+	case models.AlertStatusCleared:
+		conditions = append(conditions, "FechaResolucion IS NOT NULL")
+	}
+	if q.From != nil {
+		conditions = append(conditions, "FechaActivacion >= "+arg(*q.From))
+	}
+	if q.To != nil {
+		conditions = append(conditions, "FechaActivacion < "+arg(*q.To))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT idAlerta, idRegla, idTelemetria, Sensor, Valor, FechaActivacion, FechaResolucion, ValorResolucion, Descripcion
+		FROM equipos_telemetria_alertas
+		WHERE %s
+		ORDER BY FechaActivacion DESC, idAlerta DESC
+		LIMIT %s
+	`, strings.Join(conditions, " AND "), arg(q.Limit))
+
+	return r.queryAlerts(ctx, query, args...)
+}
+
+// queryAlertRules ejecuta una consulta de reglas con las columnas de alertRuleColumns
+func (r *Repository) queryAlertRules(ctx context.Context, query string, args ...interface{}) ([]models.AlertRule, error) {
+	rows, err := r.conn.GetDB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar reglas de alerta: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []models.AlertRule
+	for rows.Next() {
+		var rule models.AlertRule
+		var deviceID, groupID sql.NullInt64
+		var identifier sql.NullString
+		if err := rows.Scan(
+			&rule.IDRegla,
+			&rule.Nombre,
+			&deviceID,
+			&identifier,
+			&groupID,
+			&rule.Sensor,
+			&rule.Operador,
+			&rule.Umbral,
+			&rule.UmbralMax,
+			&rule.Histeresis,
+			&rule.Duracion,
+			&rule.Activo,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer regla de alerta: %w", err)
+		}
+		rule.IDTelemetria = nullableUint(deviceID)
+		rule.Identificador = identifier.String
+		rule.IDGrupo = nullableUint(groupID)
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar reglas de alerta: %w", err)
+	}
+
+	return rules, nil
+}
+
+// queryAlerts ejecuta una consulta del historial de alertas
+func (r *Repository) queryAlerts(ctx context.Context, query string, args ...interface{}) ([]models.Alert, error) {
+	rows, err := r.conn.GetDB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar alertas: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []models.Alert
+	for rows.Next() {
+		var alert models.Alert
+		var resolvedAt sql.NullTime
+		var descripcion sql.NullString
+		if err := rows.Scan(
+			&alert.IDAlerta,
+			&alert.IDRegla,
+			&alert.IDTelemetria,
+			&alert.Sensor,
+			&alert.Valor,
+			&alert.FechaActivacion,
+			&resolvedAt,
+			&alert.ValorResolucion,
+			&descripcion,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer alerta: %w", err)
+		}
+		alert.Estado = models.AlertStatusActive
+		if resolvedAt.Valid {
+			t := resolvedAt.Time
+			alert.FechaResolucion = &t
+			alert.Estado = models.AlertStatusCleared
+		}
+		alert.Descripcion = descripcion.String
+		alerts = append(alerts, alert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar alertas: %w", err)
+	}
+
+	return alerts, nil
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+)
+
+// Connection representa una conexión a la base de datos PostgreSQL
+type Connection struct {
+	db *sql.DB
+}
+
+// NewConnection crea una nueva conexión PostgreSQL
+func NewConnection(cfg *config.PostgresConfig) (*Connection, error) {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, cfg.Port),
+		Path:     cfg.Database,
+		RawQuery: url.Values{"sslmode": {cfg.SSLMode}}.Encode(),
+	}
+
+	db, err := sql.Open("pgx", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir la base de datos: %w", err)
+	}
+
+	// Configurar pool de conexiones
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	// Probar conexión
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("error al hacer ping a la base de datos: %w", err)
+	}
+
+	return &Connection{db: db}, nil
+}
+
+// GetDB retorna la conexión subyacente a la base de datos
+func (c *Connection) GetDB() *sql.DB {
+	return c.db
+}
+
+// Ping verifica si la conexión a la base de datos está activa
+func (c *Connection) Ping(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
+// Close cierra la conexión a la base de datos
+func (c *Connection) Close() error {
+	return c.db.Close()
+}
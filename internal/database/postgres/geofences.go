@@ -0,0 +1,358 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// ListGroups obtiene todos los grupos de dispositivos
+func (r *Repository) ListGroups(ctx context.Context) ([]models.DeviceGroup, error) {
+	query := `
+		SELECT idGrupo, Nombre
+		FROM grupos_telemetria
+		ORDER BY Nombre
+	`
+
+	rows, err := r.conn.GetDB().QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar grupos: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []models.DeviceGroup
+	for rows.Next() {
+		var group models.DeviceGroup
+		if err := rows.Scan(&group.IDGrupo, &group.Nombre); err != nil {
+			return nil, fmt.Errorf("error al leer grupo: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar grupos: %w", err)
+	}
+
+	return groups, nil
+}
+
+// CreateGroup inserta un nuevo grupo de dispositivos
+func (r *Repository) CreateGroup(ctx context.Context, group *models.DeviceGroup) error {
+	query := `
+		INSERT INTO grupos_telemetria (Nombre)
+		VALUES ($1)
+		RETURNING idGrupo
+	`
+
+	if err := r.conn.GetDB().QueryRowContext(ctx, query, group.Nombre).Scan(&group.IDGrupo); err != nil {
+		if isDuplicateKey(err) {
+			return database.ErrDuplicateKey
+		}
+		return fmt.Errorf("error al insertar grupo: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteGroup elimina un grupo; sus dispositivos quedan sin grupo
+func (r *Repository) DeleteGroup(ctx context.Context, groupID uint) (bool, error) {
+	query := `
+		DELETE FROM grupos_telemetria
+		WHERE idGrupo = $1
+	`
+
+	return r.execAffected(ctx, query, groupID)
+}
+
+// ListGeofences obtiene todas las geocercas
+func (r *Repository) ListGeofences(ctx context.Context) ([]models.Geofence, error) {
+	query := `
+		SELECT idGeocerca, Nombre, Tipo, Latitud, Longitud, Radio, Vertices, TiempoPermanencia, Activo
+		FROM geocercas
+		ORDER BY Nombre
+	`
+
+	return r.queryGeofences(ctx, query)
+}
+
+// GetGeofence obtiene una geocerca por ID
+func (r *Repository) GetGeofence(ctx context.Context, geofenceID uint) (*models.Geofence, error) {
+	query := `
+		SELECT idGeocerca, Nombre, Tipo, Latitud, Longitud, Radio, Vertices, TiempoPermanencia, Activo
+		FROM geocercas
+		WHERE idGeocerca = $1
+	`
+
+	geofences, err := r.queryGeofences(ctx, query, geofenceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(geofences) == 0 {
+		return nil, nil // Geocerca no encontrada
+	}
+
+	return &geofences[0], nil
+}
+
+// GetDeviceGeofences obtiene las geocercas activas asignadas a un dispositivo o a su grupo
+func (r *Repository) GetDeviceGeofences(ctx context.Context, deviceID uint, groupID *uint) ([]models.Geofence, error) {
+	query := `
+		SELECT DISTINCT g.idGeocerca, g.Nombre, g.Tipo, g.Latitud, g.Longitud, g.Radio, g.Vertices, g.TiempoPermanencia, g.Activo
+		FROM geocercas g
+		INNER JOIN geocercas_asignaciones a ON a.idGeocerca = g.idGeocerca
+		WHERE g.Activo = TRUE
+		  AND (a.idTelemetria = $1 OR (a.idGrupo IS NOT NULL AND a.idGrupo = $2))
+	`
+
+	return r.queryGeofences(ctx, query, deviceID, groupID)
+}
+
+// CreateGeofence inserta una nueva geocerca
+func (r *Repository) CreateGeofence(ctx context.Context, geofence *models.Geofence) error {
+	vertices, err := encodeVertices(geofence.Vertices)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO geocercas (Nombre, Tipo, Latitud, Longitud, Radio, Vertices, TiempoPermanencia, Activo)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING idGeocerca
+	`
+
+	err = r.conn.GetDB().QueryRowContext(ctx, query,
+		geofence.Nombre,
+		geofence.Tipo,
+		geofence.Latitud,
+		geofence.Longitud,
+		geofence.Radio,
+		vertices,
+		geofence.TiempoPermanencia,
+		geofence.Activo,
+	).Scan(&geofence.IDGeocerca)
+	if err != nil {
+		return fmt.Errorf("error al insertar geocerca: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateGeofence actualiza una geocerca
+func (r *Repository) UpdateGeofence(ctx context.Context, geofence *models.Geofence) (bool, error) {
+	vertices, err := encodeVertices(geofence.Vertices)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		UPDATE geocercas
+		SET Nombre = $1, Tipo = $2, Latitud = $3, Longitud = $4, Radio = $5, Vertices = $6, TiempoPermanencia = $7, Activo = $8
+		WHERE idGeocerca = $9
+	`
+
+	updated, err := r.execAffected(ctx, query,
+		geofence.Nombre,
+		geofence.Tipo,
+		geofence.Latitud,
+		geofence.Longitud,
+		geofence.Radio,
+		vertices,
+		geofence.TiempoPermanencia,
+		geofence.Activo,
+		geofence.IDGeocerca,
+	)
+	if err != nil {
+		return false, fmt.Errorf("error al actualizar geocerca: %w", err)
+	}
+
+	return updated, nil
+}
+
+// DeleteGeofence elimina una geocerca y sus asignaciones
+func (r *Repository) DeleteGeofence(ctx context.Context, geofenceID uint) (bool, error) {
+	query := `
+		DELETE FROM geocercas
+		WHERE idGeocerca = $1
+	`
+
+	return r.execAffected(ctx, query, geofenceID)
+}
+
+// AssignGeofence asigna una geocerca a un dispositivo o a un grupo
+func (r *Repository) AssignGeofence(ctx context.Context, assignment *models.GeofenceAssignment) error {
+	query := `
+		INSERT INTO geocercas_asignaciones (idGeocerca, idTelemetria, idGrupo)
+		VALUES ($1, $2, $3)
+	`
+
+	_, err := r.conn.GetDB().ExecContext(ctx, query,
+		assignment.IDGeocerca,
+		assignment.IDTelemetria,
+		assignment.IDGrupo,
+	)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return database.ErrDuplicateKey
+		}
+		if isForeignKeyViolation(err) {
+			return database.ErrInvalidReference
+		}
+		return fmt.Errorf("error al asignar geocerca: %w", err)
+	}
+
+	return nil
+}
+
+// UnassignGeofence elimina la asignación de una geocerca a un dispositivo o a un grupo
+func (r *Repository) UnassignGeofence(ctx context.Context, assignment *models.GeofenceAssignment) (bool, error) {
+	query := `
+		DELETE FROM geocercas_asignaciones
+		WHERE idGeocerca = $1 AND idTelemetria IS NOT DISTINCT FROM $2 AND idGrupo IS NOT DISTINCT FROM $3
+	`
+
+	return r.execAffected(ctx, query, assignment.IDGeocerca, assignment.IDTelemetria, assignment.IDGrupo)
+}
+
+// InsertEvent inserta un nuevo evento de dispositivo
+func (r *Repository) InsertEvent(ctx context.Context, event *models.Event) error {
+	query := `
+		INSERT INTO equipos_telemetria_eventos
+		(idTelemetria, idGeocerca, Tipo, Fecha, Latitud, Longitud, Descripcion)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING idEvento
+	`
+
+	err := r.conn.GetDB().QueryRowContext(ctx, query,
+		event.IDTelemetria,
+		event.IDGeocerca,
+		event.Tipo,
+		event.Fecha,
+		event.Latitud,
+		event.Longitud,
+		event.Descripcion,
+	).Scan(&event.IDEvento)
+	if err != nil {
+		return fmt.Errorf("error al insertar evento: %w", err)
+	}
+
+	return nil
+}
+
+// ListEvents consulta los eventos de un dispositivo, del más reciente al más antiguo
+func (r *Repository) ListEvents(ctx context.Context, q *models.EventQuery) ([]models.Event, error) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"idTelemetria = " + arg(q.IDTelemetria)}
+
+	if q.Tipo != "" {
+		conditions = append(conditions, "Tipo = "+arg(q.Tipo))
+	}
+	if q.From != nil {
+		conditions = append(conditions, "Fecha >= "+arg(*q.From))
+	}
+	if q.To != nil {
+		conditions = append(conditions, "Fecha < "+arg(*q.To))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT idEvento, idTelemetria, idGeocerca, Tipo, Fecha, Latitud, Longitud, Descripcion
+		FROM equipos_telemetria_eventos
+		WHERE %s
+		ORDER BY Fecha DESC, idEvento DESC
+		LIMIT %s
+	`, strings.Join(conditions, " AND "), arg(q.Limit))
+
+	rows, err := r.conn.GetDB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar eventos: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var event models.Event
+		var geofenceID sql.NullInt64
+		var descripcion sql.NullString
+		if err := rows.Scan(
+			&event.IDEvento,
+			&event.IDTelemetria,
+			&geofenceID,
+			&event.Tipo,
+			&event.Fecha,
+			&event.Latitud,
+			&event.Longitud,
+			&descripcion,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer evento: %w", err)
+		}
+		event.IDGeocerca = nullableUint(geofenceID)
+		event.Descripcion = descripcion.String
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar eventos: %w", err)
+	}
+
+	return events, nil
+}
+
+// queryGeofences ejecuta una consulta de geocercas con las columnas de la tabla geocercas
+func (r *Repository) queryGeofences(ctx context.Context, query string, args ...interface{}) ([]models.Geofence, error) {
+	rows, err := r.conn.GetDB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar geocercas: %w", err)
+	}
+	defer rows.Close()
+
+	var geofences []models.Geofence
+	for rows.Next() {
+		var geofence models.Geofence
+		var vertices sql.NullString
+		if err := rows.Scan(
+			&geofence.IDGeocerca,
+			&geofence.Nombre,
+			&geofence.Tipo,
+			&geofence.Latitud,
+			&geofence.Longitud,
+			&geofence.Radio,
+			&vertices,
+			&geofence.TiempoPermanencia,
+			&geofence.Activo,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer geocerca: %w", err)
+		}
+		if vertices.Valid && vertices.String != "" {
+			if err := json.Unmarshal([]byte(vertices.String), &geofence.Vertices); err != nil {
+				return nil, fmt.Errorf("error al decodificar vértices de geocerca %d: %w", geofence.IDGeocerca, err)
+			}
+		}
+		geofences = append(geofences, geofence)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar geocercas: %w", err)
+	}
+
+	return geofences, nil
+}
+
+// encodeVertices serializa los vértices de un polígono como JSON (nil si no hay vértices)
+func encodeVertices(vertices [][2]float64) (interface{}, error) {
+	if len(vertices) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(vertices)
+	if err != nil {
+		return nil, fmt.Errorf("error al codificar vértices: %w", err)
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,744 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// Códigos SQLSTATE de PostgreSQL utilizados por el repositorio
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+// Repository implementa la interfaz database.Repository para PostgreSQL
+type Repository struct {
+	conn *Connection
+}
+
+// NewRepository crea un nuevo repositorio PostgreSQL
+func NewRepository(conn *Connection) *Repository {
+	return &Repository{conn: conn}
+}
+
+// GetDeviceByIdentifier obtiene un dispositivo por su identificador
+func (r *Repository) GetDeviceByIdentifier(ctx context.Context, identifier string) (*models.Device, error) {
+	query := `
+		SELECT idTelemetria, Identificador, Nombre, UltimaConexion, TiempoFueraLinea, Activo, idGrupo
+		FROM equipos_telemetria
+		WHERE Identificador = $1
+	`
+
+	devices, err := r.queryDevices(ctx, query, identifier)
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, nil // Dispositivo no encontrado
+	}
+
+	return &devices[0], nil
+}
+
+// UpdateDeviceConnection actualiza la marca de tiempo de la última conexión de un dispositivo
+func (r *Repository) UpdateDeviceConnection(ctx context.Context, deviceID uint, timestamp time.Time) error {
+	query := `
+		UPDATE equipos_telemetria
+		SET UltimaConexion = $1
+		WHERE idTelemetria = $2
+	`
+
+	updated, err := r.execAffected(ctx, query, timestamp, deviceID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar conexión del dispositivo: %w", err)
+	}
+	if !updated {
+		return fmt.Errorf("dispositivo no encontrado: %d", deviceID)
+	}
+
+	return nil
+}
+
+// ListDevices obtiene todos los dispositivos registrados
+func (r *Repository) ListDevices(ctx context.Context) ([]models.Device, error) {
+	query := `
+		SELECT idTelemetria, Identificador, Nombre, UltimaConexion, TiempoFueraLinea, Activo, idGrupo
+		FROM equipos_telemetria
+		ORDER BY Identificador
+	`
+
+	return r.queryDevices(ctx, query)
+}
+
+// queryDevices ejecuta una consulta de dispositivos con las columnas
+// idTelemetria, Identificador, Nombre, UltimaConexion, TiempoFueraLinea, Activo, idGrupo
+func (r *Repository) queryDevices(ctx context.Context, query string, args ...interface{}) ([]models.Device, error) {
+	rows, err := r.conn.GetDB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar dispositivos: %w", err)
+	}
+	defer rows.Close()
+
+	var devices []models.Device
+	for rows.Next() {
+		var device models.Device
+		var groupID sql.NullInt64
+		if err := rows.Scan(
+			&device.IDTelemetria,
+			&device.Identificador,
+			&device.Nombre,
+			&device.UltimaConexion,
+			&device.TiempoFueraLinea,
+			&device.Activo,
+			&groupID,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer dispositivo: %w", err)
+		}
+		device.IDGrupo = nullableUint(groupID)
+		devices = append(devices, device)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar dispositivos: %w", err)
+	}
+
+	return devices, nil
+}
+
+// CreateDevice inserta un nuevo dispositivo
+func (r *Repository) CreateDevice(ctx context.Context, device *models.Device) error {
+	query := `
+		INSERT INTO equipos_telemetria (Identificador, Nombre, UltimaConexion, TiempoFueraLinea, Activo, idGrupo)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING idTelemetria
+	`
+
+	err := r.conn.GetDB().QueryRowContext(ctx, query,
+		device.Identificador,
+		device.Nombre,
+		device.UltimaConexion,
+		device.TiempoFueraLinea,
+		device.Activo,
+		device.IDGrupo,
+	).Scan(&device.IDTelemetria)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return database.ErrDuplicateKey
+		}
+		if isForeignKeyViolation(err) {
+			return database.ErrInvalidReference
+		}
+		return fmt.Errorf("error al insertar dispositivo: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateDevice actualiza identificador, nombre, tiempo fuera de línea y grupo de un dispositivo
+func (r *Repository) UpdateDevice(ctx context.Context, device *models.Device) error {
+	query := `
+		UPDATE equipos_telemetria
+		SET Identificador = $1, Nombre = $2, TiempoFueraLinea = $3, idGrupo = $4
+		WHERE idTelemetria = $5
+	`
+
+	_, err := r.conn.GetDB().ExecContext(ctx, query,
+		device.Identificador,
+		device.Nombre,
+		device.TiempoFueraLinea,
+		device.IDGrupo,
+		device.IDTelemetria,
+	)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return database.ErrDuplicateKey
+		}
+		if isForeignKeyViolation(err) {
+			return database.ErrInvalidReference
+		}
+		return fmt.Errorf("error al actualizar dispositivo: %w", err)
+	}
+
+	return nil
+}
+
+// SetDeviceActive habilita o deshabilita un dispositivo
+func (r *Repository) SetDeviceActive(ctx context.Context, deviceID uint, active bool) error {
+	query := `
+		UPDATE equipos_telemetria
+		SET Activo = $1
+		WHERE idTelemetria = $2
+	`
+
+	if _, err := r.conn.GetDB().ExecContext(ctx, query, active, deviceID); err != nil {
+		return fmt.Errorf("error al actualizar estado del dispositivo: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteDevice elimina un dispositivo y sus mediciones. Los sensores con nombre
+// se eliminan explícitamente porque una hypertable de TimescaleDB no admite
+// claves foráneas que la referencien
+func (r *Repository) DeleteDevice(ctx context.Context, deviceID uint) error {
+	tx, err := r.conn.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción: %w", err)
+	}
+	defer tx.Rollback()
+
+	sensors := `
+		DELETE FROM equipos_telemetria_datos_sensores
+		WHERE idMedicion IN (SELECT idMedicion FROM equipos_telemetria_datos WHERE idTelemetria = $1)
+	`
+	if _, err := tx.ExecContext(ctx, sensors, deviceID); err != nil {
+		return fmt.Errorf("error al eliminar sensores de las mediciones: %w", err)
+	}
+
+	query := `
+		DELETE FROM equipos_telemetria
+		WHERE idTelemetria = $1
+	`
+
+	result, err := tx.ExecContext(ctx, query, deviceID)
+	if err != nil {
+		return fmt.Errorf("error al eliminar dispositivo: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error al obtener filas afectadas: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("dispositivo no encontrado: %d", deviceID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar transacción: %w", err)
+	}
+
+	return nil
+}
+
+// ListOverdueDevices obtiene los dispositivos activos, aún no marcados fuera de línea,
+// cuya última conexión más su TiempoFueraLinea ya transcurrió.
+// Un TiempoFueraLinea de 00:00:00 deshabilita la detección
+func (r *Repository) ListOverdueDevices(ctx context.Context, now time.Time) ([]models.Device, error) {
+	query := `
+		SELECT idTelemetria, Identificador, Nombre, UltimaConexion, TiempoFueraLinea, Activo, idGrupo
+		FROM equipos_telemetria
+		WHERE Activo = TRUE
+		  AND FueraLinea = FALSE
+		  AND TiempoFueraLinea > '00:00:00'
+		  AND UltimaConexion + CAST(TiempoFueraLinea AS INTERVAL) < $1
+	`
+
+	return r.queryDevices(ctx, query, now)
+}
+
+// ListOfflineDevices obtiene los dispositivos actualmente marcados fuera de línea
+func (r *Repository) ListOfflineDevices(ctx context.Context) ([]models.Device, error) {
+	query := `
+		SELECT idTelemetria, Identificador, Nombre, UltimaConexion, TiempoFueraLinea, Activo, idGrupo, FechaFueraLinea
+		FROM equipos_telemetria
+		WHERE FueraLinea = TRUE
+		ORDER BY FechaFueraLinea
+	`
+
+	rows, err := r.conn.GetDB().QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar dispositivos fuera de línea: %w", err)
+	}
+	defer rows.Close()
+
+	var devices []models.Device
+	for rows.Next() {
+		var device models.Device
+		var groupID sql.NullInt64
+		var fechaFueraLinea sql.NullTime
+		if err := rows.Scan(
+			&device.IDTelemetria,
+			&device.Identificador,
+			&device.Nombre,
+			&device.UltimaConexion,
+			&device.TiempoFueraLinea,
+			&device.Activo,
+			&groupID,
+			&fechaFueraLinea,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer dispositivo: %w", err)
+		}
+		device.IDGrupo = nullableUint(groupID)
+		device.FueraLinea = true
+		if fechaFueraLinea.Valid {
+			device.FechaFueraLinea = &fechaFueraLinea.Time
+		}
+		devices = append(devices, device)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar dispositivos: %w", err)
+	}
+
+	return devices, nil
+}
+
+// MarkDeviceOffline marca un dispositivo fuera de línea si su última conexión es
+// anterior a cutoff. Retorna true solo si el dispositivo no estaba marcado ni volvió
+// a conectarse desde la revisión, de modo que cada corte se registra una única vez
+func (r *Repository) MarkDeviceOffline(ctx context.Context, deviceID uint, timestamp, cutoff time.Time) (bool, error) {
+	query := `
+		UPDATE equipos_telemetria
+		SET FueraLinea = TRUE, FechaFueraLinea = $1
+		WHERE idTelemetria = $2 AND FueraLinea = FALSE AND UltimaConexion < $3
+	`
+
+	return r.execAffected(ctx, query, timestamp, deviceID, cutoff)
+}
+
+// MarkDeviceOnline quita la marca fuera de línea de un dispositivo.
+// Retorna true solo si el dispositivo estaba marcado fuera de línea
+func (r *Repository) MarkDeviceOnline(ctx context.Context, deviceID uint) (bool, error) {
+	query := `
+		UPDATE equipos_telemetria
+		SET FueraLinea = FALSE, FechaFueraLinea = NULL
+		WHERE idTelemetria = $1 AND FueraLinea = TRUE
+	`
+
+	return r.execAffected(ctx, query, deviceID)
+}
+
+// GetDeviceCredentials obtiene las credenciales activas y vigentes de un dispositivo
+func (r *Repository) GetDeviceCredentials(ctx context.Context, identifier string) ([]models.DeviceCredential, error) {
+	query := `
+		SELECT c.idCredencial, c.idTelemetria, c.Tipo, c.TokenHash, c.Secreto, c.Descripcion, c.FechaExpiracion
+		FROM equipos_telemetria_credenciales c
+		INNER JOIN equipos_telemetria et ON et.idTelemetria = c.idTelemetria
+		WHERE et.Identificador = $1
+		  AND c.Activo = TRUE
+		  AND (c.FechaExpiracion IS NULL OR c.FechaExpiracion > NOW())
+	`
+
+	rows, err := r.conn.GetDB().QueryContext(ctx, query, identifier)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar credenciales del dispositivo: %w", err)
+	}
+	defer rows.Close()
+
+	var credentials []models.DeviceCredential
+	for rows.Next() {
+		var credential models.DeviceCredential
+		var tokenHash, secreto, descripcion sql.NullString
+		var expiracion sql.NullTime
+		if err := rows.Scan(
+			&credential.IDCredencial,
+			&credential.IDTelemetria,
+			&credential.Tipo,
+			&tokenHash,
+			&secreto,
+			&descripcion,
+			&expiracion,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer credencial del dispositivo: %w", err)
+		}
+		credential.TokenHash = tokenHash.String
+		credential.Secreto = secreto.String
+		credential.Descripcion = descripcion.String
+		if expiracion.Valid {
+			credential.FechaExpiracion = &expiracion.Time
+		}
+		credentials = append(credentials, credential)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar credenciales del dispositivo: %w", err)
+	}
+
+	return credentials, nil
+}
+
+// GetDeviceSensors obtiene el catálogo de sensores activos de un dispositivo
+func (r *Repository) GetDeviceSensors(ctx context.Context, deviceID uint) ([]models.SensorDefinition, error) {
+	query := `
+		SELECT idSensor, idTelemetria, Nombre, Unidad, TipoDato, Slot
+		FROM equipos_telemetria_sensores
+		WHERE idTelemetria = $1 AND Activo = TRUE
+		ORDER BY Nombre
+	`
+
+	rows, err := r.conn.GetDB().QueryContext(ctx, query, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar sensores del dispositivo: %w", err)
+	}
+	defer rows.Close()
+
+	var sensors []models.SensorDefinition
+	for rows.Next() {
+		var sensor models.SensorDefinition
+		var unidad sql.NullString
+		var slot sql.NullInt64
+		if err := rows.Scan(
+			&sensor.IDSensor,
+			&sensor.IDTelemetria,
+			&sensor.Nombre,
+			&unidad,
+			&sensor.TipoDato,
+			&slot,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer sensor del dispositivo: %w", err)
+		}
+		sensor.Unidad = unidad.String
+		if slot.Valid {
+			n := int(slot.Int64)
+			sensor.Slot = &n
+		}
+		sensors = append(sensors, sensor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar sensores del dispositivo: %w", err)
+	}
+
+	return sensors, nil
+}
+
+// InsertMeasurement inserta un nuevo registro de medición junto con sus sensores con nombre
+func (r *Repository) InsertMeasurement(ctx context.Context, measurement *models.Measurement) error {
+	return r.InsertMeasurements(ctx, []*models.Measurement{measurement})
+}
+
+// InsertMeasurements inserta un lote de mediciones junto con sus sensores con nombre
+// en una sola transacción, usando sentencias INSERT de múltiples filas. Asigna a
+// cada medición su idMedicion
+func (r *Repository) InsertMeasurements(ctx context.Context, measurements []*models.Measurement) error {
+	if len(measurements) == 0 {
+		return nil
+	}
+
+	tx, err := r.conn.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción: %w", err)
+	}
+	defer tx.Rollback()
+
+	// RETURNING no garantiza el orden de las filas, por lo que los IDs se reservan
+	// antes desde la secuencia de la columna de identidad y se insertan explícitamente
+	ids, err := nextMeasurementIDs(ctx, tx, len(measurements))
+	if err != nil {
+		return err
+	}
+
+	var sensorArgs []interface{}
+	for i, measurement := range measurements {
+		for name, value := range measurement.Sensores {
+			sensorArgs = append(sensorArgs, ids[i], name, value)
+		}
+	}
+
+	for start := 0; start < len(measurements); start += maxInsertRows {
+		end := min(start+maxInsertRows, len(measurements))
+
+		query := `
+			INSERT INTO equipos_telemetria_datos
+			(idMedicion, idTelemetria, Fecha, FechaDispositivo, FechaRecepcion, Latitud, Longitud, Distancia, Sensor_1, Sensor_2, Sensor_3, Sensor_4, Sensor_5)
+			VALUES ` + placeholders(end-start, 13)
+
+		args := make([]interface{}, 0, (end-start)*13)
+		for i, measurement := range measurements[start:end] {
+			args = append(args,
+				ids[start+i],
+				measurement.IDTelemetria,
+				measurement.Fecha,
+				measurement.FechaDispositivo,
+				measurement.FechaRecepcion,
+				measurement.Latitud,
+				measurement.Longitud,
+				measurement.Distancia,
+				measurement.Sensor1,
+				measurement.Sensor2,
+				measurement.Sensor3,
+				measurement.Sensor4,
+				measurement.Sensor5,
+			)
+		}
+
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("error al insertar mediciones: %w", err)
+		}
+	}
+
+	// Insertar sensores con nombre
+	for start := 0; start < len(sensorArgs); start += maxInsertRows * 3 {
+		chunk := sensorArgs[start:min(start+maxInsertRows*3, len(sensorArgs))]
+
+		query := `
+			INSERT INTO equipos_telemetria_datos_sensores (idMedicion, Nombre, Valor)
+			VALUES ` + placeholders(len(chunk)/3, 3)
+
+		if _, err := tx.ExecContext(ctx, query, chunk...); err != nil {
+			return fmt.Errorf("error al insertar sensores de las mediciones: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar transacción: %w", err)
+	}
+
+	for i, measurement := range measurements {
+		measurement.IDMedicion = ids[i]
+	}
+	return nil
+}
+
+// nextMeasurementIDs reserva n valores de la secuencia de idMedicion
+func nextMeasurementIDs(ctx context.Context, tx *sql.Tx, n int) ([]uint64, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT nextval(pg_get_serial_sequence('equipos_telemetria_datos', 'idmedicion'))
+		FROM generate_series(1, $1)
+	`, n)
+	if err != nil {
+		return nil, fmt.Errorf("error al reservar IDs de mediciones: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]uint64, 0, n)
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error al escanear ID de medición: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar IDs de mediciones: %w", err)
+	}
+	if len(ids) != n {
+		return nil, fmt.Errorf("error al reservar IDs de mediciones: se obtuvieron %d de %d", len(ids), n)
+	}
+
+	return ids, nil
+}
+
+// ListMeasurements consulta el historial de mediciones de un dispositivo usando
+// paginación por cursor sobre (Fecha, idMedicion), apoyada en el índice idx_datos_telemetria_fecha
+func (r *Repository) ListMeasurements(ctx context.Context, q *models.MeasurementQuery) ([]models.Measurement, error) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"idTelemetria = " + arg(q.IDTelemetria)}
+
+	if q.From != nil {
+		conditions = append(conditions, "Fecha >= "+arg(*q.From))
+	}
+	if q.To != nil {
+		conditions = append(conditions, "Fecha < "+arg(*q.To))
+	}
+
+	order := "DESC"
+	comparator := "<"
+	if q.Ascending {
+		order = "ASC"
+		comparator = ">"
+	}
+
+	if q.Cursor != nil {
+		fecha := arg(q.Cursor.Fecha)
+		conditions = append(conditions, fmt.Sprintf("(Fecha %s %s OR (Fecha = %s AND idMedicion %s %s))",
+			comparator, fecha, fecha, comparator, arg(q.Cursor.IDMedicion)))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT idMedicion, idTelemetria, Fecha, FechaDispositivo, FechaRecepcion, Latitud, Longitud, Distancia,
+		       Sensor_1, Sensor_2, Sensor_3, Sensor_4, Sensor_5
+		FROM equipos_telemetria_datos
+		WHERE %s
+		ORDER BY Fecha %s, idMedicion %s
+		LIMIT %s
+	`, strings.Join(conditions, " AND "), order, order, arg(q.Limit))
+
+	rows, err := r.conn.GetDB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar mediciones: %w", err)
+	}
+	defer rows.Close()
+
+	var measurements []models.Measurement
+	for rows.Next() {
+		var m models.Measurement
+		var fechaDispositivo, fechaRecepcion sql.NullTime
+		if err := rows.Scan(
+			&m.IDMedicion,
+			&m.IDTelemetria,
+			&m.Fecha,
+			&fechaDispositivo,
+			&fechaRecepcion,
+			&m.Latitud,
+			&m.Longitud,
+			&m.Distancia,
+			&m.Sensor1,
+			&m.Sensor2,
+			&m.Sensor3,
+			&m.Sensor4,
+			&m.Sensor5,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer medición: %w", err)
+		}
+		if fechaDispositivo.Valid {
+			m.FechaDispositivo = &fechaDispositivo.Time
+		}
+		m.FechaRecepcion = fechaRecepcion.Time
+		measurements = append(measurements, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar mediciones: %w", err)
+	}
+
+	if q.IncludeSensors && len(measurements) > 0 {
+		if err := r.loadMeasurementSensors(ctx, measurements); err != nil {
+			return nil, err
+		}
+	}
+
+	return measurements, nil
+}
+
+// loadMeasurementSensors carga los sensores con nombre de un conjunto de mediciones
+func (r *Repository) loadMeasurementSensors(ctx context.Context, measurements []models.Measurement) error {
+	index := make(map[uint64]*models.Measurement, len(measurements))
+	args := make([]interface{}, len(measurements))
+	for i := range measurements {
+		index[measurements[i].IDMedicion] = &measurements[i]
+		args[i] = measurements[i].IDMedicion
+	}
+
+	query := `
+		SELECT idMedicion, Nombre, Valor
+		FROM equipos_telemetria_datos_sensores
+		WHERE idMedicion IN (` + params(1, len(measurements)) + `)
+	`
+
+	rows, err := r.conn.GetDB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("error al consultar sensores de mediciones: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uint64
+		var name string
+		var value float64
+		if err := rows.Scan(&id, &name, &value); err != nil {
+			return fmt.Errorf("error al leer sensor de medición: %w", err)
+		}
+		if m, ok := index[id]; ok {
+			if m.Sensores == nil {
+				m.Sensores = make(map[string]float64)
+			}
+			m.Sensores[name] = value
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error al iterar sensores de mediciones: %w", err)
+	}
+
+	return nil
+}
+
+// InsertError inserta un nuevo registro de error
+func (r *Repository) InsertError(ctx context.Context, errorRecord *models.ErrorRecord) error {
+	query := `
+		INSERT INTO equipos_telemetria_errores
+		(idTelemetria, Identificador, Fecha, descripcion, idSolicitud)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING idError
+	`
+
+	err := r.conn.GetDB().QueryRowContext(ctx, query,
+		errorRecord.IDTelemetria,
+		errorRecord.Identificador,
+		errorRecord.Fecha,
+		errorRecord.Descripcion,
+		errorRecord.RequestID,
+	).Scan(&errorRecord.IDMedicion)
+	if err != nil {
+		return fmt.Errorf("error al insertar error: %w", err)
+	}
+
+	return nil
+}
+
+// Ping verifica si la conexión a la base de datos está activa
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.conn.Ping(ctx)
+}
+
+// Close cierra la conexión a la base de datos
+func (r *Repository) Close() error {
+	return r.conn.Close()
+}
+
+// execAffected ejecuta una sentencia y retorna si afectó al menos una fila
+func (r *Repository) execAffected(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	result, err := r.conn.GetDB().ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("error al ejecutar actualización: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error al obtener filas afectadas: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// nullableUint convierte un entero nulo de SQL en un puntero
+func nullableUint(v sql.NullInt64) *uint {
+	if !v.Valid {
+		return nil
+	}
+	u := uint(v.Int64)
+	return &u
+}
+
+// isDuplicateKey indica si el error corresponde a una violación de clave única
+func isDuplicateKey(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// isForeignKeyViolation indica si el error corresponde a una referencia inexistente
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation
+}
+
+// params genera n placeholders numerados consecutivos a partir de $start
+func params(start, n int) string {
+	list := make([]string, n)
+	for i := range list {
+		list[i] = fmt.Sprintf("$%d", start+i)
+	}
+	return strings.Join(list, ", ")
+}
+
+// maxInsertRows es la cantidad máxima de filas por sentencia INSERT, acotada
+// para no superar el límite de 65535 parámetros por sentencia
+const maxInsertRows = 1000
+
+// placeholders genera n grupos de cols placeholders numerados, separados por coma
+func placeholders(n, cols int) string {
+	groups := make([]string, n)
+	for i := range groups {
+		groups[i] = "(" + params(i*cols+1, cols) + ")"
+	}
+	return strings.Join(groups, ", ")
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
 )
 
@@ -63,6 +65,22 @@ func (c *Cache) GetDevice(ctx context.Context, identifier string) (*models.Devic
 		device.TiempoFueraLinea = val
 	}
 
+	// Parsear idGrupo
+	if val, ok := result["idGrupo"]; ok && val != "" {
+		if id, err := strconv.ParseUint(val, 10, 64); err == nil {
+			groupID := uint(id)
+			device.IDGrupo = &groupID
+		}
+	}
+
+	// Parsear activo (las entradas anteriores a este campo se consideran activas)
+	device.Activo = true
+	if val, ok := result["activo"]; ok {
+		if active, err := strconv.ParseBool(val); err == nil {
+			device.Activo = active
+		}
+	}
+
 	// Parsear latitud
 	if val, ok := result["latitud"]; ok {
 		var lat float64
@@ -92,6 +110,12 @@ func (c *Cache) SetDevice(ctx context.Context, device *models.Device) error {
 		"nombre":           device.Nombre,
 		"ultimaConexion":   device.UltimaConexion.Format(time.RFC3339),
 		"tiempoFueraLinea": device.TiempoFueraLinea,
+		"activo":           strconv.FormatBool(device.Activo),
+		"idGrupo":          "",
+	}
+
+	if device.IDGrupo != nil {
+		data["idGrupo"] = *device.IDGrupo
 	}
 
 	if device.Latitud != nil {
@@ -126,6 +150,355 @@ func (c *Cache) DeleteDevice(ctx context.Context, identifier string) error {
 	return nil
 }
 
+// GetDeviceCredentials obtiene las credenciales de un dispositivo desde el caché.
+// El segundo valor indica si la entrada existía (una lista vacía también se cachea)
+func (c *Cache) GetDeviceCredentials(ctx context.Context, identifier string) ([]models.DeviceCredential, bool, error) {
+	key := fmt.Sprintf("device:%s:credentials", identifier)
+
+	val, err := c.conn.GetClient().Get(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, false, nil // Credenciales no están en caché
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error al obtener credenciales del caché: %w", err)
+	}
+
+	var entries []credentialEntry
+	if err := json.Unmarshal([]byte(val), &entries); err != nil {
+		return nil, false, fmt.Errorf("error al decodificar credenciales del caché: %w", err)
+	}
+
+	credentials := make([]models.DeviceCredential, len(entries))
+	for i, entry := range entries {
+		credentials[i] = models.DeviceCredential{
+			IDCredencial:    entry.IDCredencial,
+			IDTelemetria:    entry.IDTelemetria,
+			Tipo:            entry.Tipo,
+			TokenHash:       entry.TokenHash,
+			Secreto:         entry.Secreto,
+			FechaExpiracion: entry.FechaExpiracion,
+		}
+	}
+
+	return credentials, true, nil
+}
+
+// SetDeviceCredentials almacena las credenciales de un dispositivo en caché
+func (c *Cache) SetDeviceCredentials(ctx context.Context, identifier string, credentials []models.DeviceCredential) error {
+	key := fmt.Sprintf("device:%s:credentials", identifier)
+
+	entries := make([]credentialEntry, len(credentials))
+	for i, credential := range credentials {
+		entries[i] = credentialEntry{
+			IDCredencial:    credential.IDCredencial,
+			IDTelemetria:    credential.IDTelemetria,
+			Tipo:            credential.Tipo,
+			TokenHash:       credential.TokenHash,
+			Secreto:         credential.Secreto,
+			FechaExpiracion: credential.FechaExpiracion,
+		}
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("error al codificar credenciales: %w", err)
+	}
+
+	if err := c.conn.GetClient().Set(ctx, key, data, c.conn.GetTTL()).Err(); err != nil {
+		return fmt.Errorf("error al establecer credenciales en caché: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteDeviceCredentials elimina las credenciales de un dispositivo del caché
+func (c *Cache) DeleteDeviceCredentials(ctx context.Context, identifier string) error {
+	key := fmt.Sprintf("device:%s:credentials", identifier)
+
+	if err := c.conn.GetClient().Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("error al eliminar credenciales del caché: %w", err)
+	}
+
+	return nil
+}
+
+// credentialEntry es la representación en caché de una credencial
+// (models.DeviceCredential omite el hash y el secreto al serializar a JSON)
+type credentialEntry struct {
+	IDCredencial    uint       `json:"idCredencial"`
+	IDTelemetria    uint       `json:"idTelemetria"`
+	Tipo            string     `json:"tipo"`
+	TokenHash       string     `json:"tokenHash,omitempty"`
+	Secreto         string     `json:"secreto,omitempty"`
+	FechaExpiracion *time.Time `json:"fechaExpiracion,omitempty"`
+}
+
+// GetDeviceSensors obtiene el catálogo de sensores de un dispositivo desde el caché.
+// El segundo valor indica si la entrada existía (un catálogo vacío también se cachea)
+func (c *Cache) GetDeviceSensors(ctx context.Context, identifier string) ([]models.SensorDefinition, bool, error) {
+	key := fmt.Sprintf("device:%s:sensors", identifier)
+
+	val, err := c.conn.GetClient().Get(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, false, nil // Catálogo no está en caché
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error al obtener sensores del caché: %w", err)
+	}
+
+	var sensors []models.SensorDefinition
+	if err := json.Unmarshal([]byte(val), &sensors); err != nil {
+		return nil, false, fmt.Errorf("error al decodificar sensores del caché: %w", err)
+	}
+
+	return sensors, true, nil
+}
+
+// SetDeviceSensors almacena el catálogo de sensores de un dispositivo en caché
+func (c *Cache) SetDeviceSensors(ctx context.Context, identifier string, sensors []models.SensorDefinition) error {
+	key := fmt.Sprintf("device:%s:sensors", identifier)
+
+	data, err := json.Marshal(sensors)
+	if err != nil {
+		return fmt.Errorf("error al codificar sensores: %w", err)
+	}
+
+	if err := c.conn.GetClient().Set(ctx, key, data, c.conn.GetTTL()).Err(); err != nil {
+		return fmt.Errorf("error al establecer sensores en caché: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteDeviceSensors elimina el catálogo de sensores de un dispositivo del caché
+func (c *Cache) DeleteDeviceSensors(ctx context.Context, identifier string) error {
+	key := fmt.Sprintf("device:%s:sensors", identifier)
+
+	if err := c.conn.GetClient().Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("error al eliminar sensores del caché: %w", err)
+	}
+
+	return nil
+}
+
+// GetGeofenceStates obtiene la permanencia del dispositivo en cada geocerca en la que está dentro
+func (c *Cache) GetGeofenceStates(ctx context.Context, identifier string) (map[uint]models.GeofenceState, error) {
+	key := fmt.Sprintf("device:%s:geofences", identifier)
+
+	result, err := c.conn.GetClient().HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener estado de geocercas del caché: %w", err)
+	}
+
+	states := make(map[uint]models.GeofenceState, len(result))
+	for field, val := range result {
+		id, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		var state models.GeofenceState
+		if err := json.Unmarshal([]byte(val), &state); err != nil {
+			continue
+		}
+		states[uint(id)] = state
+	}
+
+	return states, nil
+}
+
+// SetGeofenceState almacena la permanencia del dispositivo en una geocerca
+func (c *Cache) SetGeofenceState(ctx context.Context, identifier string, geofenceID uint, state *models.GeofenceState) error {
+	key := fmt.Sprintf("device:%s:geofences", identifier)
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error al codificar estado de geocerca: %w", err)
+	}
+
+	if err := c.conn.GetClient().HSet(ctx, key, strconv.FormatUint(uint64(geofenceID), 10), data).Err(); err != nil {
+		return fmt.Errorf("error al establecer estado de geocerca en caché: %w", err)
+	}
+
+	// Refrescar expiración
+	if err := c.conn.GetClient().Expire(ctx, key, c.conn.GetTTL()).Err(); err != nil {
+		return fmt.Errorf("error al refrescar expiración del caché: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteGeofenceState elimina la permanencia del dispositivo en una geocerca
+func (c *Cache) DeleteGeofenceState(ctx context.Context, identifier string, geofenceID uint) error {
+	key := fmt.Sprintf("device:%s:geofences", identifier)
+
+	if err := c.conn.GetClient().HDel(ctx, key, strconv.FormatUint(uint64(geofenceID), 10)).Err(); err != nil {
+		return fmt.Errorf("error al eliminar estado de geocerca del caché: %w", err)
+	}
+
+	return nil
+}
+
+// GetAlertStates obtiene el estado de cada regla de alerta evaluada para el dispositivo
+func (c *Cache) GetAlertStates(ctx context.Context, identifier string) (map[uint]models.AlertState, error) {
+	key := fmt.Sprintf("device:%s:alerts", identifier)
+
+	result, err := c.conn.GetClient().HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener estado de alertas del caché: %w", err)
+	}
+
+	states := make(map[uint]models.AlertState, len(result))
+	for field, val := range result {
+		id, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		var state models.AlertState
+		if err := json.Unmarshal([]byte(val), &state); err != nil {
+			continue
+		}
+		states[uint(id)] = state
+	}
+
+	return states, nil
+}
+
+// SetAlertState almacena el estado de una regla de alerta para el dispositivo
+func (c *Cache) SetAlertState(ctx context.Context, identifier string, ruleID uint, state *models.AlertState) error {
+	key := fmt.Sprintf("device:%s:alerts", identifier)
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error al codificar estado de alerta: %w", err)
+	}
+
+	if err := c.conn.GetClient().HSet(ctx, key, strconv.FormatUint(uint64(ruleID), 10), data).Err(); err != nil {
+		return fmt.Errorf("error al establecer estado de alerta en caché: %w", err)
+	}
+
+	// Refrescar expiración
+	if err := c.conn.GetClient().Expire(ctx, key, c.conn.GetTTL()).Err(); err != nil {
+		return fmt.Errorf("error al refrescar expiración del caché: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteAlertState elimina el estado de una regla de alerta para el dispositivo
+func (c *Cache) DeleteAlertState(ctx context.Context, identifier string, ruleID uint) error {
+	key := fmt.Sprintf("device:%s:alerts", identifier)
+
+	if err := c.conn.GetClient().HDel(ctx, key, strconv.FormatUint(uint64(ruleID), 10)).Err(); err != nil {
+		return fmt.Errorf("error al eliminar estado de alerta del caché: %w", err)
+	}
+
+	return nil
+}
+
+// Publish publica un mensaje en un canal Redis pub/sub
+func (c *Cache) Publish(ctx context.Context, channel string, payload []byte) error {
+	if err := c.conn.GetClient().Publish(ctx, channel, payload).Err(); err != nil {
+		return fmt.Errorf("error al publicar mensaje en Redis: %w", err)
+	}
+	return nil
+}
+
+// Subscribe se suscribe a un canal Redis pub/sub. El cliente se reconecta
+// automáticamente; el canal retornado se cierra al cancelar el contexto
+func (c *Cache) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	pubsub := c.conn.GetClient().Subscribe(ctx, channel)
+
+	// Confirmar la suscripción antes de retornar
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("error al suscribirse al canal Redis %s: %w", channel, err)
+	}
+
+	out := make(chan []byte, 256)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case out <- []byte(msg.Payload):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// RegisterNonce registra un nonce de solicitud firmada con SETNX.
+// Retorna false si el nonce ya existía (solicitud repetida)
+func (c *Cache) RegisterNonce(ctx context.Context, identifier, nonce string, ttl time.Duration) (bool, error) {
+	key := fmt.Sprintf("nonce:%s:%s", identifier, nonce)
+
+	created, err := c.conn.GetClient().SetNX(ctx, key, 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("error al registrar nonce en caché: %w", err)
+	}
+
+	return created, nil
+}
+
+// ClaimMessage reserva el ID de un mensaje con SETNX. Si ya existía retorna el
+// resultado guardado, vacío mientras el mensaje original se procesa
+func (c *Cache) ClaimMessage(ctx context.Context, identifier, messageID string, ttl time.Duration) (bool, string, error) {
+	key := fmt.Sprintf("message:%s:%s", identifier, messageID)
+
+	created, err := c.conn.GetClient().SetNX(ctx, key, "", ttl).Result()
+	if err != nil {
+		return false, "", fmt.Errorf("error al reservar mensaje en caché: %w", err)
+	}
+	if created {
+		return true, "", nil
+	}
+
+	result, err := c.conn.GetClient().Get(ctx, key).Result()
+	if err == redis.Nil {
+		return false, "", nil // Expiró entre SETNX y GET; se trata como en proceso
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("error al obtener mensaje del caché: %w", err)
+	}
+
+	return false, result, nil
+}
+
+// CompleteMessage guarda el resultado de un mensaje procesado
+func (c *Cache) CompleteMessage(ctx context.Context, identifier, messageID, result string, ttl time.Duration) error {
+	key := fmt.Sprintf("message:%s:%s", identifier, messageID)
+
+	if err := c.conn.GetClient().Set(ctx, key, result, ttl).Err(); err != nil {
+		return fmt.Errorf("error al guardar resultado del mensaje en caché: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseMessage elimina la reserva de un mensaje
+func (c *Cache) ReleaseMessage(ctx context.Context, identifier, messageID string) error {
+	key := fmt.Sprintf("message:%s:%s", identifier, messageID)
+
+	if err := c.conn.GetClient().Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("error al liberar mensaje en caché: %w", err)
+	}
+
+	return nil
+}
+
 // UpdateDeviceLocation actualiza solo los campos de ubicación en caché
 func (c *Cache) UpdateDeviceLocation(ctx context.Context, identifier string, latitud, longitud float64, timestamp time.Time) error {
 	key := fmt.Sprintf("device:%s", identifier)
@@ -31,6 +31,7 @@ func NewConnection(cfg *config.RedisConfig) (*Connection, error) {
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("error al hacer ping a Redis: %w", err)
 	}
 
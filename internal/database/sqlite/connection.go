@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+)
+
+// schema es el esquema de la base de datos, equivalente a migrations/schema.sql.
+// Se aplica al abrir la conexión; todas las sentencias son idempotentes
+//
+//go:embed schema.sql
+var schema string
+
+// addedColumns son las columnas incorporadas al esquema después de su primera
+// versión. Se agregan a las bases existentes antes de aplicar el esquema, ya que
+// CREATE TABLE IF NOT EXISTS no modifica tablas ya creadas
+var addedColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"equipos_telemetria_errores", "idSolicitud", "VARCHAR(128)"},
+}
+
+// Connection representa una conexión a una base de datos SQLite embebida
+type Connection struct {
+	db *sql.DB
+}
+
+// NewConnection abre (o crea) la base de datos SQLite y aplica el esquema
+func NewConnection(cfg *config.SQLiteConfig) (*Connection, error) {
+	if dir := filepath.Dir(cfg.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("error al crear directorio de la base de datos: %w", err)
+		}
+	}
+
+	params := url.Values{}
+	params.Set("_foreign_keys", "on")
+	params.Set("_journal_mode", "WAL")
+	params.Set("_busy_timeout", fmt.Sprintf("%d", cfg.BusyTimeout.Milliseconds()))
+
+	db, err := sql.Open("sqlite3", "file:"+cfg.Path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir la base de datos: %w", err)
+	}
+
+	// SQLite admite un único escritor; una sola conexión evita errores de bloqueo
+	db.SetMaxOpenConns(1)
+
+	// Probar conexión y aplicar esquema
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error al hacer ping a la base de datos: %w", err)
+	}
+
+	if err := addMissingColumns(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error al aplicar el esquema de la base de datos: %w", err)
+	}
+
+	return &Connection{db: db}, nil
+}
+
+// addMissingColumns agrega a las tablas existentes las columnas de addedColumns que
+// no tengan. Las tablas que aún no existen las crea el esquema completo
+func addMissingColumns(ctx context.Context, db *sql.DB) error {
+	for _, c := range addedColumns {
+		var columns, found int
+		err := db.QueryRowContext(ctx,
+			`SELECT COUNT(*), COUNT(CASE WHEN name = ? THEN 1 END) FROM pragma_table_info(?)`,
+			c.column, c.table,
+		).Scan(&columns, &found)
+		if err != nil {
+			return fmt.Errorf("error al consultar columnas de %s: %w", c.table, err)
+		}
+		if columns == 0 || found > 0 {
+			continue
+		}
+
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition)); err != nil {
+			return fmt.Errorf("error al agregar columna %s.%s: %w", c.table, c.column, err)
+		}
+	}
+	return nil
+}
+
+// GetDB retorna la conexión subyacente a la base de datos
+func (c *Connection) GetDB() *sql.DB {
+	return c.db
+}
+
+// Ping verifica si la conexión a la base de datos está activa
+func (c *Connection) Ping(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
+// Close cierra la conexión a la base de datos
+func (c *Connection) Close() error {
+	return c.db.Close()
+}
@@ -0,0 +1,748 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// Repository implementa la interfaz database.Repository para SQLite
+type Repository struct {
+	conn *Connection
+}
+
+// NewRepository crea un nuevo repositorio SQLite
+func NewRepository(conn *Connection) *Repository {
+	return &Repository{conn: conn}
+}
+
+// GetDeviceByIdentifier obtiene un dispositivo por su identificador
+func (r *Repository) GetDeviceByIdentifier(ctx context.Context, identifier string) (*models.Device, error) {
+	query := `
+		SELECT idTelemetria, Identificador, Nombre, UltimaConexion, TiempoFueraLinea, Activo, idGrupo
+		FROM equipos_telemetria
+		WHERE Identificador = ?
+	`
+
+	var device models.Device
+	var groupID sql.NullInt64
+	err := r.conn.GetDB().QueryRowContext(ctx, query, identifier).Scan(
+		&device.IDTelemetria,
+		&device.Identificador,
+		&device.Nombre,
+		&device.UltimaConexion,
+		&device.TiempoFueraLinea,
+		&device.Activo,
+		&groupID,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil // Dispositivo no encontrado
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar dispositivo: %w", err)
+	}
+	device.IDGrupo = nullableUint(groupID)
+
+	return &device, nil
+}
+
+// UpdateDeviceConnection actualiza la marca de tiempo de la última conexión de un dispositivo
+func (r *Repository) UpdateDeviceConnection(ctx context.Context, deviceID uint, timestamp time.Time) error {
+	query := `
+		UPDATE equipos_telemetria
+		SET UltimaConexion = ?
+		WHERE idTelemetria = ?
+	`
+
+	updated, err := r.execAffected(ctx, query, timestamp, deviceID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar conexión del dispositivo: %w", err)
+	}
+
+	if !updated {
+		return fmt.Errorf("dispositivo no encontrado: %d", deviceID)
+	}
+
+	return nil
+}
+
+// ListDevices obtiene todos los dispositivos registrados
+func (r *Repository) ListDevices(ctx context.Context) ([]models.Device, error) {
+	query := `
+		SELECT idTelemetria, Identificador, Nombre, UltimaConexion, TiempoFueraLinea, Activo, idGrupo
+		FROM equipos_telemetria
+		ORDER BY Identificador
+	`
+
+	return r.queryDevices(ctx, query)
+}
+
+// queryDevices ejecuta una consulta de dispositivos con las columnas
+// idTelemetria, Identificador, Nombre, UltimaConexion, TiempoFueraLinea, Activo
+func (r *Repository) queryDevices(ctx context.Context, query string, args ...interface{}) ([]models.Device, error) {
+	rows, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar dispositivos: %w", err)
+	}
+	defer rows.Close()
+
+	var devices []models.Device
+	for rows.Next() {
+		var device models.Device
+		var groupID sql.NullInt64
+		if err := rows.Scan(
+			&device.IDTelemetria,
+			&device.Identificador,
+			&device.Nombre,
+			&device.UltimaConexion,
+			&device.TiempoFueraLinea,
+			&device.Activo,
+			&groupID,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer dispositivo: %w", err)
+		}
+		device.IDGrupo = nullableUint(groupID)
+		devices = append(devices, device)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar dispositivos: %w", err)
+	}
+
+	return devices, nil
+}
+
+// CreateDevice inserta un nuevo dispositivo
+func (r *Repository) CreateDevice(ctx context.Context, device *models.Device) error {
+	query := `
+		INSERT INTO equipos_telemetria (Identificador, Nombre, UltimaConexion, TiempoFueraLinea, Activo, idGrupo)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.exec(ctx, query,
+		device.Identificador,
+		device.Nombre,
+		device.UltimaConexion,
+		device.TiempoFueraLinea,
+		device.Activo,
+		device.IDGrupo,
+	)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return database.ErrDuplicateKey
+		}
+		if isForeignKeyViolation(err) {
+			return database.ErrInvalidReference
+		}
+		return fmt.Errorf("error al insertar dispositivo: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error al obtener último ID insertado: %w", err)
+	}
+
+	device.IDTelemetria = uint(id)
+	return nil
+}
+
+// UpdateDevice actualiza identificador, nombre, tiempo fuera de línea y grupo de un dispositivo
+func (r *Repository) UpdateDevice(ctx context.Context, device *models.Device) error {
+	query := `
+		UPDATE equipos_telemetria
+		SET Identificador = ?, Nombre = ?, TiempoFueraLinea = ?, idGrupo = ?
+		WHERE idTelemetria = ?
+	`
+
+	_, err := r.exec(ctx, query,
+		device.Identificador,
+		device.Nombre,
+		device.TiempoFueraLinea,
+		device.IDGrupo,
+		device.IDTelemetria,
+	)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return database.ErrDuplicateKey
+		}
+		if isForeignKeyViolation(err) {
+			return database.ErrInvalidReference
+		}
+		return fmt.Errorf("error al actualizar dispositivo: %w", err)
+	}
+
+	return nil
+}
+
+// SetDeviceActive habilita o deshabilita un dispositivo
+func (r *Repository) SetDeviceActive(ctx context.Context, deviceID uint, active bool) error {
+	query := `
+		UPDATE equipos_telemetria
+		SET Activo = ?
+		WHERE idTelemetria = ?
+	`
+
+	if _, err := r.exec(ctx, query, active, deviceID); err != nil {
+		return fmt.Errorf("error al actualizar estado del dispositivo: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteDevice elimina un dispositivo (sus mediciones se eliminan en cascada)
+func (r *Repository) DeleteDevice(ctx context.Context, deviceID uint) error {
+	query := `
+		DELETE FROM equipos_telemetria
+		WHERE idTelemetria = ?
+	`
+
+	deleted, err := r.execAffected(ctx, query, deviceID)
+	if err != nil {
+		return fmt.Errorf("error al eliminar dispositivo: %w", err)
+	}
+
+	if !deleted {
+		return fmt.Errorf("dispositivo no encontrado: %d", deviceID)
+	}
+
+	return nil
+}
+
+// ListOverdueDevices obtiene los dispositivos activos, aún no marcados fuera de línea,
+// cuya última conexión más su TiempoFueraLinea ya transcurrió.
+// Un TiempoFueraLinea de 00:00:00 deshabilita la detección
+func (r *Repository) ListOverdueDevices(ctx context.Context, now time.Time) ([]models.Device, error) {
+	query := `
+		SELECT idTelemetria, Identificador, Nombre, UltimaConexion, TiempoFueraLinea, Activo, idGrupo
+		FROM equipos_telemetria
+		WHERE Activo = 1
+		  AND FueraLinea = 0
+	`
+
+	candidates, err := r.queryDevices(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite no tiene un tipo TIME: el plazo se evalúa al leer cada dispositivo
+	var devices []models.Device
+	for _, device := range candidates {
+		threshold, ok := parseOfflineThreshold(device.TiempoFueraLinea)
+		if ok && device.UltimaConexion.Add(threshold).Before(now) {
+			devices = append(devices, device)
+		}
+	}
+
+	return devices, nil
+}
+
+// ListOfflineDevices obtiene los dispositivos actualmente marcados fuera de línea
+func (r *Repository) ListOfflineDevices(ctx context.Context) ([]models.Device, error) {
+	query := `
+		SELECT idTelemetria, Identificador, Nombre, UltimaConexion, TiempoFueraLinea, Activo, idGrupo, FechaFueraLinea
+		FROM equipos_telemetria
+		WHERE FueraLinea = 1
+		ORDER BY FechaFueraLinea
+	`
+
+	rows, err := r.query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar dispositivos fuera de línea: %w", err)
+	}
+	defer rows.Close()
+
+	var devices []models.Device
+	for rows.Next() {
+		var device models.Device
+		var groupID sql.NullInt64
+		var fechaFueraLinea sql.NullTime
+		if err := rows.Scan(
+			&device.IDTelemetria,
+			&device.Identificador,
+			&device.Nombre,
+			&device.UltimaConexion,
+			&device.TiempoFueraLinea,
+			&device.Activo,
+			&groupID,
+			&fechaFueraLinea,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer dispositivo: %w", err)
+		}
+		device.IDGrupo = nullableUint(groupID)
+		device.FueraLinea = true
+		if fechaFueraLinea.Valid {
+			device.FechaFueraLinea = &fechaFueraLinea.Time
+		}
+		devices = append(devices, device)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar dispositivos: %w", err)
+	}
+
+	return devices, nil
+}
+
+// MarkDeviceOffline marca un dispositivo fuera de línea si su última conexión es
+// anterior a cutoff. Retorna true solo si el dispositivo no estaba marcado ni volvió
+// a conectarse desde la revisión, de modo que cada corte se registra una única vez
+func (r *Repository) MarkDeviceOffline(ctx context.Context, deviceID uint, timestamp, cutoff time.Time) (bool, error) {
+	query := `
+		UPDATE equipos_telemetria
+		SET FueraLinea = 1, FechaFueraLinea = ?
+		WHERE idTelemetria = ? AND FueraLinea = 0 AND UltimaConexion < ?
+	`
+
+	return r.execAffected(ctx, query, timestamp, deviceID, cutoff)
+}
+
+// MarkDeviceOnline quita la marca fuera de línea de un dispositivo.
+// Retorna true solo si el dispositivo estaba marcado fuera de línea
+func (r *Repository) MarkDeviceOnline(ctx context.Context, deviceID uint) (bool, error) {
+	query := `
+		UPDATE equipos_telemetria
+		SET FueraLinea = 0, FechaFueraLinea = NULL
+		WHERE idTelemetria = ? AND FueraLinea = 1
+	`
+
+	return r.execAffected(ctx, query, deviceID)
+}
+
+// GetDeviceCredentials obtiene las credenciales activas y vigentes de un dispositivo
+func (r *Repository) GetDeviceCredentials(ctx context.Context, identifier string) ([]models.DeviceCredential, error) {
+	query := `
+		SELECT c.idCredencial, c.idTelemetria, c.Tipo, c.TokenHash, c.Secreto, c.Descripcion, c.FechaExpiracion
+		FROM equipos_telemetria_credenciales c
+		INNER JOIN equipos_telemetria et ON et.idTelemetria = c.idTelemetria
+		WHERE et.Identificador = ?
+		  AND c.Activo = 1
+		  AND (c.FechaExpiracion IS NULL OR c.FechaExpiracion > ?)
+	`
+
+	rows, err := r.query(ctx, query, identifier, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar credenciales del dispositivo: %w", err)
+	}
+	defer rows.Close()
+
+	var credentials []models.DeviceCredential
+	for rows.Next() {
+		var credential models.DeviceCredential
+		var tokenHash, secreto, descripcion sql.NullString
+		var expiracion sql.NullTime
+		if err := rows.Scan(
+			&credential.IDCredencial,
+			&credential.IDTelemetria,
+			&credential.Tipo,
+			&tokenHash,
+			&secreto,
+			&descripcion,
+			&expiracion,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer credencial del dispositivo: %w", err)
+		}
+		credential.TokenHash = tokenHash.String
+		credential.Secreto = secreto.String
+		credential.Descripcion = descripcion.String
+		if expiracion.Valid {
+			credential.FechaExpiracion = &expiracion.Time
+		}
+		credentials = append(credentials, credential)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar credenciales del dispositivo: %w", err)
+	}
+
+	return credentials, nil
+}
+
+// GetDeviceSensors obtiene el catálogo de sensores activos de un dispositivo
+func (r *Repository) GetDeviceSensors(ctx context.Context, deviceID uint) ([]models.SensorDefinition, error) {
+	query := `
+		SELECT idSensor, idTelemetria, Nombre, Unidad, TipoDato, Slot
+		FROM equipos_telemetria_sensores
+		WHERE idTelemetria = ? AND Activo = 1
+		ORDER BY Nombre
+	`
+
+	rows, err := r.query(ctx, query, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar sensores del dispositivo: %w", err)
+	}
+	defer rows.Close()
+
+	var sensors []models.SensorDefinition
+	for rows.Next() {
+		var sensor models.SensorDefinition
+		var unidad sql.NullString
+		var slot sql.NullInt64
+		if err := rows.Scan(
+			&sensor.IDSensor,
+			&sensor.IDTelemetria,
+			&sensor.Nombre,
+			&unidad,
+			&sensor.TipoDato,
+			&slot,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer sensor del dispositivo: %w", err)
+		}
+		sensor.Unidad = unidad.String
+		if slot.Valid {
+			n := int(slot.Int64)
+			sensor.Slot = &n
+		}
+		sensors = append(sensors, sensor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar sensores del dispositivo: %w", err)
+	}
+
+	return sensors, nil
+}
+
+// InsertMeasurement inserta un nuevo registro de medición junto con sus sensores con nombre
+func (r *Repository) InsertMeasurement(ctx context.Context, measurement *models.Measurement) error {
+	return r.InsertMeasurements(ctx, []*models.Measurement{measurement})
+}
+
+// InsertMeasurements inserta un lote de mediciones junto con sus sensores con nombre
+// en una sola transacción, usando sentencias INSERT de múltiples filas. Asigna a
+// cada medición su idMedicion
+func (r *Repository) InsertMeasurements(ctx context.Context, measurements []*models.Measurement) error {
+	if len(measurements) == 0 {
+		return nil
+	}
+
+	tx, err := r.conn.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids := make([]uint64, len(measurements))
+	var sensorArgs []interface{}
+
+	for start := 0; start < len(measurements); start += maxInsertRows {
+		chunk := measurements[start:min(start+maxInsertRows, len(measurements))]
+
+		query := `
+			INSERT INTO equipos_telemetria_datos 
+			(idTelemetria, Fecha, FechaDispositivo, FechaRecepcion, Latitud, Longitud, Distancia, Sensor_1, Sensor_2, Sensor_3, Sensor_4, Sensor_5)
+			VALUES ` + placeholders(len(chunk), "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+
+		args := make([]interface{}, 0, len(chunk)*12)
+		for _, measurement := range chunk {
+			args = append(args,
+				measurement.IDTelemetria,
+				measurement.Fecha,
+				measurement.FechaDispositivo,
+				measurement.FechaRecepcion,
+				measurement.Latitud,
+				measurement.Longitud,
+				measurement.Distancia,
+				measurement.Sensor1,
+				measurement.Sensor2,
+				measurement.Sensor3,
+				measurement.Sensor4,
+				measurement.Sensor5,
+			)
+		}
+
+		result, err := tx.ExecContext(ctx, query, utcArgs(args...)...)
+		if err != nil {
+			return fmt.Errorf("error al insertar mediciones: %w", err)
+		}
+
+		// LastInsertId retorna el ID de la última fila del INSERT. Con una única
+		// conexión de escritura las filas de la sentencia reciben IDs consecutivos
+		lastID, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("error al obtener último ID insertado: %w", err)
+		}
+
+		firstID := uint64(lastID) - uint64(len(chunk)) + 1
+		for i, measurement := range chunk {
+			id := firstID + uint64(i)
+			ids[start+i] = id
+			for name, value := range measurement.Sensores {
+				sensorArgs = append(sensorArgs, id, name, value)
+			}
+		}
+	}
+
+	// Insertar sensores con nombre
+	for start := 0; start < len(sensorArgs); start += maxInsertRows * 3 {
+		chunk := sensorArgs[start:min(start+maxInsertRows*3, len(sensorArgs))]
+
+		query := `
+			INSERT INTO equipos_telemetria_datos_sensores (idMedicion, Nombre, Valor)
+			VALUES ` + placeholders(len(chunk)/3, "(?, ?, ?)")
+
+		if _, err := tx.ExecContext(ctx, query, chunk...); err != nil {
+			return fmt.Errorf("error al insertar sensores de las mediciones: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar transacción: %w", err)
+	}
+
+	for i, measurement := range measurements {
+		measurement.IDMedicion = ids[i]
+	}
+	return nil
+}
+
+// ListMeasurements consulta el historial de mediciones de un dispositivo usando
+// paginación por cursor sobre (Fecha, idMedicion), apoyada en el índice idx_telemetria_fecha
+func (r *Repository) ListMeasurements(ctx context.Context, q *models.MeasurementQuery) ([]models.Measurement, error) {
+	conditions := []string{"idTelemetria = ?"}
+	args := []interface{}{q.IDTelemetria}
+
+	if q.From != nil {
+		conditions = append(conditions, "Fecha >= ?")
+		args = append(args, *q.From)
+	}
+	if q.To != nil {
+		conditions = append(conditions, "Fecha < ?")
+		args = append(args, *q.To)
+	}
+
+	order := "DESC"
+	comparator := "<"
+	if q.Ascending {
+		order = "ASC"
+		comparator = ">"
+	}
+
+	if q.Cursor != nil {
+		conditions = append(conditions, fmt.Sprintf("(Fecha %s ? OR (Fecha = ? AND idMedicion %s ?))", comparator, comparator))
+		args = append(args, q.Cursor.Fecha, q.Cursor.Fecha, q.Cursor.IDMedicion)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT idMedicion, idTelemetria, Fecha, FechaDispositivo, FechaRecepcion, Latitud, Longitud, Distancia,
+		       Sensor_1, Sensor_2, Sensor_3, Sensor_4, Sensor_5
+		FROM equipos_telemetria_datos
+		WHERE %s
+		ORDER BY Fecha %s, idMedicion %s
+		LIMIT ?
+	`, strings.Join(conditions, " AND "), order, order)
+	args = append(args, q.Limit)
+
+	rows, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar mediciones: %w", err)
+	}
+	defer rows.Close()
+
+	var measurements []models.Measurement
+	for rows.Next() {
+		var m models.Measurement
+		var fechaDispositivo, fechaRecepcion sql.NullTime
+		if err := rows.Scan(
+			&m.IDMedicion,
+			&m.IDTelemetria,
+			&m.Fecha,
+			&fechaDispositivo,
+			&fechaRecepcion,
+			&m.Latitud,
+			&m.Longitud,
+			&m.Distancia,
+			&m.Sensor1,
+			&m.Sensor2,
+			&m.Sensor3,
+			&m.Sensor4,
+			&m.Sensor5,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer medición: %w", err)
+		}
+		if fechaDispositivo.Valid {
+			m.FechaDispositivo = &fechaDispositivo.Time
+		}
+		m.FechaRecepcion = fechaRecepcion.Time
+		measurements = append(measurements, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar mediciones: %w", err)
+	}
+
+	if q.IncludeSensors && len(measurements) > 0 {
+		if err := r.loadMeasurementSensors(ctx, measurements); err != nil {
+			return nil, err
+		}
+	}
+
+	return measurements, nil
+}
+
+// loadMeasurementSensors carga los sensores con nombre de un conjunto de mediciones
+func (r *Repository) loadMeasurementSensors(ctx context.Context, measurements []models.Measurement) error {
+	index := make(map[uint64]*models.Measurement, len(measurements))
+	args := make([]interface{}, len(measurements))
+	for i := range measurements {
+		index[measurements[i].IDMedicion] = &measurements[i]
+		args[i] = measurements[i].IDMedicion
+	}
+
+	query := `
+		SELECT idMedicion, Nombre, Valor
+		FROM equipos_telemetria_datos_sensores
+		WHERE idMedicion IN (` + placeholders(len(measurements), "?") + `)
+	`
+
+	rows, err := r.query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("error al consultar sensores de mediciones: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uint64
+		var name string
+		var value float64
+		if err := rows.Scan(&id, &name, &value); err != nil {
+			return fmt.Errorf("error al leer sensor de medición: %w", err)
+		}
+		if m, ok := index[id]; ok {
+			if m.Sensores == nil {
+				m.Sensores = make(map[string]float64)
+			}
+			m.Sensores[name] = value
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error al iterar sensores de mediciones: %w", err)
+	}
+
+	return nil
+}
+
+// InsertError inserta un nuevo registro de error
+func (r *Repository) InsertError(ctx context.Context, errorRecord *models.ErrorRecord) error {
+	query := `
+		INSERT INTO equipos_telemetria_errores 
+		(idTelemetria, Identificador, Fecha, descripcion, idSolicitud)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	result, err := r.exec(ctx, query,
+		errorRecord.IDTelemetria,
+		errorRecord.Identificador,
+		errorRecord.Fecha,
+		errorRecord.Descripcion,
+		errorRecord.RequestID,
+	)
+	if err != nil {
+		return fmt.Errorf("error al insertar error: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error al obtener último ID insertado: %w", err)
+	}
+
+	errorRecord.IDMedicion = uint64(id)
+	return nil
+}
+
+// Ping verifica si la conexión a la base de datos está activa
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.conn.Ping(ctx)
+}
+
+// Close cierra la conexión a la base de datos
+func (r *Repository) Close() error {
+	return r.conn.Close()
+}
+
+// exec ejecuta una sentencia con las fechas de los argumentos normalizadas a UTC
+func (r *Repository) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.conn.GetDB().ExecContext(ctx, query, utcArgs(args...)...)
+}
+
+// query ejecuta una consulta con las fechas de los argumentos normalizadas a UTC
+func (r *Repository) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return r.conn.GetDB().QueryContext(ctx, query, utcArgs(args...)...)
+}
+
+// execAffected ejecuta una sentencia y retorna si afectó al menos una fila
+func (r *Repository) execAffected(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	result, err := r.exec(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("error al ejecutar actualización: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error al obtener filas afectadas: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// nullableUint convierte un entero nulo de SQL en un puntero
+func nullableUint(v sql.NullInt64) *uint {
+	if !v.Valid {
+		return nil
+	}
+	u := uint(v.Int64)
+	return &u
+}
+
+// utcArgs convierte a UTC los argumentos de tipo fecha. SQLite almacena las
+// fechas como texto, por lo que solo se ordenan y comparan correctamente si
+// todas comparten la misma zona horaria
+func utcArgs(args ...interface{}) []interface{} {
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			args[i] = v.UTC()
+		case *time.Time:
+			if v != nil {
+				args[i] = v.UTC()
+			}
+		}
+	}
+	return args
+}
+
+// parseOfflineThreshold interpreta un TiempoFueraLinea con formato HH:MM:SS.
+// Retorna false si el formato es inválido o el tiempo es cero (detección deshabilitada)
+func parseOfflineThreshold(value string) (time.Duration, bool) {
+	var hours, minutes, seconds int
+	if _, err := fmt.Sscanf(value, "%d:%d:%d", &hours, &minutes, &seconds); err != nil {
+		return 0, false
+	}
+
+	threshold := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+	return threshold, threshold > 0
+}
+
+// isDuplicateKey indica si el error corresponde a una violación de clave única
+func isDuplicateKey(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+// isForeignKeyViolation indica si el error corresponde a una referencia inexistente
+func isForeignKeyViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey
+}
+
+// maxInsertRows es la cantidad máxima de filas por sentencia INSERT, acotada
+// para no superar el límite de 32766 parámetros por sentencia de SQLite
+const maxInsertRows = 1000
+
+// placeholders genera n grupos de placeholders separados por coma
+func placeholders(n int, group string) string {
+	return strings.TrimSuffix(strings.Repeat(group+", ", n), ", ")
+}
@@ -0,0 +1,295 @@
+-- ============================================================================
+-- Schema de Base de Datos para Sistema de Telemetría
+-- Motor: SQLite 3.35+ (embebido, aplicado automáticamente al iniciar)
+-- Descripción: Equivalente a migrations/schema.sql. Las fechas se almacenan
+--              como texto en UTC y los valores lógicos como 0/1
+-- ============================================================================
+
+-- ============================================================================
+-- Tabla: grupos_telemetria
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS grupos_telemetria (
+    idGrupo INTEGER PRIMARY KEY AUTOINCREMENT,
+    Nombre VARCHAR(255) NOT NULL
+);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria (
+    idTelemetria INTEGER PRIMARY KEY AUTOINCREMENT,
+    Identificador VARCHAR(255) NOT NULL,
+    Nombre VARCHAR(255) NOT NULL,
+    UltimaConexion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    TiempoFueraLinea VARCHAR(10) DEFAULT '00:00:00',
+    Activo BOOLEAN NOT NULL DEFAULT 1,
+    FueraLinea BOOLEAN NOT NULL DEFAULT 0,
+    FechaFueraLinea TIMESTAMP NULL DEFAULT NULL,
+    idGrupo INTEGER NULL
+        REFERENCES grupos_telemetria(idGrupo) ON DELETE SET NULL ON UPDATE CASCADE,
+
+    CONSTRAINT uk_identificador UNIQUE (Identificador)
+);
+
+CREATE INDEX IF NOT EXISTS idx_telemetria_ultima_conexion ON equipos_telemetria (UltimaConexion);
+CREATE INDEX IF NOT EXISTS idx_telemetria_fuera_linea ON equipos_telemetria (FueraLinea, Activo);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_credenciales
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_credenciales (
+    idCredencial INTEGER PRIMARY KEY AUTOINCREMENT,
+    idTelemetria INTEGER NOT NULL
+        REFERENCES equipos_telemetria(idTelemetria) ON DELETE CASCADE ON UPDATE CASCADE,
+    Tipo VARCHAR(8) NOT NULL DEFAULT 'token' CHECK (Tipo IN ('token', 'hmac')),
+    TokenHash CHAR(64) NULL,
+    Secreto VARCHAR(255) NULL,
+    Descripcion VARCHAR(255),
+    Activo BOOLEAN NOT NULL DEFAULT 1,
+    FechaCreacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FechaExpiracion TIMESTAMP NULL DEFAULT NULL,
+
+    CONSTRAINT uk_token_hash UNIQUE (TokenHash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_credenciales_telemetria_activo ON equipos_telemetria_credenciales (idTelemetria, Activo);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_sensores
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_sensores (
+    idSensor INTEGER PRIMARY KEY AUTOINCREMENT,
+    idTelemetria INTEGER NOT NULL
+        REFERENCES equipos_telemetria(idTelemetria) ON DELETE CASCADE ON UPDATE CASCADE,
+    Nombre VARCHAR(64) NOT NULL,
+    Unidad VARCHAR(32),
+    TipoDato VARCHAR(8) NOT NULL DEFAULT 'float' CHECK (TipoDato IN ('float', 'int', 'bool')),
+    Slot INTEGER NULL,
+    Activo BOOLEAN NOT NULL DEFAULT 1,
+
+    CONSTRAINT uk_telemetria_nombre UNIQUE (idTelemetria, Nombre)
+);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_datos
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_datos (
+    idMedicion INTEGER PRIMARY KEY AUTOINCREMENT,
+    idTelemetria INTEGER NOT NULL
+        REFERENCES equipos_telemetria(idTelemetria) ON DELETE CASCADE ON UPDATE CASCADE,
+    Fecha TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FechaDispositivo TIMESTAMP NULL DEFAULT NULL,
+    FechaRecepcion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    Latitud DECIMAL(9, 6),
+    Longitud DECIMAL(9, 6),
+    Distancia DECIMAL(9, 6),
+    Sensor_1 DECIMAL(9, 6),
+    Sensor_2 DECIMAL(9, 6),
+    Sensor_3 DECIMAL(9, 6),
+    Sensor_4 DECIMAL(9, 6),
+    Sensor_5 DECIMAL(9, 6)
+);
+
+CREATE INDEX IF NOT EXISTS idx_datos_telemetria_fecha ON equipos_telemetria_datos (idTelemetria, Fecha);
+CREATE INDEX IF NOT EXISTS idx_datos_fecha ON equipos_telemetria_datos (Fecha);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_datos_sensores
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_datos_sensores (
+    idMedicion INTEGER NOT NULL
+        REFERENCES equipos_telemetria_datos(idMedicion) ON DELETE CASCADE ON UPDATE CASCADE,
+    Nombre VARCHAR(64) NOT NULL,
+    Valor DOUBLE NOT NULL,
+
+    PRIMARY KEY (idMedicion, Nombre)
+);
+
+CREATE INDEX IF NOT EXISTS idx_datos_sensores_nombre ON equipos_telemetria_datos_sensores (Nombre);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_errores
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_errores (
+    idError INTEGER PRIMARY KEY AUTOINCREMENT,
+    idTelemetria INTEGER
+        REFERENCES equipos_telemetria(idTelemetria) ON DELETE SET NULL ON UPDATE CASCADE,
+    Identificador VARCHAR(255),
+    Fecha TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    descripcion TEXT,
+    idSolicitud VARCHAR(128)
+);
+
+CREATE INDEX IF NOT EXISTS idx_errores_telemetria_fecha ON equipos_telemetria_errores (idTelemetria, Fecha);
+CREATE INDEX IF NOT EXISTS idx_errores_identificador ON equipos_telemetria_errores (Identificador);
+CREATE INDEX IF NOT EXISTS idx_errores_fecha ON equipos_telemetria_errores (Fecha);
+CREATE INDEX IF NOT EXISTS idx_errores_solicitud ON equipos_telemetria_errores (idSolicitud);
+
+-- ============================================================================
+-- Tabla: geocercas
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS geocercas (
+    idGeocerca INTEGER PRIMARY KEY AUTOINCREMENT,
+    Nombre VARCHAR(255) NOT NULL,
+    Tipo VARCHAR(8) NOT NULL CHECK (Tipo IN ('circle', 'polygon')),
+    Latitud DECIMAL(9, 6) NULL,
+    Longitud DECIMAL(9, 6) NULL,
+    Radio DOUBLE NULL,
+    Vertices TEXT NULL,
+    TiempoPermanencia INTEGER NOT NULL DEFAULT 0,
+    Activo BOOLEAN NOT NULL DEFAULT 1
+);
+
+-- ============================================================================
+-- Tabla: geocercas_asignaciones
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS geocercas_asignaciones (
+    idAsignacion INTEGER PRIMARY KEY AUTOINCREMENT,
+    idGeocerca INTEGER NOT NULL
+        REFERENCES geocercas(idGeocerca) ON DELETE CASCADE ON UPDATE CASCADE,
+    idTelemetria INTEGER NULL
+        REFERENCES equipos_telemetria(idTelemetria) ON DELETE CASCADE ON UPDATE CASCADE,
+    idGrupo INTEGER NULL
+        REFERENCES grupos_telemetria(idGrupo) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    CONSTRAINT uk_geocerca_telemetria UNIQUE (idGeocerca, idTelemetria),
+    CONSTRAINT uk_geocerca_grupo UNIQUE (idGeocerca, idGrupo)
+);
+
+CREATE INDEX IF NOT EXISTS idx_asignaciones_telemetria ON geocercas_asignaciones (idTelemetria);
+CREATE INDEX IF NOT EXISTS idx_asignaciones_grupo ON geocercas_asignaciones (idGrupo);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_eventos
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_eventos (
+    idEvento INTEGER PRIMARY KEY AUTOINCREMENT,
+    idTelemetria INTEGER NOT NULL
+        REFERENCES equipos_telemetria(idTelemetria) ON DELETE CASCADE ON UPDATE CASCADE,
+    idGeocerca INTEGER NULL
+        REFERENCES geocercas(idGeocerca) ON DELETE SET NULL ON UPDATE CASCADE,
+    Tipo VARCHAR(32) NOT NULL,
+    Fecha TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    Latitud DECIMAL(9, 6),
+    Longitud DECIMAL(9, 6),
+    Descripcion TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_eventos_telemetria_fecha ON equipos_telemetria_eventos (idTelemetria, Fecha);
+CREATE INDEX IF NOT EXISTS idx_eventos_tipo ON equipos_telemetria_eventos (Tipo);
+
+-- ============================================================================
+-- Tabla: alertas_reglas
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS alertas_reglas (
+    idRegla INTEGER PRIMARY KEY AUTOINCREMENT,
+    Nombre VARCHAR(255) NOT NULL,
+    idTelemetria INTEGER NULL
+        REFERENCES equipos_telemetria(idTelemetria) ON DELETE CASCADE ON UPDATE CASCADE,
+    idGrupo INTEGER NULL
+        REFERENCES grupos_telemetria(idGrupo) ON DELETE CASCADE ON UPDATE CASCADE,
+    Sensor VARCHAR(64) NOT NULL,
+    Operador VARCHAR(8) NOT NULL CHECK (Operador IN ('gt', 'lt', 'between', 'outside', 'rate')),
+    Umbral DOUBLE NOT NULL,
+    UmbralMax DOUBLE NULL,
+    Histeresis DOUBLE NOT NULL DEFAULT 0,
+    Duracion INTEGER NOT NULL DEFAULT 0,
+    Activo BOOLEAN NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS idx_reglas_telemetria ON alertas_reglas (idTelemetria);
+CREATE INDEX IF NOT EXISTS idx_reglas_grupo ON alertas_reglas (idGrupo);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_alertas
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_alertas (
+    idAlerta INTEGER PRIMARY KEY AUTOINCREMENT,
+    idRegla INTEGER NOT NULL
+        REFERENCES alertas_reglas(idRegla) ON DELETE CASCADE ON UPDATE CASCADE,
+    idTelemetria INTEGER NOT NULL
+        REFERENCES equipos_telemetria(idTelemetria) ON DELETE CASCADE ON UPDATE CASCADE,
+    Sensor VARCHAR(64) NOT NULL,
+    Valor DOUBLE NOT NULL,
+    FechaActivacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FechaResolucion TIMESTAMP NULL DEFAULT NULL,
+    ValorResolucion DOUBLE NULL,
+    Descripcion TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_alertas_telemetria_fecha ON equipos_telemetria_alertas (idTelemetria, FechaActivacion);
+CREATE INDEX IF NOT EXISTS idx_alertas_regla_abierta ON equipos_telemetria_alertas (idRegla, idTelemetria, FechaResolucion);
+
+-- ============================================================================
+-- Tabla: webhooks
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS webhooks (
+    idWebhook INTEGER PRIMARY KEY AUTOINCREMENT,
+    Nombre VARCHAR(255) NOT NULL,
+    URL VARCHAR(2048) NOT NULL,
+    Secreto VARCHAR(255) NOT NULL,
+    idGrupo INTEGER NULL
+        REFERENCES grupos_telemetria(idGrupo) ON DELETE CASCADE ON UPDATE CASCADE,
+    Eventos TEXT NULL,
+    Activo BOOLEAN NOT NULL DEFAULT 1
+);
+
+-- ============================================================================
+-- Tabla: webhooks_entregas
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS webhooks_entregas (
+    idEntrega INTEGER PRIMARY KEY AUTOINCREMENT,
+    idWebhook INTEGER NOT NULL
+        REFERENCES webhooks(idWebhook) ON DELETE CASCADE ON UPDATE CASCADE,
+    Tipo VARCHAR(32) NOT NULL,
+    Payload TEXT NOT NULL,
+    Estado VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (Estado IN ('pending', 'delivered', 'failed')),
+    Intentos INTEGER NOT NULL DEFAULT 0,
+    ProximoIntento TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    Bloqueo CHAR(32) NULL,
+    UltimoError TEXT,
+    CodigoRespuesta INTEGER NULL,
+    FechaCreacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FechaEntrega TIMESTAMP NULL DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_entregas_estado_proximo ON webhooks_entregas (Estado, ProximoIntento);
+CREATE INDEX IF NOT EXISTS idx_entregas_bloqueo ON webhooks_entregas (Bloqueo);
+CREATE INDEX IF NOT EXISTS idx_entregas_webhook ON webhooks_entregas (idWebhook, idEntrega);
+
+-- ============================================================================
+-- Vistas útiles
+-- ============================================================================
+
+-- Vista: Última posición de cada dispositivo
+CREATE VIEW IF NOT EXISTS v_ultima_posicion AS
+SELECT
+    et.idTelemetria,
+    et.Identificador,
+    et.Nombre,
+    et.UltimaConexion,
+    etd.Latitud,
+    etd.Longitud,
+    etd.Fecha AS FechaUltimaMedicion
+FROM equipos_telemetria et
+LEFT JOIN (
+    SELECT
+        idTelemetria,
+        Latitud,
+        Longitud,
+        Fecha,
+        ROW_NUMBER() OVER (PARTITION BY idTelemetria ORDER BY Fecha DESC) AS rn
+    FROM equipos_telemetria_datos
+) etd ON et.idTelemetria = etd.idTelemetria AND etd.rn = 1;
+
+-- Vista: Resumen de errores por dispositivo
+CREATE VIEW IF NOT EXISTS v_resumen_errores AS
+SELECT
+    et.idTelemetria,
+    et.Identificador,
+    et.Nombre,
+    COUNT(ete.idError) AS TotalErrores,
+    MAX(ete.Fecha) AS UltimoError
+FROM equipos_telemetria et
+LEFT JOIN equipos_telemetria_errores ete ON et.idTelemetria = ete.idTelemetria
+GROUP BY et.idTelemetria, et.Identificador, et.Nombre;
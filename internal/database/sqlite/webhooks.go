@@ -0,0 +1,339 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// ListWebhooks obtiene todos los webhooks
+func (r *Repository) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	query := `
+		SELECT idWebhook, Nombre, URL, Secreto, idGrupo, Eventos, Activo
+		FROM webhooks
+		ORDER BY idWebhook
+	`
+
+	return r.queryWebhooks(ctx, query)
+}
+
+// GetWebhook obtiene un webhook por ID
+func (r *Repository) GetWebhook(ctx context.Context, webhookID uint) (*models.Webhook, error) {
+	query := `
+		SELECT idWebhook, Nombre, URL, Secreto, idGrupo, Eventos, Activo
+		FROM webhooks
+		WHERE idWebhook = ?
+	`
+
+	webhooks, err := r.queryWebhooks(ctx, query, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if len(webhooks) == 0 {
+		return nil, nil // Webhook no encontrado
+	}
+
+	return &webhooks[0], nil
+}
+
+// CreateWebhook inserta un nuevo webhook
+func (r *Repository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	events, err := encodeEvents(webhook.Eventos)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO webhooks (Nombre, URL, Secreto, idGrupo, Eventos, Activo)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.exec(ctx, query,
+		webhook.Nombre,
+		webhook.URL,
+		webhook.Secreto,
+		webhook.IDGrupo,
+		events,
+		webhook.Activo,
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return database.ErrInvalidReference
+		}
+		return fmt.Errorf("error al insertar webhook: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error al obtener último ID insertado: %w", err)
+	}
+
+	webhook.IDWebhook = uint(id)
+	return nil
+}
+
+// UpdateWebhook actualiza un webhook; un secreto vacío conserva el actual
+func (r *Repository) UpdateWebhook(ctx context.Context, webhook *models.Webhook) (bool, error) {
+	events, err := encodeEvents(webhook.Eventos)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		UPDATE webhooks
+		SET Nombre = ?, URL = ?, Secreto = COALESCE(NULLIF(?, ''), Secreto), idGrupo = ?, Eventos = ?, Activo = ?
+		WHERE idWebhook = ?
+	`
+
+	updated, err := r.execAffected(ctx, query,
+		webhook.Nombre,
+		webhook.URL,
+		webhook.Secreto,
+		webhook.IDGrupo,
+		events,
+		webhook.Activo,
+		webhook.IDWebhook,
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return false, database.ErrInvalidReference
+		}
+		return false, fmt.Errorf("error al actualizar webhook: %w", err)
+	}
+
+	return updated, nil
+}
+
+// DeleteWebhook elimina un webhook y su registro de entregas
+func (r *Repository) DeleteWebhook(ctx context.Context, webhookID uint) (bool, error) {
+	query := `
+		DELETE FROM webhooks
+		WHERE idWebhook = ?
+	`
+
+	return r.execAffected(ctx, query, webhookID)
+}
+
+// EnqueueDeliveries inserta entregas pendientes en la cola persistente
+func (r *Repository) EnqueueDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO webhooks_entregas (idWebhook, Tipo, Payload, Estado, Intentos, ProximoIntento, FechaCreacion)
+		VALUES ` + placeholders(len(deliveries), "(?, ?, ?, ?, ?, ?, ?)")
+
+	args := make([]interface{}, 0, len(deliveries)*7)
+	for _, delivery := range deliveries {
+		args = append(args,
+			delivery.IDWebhook,
+			delivery.Tipo,
+			string(delivery.Payload),
+			delivery.Estado,
+			delivery.Intentos,
+			delivery.ProximoIntento,
+			delivery.FechaCreacion,
+		)
+	}
+
+	if _, err := r.exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("error al encolar entregas de webhook: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDeliveries reserva hasta limit entregas pendientes vencidas. Las entregas
+// reservadas se posponen por lease, de modo que otra instancia no las tome y se
+// reintenten si el proceso termina antes de actualizarlas
+func (r *Repository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	claim := `
+		UPDATE webhooks_entregas
+		SET Bloqueo = ?, ProximoIntento = ?
+		WHERE idEntrega IN (
+			SELECT idEntrega
+			FROM webhooks_entregas
+			WHERE Estado = ? AND ProximoIntento <= ?
+			ORDER BY ProximoIntento
+			LIMIT ?
+		)
+	`
+
+	claimed, err := r.execAffected(ctx, claim, token, now.Add(lease), models.DeliveryStatusPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error al reservar entregas de webhook: %w", err)
+	}
+	if !claimed {
+		return nil, nil
+	}
+
+	query := `
+		SELECT idEntrega, idWebhook, Tipo, Payload, Estado, Intentos, ProximoIntento, UltimoError, CodigoRespuesta, FechaCreacion, FechaEntrega
+		FROM webhooks_entregas
+		WHERE Bloqueo = ?
+		ORDER BY idEntrega
+	`
+
+	return r.queryDeliveries(ctx, query, token)
+}
+
+// UpdateDelivery actualiza el resultado de un intento de entrega y libera la reserva
+func (r *Repository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := `
+		UPDATE webhooks_entregas
+		SET Estado = ?, Intentos = ?, ProximoIntento = ?, UltimoError = ?, CodigoRespuesta = ?, FechaEntrega = ?, Bloqueo = NULL
+		WHERE idEntrega = ?
+	`
+
+	_, err := r.exec(ctx, query,
+		delivery.Estado,
+		delivery.Intentos,
+		delivery.ProximoIntento,
+		delivery.UltimoError,
+		delivery.CodigoRespuesta,
+		delivery.FechaEntrega,
+		delivery.IDEntrega,
+	)
+	if err != nil {
+		return fmt.Errorf("error al actualizar entrega de webhook: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeliveries consulta el registro de entregas de un webhook, de la más reciente a la más antigua
+func (r *Repository) ListDeliveries(ctx context.Context, q *models.DeliveryQuery) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT idEntrega, idWebhook, Tipo, Payload, Estado, Intentos, ProximoIntento, UltimoError, CodigoRespuesta, FechaCreacion, FechaEntrega
+		FROM webhooks_entregas
+		WHERE idWebhook = ? AND (? = '' OR Estado = ?)
+		ORDER BY idEntrega DESC
+		LIMIT ?
+	`
+
+	return r.queryDeliveries(ctx, query, q.IDWebhook, q.Estado, q.Estado, q.Limit)
+}
+
+// queryWebhooks ejecuta una consulta de webhooks con las columnas de la tabla webhooks
+func (r *Repository) queryWebhooks(ctx context.Context, query string, args ...interface{}) ([]models.Webhook, error) {
+	rows, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		var webhook models.Webhook
+		var groupID sql.NullInt64
+		var events sql.NullString
+		if err := rows.Scan(
+			&webhook.IDWebhook,
+			&webhook.Nombre,
+			&webhook.URL,
+			&webhook.Secreto,
+			&groupID,
+			&events,
+			&webhook.Activo,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer webhook: %w", err)
+		}
+		webhook.IDGrupo = nullableUint(groupID)
+		if events.Valid && events.String != "" {
+			if err := json.Unmarshal([]byte(events.String), &webhook.Eventos); err != nil {
+				return nil, fmt.Errorf("error al decodificar eventos del webhook %d: %w", webhook.IDWebhook, err)
+			}
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// queryDeliveries ejecuta una consulta del registro de entregas
+func (r *Repository) queryDeliveries(ctx context.Context, query string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	rows, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar entregas de webhook: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var payload string
+		var lastError sql.NullString
+		var statusCode sql.NullInt64
+		var deliveredAt sql.NullTime
+		if err := rows.Scan(
+			&delivery.IDEntrega,
+			&delivery.IDWebhook,
+			&delivery.Tipo,
+			&payload,
+			&delivery.Estado,
+			&delivery.Intentos,
+			&delivery.ProximoIntento,
+			&lastError,
+			&statusCode,
+			&delivery.FechaCreacion,
+			&deliveredAt,
+		); err != nil {
+			return nil, fmt.Errorf("error al leer entrega de webhook: %w", err)
+		}
+		delivery.Payload = json.RawMessage(payload)
+		delivery.UltimoError = lastError.String
+		if statusCode.Valid {
+			code := int(statusCode.Int64)
+			delivery.CodigoRespuesta = &code
+		}
+		if deliveredAt.Valid {
+			t := deliveredAt.Time
+			delivery.FechaEntrega = &t
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al iterar entregas de webhook: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// encodeEvents serializa la lista de eventos de un webhook como JSON (nil si está vacía)
+func encodeEvents(events []string) (interface{}, error) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(events)
+	if err != nil {
+		return nil, fmt.Errorf("error al codificar eventos: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// randomToken genera un identificador aleatorio para reservar entregas
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error al generar token de reserva: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
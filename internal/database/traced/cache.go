@@ -0,0 +1,249 @@
+package traced
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/tracing"
+)
+
+// Cache envuelve un caché registrando un span por cada operación
+type Cache struct {
+	cache  database.Cache
+	system string
+}
+
+// NewCache crea un caché instrumentado con trazas. system identifica el motor
+// en el atributo db.system (valor de CACHE_DRIVER)
+func NewCache(cache database.Cache, system string) *Cache {
+	return &Cache{cache: cache, system: system}
+}
+
+// start inicia el span de una operación del caché
+func (c *Cache) start(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "Cache."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String(string(semconv.DBSystemKey), c.system),
+			semconv.DBOperationName(operation),
+		),
+	)
+}
+
+// GetDevice registra un span y delega en el caché envuelto
+func (c *Cache) GetDevice(ctx context.Context, identifier string) (result *models.Device, err error) {
+	ctx, span := c.start(ctx, "GetDevice")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = c.cache.GetDevice(ctx, identifier)
+	return result, err
+}
+
+// SetDevice registra un span y delega en el caché envuelto
+func (c *Cache) SetDevice(ctx context.Context, device *models.Device) (err error) {
+	ctx, span := c.start(ctx, "SetDevice")
+	defer func() { tracing.End(span, err) }()
+
+	err = c.cache.SetDevice(ctx, device)
+	return err
+}
+
+// DeleteDevice registra un span y delega en el caché envuelto
+func (c *Cache) DeleteDevice(ctx context.Context, identifier string) (err error) {
+	ctx, span := c.start(ctx, "DeleteDevice")
+	defer func() { tracing.End(span, err) }()
+
+	err = c.cache.DeleteDevice(ctx, identifier)
+	return err
+}
+
+// UpdateDeviceLocation registra un span y delega en el caché envuelto
+func (c *Cache) UpdateDeviceLocation(ctx context.Context, identifier string, latitud, longitud float64, timestamp time.Time) (err error) {
+	ctx, span := c.start(ctx, "UpdateDeviceLocation")
+	defer func() { tracing.End(span, err) }()
+
+	err = c.cache.UpdateDeviceLocation(ctx, identifier, latitud, longitud, timestamp)
+	return err
+}
+
+// GetDeviceCredentials registra un span y delega en el caché envuelto
+func (c *Cache) GetDeviceCredentials(ctx context.Context, identifier string) (result []models.DeviceCredential, found bool, err error) {
+	ctx, span := c.start(ctx, "GetDeviceCredentials")
+	defer func() { tracing.End(span, err) }()
+
+	result, found, err = c.cache.GetDeviceCredentials(ctx, identifier)
+	return result, found, err
+}
+
+// SetDeviceCredentials registra un span y delega en el caché envuelto
+func (c *Cache) SetDeviceCredentials(ctx context.Context, identifier string, credentials []models.DeviceCredential) (err error) {
+	ctx, span := c.start(ctx, "SetDeviceCredentials")
+	defer func() { tracing.End(span, err) }()
+
+	err = c.cache.SetDeviceCredentials(ctx, identifier, credentials)
+	return err
+}
+
+// DeleteDeviceCredentials registra un span y delega en el caché envuelto
+func (c *Cache) DeleteDeviceCredentials(ctx context.Context, identifier string) (err error) {
+	ctx, span := c.start(ctx, "DeleteDeviceCredentials")
+	defer func() { tracing.End(span, err) }()
+
+	err = c.cache.DeleteDeviceCredentials(ctx, identifier)
+	return err
+}
+
+// GetDeviceSensors registra un span y delega en el caché envuelto
+func (c *Cache) GetDeviceSensors(ctx context.Context, identifier string) (result []models.SensorDefinition, found bool, err error) {
+	ctx, span := c.start(ctx, "GetDeviceSensors")
+	defer func() { tracing.End(span, err) }()
+
+	result, found, err = c.cache.GetDeviceSensors(ctx, identifier)
+	return result, found, err
+}
+
+// SetDeviceSensors registra un span y delega en el caché envuelto
+func (c *Cache) SetDeviceSensors(ctx context.Context, identifier string, sensors []models.SensorDefinition) (err error) {
+	ctx, span := c.start(ctx, "SetDeviceSensors")
+	defer func() { tracing.End(span, err) }()
+
+	err = c.cache.SetDeviceSensors(ctx, identifier, sensors)
+	return err
+}
+
+// DeleteDeviceSensors registra un span y delega en el caché envuelto
+func (c *Cache) DeleteDeviceSensors(ctx context.Context, identifier string) (err error) {
+	ctx, span := c.start(ctx, "DeleteDeviceSensors")
+	defer func() { tracing.End(span, err) }()
+
+	err = c.cache.DeleteDeviceSensors(ctx, identifier)
+	return err
+}
+
+// GetGeofenceStates registra un span y delega en el caché envuelto
+func (c *Cache) GetGeofenceStates(ctx context.Context, identifier string) (result map[uint]models.GeofenceState, err error) {
+	ctx, span := c.start(ctx, "GetGeofenceStates")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = c.cache.GetGeofenceStates(ctx, identifier)
+	return result, err
+}
+
+// SetGeofenceState registra un span y delega en el caché envuelto
+func (c *Cache) SetGeofenceState(ctx context.Context, identifier string, geofenceID uint, state *models.GeofenceState) (err error) {
+	ctx, span := c.start(ctx, "SetGeofenceState")
+	defer func() { tracing.End(span, err) }()
+
+	err = c.cache.SetGeofenceState(ctx, identifier, geofenceID, state)
+	return err
+}
+
+// DeleteGeofenceState registra un span y delega en el caché envuelto
+func (c *Cache) DeleteGeofenceState(ctx context.Context, identifier string, geofenceID uint) (err error) {
+	ctx, span := c.start(ctx, "DeleteGeofenceState")
+	defer func() { tracing.End(span, err) }()
+
+	err = c.cache.DeleteGeofenceState(ctx, identifier, geofenceID)
+	return err
+}
+
+// GetAlertStates registra un span y delega en el caché envuelto
+func (c *Cache) GetAlertStates(ctx context.Context, identifier string) (result map[uint]models.AlertState, err error) {
+	ctx, span := c.start(ctx, "GetAlertStates")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = c.cache.GetAlertStates(ctx, identifier)
+	return result, err
+}
+
+// SetAlertState registra un span y delega en el caché envuelto
+func (c *Cache) SetAlertState(ctx context.Context, identifier string, ruleID uint, state *models.AlertState) (err error) {
+	ctx, span := c.start(ctx, "SetAlertState")
+	defer func() { tracing.End(span, err) }()
+
+	err = c.cache.SetAlertState(ctx, identifier, ruleID, state)
+	return err
+}
+
+// DeleteAlertState registra un span y delega en el caché envuelto
+func (c *Cache) DeleteAlertState(ctx context.Context, identifier string, ruleID uint) (err error) {
+	ctx, span := c.start(ctx, "DeleteAlertState")
+	defer func() { tracing.End(span, err) }()
+
+	err = c.cache.DeleteAlertState(ctx, identifier, ruleID)
+	return err
+}
+
+// Publish registra un span y delega en el caché envuelto
+func (c *Cache) Publish(ctx context.Context, channel string, payload []byte) (err error) {
+	ctx, span := c.start(ctx, "Publish")
+	defer func() { tracing.End(span, err) }()
+
+	err = c.cache.Publish(ctx, channel, payload)
+	return err
+}
+
+// Subscribe registra un span y delega en el caché envuelto
+func (c *Cache) Subscribe(ctx context.Context, channel string) (result <-chan []byte, err error) {
+	ctx, span := c.start(ctx, "Subscribe")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = c.cache.Subscribe(ctx, channel)
+	return result, err
+}
+
+// RegisterNonce registra un span y delega en el caché envuelto
+func (c *Cache) RegisterNonce(ctx context.Context, identifier, nonce string, ttl time.Duration) (ok bool, err error) {
+	ctx, span := c.start(ctx, "RegisterNonce")
+	defer func() { tracing.End(span, err) }()
+
+	ok, err = c.cache.RegisterNonce(ctx, identifier, nonce, ttl)
+	return ok, err
+}
+
+// ClaimMessage registra un span y delega en el caché envuelto
+func (c *Cache) ClaimMessage(ctx context.Context, identifier, messageID string, ttl time.Duration) (ok bool, result string, err error) {
+	ctx, span := c.start(ctx, "ClaimMessage")
+	defer func() { tracing.End(span, err) }()
+
+	ok, result, err = c.cache.ClaimMessage(ctx, identifier, messageID, ttl)
+	return ok, result, err
+}
+
+// CompleteMessage registra un span y delega en el caché envuelto
+func (c *Cache) CompleteMessage(ctx context.Context, identifier, messageID, result string, ttl time.Duration) (err error) {
+	ctx, span := c.start(ctx, "CompleteMessage")
+	defer func() { tracing.End(span, err) }()
+
+	err = c.cache.CompleteMessage(ctx, identifier, messageID, result, ttl)
+	return err
+}
+
+// ReleaseMessage registra un span y delega en el caché envuelto
+func (c *Cache) ReleaseMessage(ctx context.Context, identifier, messageID string) (err error) {
+	ctx, span := c.start(ctx, "ReleaseMessage")
+	defer func() { tracing.End(span, err) }()
+
+	err = c.cache.ReleaseMessage(ctx, identifier, messageID)
+	return err
+}
+
+// Ping registra un span y delega en el caché envuelto
+func (c *Cache) Ping(ctx context.Context) (err error) {
+	ctx, span := c.start(ctx, "Ping")
+	defer func() { tracing.End(span, err) }()
+
+	err = c.cache.Ping(ctx)
+	return err
+}
+
+// Close cierra la conexión subyacente
+func (c *Cache) Close() error {
+	return c.cache.Close()
+}
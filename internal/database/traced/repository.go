@@ -0,0 +1,492 @@
+package traced
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/tracing"
+)
+
+// Repository envuelve un repositorio registrando un span por cada operación
+type Repository struct {
+	repo   database.Repository
+	system string
+}
+
+// NewRepository crea un repositorio instrumentado con trazas. system identifica
+// el motor en el atributo db.system (valor de DB_DRIVER)
+func NewRepository(repo database.Repository, system string) *Repository {
+	return &Repository{repo: repo, system: system}
+}
+
+// start inicia el span de una operación del repositorio
+func (r *Repository) start(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "Repository."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String(string(semconv.DBSystemKey), r.system),
+			semconv.DBOperationName(operation),
+		),
+	)
+}
+
+// GetDeviceByIdentifier registra un span y delega en el repositorio envuelto
+func (r *Repository) GetDeviceByIdentifier(ctx context.Context, identifier string) (result *models.Device, err error) {
+	ctx, span := r.start(ctx, "GetDeviceByIdentifier")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.GetDeviceByIdentifier(ctx, identifier)
+	return result, err
+}
+
+// UpdateDeviceConnection registra un span y delega en el repositorio envuelto
+func (r *Repository) UpdateDeviceConnection(ctx context.Context, deviceID uint, timestamp time.Time) (err error) {
+	ctx, span := r.start(ctx, "UpdateDeviceConnection")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.UpdateDeviceConnection(ctx, deviceID, timestamp)
+	return err
+}
+
+// ListDevices registra un span y delega en el repositorio envuelto
+func (r *Repository) ListDevices(ctx context.Context) (result []models.Device, err error) {
+	ctx, span := r.start(ctx, "ListDevices")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.ListDevices(ctx)
+	return result, err
+}
+
+// CreateDevice registra un span y delega en el repositorio envuelto
+func (r *Repository) CreateDevice(ctx context.Context, device *models.Device) (err error) {
+	ctx, span := r.start(ctx, "CreateDevice")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.CreateDevice(ctx, device)
+	return err
+}
+
+// UpdateDevice registra un span y delega en el repositorio envuelto
+func (r *Repository) UpdateDevice(ctx context.Context, device *models.Device) (err error) {
+	ctx, span := r.start(ctx, "UpdateDevice")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.UpdateDevice(ctx, device)
+	return err
+}
+
+// SetDeviceActive registra un span y delega en el repositorio envuelto
+func (r *Repository) SetDeviceActive(ctx context.Context, deviceID uint, active bool) (err error) {
+	ctx, span := r.start(ctx, "SetDeviceActive")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.SetDeviceActive(ctx, deviceID, active)
+	return err
+}
+
+// DeleteDevice registra un span y delega en el repositorio envuelto
+func (r *Repository) DeleteDevice(ctx context.Context, deviceID uint) (err error) {
+	ctx, span := r.start(ctx, "DeleteDevice")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.DeleteDevice(ctx, deviceID)
+	return err
+}
+
+// ListOverdueDevices registra un span y delega en el repositorio envuelto
+func (r *Repository) ListOverdueDevices(ctx context.Context, now time.Time) (result []models.Device, err error) {
+	ctx, span := r.start(ctx, "ListOverdueDevices")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.ListOverdueDevices(ctx, now)
+	return result, err
+}
+
+// ListOfflineDevices registra un span y delega en el repositorio envuelto
+func (r *Repository) ListOfflineDevices(ctx context.Context) (result []models.Device, err error) {
+	ctx, span := r.start(ctx, "ListOfflineDevices")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.ListOfflineDevices(ctx)
+	return result, err
+}
+
+// MarkDeviceOffline registra un span y delega en el repositorio envuelto
+func (r *Repository) MarkDeviceOffline(ctx context.Context, deviceID uint, timestamp, cutoff time.Time) (ok bool, err error) {
+	ctx, span := r.start(ctx, "MarkDeviceOffline")
+	defer func() { tracing.End(span, err) }()
+
+	ok, err = r.repo.MarkDeviceOffline(ctx, deviceID, timestamp, cutoff)
+	return ok, err
+}
+
+// MarkDeviceOnline registra un span y delega en el repositorio envuelto
+func (r *Repository) MarkDeviceOnline(ctx context.Context, deviceID uint) (ok bool, err error) {
+	ctx, span := r.start(ctx, "MarkDeviceOnline")
+	defer func() { tracing.End(span, err) }()
+
+	ok, err = r.repo.MarkDeviceOnline(ctx, deviceID)
+	return ok, err
+}
+
+// GetDeviceCredentials registra un span y delega en el repositorio envuelto
+func (r *Repository) GetDeviceCredentials(ctx context.Context, identifier string) (result []models.DeviceCredential, err error) {
+	ctx, span := r.start(ctx, "GetDeviceCredentials")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.GetDeviceCredentials(ctx, identifier)
+	return result, err
+}
+
+// GetDeviceSensors registra un span y delega en el repositorio envuelto
+func (r *Repository) GetDeviceSensors(ctx context.Context, deviceID uint) (result []models.SensorDefinition, err error) {
+	ctx, span := r.start(ctx, "GetDeviceSensors")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.GetDeviceSensors(ctx, deviceID)
+	return result, err
+}
+
+// ListGroups registra un span y delega en el repositorio envuelto
+func (r *Repository) ListGroups(ctx context.Context) (result []models.DeviceGroup, err error) {
+	ctx, span := r.start(ctx, "ListGroups")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.ListGroups(ctx)
+	return result, err
+}
+
+// CreateGroup registra un span y delega en el repositorio envuelto
+func (r *Repository) CreateGroup(ctx context.Context, group *models.DeviceGroup) (err error) {
+	ctx, span := r.start(ctx, "CreateGroup")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.CreateGroup(ctx, group)
+	return err
+}
+
+// DeleteGroup registra un span y delega en el repositorio envuelto
+func (r *Repository) DeleteGroup(ctx context.Context, groupID uint) (ok bool, err error) {
+	ctx, span := r.start(ctx, "DeleteGroup")
+	defer func() { tracing.End(span, err) }()
+
+	ok, err = r.repo.DeleteGroup(ctx, groupID)
+	return ok, err
+}
+
+// ListGeofences registra un span y delega en el repositorio envuelto
+func (r *Repository) ListGeofences(ctx context.Context) (result []models.Geofence, err error) {
+	ctx, span := r.start(ctx, "ListGeofences")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.ListGeofences(ctx)
+	return result, err
+}
+
+// GetGeofence registra un span y delega en el repositorio envuelto
+func (r *Repository) GetGeofence(ctx context.Context, geofenceID uint) (result *models.Geofence, err error) {
+	ctx, span := r.start(ctx, "GetGeofence")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.GetGeofence(ctx, geofenceID)
+	return result, err
+}
+
+// GetDeviceGeofences registra un span y delega en el repositorio envuelto
+func (r *Repository) GetDeviceGeofences(ctx context.Context, deviceID uint, groupID *uint) (result []models.Geofence, err error) {
+	ctx, span := r.start(ctx, "GetDeviceGeofences")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.GetDeviceGeofences(ctx, deviceID, groupID)
+	return result, err
+}
+
+// CreateGeofence registra un span y delega en el repositorio envuelto
+func (r *Repository) CreateGeofence(ctx context.Context, geofence *models.Geofence) (err error) {
+	ctx, span := r.start(ctx, "CreateGeofence")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.CreateGeofence(ctx, geofence)
+	return err
+}
+
+// UpdateGeofence registra un span y delega en el repositorio envuelto
+func (r *Repository) UpdateGeofence(ctx context.Context, geofence *models.Geofence) (ok bool, err error) {
+	ctx, span := r.start(ctx, "UpdateGeofence")
+	defer func() { tracing.End(span, err) }()
+
+	ok, err = r.repo.UpdateGeofence(ctx, geofence)
+	return ok, err
+}
+
+// DeleteGeofence registra un span y delega en el repositorio envuelto
+func (r *Repository) DeleteGeofence(ctx context.Context, geofenceID uint) (ok bool, err error) {
+	ctx, span := r.start(ctx, "DeleteGeofence")
+	defer func() { tracing.End(span, err) }()
+
+	ok, err = r.repo.DeleteGeofence(ctx, geofenceID)
+	return ok, err
+}
+
+// AssignGeofence registra un span y delega en el repositorio envuelto
+func (r *Repository) AssignGeofence(ctx context.Context, assignment *models.GeofenceAssignment) (err error) {
+	ctx, span := r.start(ctx, "AssignGeofence")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.AssignGeofence(ctx, assignment)
+	return err
+}
+
+// UnassignGeofence registra un span y delega en el repositorio envuelto
+func (r *Repository) UnassignGeofence(ctx context.Context, assignment *models.GeofenceAssignment) (ok bool, err error) {
+	ctx, span := r.start(ctx, "UnassignGeofence")
+	defer func() { tracing.End(span, err) }()
+
+	ok, err = r.repo.UnassignGeofence(ctx, assignment)
+	return ok, err
+}
+
+// ListAlertRules registra un span y delega en el repositorio envuelto
+func (r *Repository) ListAlertRules(ctx context.Context) (result []models.AlertRule, err error) {
+	ctx, span := r.start(ctx, "ListAlertRules")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.ListAlertRules(ctx)
+	return result, err
+}
+
+// GetAlertRule registra un span y delega en el repositorio envuelto
+func (r *Repository) GetAlertRule(ctx context.Context, ruleID uint) (result *models.AlertRule, err error) {
+	ctx, span := r.start(ctx, "GetAlertRule")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.GetAlertRule(ctx, ruleID)
+	return result, err
+}
+
+// GetDeviceAlertRules registra un span y delega en el repositorio envuelto
+func (r *Repository) GetDeviceAlertRules(ctx context.Context, deviceID uint, groupID *uint) (result []models.AlertRule, err error) {
+	ctx, span := r.start(ctx, "GetDeviceAlertRules")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.GetDeviceAlertRules(ctx, deviceID, groupID)
+	return result, err
+}
+
+// CreateAlertRule registra un span y delega en el repositorio envuelto
+func (r *Repository) CreateAlertRule(ctx context.Context, rule *models.AlertRule) (err error) {
+	ctx, span := r.start(ctx, "CreateAlertRule")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.CreateAlertRule(ctx, rule)
+	return err
+}
+
+// UpdateAlertRule registra un span y delega en el repositorio envuelto
+func (r *Repository) UpdateAlertRule(ctx context.Context, rule *models.AlertRule) (ok bool, err error) {
+	ctx, span := r.start(ctx, "UpdateAlertRule")
+	defer func() { tracing.End(span, err) }()
+
+	ok, err = r.repo.UpdateAlertRule(ctx, rule)
+	return ok, err
+}
+
+// DeleteAlertRule registra un span y delega en el repositorio envuelto
+func (r *Repository) DeleteAlertRule(ctx context.Context, ruleID uint) (ok bool, err error) {
+	ctx, span := r.start(ctx, "DeleteAlertRule")
+	defer func() { tracing.End(span, err) }()
+
+	ok, err = r.repo.DeleteAlertRule(ctx, ruleID)
+	return ok, err
+}
+
+// InsertAlert registra un span y delega en el repositorio envuelto
+func (r *Repository) InsertAlert(ctx context.Context, alert *models.Alert) (err error) {
+	ctx, span := r.start(ctx, "InsertAlert")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.InsertAlert(ctx, alert)
+	return err
+}
+
+// GetOpenAlert registra un span y delega en el repositorio envuelto
+func (r *Repository) GetOpenAlert(ctx context.Context, ruleID, deviceID uint) (result *models.Alert, err error) {
+	ctx, span := r.start(ctx, "GetOpenAlert")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.GetOpenAlert(ctx, ruleID, deviceID)
+	return result, err
+}
+
+// ResolveAlert registra un span y delega en el repositorio envuelto
+func (r *Repository) ResolveAlert(ctx context.Context, alertID uint64, timestamp time.Time, value float64) (err error) {
+	ctx, span := r.start(ctx, "ResolveAlert")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.ResolveAlert(ctx, alertID, timestamp, value)
+	return err
+}
+
+// ListAlerts registra un span y delega en el repositorio envuelto
+func (r *Repository) ListAlerts(ctx context.Context, query *models.AlertQuery) (result []models.Alert, err error) {
+	ctx, span := r.start(ctx, "ListAlerts")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.ListAlerts(ctx, query)
+	return result, err
+}
+
+// ListWebhooks registra un span y delega en el repositorio envuelto
+func (r *Repository) ListWebhooks(ctx context.Context) (result []models.Webhook, err error) {
+	ctx, span := r.start(ctx, "ListWebhooks")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.ListWebhooks(ctx)
+	return result, err
+}
+
+// GetWebhook registra un span y delega en el repositorio envuelto
+func (r *Repository) GetWebhook(ctx context.Context, webhookID uint) (result *models.Webhook, err error) {
+	ctx, span := r.start(ctx, "GetWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.GetWebhook(ctx, webhookID)
+	return result, err
+}
+
+// CreateWebhook registra un span y delega en el repositorio envuelto
+func (r *Repository) CreateWebhook(ctx context.Context, webhook *models.Webhook) (err error) {
+	ctx, span := r.start(ctx, "CreateWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.CreateWebhook(ctx, webhook)
+	return err
+}
+
+// UpdateWebhook registra un span y delega en el repositorio envuelto
+func (r *Repository) UpdateWebhook(ctx context.Context, webhook *models.Webhook) (ok bool, err error) {
+	ctx, span := r.start(ctx, "UpdateWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	ok, err = r.repo.UpdateWebhook(ctx, webhook)
+	return ok, err
+}
+
+// DeleteWebhook registra un span y delega en el repositorio envuelto
+func (r *Repository) DeleteWebhook(ctx context.Context, webhookID uint) (ok bool, err error) {
+	ctx, span := r.start(ctx, "DeleteWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	ok, err = r.repo.DeleteWebhook(ctx, webhookID)
+	return ok, err
+}
+
+// EnqueueDeliveries registra un span y delega en el repositorio envuelto
+func (r *Repository) EnqueueDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) (err error) {
+	ctx, span := r.start(ctx, "EnqueueDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.EnqueueDeliveries(ctx, deliveries)
+	return err
+}
+
+// ClaimDeliveries registra un span y delega en el repositorio envuelto
+func (r *Repository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) (result []models.WebhookDelivery, err error) {
+	ctx, span := r.start(ctx, "ClaimDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.ClaimDeliveries(ctx, now, lease, limit)
+	return result, err
+}
+
+// UpdateDelivery registra un span y delega en el repositorio envuelto
+func (r *Repository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) (err error) {
+	ctx, span := r.start(ctx, "UpdateDelivery")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.UpdateDelivery(ctx, delivery)
+	return err
+}
+
+// ListDeliveries registra un span y delega en el repositorio envuelto
+func (r *Repository) ListDeliveries(ctx context.Context, query *models.DeliveryQuery) (result []models.WebhookDelivery, err error) {
+	ctx, span := r.start(ctx, "ListDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.ListDeliveries(ctx, query)
+	return result, err
+}
+
+// InsertEvent registra un span y delega en el repositorio envuelto
+func (r *Repository) InsertEvent(ctx context.Context, event *models.Event) (err error) {
+	ctx, span := r.start(ctx, "InsertEvent")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.InsertEvent(ctx, event)
+	return err
+}
+
+// ListEvents registra un span y delega en el repositorio envuelto
+func (r *Repository) ListEvents(ctx context.Context, query *models.EventQuery) (result []models.Event, err error) {
+	ctx, span := r.start(ctx, "ListEvents")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.ListEvents(ctx, query)
+	return result, err
+}
+
+// InsertMeasurement registra un span y delega en el repositorio envuelto
+func (r *Repository) InsertMeasurement(ctx context.Context, measurement *models.Measurement) (err error) {
+	ctx, span := r.start(ctx, "InsertMeasurement")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.InsertMeasurement(ctx, measurement)
+	return err
+}
+
+// InsertMeasurements registra un span y delega en el repositorio envuelto
+func (r *Repository) InsertMeasurements(ctx context.Context, measurements []*models.Measurement) (err error) {
+	ctx, span := r.start(ctx, "InsertMeasurements")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.InsertMeasurements(ctx, measurements)
+	return err
+}
+
+// ListMeasurements registra un span y delega en el repositorio envuelto
+func (r *Repository) ListMeasurements(ctx context.Context, query *models.MeasurementQuery) (result []models.Measurement, err error) {
+	ctx, span := r.start(ctx, "ListMeasurements")
+	defer func() { tracing.End(span, err) }()
+
+	result, err = r.repo.ListMeasurements(ctx, query)
+	return result, err
+}
+
+// InsertError registra un span y delega en el repositorio envuelto
+func (r *Repository) InsertError(ctx context.Context, errorRecord *models.ErrorRecord) (err error) {
+	ctx, span := r.start(ctx, "InsertError")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.InsertError(ctx, errorRecord)
+	return err
+}
+
+// Ping registra un span y delega en el repositorio envuelto
+func (r *Repository) Ping(ctx context.Context) (err error) {
+	ctx, span := r.start(ctx, "Ping")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.repo.Ping(ctx)
+	return err
+}
+
+// Close cierra la conexión subyacente
+func (r *Repository) Close() error {
+	return r.repo.Close()
+}
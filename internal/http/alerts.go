@@ -0,0 +1,209 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/service"
+)
+
+// Límites de la consulta de alertas
+const (
+	defaultAlertsLimit = 100
+	maxAlertsLimit     = 1000
+)
+
+// handleListAlertRules retorna todas las reglas de alerta
+func (s *Server) handleListAlertRules(c *gin.Context) {
+	rules, err := s.alertService.ListAlertRules(c.Request.Context())
+	if err != nil {
+		s.respondAlertError(c, err)
+		return
+	}
+
+	if rules == nil {
+		rules = []models.AlertRule{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(rules),
+		"data":  rules,
+	})
+}
+
+// handleGetAlertRule retorna una regla de alerta por ID
+func (s *Server) handleGetAlertRule(c *gin.Context) {
+	id, ok := s.parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	rule, err := s.alertService.GetAlertRule(c.Request.Context(), id)
+	if err != nil {
+		s.respondAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// handleCreateAlertRule registra una nueva regla de alerta
+func (s *Server) handleCreateAlertRule(c *gin.Context) {
+	// Las reglas quedan activas salvo que se indique lo contrario
+	rule := models.AlertRule{Activo: true}
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Formato JSON inválido",
+		})
+		return
+	}
+
+	if err := s.alertService.CreateAlertRule(c.Request.Context(), &rule); err != nil {
+		s.respondAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// handleUpdateAlertRule reemplaza una regla de alerta
+func (s *Server) handleUpdateAlertRule(c *gin.Context) {
+	id, ok := s.parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	rule := models.AlertRule{Activo: true}
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Formato JSON inválido",
+		})
+		return
+	}
+	rule.IDRegla = id
+
+	if err := s.alertService.UpdateAlertRule(c.Request.Context(), &rule); err != nil {
+		s.respondAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// handleDeleteAlertRule elimina una regla de alerta
+func (s *Server) handleDeleteAlertRule(c *gin.Context) {
+	id, ok := s.parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := s.alertService.DeleteAlertRule(c.Request.Context(), id); err != nil {
+		s.respondAlertError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleListAlerts retorna el historial de alertas de un dispositivo.
+// Parámetros: status (active|cleared), from, to (RFC3339 o epoch) y limit
+func (s *Server) handleListAlerts(c *gin.Context) {
+	identifier := c.Param("identificador")
+
+	query, err := parseAlertQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	alerts, err := s.alertService.ListAlerts(c.Request.Context(), identifier, query)
+	if err != nil {
+		s.respondAlertError(c, err)
+		return
+	}
+
+	if alerts == nil {
+		alerts = []models.Alert{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"identificador": identifier,
+		"count":         len(alerts),
+		"data":          alerts,
+	})
+}
+
+// parseAlertQuery construye la consulta de alertas a partir de los parámetros de la URL
+func parseAlertQuery(c *gin.Context) (*models.AlertQuery, error) {
+	query := &models.AlertQuery{
+		Estado: c.Query("status"),
+		Limit:  defaultAlertsLimit,
+	}
+
+	switch query.Estado {
+	case "", models.AlertStatusActive, models.AlertStatusCleared:
+	default:
+		return nil, errors.New("parámetro status inválido: debe ser active o cleared")
+	}
+
+	if v := c.Query("from"); v != "" {
+		from, err := parseTimeParam(v)
+		if err != nil {
+			return nil, fmt.Errorf("parámetro from inválido: %s", v)
+		}
+		query.From = &from
+	}
+
+	if v := c.Query("to"); v != "" {
+		to, err := parseTimeParam(v)
+		if err != nil {
+			return nil, fmt.Errorf("parámetro to inválido: %s", v)
+		}
+		query.To = &to
+	}
+
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return nil, errors.New("el parámetro from debe ser anterior a to")
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxAlertsLimit {
+			return nil, fmt.Errorf("parámetro limit inválido: debe estar entre 1 y %d", maxAlertsLimit)
+		}
+		query.Limit = limit
+	}
+
+	return query, nil
+}
+
+// respondAlertError traduce los errores del servicio de alertas a respuestas HTTP
+func (s *Server) respondAlertError(c *gin.Context, err error) {
+	var ve *service.ValidationErrors
+	switch {
+	case errors.As(err, &ve):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Validación fallida",
+			"fields": ve.GetErrors(),
+		})
+	case errors.Is(err, service.ErrAlertRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Regla de alerta no encontrada",
+		})
+	case errors.Is(err, service.ErrDeviceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Dispositivo no encontrado",
+		})
+	default:
+		s.logger.Error("Error en administración de alertas: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error al procesar la solicitud",
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/service"
+)
+
+// handleListDevices retorna todos los dispositivos registrados
+func (s *Server) handleListDevices(c *gin.Context) {
+	devices, err := s.deviceService.ListDevices(c.Request.Context())
+	if err != nil {
+		s.logger.Error("Error al listar dispositivos: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error al listar dispositivos",
+		})
+		return
+	}
+
+	if devices == nil {
+		devices = []models.Device{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(devices),
+		"data":  devices,
+	})
+}
+
+// handleGetDevice retorna un dispositivo por identificador
+func (s *Server) handleGetDevice(c *gin.Context) {
+	device, err := s.deviceService.GetDevice(c.Request.Context(), c.Param("identificador"))
+	if err != nil {
+		s.respondDeviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, device)
+}
+
+// handleCreateDevice registra un nuevo dispositivo
+func (s *Server) handleCreateDevice(c *gin.Context) {
+	var input models.DeviceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Formato JSON inválido",
+		})
+		return
+	}
+
+	device, err := s.deviceService.CreateDevice(c.Request.Context(), &input)
+	if err != nil {
+		s.respondDeviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, device)
+}
+
+// handleUpdateDevice actualiza un dispositivo
+func (s *Server) handleUpdateDevice(c *gin.Context) {
+	var input models.DeviceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Formato JSON inválido",
+		})
+		return
+	}
+
+	device, err := s.deviceService.UpdateDevice(c.Request.Context(), c.Param("identificador"), &input)
+	if err != nil {
+		s.respondDeviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, device)
+}
+
+// handleEnableDevice habilita un dispositivo
+func (s *Server) handleEnableDevice(c *gin.Context) {
+	s.setDeviceActive(c, true)
+}
+
+// handleDisableDevice deshabilita un dispositivo
+func (s *Server) handleDisableDevice(c *gin.Context) {
+	s.setDeviceActive(c, false)
+}
+
+// setDeviceActive cambia el estado de un dispositivo
+func (s *Server) setDeviceActive(c *gin.Context, active bool) {
+	device, err := s.deviceService.SetDeviceActive(c.Request.Context(), c.Param("identificador"), active)
+	if err != nil {
+		s.respondDeviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, device)
+}
+
+// handleDeleteDevice elimina un dispositivo
+func (s *Server) handleDeleteDevice(c *gin.Context) {
+	if err := s.deviceService.DeleteDevice(c.Request.Context(), c.Param("identificador")); err != nil {
+		s.respondDeviceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondDeviceError traduce los errores del servicio de dispositivos a respuestas HTTP
+func (s *Server) respondDeviceError(c *gin.Context, err error) {
+	var ve *service.ValidationErrors
+	switch {
+	case errors.As(err, &ve):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Validación fallida",
+			"fields": ve.GetErrors(),
+		})
+	case errors.Is(err, service.ErrDeviceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Dispositivo no encontrado",
+		})
+	case errors.Is(err, service.ErrDeviceExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Ya existe un dispositivo con ese identificador",
+		})
+	default:
+		s.logger.Error("Error en administración de dispositivos: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error al procesar la solicitud",
+		})
+	}
+}
+
+// handleListOfflineDevices retorna los dispositivos actualmente fuera de línea
+func (s *Server) handleListOfflineDevices(c *gin.Context) {
+	devices, err := s.telemetryService.ListOfflineDevices(c.Request.Context())
+	if err != nil {
+		s.logger.Error("Error al listar dispositivos fuera de línea: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error al listar dispositivos fuera de línea",
+		})
+		return
+	}
+
+	if devices == nil {
+		devices = []models.Device{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(devices),
+		"data":  devices,
+	})
+}
@@ -0,0 +1,326 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/service"
+)
+
+// Límites de la consulta de eventos
+const (
+	defaultEventsLimit = 100
+	maxEventsLimit     = 1000
+)
+
+// handleListGroups retorna todos los grupos de dispositivos
+func (s *Server) handleListGroups(c *gin.Context) {
+	groups, err := s.geofenceService.ListGroups(c.Request.Context())
+	if err != nil {
+		s.respondGeofenceError(c, err)
+		return
+	}
+
+	if groups == nil {
+		groups = []models.DeviceGroup{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(groups),
+		"data":  groups,
+	})
+}
+
+// handleCreateGroup registra un nuevo grupo de dispositivos
+func (s *Server) handleCreateGroup(c *gin.Context) {
+	var group models.DeviceGroup
+	if err := c.ShouldBindJSON(&group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Formato JSON inválido",
+		})
+		return
+	}
+
+	if err := s.geofenceService.CreateGroup(c.Request.Context(), &group); err != nil {
+		s.respondGeofenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+// handleDeleteGroup elimina un grupo de dispositivos
+func (s *Server) handleDeleteGroup(c *gin.Context) {
+	id, ok := s.parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := s.geofenceService.DeleteGroup(c.Request.Context(), id); err != nil {
+		s.respondGeofenceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleListGeofences retorna todas las geocercas
+func (s *Server) handleListGeofences(c *gin.Context) {
+	geofences, err := s.geofenceService.ListGeofences(c.Request.Context())
+	if err != nil {
+		s.respondGeofenceError(c, err)
+		return
+	}
+
+	if geofences == nil {
+		geofences = []models.Geofence{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(geofences),
+		"data":  geofences,
+	})
+}
+
+// handleGetGeofence retorna una geocerca por ID
+func (s *Server) handleGetGeofence(c *gin.Context) {
+	id, ok := s.parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	geofence, err := s.geofenceService.GetGeofence(c.Request.Context(), id)
+	if err != nil {
+		s.respondGeofenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, geofence)
+}
+
+// handleCreateGeofence registra una nueva geocerca
+func (s *Server) handleCreateGeofence(c *gin.Context) {
+	// Las geocercas quedan activas salvo que se indique lo contrario
+	geofence := models.Geofence{Activo: true}
+	if err := c.ShouldBindJSON(&geofence); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Formato JSON inválido",
+		})
+		return
+	}
+
+	if err := s.geofenceService.CreateGeofence(c.Request.Context(), &geofence); err != nil {
+		s.respondGeofenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, geofence)
+}
+
+// handleUpdateGeofence reemplaza una geocerca
+func (s *Server) handleUpdateGeofence(c *gin.Context) {
+	id, ok := s.parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	geofence := models.Geofence{Activo: true}
+	if err := c.ShouldBindJSON(&geofence); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Formato JSON inválido",
+		})
+		return
+	}
+	geofence.IDGeocerca = id
+
+	if err := s.geofenceService.UpdateGeofence(c.Request.Context(), &geofence); err != nil {
+		s.respondGeofenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, geofence)
+}
+
+// handleDeleteGeofence elimina una geocerca
+func (s *Server) handleDeleteGeofence(c *gin.Context) {
+	id, ok := s.parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := s.geofenceService.DeleteGeofence(c.Request.Context(), id); err != nil {
+		s.respondGeofenceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleAssignGeofence asigna una geocerca a un dispositivo o a un grupo
+func (s *Server) handleAssignGeofence(c *gin.Context) {
+	s.changeAssignment(c, true)
+}
+
+// handleUnassignGeofence quita la asignación de una geocerca
+func (s *Server) handleUnassignGeofence(c *gin.Context) {
+	s.changeAssignment(c, false)
+}
+
+// changeAssignment asigna o quita una geocerca según el cuerpo de la solicitud
+func (s *Server) changeAssignment(c *gin.Context, assign bool) {
+	id, ok := s.parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input service.AssignmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Formato JSON inválido",
+		})
+		return
+	}
+
+	var err error
+	if assign {
+		err = s.geofenceService.AssignGeofence(c.Request.Context(), id, &input)
+	} else {
+		err = s.geofenceService.UnassignGeofence(c.Request.Context(), id, &input)
+	}
+	if err != nil {
+		s.respondGeofenceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleListEvents retorna los eventos de un dispositivo.
+// Parámetros: type, from, to (RFC3339 o epoch) y limit
+func (s *Server) handleListEvents(c *gin.Context) {
+	identifier := c.Param("identificador")
+
+	query, err := parseEventQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	events, err := s.telemetryService.ListEvents(c.Request.Context(), identifier, query)
+	if errors.Is(err, service.ErrDeviceNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Dispositivo no encontrado",
+		})
+		return
+	}
+	if err != nil {
+		s.logger.Error("Error al consultar eventos del dispositivo %s: %v", identifier, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error al consultar eventos",
+		})
+		return
+	}
+
+	if events == nil {
+		events = []models.Event{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"identificador": identifier,
+		"count":         len(events),
+		"data":          events,
+	})
+}
+
+// parseEventQuery construye la consulta de eventos a partir de los parámetros de la URL
+func parseEventQuery(c *gin.Context) (*models.EventQuery, error) {
+	query := &models.EventQuery{
+		Tipo:  c.Query("type"),
+		Limit: defaultEventsLimit,
+	}
+
+	if v := c.Query("from"); v != "" {
+		from, err := parseTimeParam(v)
+		if err != nil {
+			return nil, fmt.Errorf("parámetro from inválido: %s", v)
+		}
+		query.From = &from
+	}
+
+	if v := c.Query("to"); v != "" {
+		to, err := parseTimeParam(v)
+		if err != nil {
+			return nil, fmt.Errorf("parámetro to inválido: %s", v)
+		}
+		query.To = &to
+	}
+
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return nil, errors.New("el parámetro from debe ser anterior a to")
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxEventsLimit {
+			return nil, fmt.Errorf("parámetro limit inválido: debe estar entre 1 y %d", maxEventsLimit)
+		}
+		query.Limit = limit
+	}
+
+	return query, nil
+}
+
+// parseIDParam obtiene el parámetro numérico "id" de la ruta
+func (s *Server) parseIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "ID inválido",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondGeofenceError traduce los errores del servicio de geocercas a respuestas HTTP
+func (s *Server) respondGeofenceError(c *gin.Context, err error) {
+	var ve *service.ValidationErrors
+	switch {
+	case errors.As(err, &ve):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Validación fallida",
+			"fields": ve.GetErrors(),
+		})
+	case errors.Is(err, service.ErrGeofenceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Geocerca no encontrada",
+		})
+	case errors.Is(err, service.ErrGroupNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Grupo no encontrado",
+		})
+	case errors.Is(err, service.ErrAssignmentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Asignación no encontrada",
+		})
+	case errors.Is(err, service.ErrDeviceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Dispositivo no encontrado",
+		})
+	case errors.Is(err, service.ErrAssignmentExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "La geocerca ya está asignada",
+		})
+	default:
+		s.logger.Error("Error en administración de geocercas: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error al procesar la solicitud",
+		})
+	}
+}
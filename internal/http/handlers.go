@@ -1,21 +1,40 @@
 package http
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/metrics"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/service"
 )
 
 // handleTelemetry maneja los datos de telemetría entrantes vía HTTP POST
 func (s *Server) handleTelemetry(c *gin.Context) {
+	log := s.requestLogger(c.Request.Context())
+
 	var req models.TelemetryRequest
 
 	// Vincular solicitud JSON
-	if err := c.ShouldBindJSON(&req); err != nil {
-		s.logger.Warning("Solicitud JSON inválida: %v", err)
+	err := c.ShouldBindJSON(&req)
+	if isBodyTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": "El cuerpo de la solicitud excede el tamaño máximo",
+		})
+		return
+	}
+	if err != nil {
+		metrics.RecordIngest(metrics.TransportHTTP, metrics.OutcomeInvalid)
+		log.Warning("Solicitud JSON inválida: %v", err)
 
 		// Registrar solicitud inválida
 		invalidReq := &models.InvalidRequest{
@@ -28,7 +47,7 @@ func (s *Server) handleTelemetry(c *gin.Context) {
 				},
 			},
 		}
-		s.logger.LogInvalidRequest(invalidReq)
+		log.LogInvalidRequest(invalidReq)
 
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Formato JSON inválido",
@@ -36,9 +55,15 @@ func (s *Server) handleTelemetry(c *gin.Context) {
 		return
 	}
 
+	// El ID de mensaje también puede enviarse en el header Idempotency-Key
+	if req.MessageID == "" {
+		req.MessageID = c.GetHeader(idempotencyKeyHeader)
+	}
+
 	// Validar campos requeridos
 	if err := service.ValidateRequiredFields(&req); err != nil {
-		s.logger.Warning("Validación fallida: %v", err)
+		metrics.RecordIngest(metrics.TransportHTTP, metrics.OutcomeInvalid)
+		log.Warning("Validación fallida: %v", err)
 
 		// Extraer errores de validación
 		var validationErrors []models.ValidationError
@@ -55,7 +80,7 @@ func (s *Server) handleTelemetry(c *gin.Context) {
 			Longitud:      req.Longitud,
 			Errors:        validationErrors,
 		}
-		s.logger.LogInvalidRequest(invalidReq)
+		log.LogInvalidRequest(invalidReq)
 
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":  "Validación fallida",
@@ -64,10 +89,67 @@ func (s *Server) handleTelemetry(c *gin.Context) {
 		return
 	}
 
-	// Procesar datos de telemetría
+	// Responder a un mensaje repetido con el resultado del original
 	ctx := c.Request.Context()
-	if err := s.telemetryService.ProcessTelemetryData(ctx, &req); err != nil {
-		s.logger.Error("Error al procesar datos de telemetría: %v", err)
+	claim, original, err := s.dedupService.Claim(ctx, &req)
+	if errors.Is(err, service.ErrDuplicate) {
+		metrics.RecordIngest(metrics.TransportHTTP, metrics.OutcomeDuplicate)
+		log.With(logger.KeyDevice, req.Identificador).Info("Mensaje duplicado, se responde el resultado original")
+		respondDuplicate(c, original)
+		return
+	}
+
+	// Encolar para procesamiento asíncrono. La cola completa la reserva del
+	// mensaje con el resultado de su procesamiento
+	if s.ingestPipeline != nil {
+		if err := s.ingestPipeline.Enqueue(ctx, &req, "HTTP", claim); err != nil {
+			s.dedupService.Release(ctx, claim)
+			metrics.RecordIngest(metrics.TransportHTTP, metrics.OutcomeRejected)
+			s.respondQueueError(c, err)
+			return
+		}
+
+		respondAccepted(c)
+		return
+	}
+
+	// Procesar datos de telemetría
+	err = s.telemetryService.ProcessTelemetryData(ctx, &req)
+	metrics.RecordIngest(metrics.TransportHTTP, service.IngestOutcome(err))
+	if err != nil {
+		// Base de datos no disponible: la medición quedó en el spool para procesarse después
+		if errors.Is(err, service.ErrSpooled) {
+			s.dedupService.Complete(ctx, claim, service.MessageAccepted)
+			c.JSON(http.StatusAccepted, gin.H{
+				"status":  "accepted",
+				"message": "Datos de telemetría recibidos; se procesarán cuando la base de datos esté disponible",
+			})
+			return
+		}
+
+		s.dedupService.Release(ctx, claim)
+
+		// Errores de validación detectados durante el procesamiento (ej. desfase de reloj)
+		var ve *service.ValidationErrors
+		if errors.As(err, &ve) {
+			log.Warning("Validación fallida: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validación fallida",
+				"fields": ve.GetErrors(),
+			})
+			return
+		}
+
+		// Dispositivo no registrado o deshabilitado: reintentar no sirve
+		if status, message, ok := deviceError(err); ok {
+			log.With(logger.KeyDevice, req.Identificador).Warning("%v", err)
+			c.JSON(status, gin.H{
+				"error": message,
+			})
+			return
+		}
+
+		log.With(logger.KeyDevice, req.Identificador).Error("Error al procesar datos de telemetría: %v", err)
 
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Error al procesar datos de telemetría",
@@ -75,9 +157,372 @@ func (s *Server) handleTelemetry(c *gin.Context) {
 		return
 	}
 
-	// Respuesta exitosa
+	s.dedupService.Complete(ctx, claim, service.MessageProcessed)
+	respondProcessed(c)
+}
+
+// idempotencyKeyHeader es el header alternativo al campo messageId de la medición
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// respondDuplicate responde a un mensaje repetido con el resultado del original,
+// indicándolo en el header Idempotent-Replayed. Si el original aún se procesa
+// responde 409 para que el dispositivo reintente
+func respondDuplicate(c *gin.Context, original string) {
+	switch original {
+	case service.MessageProcessed:
+		c.Header("Idempotent-Replayed", "true")
+		respondProcessed(c)
+	case service.MessageAccepted:
+		c.Header("Idempotent-Replayed", "true")
+		respondAccepted(c)
+	default:
+		c.Header("Retry-After", queueRetryAfter)
+		c.JSON(http.StatusConflict, gin.H{
+			"error": messageInProgress,
+		})
+	}
+}
+
+// messageInProgress es el error retornado a la repetición de un mensaje que aún se procesa
+const messageInProgress = "El mensaje se está procesando, reintente más tarde"
+
+// respondProcessed responde a una medición procesada y almacenada
+func respondProcessed(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"message": "Datos de telemetría procesados exitosamente",
 	})
 }
+
+// respondAccepted responde a una medición encolada para procesamiento asíncrono
+func respondAccepted(c *gin.Context) {
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "accepted",
+		"message": "Datos de telemetría recibidos para procesamiento",
+	})
+}
+
+// Estados posibles de un elemento de un lote
+const (
+	batchStatusSuccess  = "success"
+	batchStatusAccepted = "accepted" // Almacenado en el spool, se procesará después
+	batchStatusInvalid  = "invalid"
+	batchStatusError    = "error"
+)
+
+// handleTelemetryBatch maneja lotes de datos de telemetría vía HTTP POST.
+// Acepta un arreglo JSON o NDJSON (un objeto por línea) y retorna el resultado
+// de cada elemento para que el dispositivo sepa qué lecturas debe reintentar
+func (s *Server) handleTelemetryBatch(c *gin.Context) {
+	log := s.requestLogger(c.Request.Context())
+
+	body, err := c.GetRawData()
+	if isBodyTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("El lote excede el tamaño máximo de %d MB", s.config.BatchMaxSizeMB),
+		})
+		return
+	}
+	if err != nil {
+		log.Warning("Error al leer cuerpo del lote: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Error al leer el cuerpo de la solicitud",
+		})
+		return
+	}
+
+	// Separar los elementos del lote según el tipo de contenido
+	var items []json.RawMessage
+	if isNDJSON(c.ContentType()) {
+		items, err = splitNDJSON(body)
+	} else {
+		err = json.Unmarshal(body, &items)
+	}
+	if err != nil {
+		log.Warning("Lote de telemetría inválido: %v", err)
+
+		invalidReq := &models.InvalidRequest{
+			Timestamp: time.Now(),
+			IPAddress: c.ClientIP(),
+			Errors: []models.ValidationError{
+				{
+					Field:   "json",
+					Message: "Formato de lote inválido",
+				},
+			},
+		}
+		log.LogInvalidRequest(invalidReq)
+
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Formato de lote inválido",
+		})
+		return
+	}
+
+	if len(items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "El lote está vacío",
+		})
+		return
+	}
+
+	if len(items) > s.config.BatchMaxItems {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("El lote excede el máximo de %d elementos", s.config.BatchMaxItems),
+		})
+		return
+	}
+
+	// Procesar cada elemento de forma independiente. Con la cola de ingesta se
+	// encolan todos los elementos y luego se espera el resultado de cada uno
+	ctx := c.Request.Context()
+	batch := make([]*batchItem, len(items))
+	for i, raw := range items {
+		batch[i] = s.startBatchItem(ctx, c.ClientIP(), i, raw)
+	}
+
+	results := make([]models.BatchItemResult, len(items))
+	accepted := 0
+
+	for i, item := range batch {
+		if item.done != nil {
+			s.finishBatchItem(ctx, item, <-item.done)
+		}
+		results[i] = item.result
+		// Los elementos almacenados en el spool no deben reintentarse
+		if results[i].Status == batchStatusSuccess || results[i].Status == batchStatusAccepted {
+			accepted++
+		}
+	}
+
+	log.Info("Lote de telemetría procesado: %d elementos, %d aceptados, %d rechazados",
+		len(items), accepted, len(items)-accepted)
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":    len(items),
+		"accepted": accepted,
+		"rejected": len(items) - accepted,
+		"results":  results,
+	})
+}
+
+// batchItem es un elemento de un lote y su resultado
+type batchItem struct {
+	result models.BatchItemResult
+	req    *models.TelemetryRequest
+	claim  *service.MessageClaim
+	done   <-chan error // Resultado pendiente de la cola de ingesta, si el elemento se encoló
+}
+
+// startBatchItem valida un único elemento de un lote y lo procesa, o lo encola
+// si la cola de ingesta está habilitada; en ese caso el resultado queda pendiente en done
+func (s *Server) startBatchItem(ctx context.Context, ip string, index int, raw json.RawMessage) *batchItem {
+	item := &batchItem{result: models.BatchItemResult{Index: index}}
+	log := s.requestLogger(ctx)
+
+	var req models.TelemetryRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		metrics.RecordIngest(metrics.TransportHTTP, metrics.OutcomeInvalid)
+
+		invalidReq := &models.InvalidRequest{
+			Timestamp: time.Now(),
+			IPAddress: ip,
+			Errors: []models.ValidationError{
+				{
+					Field:   "json",
+					Message: "Formato JSON inválido",
+				},
+			},
+		}
+		log.LogInvalidRequest(invalidReq)
+
+		item.result.Status = batchStatusInvalid
+		item.result.Error = "Formato JSON inválido"
+		return item
+	}
+	item.result.Identificador = req.Identificador
+
+	// Validar campos requeridos
+	if err := service.ValidateRequiredFields(&req); err != nil {
+		metrics.RecordIngest(metrics.TransportHTTP, metrics.OutcomeInvalid)
+
+		var validationErrors []models.ValidationError
+		if ve, ok := err.(*service.ValidationErrors); ok {
+			validationErrors = ve.GetErrors()
+		}
+
+		invalidReq := &models.InvalidRequest{
+			Timestamp:     time.Now(),
+			IPAddress:     ip,
+			Identificador: req.Identificador,
+			Latitud:       req.Latitud,
+			Longitud:      req.Longitud,
+			Errors:        validationErrors,
+		}
+		log.LogInvalidRequest(invalidReq)
+
+		item.result.Status = batchStatusInvalid
+		item.result.Error = "Validación fallida"
+		item.result.Fields = validationErrors
+		return item
+	}
+
+	// Responder a un mensaje repetido con el resultado del original
+	claim, original, err := s.dedupService.Claim(ctx, &req)
+	if errors.Is(err, service.ErrDuplicate) {
+		metrics.RecordIngest(metrics.TransportHTTP, metrics.OutcomeDuplicate)
+		item.result.Duplicate = true
+		switch original {
+		case service.MessageProcessed:
+			item.result.Status = batchStatusSuccess
+		case service.MessageAccepted:
+			item.result.Status = batchStatusAccepted
+		default:
+			item.result.Status = batchStatusError
+			item.result.Error = messageInProgress
+		}
+		return item
+	}
+	item.req = &req
+	item.claim = claim
+
+	// Encolar y esperar el resultado después, para procesar el lote en paralelo
+	if s.ingestPipeline != nil {
+		done, err := s.ingestPipeline.EnqueueResult(ctx, &req, "HTTP")
+		if err != nil {
+			s.dedupService.Release(ctx, claim)
+			metrics.RecordIngest(metrics.TransportHTTP, metrics.OutcomeRejected)
+			item.result.Status = batchStatusError
+			item.result.Error = queueErrorMessage(err)
+			return item
+		}
+
+		item.done = done
+		return item
+	}
+
+	// Procesar datos de telemetría
+	err = s.telemetryService.ProcessTelemetryData(ctx, &req)
+	metrics.RecordIngest(metrics.TransportHTTP, service.IngestOutcome(err))
+	s.finishBatchItem(ctx, item, err)
+	return item
+}
+
+// finishBatchItem registra el resultado del procesamiento de un elemento de un lote
+func (s *Server) finishBatchItem(ctx context.Context, item *batchItem, err error) {
+	if err != nil {
+		if errors.Is(err, service.ErrSpooled) {
+			s.dedupService.Complete(ctx, item.claim, service.MessageAccepted)
+			item.result.Status = batchStatusAccepted
+			return
+		}
+		s.dedupService.Release(ctx, item.claim)
+
+		var ve *service.ValidationErrors
+		if errors.As(err, &ve) {
+			item.result.Status = batchStatusInvalid
+			item.result.Error = "Validación fallida"
+			item.result.Fields = ve.GetErrors()
+			return
+		}
+
+		if _, message, ok := deviceError(err); ok {
+			item.result.Status = batchStatusInvalid
+			item.result.Error = message
+			return
+		}
+
+		item.result.Status = batchStatusError
+		if errors.Is(err, service.ErrQueueClosed) {
+			item.result.Error = queueErrorMessage(err)
+			return
+		}
+
+		// La cola de ingesta ya registró el error de los elementos encolados
+		if item.done == nil {
+			s.requestLogger(ctx).With(logger.KeyDevice, item.req.Identificador).Error("Error al procesar elemento %d del lote: %v", item.result.Index, err)
+		}
+		item.result.Error = "Error al procesar datos de telemetría"
+		return
+	}
+
+	s.dedupService.Complete(ctx, item.claim, service.MessageProcessed)
+	item.result.Status = batchStatusSuccess
+}
+
+// deviceError retorna el código y el mensaje de respuesta de una medición cuyo
+// dispositivo no está registrado (404) o está deshabilitado (403)
+func deviceError(err error) (int, string, bool) {
+	switch {
+	case errors.Is(err, service.ErrDeviceNotFound):
+		return http.StatusNotFound, "Dispositivo no encontrado", true
+	case errors.Is(err, service.ErrDeviceDisabled):
+		return http.StatusForbidden, "Dispositivo deshabilitado", true
+	}
+	return 0, "", false
+}
+
+// requestLogger retorna el logger de una solicitud de ingesta HTTP, con su ID de
+// solicitud y el transporte en cada línea
+func (s *Server) requestLogger(ctx context.Context) *logger.Logger {
+	return s.logger.WithContext(ctx).With(logger.KeyTransport, metrics.TransportHTTP)
+}
+
+// queueRetryAfter es el tiempo sugerido al cliente para reintentar con la cola de ingesta llena
+const queueRetryAfter = "1"
+
+// respondQueueError responde a una solicitud que no se pudo encolar: 429 si la
+// cola está llena (el cliente debe reintentar) y 503 si el servidor se está apagando
+func (s *Server) respondQueueError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrQueueClosed) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": queueErrorMessage(err),
+		})
+		return
+	}
+
+	s.requestLogger(c.Request.Context()).Warning("Cola de ingesta llena, solicitud rechazada")
+	c.Header("Retry-After", queueRetryAfter)
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": queueErrorMessage(err),
+	})
+}
+
+// queueErrorMessage retorna el mensaje para el cliente según el error de la cola de ingesta
+func queueErrorMessage(err error) string {
+	if errors.Is(err, service.ErrQueueClosed) {
+		return "Servidor apagándose, reintente más tarde"
+	}
+	return "Servidor saturado, reintente más tarde"
+}
+
+// isNDJSON indica si el tipo de contenido corresponde a JSON delimitado por líneas
+func isNDJSON(contentType string) bool {
+	switch strings.ToLower(contentType) {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return true
+	}
+	return false
+}
+
+// splitNDJSON separa un cuerpo NDJSON en mensajes JSON individuales, ignorando líneas vacías
+func splitNDJSON(body []byte) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		item := make(json.RawMessage, len(line))
+		copy(item, line)
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error al leer NDJSON: %w", err)
+	}
+
+	return items, nil
+}
@@ -0,0 +1,247 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/service"
+)
+
+// Límites de paginación del historial de mediciones
+const (
+	defaultMeasurementsLimit = 100
+	maxMeasurementsLimit     = 1000
+)
+
+// measurementFields son los campos seleccionables con el parámetro "fields"
+var measurementFields = map[string]bool{
+	"idMedicion":       true,
+	"idTelemetria":     true,
+	"fecha":            true,
+	"fechaDispositivo": true,
+	"fechaRecepcion":   true,
+	"latitud":          true,
+	"longitud":         true,
+	"distancia":        true,
+	"sensor_1":         true,
+	"sensor_2":         true,
+	"sensor_3":         true,
+	"sensor_4":         true,
+	"sensor_5":         true,
+	"sensores":         true,
+}
+
+// handleListMeasurements retorna el historial de mediciones de un dispositivo.
+// Parámetros: from, to (RFC3339 o epoch), limit, order (asc|desc), cursor y fields (separados por coma)
+func (s *Server) handleListMeasurements(c *gin.Context) {
+	identifier := c.Param("identificador")
+
+	query, fields, err := parseMeasurementQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Se pide una medición extra para saber si existe una página siguiente
+	limit := query.Limit
+	query.Limit = limit + 1
+
+	measurements, err := s.telemetryService.ListMeasurements(c.Request.Context(), identifier, query)
+	if errors.Is(err, service.ErrDeviceNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Dispositivo no encontrado",
+		})
+		return
+	}
+	if err != nil {
+		s.logger.Error("Error al consultar mediciones del dispositivo %s: %v", identifier, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error al consultar mediciones",
+		})
+		return
+	}
+
+	var nextCursor *string
+	if len(measurements) > limit {
+		measurements = measurements[:limit]
+		last := measurements[limit-1]
+		cursor := encodeCursor(&models.MeasurementCursor{Fecha: last.Fecha, IDMedicion: last.IDMedicion})
+		nextCursor = &cursor
+	}
+
+	data, err := selectFields(measurements, fields)
+	if err != nil {
+		s.logger.Error("Error al serializar mediciones: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error al consultar mediciones",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"identificador": identifier,
+		"count":         len(data),
+		"data":          data,
+		"nextCursor":    nextCursor,
+	})
+}
+
+// parseMeasurementQuery construye la consulta a partir de los parámetros de la URL
+func parseMeasurementQuery(c *gin.Context) (*models.MeasurementQuery, []string, error) {
+	query := &models.MeasurementQuery{
+		Limit:          defaultMeasurementsLimit,
+		IncludeSensors: true,
+	}
+
+	if v := c.Query("from"); v != "" {
+		from, err := parseTimeParam(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parámetro from inválido: %s", v)
+		}
+		query.From = &from
+	}
+
+	if v := c.Query("to"); v != "" {
+		to, err := parseTimeParam(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parámetro to inválido: %s", v)
+		}
+		query.To = &to
+	}
+
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return nil, nil, errors.New("el parámetro from debe ser anterior a to")
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxMeasurementsLimit {
+			return nil, nil, fmt.Errorf("parámetro limit inválido: debe estar entre 1 y %d", maxMeasurementsLimit)
+		}
+		query.Limit = limit
+	}
+
+	switch strings.ToLower(c.DefaultQuery("order", "desc")) {
+	case "asc":
+		query.Ascending = true
+	case "desc":
+		query.Ascending = false
+	default:
+		return nil, nil, errors.New("parámetro order inválido: debe ser asc o desc")
+	}
+
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := decodeCursor(v)
+		if err != nil {
+			return nil, nil, errors.New("parámetro cursor inválido")
+		}
+		query.Cursor = cursor
+	}
+
+	var fields []string
+	if v := c.Query("fields"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if !measurementFields[field] {
+				return nil, nil, fmt.Errorf("campo desconocido en fields: %s", field)
+			}
+			fields = append(fields, field)
+		}
+
+		// Evitar consultar sensores con nombre si no se solicitan
+		query.IncludeSensors = false
+		for _, field := range fields {
+			if field == "sensores" {
+				query.IncludeSensors = true
+			}
+		}
+	}
+
+	return query, fields, nil
+}
+
+// parseTimeParam parsea una fecha en RFC3339 o epoch en segundos o milisegundos
+func parseTimeParam(value string) (time.Time, error) {
+	var t models.DeviceTime
+	if err := t.UnmarshalJSON([]byte(strconv.Quote(value))); err != nil {
+		return time.Time{}, err
+	}
+	return t.Time, nil
+}
+
+// selectFields proyecta las mediciones a los campos solicitados (todos si fields está vacío)
+func selectFields(measurements []models.Measurement, fields []string) ([]map[string]json.RawMessage, error) {
+	data := make([]map[string]json.RawMessage, 0, len(measurements))
+
+	for i := range measurements {
+		encoded, err := json.Marshal(&measurements[i])
+		if err != nil {
+			return nil, err
+		}
+
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(encoded, &all); err != nil {
+			return nil, err
+		}
+
+		if len(fields) == 0 {
+			data = append(data, all)
+			continue
+		}
+
+		selected := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := all[field]; ok {
+				selected[field] = value
+			} else {
+				selected[field] = json.RawMessage("null")
+			}
+		}
+		data = append(data, selected)
+	}
+
+	return data, nil
+}
+
+// encodeCursor codifica un cursor de paginación como cadena opaca
+func encodeCursor(cursor *models.MeasurementCursor) string {
+	raw := fmt.Sprintf("%d:%d", cursor.Fecha.UnixNano(), cursor.IDMedicion)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor decodifica un cursor de paginación
+func decodeCursor(value string) (*models.MeasurementCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("formato de cursor inválido")
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MeasurementCursor{
+		Fecha:      time.Unix(0, nanos),
+		IDMedicion: id,
+	}, nil
+}
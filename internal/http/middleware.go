@@ -1,15 +1,106 @@
 package http
 
 import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/metrics"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/payload"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/service"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
 )
 
+// TracingMiddleware inicia un span por solicitud HTTP como hijo del contexto de
+// traza recibido en los headers traceparent/tracestate, y lo deja en el contexto
+// de la solicitud para que los handlers y servicios creen sus spans bajo él
+func TracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := tracing.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+
+		ctx, span := tracing.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// requestIDHeader es el header con el que se recibe y se responde el ID de solicitud
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength es el largo máximo aceptado para un ID de solicitud recibido
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware acepta el ID de solicitud recibido en X-Request-ID o genera
+// uno nuevo, lo retorna en la respuesta y lo deja en el contexto de la solicitud,
+// desde donde llega a los logs y registros de error del procesamiento
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = logger.NewRequestID()
+		}
+
+		c.Header(requestIDHeader, requestID)
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("http.request.id", requestID))
+		c.Request = c.Request.WithContext(logger.ContextWithRequestID(c.Request.Context(), requestID))
+
+		c.Next()
+	}
+}
+
+// validRequestID verifica que un ID de solicitud recibido no esté vacío, no sea
+// demasiado largo y solo contenga caracteres imprimibles ASCII sin espacios
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] <= ' ' || requestID[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
 // LoggingMiddleware registra solicitudes HTTP
 func LoggingMiddleware(log *logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -24,13 +115,13 @@ func LoggingMiddleware(log *logger.Logger) gin.HandlerFunc {
 		duration := time.Since(start)
 		statusCode := c.Writer.Status()
 
-		log.Info("%s %s - Status: %d - Duration: %v - IP: %s",
-			method,
-			path,
-			statusCode,
-			duration,
-			c.ClientIP(),
-		)
+		log.WithContext(c.Request.Context()).With(
+			"method", method,
+			"path", path,
+			"status", statusCode,
+			logger.KeyLatency, float64(duration)/float64(time.Millisecond),
+			"ip", c.ClientIP(),
+		).Info("%s %s - Status: %d", method, path, statusCode)
 	}
 }
 
@@ -78,6 +169,7 @@ func RateLimitMiddleware(cfg *config.RateLimitConfig) gin.HandlerFunc {
 
 		// Verificar si la solicitud está permitida
 		if !limiter.Allow() {
+			metrics.RateLimitRejectedTotal.WithLabelValues(c.FullPath()).Inc()
 			c.JSON(429, gin.H{
 				"error": "Límite de tasa excedido",
 			})
@@ -85,9 +177,390 @@ func RateLimitMiddleware(cfg *config.RateLimitConfig) gin.HandlerFunc {
 			return
 		}
 
-		// Agregar retraso de solicitud si está configurado
-		if cfg.RequestDelay > 0 {
-			time.Sleep(cfg.RequestDelay)
+		c.Next()
+	}
+}
+
+// MaxBodyMiddleware limita el tamaño del cuerpo de la solicitud a maxBytes. Al
+// superarlo, la lectura del cuerpo falla y el manejador responde 413
+func MaxBodyMiddleware(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+// isBodyTooLarge indica si la lectura del cuerpo falló por superar el límite de
+// MaxBodyMiddleware o PayloadMiddleware
+func isBodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+// Headers de solicitudes firmadas con HMAC-SHA256
+const (
+	headerSignature          = "X-Signature"
+	headerSignatureTimestamp = "X-Signature-Timestamp"
+	headerSignatureNonce     = "X-Signature-Nonce"
+)
+
+// AuthMiddleware verifica que el token del header, o la firma HMAC de la solicitud,
+// corresponda a cada dispositivo reportado en el cuerpo (objeto JSON, arreglo JSON o NDJSON).
+// Las solicitudes firmadas se verifican siempre, aun con la autenticación por token deshabilitada
+func AuthMiddleware(cfg *config.AuthConfig, authService *service.AuthService, log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		signature, err := extractSignature(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "Headers de firma inválidos",
+			})
+			return
+		}
+
+		if !cfg.Enabled && signature == nil {
+			c.Next()
+			return
+		}
+
+		// Leer el cuerpo y restaurarlo para el manejador
+		body, err := io.ReadAll(c.Request.Body)
+		if isBodyTooLarge(err) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "El cuerpo de la solicitud excede el tamaño máximo",
+			})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "Error al leer el cuerpo de la solicitud",
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// La firma se calcula sobre el cuerpo tal como lo envió el dispositivo,
+		// antes de descomprimirlo o convertirlo a JSON
+		signed := body
+		if raw, ok := c.Get(rawBodyKey); ok {
+			signed = raw.([]byte)
+		}
+
+		identifiers, err := extractIdentifiers(body, c.ContentType())
+		if err != nil || len(identifiers) == 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "No se pudo determinar el identificador del dispositivo",
+			})
+			return
+		}
+
+		token := extractToken(c, cfg.Header)
+		for _, identifier := range identifiers {
+			var err error
+			if signature != nil {
+				err = authService.VerifySignature(c.Request.Context(), identifier, signed, signature, c.ClientIP())
+			} else {
+				err = authService.Authenticate(c.Request.Context(), identifier, token, c.ClientIP())
+			}
+			if errors.Is(err, service.ErrUnauthorized) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"error": "No autorizado",
+				})
+				return
+			}
+			if err != nil {
+				log.WithContext(c.Request.Context()).Error("Error al autenticar dispositivo %s: %v", identifier, err)
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"error": "Error al verificar credenciales",
+				})
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// rawBodyKey es la clave del contexto con el cuerpo original de una solicitud
+// convertida por PayloadMiddleware
+const rawBodyKey = "rawBody"
+
+// PayloadMiddleware descomprime los cuerpos gzip o deflate (Content-Encoding) y
+// convierte a JSON los formatos CBOR, MessagePack y Protocol Buffers
+// (Content-Type), de modo que la autenticación y los handlers de ingesta
+// procesen siempre JSON. Sin tipo de contenido el formato se reconoce por el
+// cuerpo. batch indica que el cuerpo es un lote. Las solicitudes JSON sin
+// comprimir pasan sin cambios
+func PayloadMiddleware(cfg *config.PayloadConfig, batch bool, log *logger.Logger) gin.HandlerFunc {
+	maxBody := int64(cfg.MaxBodyMB) << 20
+	maxSize := int64(cfg.MaxDecompressedMB) << 20
+
+	return func(c *gin.Context) {
+		// Los lotes ya vienen limitados por MaxBodyMiddleware (SERVER_BATCH_MAX_SIZE_MB)
+		if !batch {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBody)
+		}
+
+		format, err := payload.FormatFromContentType(c.ContentType())
+		if err != nil {
+			rejectPayload(c, err, log)
+			return
+		}
+		encoding := c.GetHeader("Content-Encoding")
+		if format == payload.FormatJSON && encoding == payload.EncodingIdentity {
+			c.Next()
+			return
+		}
+
+		raw, err := io.ReadAll(c.Request.Body)
+		if isBodyTooLarge(err) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "El cuerpo de la solicitud excede el tamaño máximo",
+			})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "Error al leer el cuerpo de la solicitud",
+			})
+			return
+		}
+
+		body, err := payload.Decompress(raw, encoding, maxSize)
+		if err == nil && format == "" {
+			format, err = payload.DetectFormat(body)
+		}
+		if err == nil {
+			body, err = payload.ToJSON(body, format, batch)
+		}
+		if err != nil {
+			rejectPayload(c, err, log)
+			return
+		}
+
+		// Reemplazar el cuerpo por su versión JSON; NDJSON comprimido conserva su tipo
+		c.Set(rawBodyKey, raw)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Request.Header.Del("Content-Encoding")
+		if format != payload.FormatJSON {
+			c.Request.Header.Set("Content-Type", "application/json")
+		}
+
+		c.Next()
+	}
+}
+
+// rejectPayload responde a un cuerpo que no se pudo descomprimir o decodificar:
+// 415 si la compresión o el formato no están soportados, 413 si excede el tamaño
+// máximo y 400 si el contenido es inválido
+func rejectPayload(c *gin.Context, err error, log *logger.Logger) {
+	metrics.RecordIngest(metrics.TransportHTTP, metrics.OutcomeInvalid)
+
+	status := http.StatusBadRequest
+	message := "Formato de payload inválido"
+	switch {
+	case errors.Is(err, payload.ErrUnsupportedEncoding):
+		status = http.StatusUnsupportedMediaType
+		message = "Codificación de contenido no soportada"
+	case errors.Is(err, payload.ErrUnsupportedFormat):
+		status = http.StatusUnsupportedMediaType
+		message = "Formato de payload no soportado"
+	case errors.Is(err, payload.ErrTooLarge):
+		status = http.StatusRequestEntityTooLarge
+		message = "El payload descomprimido excede el tamaño máximo"
+	}
+
+	requestLog := log.WithContext(c.Request.Context()).With(logger.KeyTransport, metrics.TransportHTTP)
+	requestLog.Warning("Payload rechazado: %v", err)
+	requestLog.LogInvalidRequest(&models.InvalidRequest{
+		Timestamp: time.Now(),
+		IPAddress: c.ClientIP(),
+		Errors: []models.ValidationError{
+			{
+				Field:   "payload",
+				Message: message,
+			},
+		},
+	})
+
+	c.AbortWithStatusJSON(status, gin.H{
+		"error": message,
+	})
+}
+
+// extractSignature obtiene la firma HMAC de los headers; retorna nil si la solicitud no está firmada
+func extractSignature(c *gin.Context) (*service.Signature, error) {
+	value := c.GetHeader(headerSignature)
+	if value == "" {
+		return nil, nil
+	}
+
+	timestamp, err := strconv.ParseInt(c.GetHeader(headerSignatureTimestamp), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("timestamp de firma inválido: %w", err)
+	}
+
+	return &service.Signature{
+		Timestamp: timestamp,
+		Nonce:     c.GetHeader(headerSignatureNonce),
+		Value:     strings.ToLower(strings.TrimSpace(value)),
+	}, nil
+}
+
+// extractToken obtiene el token desde el header configurado o desde "Authorization: Bearer"
+func extractToken(c *gin.Context, header string) string {
+	if token := strings.TrimSpace(c.GetHeader(header)); token != "" {
+		return token
+	}
+
+	authorization := c.GetHeader("Authorization")
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "Bearer ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+
+	return ""
+}
+
+// extractIdentifiers obtiene los identificadores distintos presentes en el cuerpo
+func extractIdentifiers(body []byte, contentType string) ([]string, error) {
+	type identified struct {
+		Identificador string `json:"identificador"`
+	}
+
+	var items []json.RawMessage
+	trimmed := bytes.TrimSpace(body)
+	switch {
+	case isNDJSON(contentType):
+		var err error
+		if items, err = splitNDJSON(trimmed); err != nil {
+			return nil, err
+		}
+	case len(trimmed) > 0 && trimmed[0] == '[':
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, err
+		}
+	default:
+		items = []json.RawMessage{trimmed}
+	}
+
+	seen := make(map[string]bool)
+	var identifiers []string
+	for _, raw := range items {
+		var item identified
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, err
+		}
+		if item.Identificador == "" {
+			return nil, errors.New("identificador ausente")
+		}
+		if !seen[item.Identificador] {
+			seen[item.Identificador] = true
+			identifiers = append(identifiers, item.Identificador)
+		}
+	}
+
+	return identifiers, nil
+}
+
+// AdminAuthMiddleware exige el token de administración en "Authorization: Bearer".
+// Si no hay token configurado, la API de administración queda deshabilitada
+func AdminAuthMiddleware(cfg *config.AdminConfig, log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.Token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "API de administración deshabilitada",
+			})
+			return
+		}
+
+		token := extractToken(c, "X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) != 1 {
+			log.Warning("Acceso de administración rechazado - IP: %s", c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "No autorizado",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ReadAuthMiddleware valida el token de las consultas de dispositivos (historial,
+// eventos y alertas): READ_TOKEN o ADMIN_TOKEN. Sin ninguno de los dos las
+// consultas quedan deshabilitadas, ya que exponen posiciones y lecturas
+func ReadAuthMiddleware(cfg *config.AdminConfig, log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.ReadToken == "" && cfg.Token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "API de consulta deshabilitada",
+			})
+			return
+		}
+
+		token := extractToken(c, "X-Read-Token")
+		if !matchesToken(token, cfg.ReadToken) && !matchesToken(token, cfg.Token) {
+			log.Warning("Acceso de consulta rechazado - IP: %s", c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "No autorizado",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// matchesToken compara en tiempo constante un token con el configurado; un
+// token configurado vacío nunca coincide
+func matchesToken(token, configured string) bool {
+	return configured != "" && subtle.ConstantTimeCompare([]byte(token), []byte(configured)) == 1
+}
+
+// StreamAuthMiddleware valida el token del stream en vivo. Además de los headers se
+// acepta el parámetro "token", ya que EventSource y WebSocket en navegadores no
+// permiten headers personalizados. Sin STREAM_TOKEN el stream no está disponible
+func StreamAuthMiddleware(cfg *config.StreamConfig, log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.Token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Stream deshabilitado",
+			})
+			return
+		}
+
+		token := extractToken(c, "X-Stream-Token")
+		if token == "" {
+			token = c.Query("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) != 1 {
+			log.Warning("Acceso al stream rechazado - IP: %s", c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "No autorizado",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// MetricsAuthMiddleware valida el token del endpoint de métricas. Sin METRICS_TOKEN
+// el acceso es libre
+func MetricsAuthMiddleware(cfg *config.MetricsConfig, log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.Token == "" {
+			c.Next()
+			return
+		}
+
+		token := extractToken(c, "X-Metrics-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) != 1 {
+			log.Warning("Acceso a métricas rechazado - IP: %s", c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "No autorizado",
+			})
+			return
 		}
 
 		c.Next()
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestLogger crea un logger que escribe en un directorio temporal de la prueba
+func newTestLogger(t *testing.T) *logger.Logger {
+	t.Helper()
+
+	dir := t.TempDir()
+	log, err := logger.New(&config.LoggingConfig{
+		LogDir:            dir,
+		AppLogFile:        "app.log",
+		InvalidLogFile:    "invalid_requests.log",
+		DeviceLogDir:      filepath.Join(dir, "devices"),
+		Level:             config.LogLevelDebug,
+		Format:            config.LogFormatJSON,
+		MaxSizeMB:         10,
+		DeviceMaxOpen:     4,
+		DeviceIdleTimeout: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("error al crear logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+	return log
+}
+
+func TestReadAuthMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.AdminConfig
+		header string
+		value  string
+		want   int
+	}{
+		{name: "sin tokens configurados", cfg: config.AdminConfig{}, header: "Authorization", value: "Bearer x", want: http.StatusForbidden},
+		{name: "sin token", cfg: config.AdminConfig{ReadToken: "lectura"}, want: http.StatusUnauthorized},
+		{name: "token de lectura", cfg: config.AdminConfig{ReadToken: "lectura"}, header: "Authorization", value: "Bearer lectura", want: http.StatusOK},
+		{name: "header X-Read-Token", cfg: config.AdminConfig{ReadToken: "lectura"}, header: "X-Read-Token", value: "lectura", want: http.StatusOK},
+		{name: "token de administración", cfg: config.AdminConfig{Token: "admin", ReadToken: "lectura"}, header: "Authorization", value: "Bearer admin", want: http.StatusOK},
+		{name: "solo token de administración", cfg: config.AdminConfig{Token: "admin"}, header: "Authorization", value: "Bearer admin", want: http.StatusOK},
+		{name: "token vacío no coincide con lectura sin configurar", cfg: config.AdminConfig{Token: "admin"}, header: "Authorization", value: "Bearer ", want: http.StatusUnauthorized},
+		{name: "token inválido", cfg: config.AdminConfig{Token: "admin", ReadToken: "lectura"}, header: "Authorization", value: "Bearer otro", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/devices/offline", ReadAuthMiddleware(&tt.cfg, newTestLogger(t)), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/devices/offline", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("se obtuvo %d, se esperaba %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+// gzipBytes comprime body con gzip
+func gzipBytes(t *testing.T, body []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(body); err != nil {
+		t.Fatalf("error al comprimir: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("error al comprimir: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestPayloadMiddleware(t *testing.T) {
+	reading := []byte(`{"identificador":"DEVICE001"}`)
+	cbor := []byte{0xa1, 0x6d, 'i', 'd', 'e', 'n', 't', 'i', 'f', 'i', 'c', 'a', 'd', 'o', 'r', 0x69, 'D', 'E', 'V', 'I', 'C', 'E', '0', '0', '1'}
+
+	// Bytes aleatorios: al comprimirlos siguen superando el límite del cuerpo
+	noise := make([]byte, 2<<20)
+	if _, err := rand.Read(noise); err != nil {
+		t.Fatalf("error al generar datos: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		encoding    string
+		body        []byte
+		want        int
+		wantBody    string // Cuerpo JSON esperado en el handler, si se indica
+	}{
+		{name: "JSON", contentType: "application/json", body: reading, want: http.StatusOK, wantBody: string(reading)},
+		{name: "JSON sin tipo de contenido", body: reading, want: http.StatusOK, wantBody: string(reading)},
+		{name: "CBOR", contentType: "application/cbor", body: cbor, want: http.StatusOK, wantBody: string(reading)},
+		{name: "CBOR como application/octet-stream", contentType: "application/octet-stream", body: cbor, want: http.StatusOK, wantBody: string(reading)},
+		{name: "JSON comprimido", contentType: "application/json", encoding: "gzip", body: gzipBytes(t, reading), want: http.StatusOK, wantBody: string(reading)},
+		{name: "tipo de contenido no soportado", contentType: "text/xml", body: []byte("<telemetria/>"), want: http.StatusUnsupportedMediaType},
+		{name: "formato no reconocido", body: []byte("hola"), want: http.StatusUnsupportedMediaType},
+		{name: "compresión no soportada", contentType: "application/json", encoding: "br", body: reading, want: http.StatusUnsupportedMediaType},
+		{name: "CBOR inválido", contentType: "application/cbor", body: cbor[:5], want: http.StatusBadRequest},
+		{name: "JSON excede el tamaño máximo", contentType: "application/json", body: noise, want: http.StatusRequestEntityTooLarge},
+		{name: "cuerpo comprimido excede el tamaño máximo", contentType: "application/json", encoding: "gzip", body: gzipBytes(t, noise), want: http.StatusRequestEntityTooLarge},
+		{name: "payload descomprimido excede el tamaño máximo", contentType: "application/json", encoding: "gzip", body: gzipBytes(t, make([]byte, 2<<20)), want: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.PayloadConfig{MaxBodyMB: 1, MaxDecompressedMB: 1}
+
+			var got []byte
+			router := gin.New()
+			router.POST("/telemetry", PayloadMiddleware(cfg, false, newTestLogger(t)), func(c *gin.Context) {
+				body, err := c.GetRawData()
+				if isBodyTooLarge(err) {
+					c.Status(http.StatusRequestEntityTooLarge)
+					return
+				}
+				got = body
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/telemetry", bytes.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("se obtuvo %d, se esperaba %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			if tt.wantBody != "" && string(got) != tt.wantBody {
+				t.Errorf("cuerpo: se obtuvo %s, se esperaba %s", got, tt.wantBody)
+			}
+		})
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/metrics"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/service"
 )
 
@@ -17,12 +19,27 @@ type Server struct {
 	router           *gin.Engine
 	server           *http.Server
 	telemetryService *service.TelemetryService
+	ingestPipeline   *service.IngestPipeline // nil si la ingesta es síncrona
+	dedupService     *service.DedupService
+	authService      *service.AuthService
+	deviceService    *service.DeviceService
+	geofenceService  *service.GeofenceService
+	alertService     *service.AlertService
+	webhookService   *service.WebhookService
+	streamHub        *service.StreamHub
+	healthService    *service.HealthService
 	logger           *logger.Logger
 	config           *config.ServerConfig
+	authConfig       *config.AuthConfig
+	adminConfig      *config.AdminConfig
+	streamConfig     *config.StreamConfig
+	streamUpgrader   *websocket.Upgrader
+	metricsConfig    *config.MetricsConfig
+	payloadConfig    *config.PayloadConfig
 }
 
 // NewServer crea un nuevo servidor HTTP
-func NewServer(cfg *config.ServerConfig, rateLimitCfg *config.RateLimitConfig, telemetryService *service.TelemetryService, log *logger.Logger) *Server {
+func NewServer(cfg *config.ServerConfig, rateLimitCfg *config.RateLimitConfig, authCfg *config.AuthConfig, adminCfg *config.AdminConfig, streamCfg *config.StreamConfig, metricsCfg *config.MetricsConfig, payloadCfg *config.PayloadConfig, telemetryService *service.TelemetryService, ingestPipeline *service.IngestPipeline, dedupService *service.DedupService, authService *service.AuthService, deviceService *service.DeviceService, geofenceService *service.GeofenceService, alertService *service.AlertService, webhookService *service.WebhookService, streamHub *service.StreamHub, healthService *service.HealthService, log *logger.Logger) *Server {
 	// Establecer modo Gin a release para producción
 	gin.SetMode(gin.ReleaseMode)
 
@@ -30,14 +47,31 @@ func NewServer(cfg *config.ServerConfig, rateLimitCfg *config.RateLimitConfig, t
 
 	// Agregar middleware
 	router.Use(gin.Recovery())
+	router.Use(TracingMiddleware())
+	router.Use(RequestIDMiddleware())
 	router.Use(LoggingMiddleware(log))
 	router.Use(RateLimitMiddleware(rateLimitCfg))
 
 	s := &Server{
 		router:           router,
 		telemetryService: telemetryService,
+		ingestPipeline:   ingestPipeline,
+		dedupService:     dedupService,
+		authService:      authService,
+		deviceService:    deviceService,
+		geofenceService:  geofenceService,
+		alertService:     alertService,
+		webhookService:   webhookService,
+		streamHub:        streamHub,
+		healthService:    healthService,
 		logger:           log,
 		config:           cfg,
+		authConfig:       authCfg,
+		adminConfig:      adminCfg,
+		streamConfig:     streamCfg,
+		streamUpgrader:   newStreamUpgrader(streamCfg),
+		metricsConfig:    metricsCfg,
+		payloadConfig:    payloadCfg,
 	}
 
 	// Registrar rutas
@@ -48,11 +82,79 @@ func NewServer(cfg *config.ServerConfig, rateLimitCfg *config.RateLimitConfig, t
 
 // registerRoutes registra todas las rutas HTTP
 func (s *Server) registerRoutes() {
-	// Endpoint de verificación de salud
+	// Endpoints de verificación de salud: resumen, sonda de vida y sonda de disponibilidad
 	s.router.GET("/health", s.healthCheck)
+	s.router.GET("/health/live", s.livenessCheck)
+	s.router.GET("/health/ready", s.readinessCheck)
+
+	// Endpoint de métricas Prometheus
+	if s.metricsConfig.Enabled {
+		s.router.GET(s.metricsConfig.Path, MetricsAuthMiddleware(s.metricsConfig, s.logger), gin.WrapH(metrics.Handler()))
+	}
+
+	// Endpoints de ingesta, protegidos por autenticación de dispositivo. El cuerpo
+	// se descomprime y se convierte a JSON antes de autenticar
+	ingest := s.router.Group("/telemetry")
+	auth := AuthMiddleware(s.authConfig, s.authService, s.logger)
 
 	// Endpoint de datos de telemetría
-	s.router.POST("/telemetry", s.handleTelemetry)
+	ingest.POST("", PayloadMiddleware(s.payloadConfig, false, s.logger), auth, s.handleTelemetry)
+
+	// Endpoint de datos de telemetría por lotes (arreglo JSON o NDJSON), con el
+	// cuerpo limitado a SERVER_BATCH_MAX_SIZE_MB
+	batchLimit := MaxBodyMiddleware(int64(s.config.BatchMaxSizeMB) << 20)
+	ingest.POST("/batch", batchLimit, PayloadMiddleware(s.payloadConfig, true, s.logger), auth, s.handleTelemetryBatch)
+
+	// Endpoints de consulta de dispositivos, protegidos por el token de consulta
+	devices := s.router.Group("/devices")
+	devices.Use(ReadAuthMiddleware(s.adminConfig, s.logger))
+	devices.GET("/offline", s.handleListOfflineDevices)
+	devices.GET("/:identificador/measurements", s.handleListMeasurements)
+	devices.GET("/:identificador/events", s.handleListEvents)
+	devices.GET("/:identificador/alerts", s.handleListAlerts)
+
+	// Stream de mediciones en vivo (SSE o WebSocket)
+	if s.streamHub != nil {
+		s.router.GET("/stream", StreamAuthMiddleware(s.streamConfig, s.logger), s.handleStream)
+	}
+
+	// Endpoints de administración de dispositivos
+	admin := s.router.Group("/admin")
+	admin.Use(AdminAuthMiddleware(s.adminConfig, s.logger))
+	admin.GET("/devices", s.handleListDevices)
+	admin.POST("/devices", s.handleCreateDevice)
+	admin.GET("/devices/:identificador", s.handleGetDevice)
+	admin.PUT("/devices/:identificador", s.handleUpdateDevice)
+	admin.POST("/devices/:identificador/enable", s.handleEnableDevice)
+	admin.POST("/devices/:identificador/disable", s.handleDisableDevice)
+	admin.DELETE("/devices/:identificador", s.handleDeleteDevice)
+
+	// Endpoints de administración de grupos y geocercas
+	admin.GET("/groups", s.handleListGroups)
+	admin.POST("/groups", s.handleCreateGroup)
+	admin.DELETE("/groups/:id", s.handleDeleteGroup)
+	admin.GET("/geofences", s.handleListGeofences)
+	admin.POST("/geofences", s.handleCreateGeofence)
+	admin.GET("/geofences/:id", s.handleGetGeofence)
+	admin.PUT("/geofences/:id", s.handleUpdateGeofence)
+	admin.DELETE("/geofences/:id", s.handleDeleteGeofence)
+	admin.POST("/geofences/:id/assignments", s.handleAssignGeofence)
+	admin.DELETE("/geofences/:id/assignments", s.handleUnassignGeofence)
+
+	// Endpoints de administración de reglas de alerta
+	admin.GET("/alert-rules", s.handleListAlertRules)
+	admin.POST("/alert-rules", s.handleCreateAlertRule)
+	admin.GET("/alert-rules/:id", s.handleGetAlertRule)
+	admin.PUT("/alert-rules/:id", s.handleUpdateAlertRule)
+	admin.DELETE("/alert-rules/:id", s.handleDeleteAlertRule)
+
+	// Endpoints de administración de webhooks
+	admin.GET("/webhooks", s.handleListWebhooks)
+	admin.POST("/webhooks", s.handleCreateWebhook)
+	admin.GET("/webhooks/:id", s.handleGetWebhook)
+	admin.PUT("/webhooks/:id", s.handleUpdateWebhook)
+	admin.DELETE("/webhooks/:id", s.handleDeleteWebhook)
+	admin.GET("/webhooks/:id/deliveries", s.handleListDeliveries)
 }
 
 // Start inicia el servidor HTTP
@@ -79,10 +181,46 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return s.server.Shutdown(ctx)
 }
 
-// healthCheck maneja solicitudes de verificación de salud
+// healthCheck maneja solicitudes de verificación de salud: estado de las
+// dependencias (igual que /health/ready) junto con el estado interno del servicio
 func (s *Server) healthCheck(c *gin.Context) {
+	readiness := s.healthService.Readiness(c.Request.Context())
+
+	response := gin.H{
+		"status":     readiness.Status,
+		"timestamp":  readiness.Timestamp,
+		"components": readiness.Components,
+		"spool":      s.telemetryService.SpoolStats(),
+		"escritura":  s.telemetryService.WriterStats(),
+	}
+	if s.ingestPipeline != nil {
+		response["ingesta"] = s.ingestPipeline.Stats()
+	}
+
+	c.JSON(readinessStatusCode(readiness), response)
+}
+
+// livenessCheck maneja la sonda de vida: responde mientras el proceso atienda
+// solicitudes, sin verificar dependencias, para no reiniciar el servicio por
+// caídas externas
+func (s *Server) livenessCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status":    "ok",
 		"timestamp": time.Now().Format(time.RFC3339),
 	})
 }
+
+// readinessCheck maneja la sonda de disponibilidad: 200 si los componentes
+// críticos responden y 503 en otro caso, con el estado y latencia de cada uno
+func (s *Server) readinessCheck(c *gin.Context) {
+	readiness := s.healthService.Readiness(c.Request.Context())
+	c.JSON(readinessStatusCode(readiness), readiness)
+}
+
+// readinessStatusCode retorna el código HTTP según la disponibilidad del servicio
+func readinessStatusCode(readiness *service.Readiness) int {
+	if !readiness.Ready {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
@@ -0,0 +1,191 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/service"
+)
+
+// newStreamUpgrader crea el upgrader de WebSocket del stream. Además del token,
+// las conexiones desde navegadores deben venir del propio host o de un origen de
+// STREAM_ALLOWED_ORIGINS, para que otros sitios no puedan abrir el stream con
+// las credenciales del usuario
+func newStreamUpgrader(cfg *config.StreamConfig) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 4096,
+		CheckOrigin: func(r *http.Request) bool {
+			return allowedOrigin(r, cfg.AllowedOrigins)
+		},
+	}
+}
+
+// allowedOrigin indica si se acepta el origen de la solicitud: sin header Origin
+// (clientes que no son navegadores), del mismo host o presente en allowed
+func allowedOrigin(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, candidate := range allowed {
+		if strings.EqualFold(strings.TrimSuffix(candidate, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleStream entrega las mediciones en vivo por WebSocket (si la solicitud pide
+// el upgrade) o por Server-Sent Events. Parámetros: devices (identificadores
+// separados por coma) y group (idGrupo)
+func (s *Server) handleStream(c *gin.Context) {
+	filter, err := parseStreamFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		s.serveWebSocket(c, filter)
+		return
+	}
+
+	s.serveSSE(c, filter)
+}
+
+// serveSSE envía las mediciones como eventos "measurement" de Server-Sent Events
+func (s *Server) serveSSE(c *gin.Context, filter service.StreamFilter) {
+	// Las conexiones del stream son de larga duración: quitar el WriteTimeout del servidor
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		s.logger.Warning("No se pudo quitar el timeout de escritura del stream: %v", err)
+	}
+
+	sub := s.streamHub.Subscribe(filter)
+	defer s.streamHub.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(s.streamConfig.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case data, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(c.Writer, "event: measurement\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// serveWebSocket envía cada medición como un mensaje de texto JSON
+func (s *Server) serveWebSocket(c *gin.Context, filter service.StreamFilter) {
+	conn, err := s.streamUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		s.logger.Warning("Error al establecer conexión WebSocket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	sub := s.streamHub.Subscribe(filter)
+	defer s.streamHub.Unsubscribe(sub)
+
+	heartbeat := s.streamConfig.Heartbeat
+	writeWait := 10 * time.Second
+
+	// Leer solo para procesar pong y cierre; los clientes no envían datos
+	closed := make(chan struct{})
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case data, ok := <-sub.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseStreamFilter construye el filtro del stream a partir de los parámetros de la URL
+func parseStreamFilter(c *gin.Context) (service.StreamFilter, error) {
+	var filter service.StreamFilter
+
+	if v := c.Query("devices"); v != "" {
+		filter.Identifiers = make(map[string]bool)
+		for _, identifier := range strings.Split(v, ",") {
+			if identifier = strings.TrimSpace(identifier); identifier != "" {
+				filter.Identifiers[identifier] = true
+			}
+		}
+	}
+
+	if v := c.Query("group"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || id == 0 {
+			return filter, fmt.Errorf("parámetro group inválido: %s", v)
+		}
+		groupID := uint(id)
+		filter.IDGrupo = &groupID
+	}
+
+	return filter, nil
+}
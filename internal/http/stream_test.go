@@ -0,0 +1,70 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+)
+
+func TestAllowedOrigin(t *testing.T) {
+	allowed := []string{"https://panel.example.com/", "http://localhost:3000"}
+
+	tests := []struct {
+		name   string
+		origin string
+		want   bool
+	}{
+		{name: "sin origen", origin: "", want: true},
+		{name: "mismo host", origin: "http://telemetria.local:8080", want: true},
+		{name: "origen permitido", origin: "https://panel.example.com", want: true},
+		{name: "origen permitido con puerto", origin: "http://localhost:3000", want: true},
+		{name: "otro puerto", origin: "http://localhost:3001", want: false},
+		{name: "otro esquema", origin: "http://panel.example.com", want: false},
+		{name: "otro sitio", origin: "https://evil.example.net", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://telemetria.local:8080/stream", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if got := allowedOrigin(req, allowed); got != tt.want {
+				t.Errorf("se obtuvo %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamAuthMiddleware(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		query string
+		want  int
+	}{
+		{name: "sin token configurado", token: "", query: "", want: http.StatusForbidden},
+		{name: "sin token", token: "stream", query: "", want: http.StatusUnauthorized},
+		{name: "token en parámetro", token: "stream", query: "?token=stream", want: http.StatusOK},
+		{name: "token inválido", token: "stream", query: "?token=otro", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/stream", StreamAuthMiddleware(&config.StreamConfig{Token: tt.token}, newTestLogger(t)), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream"+tt.query, nil))
+
+			if rec.Code != tt.want {
+				t.Errorf("se obtuvo %d, se esperaba %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
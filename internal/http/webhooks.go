@@ -0,0 +1,182 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/service"
+)
+
+// Límites de la consulta del registro de entregas
+const (
+	defaultDeliveriesLimit = 100
+	maxDeliveriesLimit     = 1000
+)
+
+// handleListWebhooks retorna todos los webhooks
+func (s *Server) handleListWebhooks(c *gin.Context) {
+	webhooks, err := s.webhookService.ListWebhooks(c.Request.Context())
+	if err != nil {
+		s.respondWebhookError(c, err)
+		return
+	}
+
+	if webhooks == nil {
+		webhooks = []models.Webhook{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(webhooks),
+		"data":  webhooks,
+	})
+}
+
+// handleGetWebhook retorna un webhook por ID
+func (s *Server) handleGetWebhook(c *gin.Context) {
+	id, ok := s.parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	webhook, err := s.webhookService.GetWebhook(c.Request.Context(), id)
+	if err != nil {
+		s.respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// handleCreateWebhook registra un nuevo webhook
+func (s *Server) handleCreateWebhook(c *gin.Context) {
+	// Los webhooks quedan activos salvo que se indique lo contrario
+	webhook := models.Webhook{Activo: true}
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Formato JSON inválido",
+		})
+		return
+	}
+
+	if err := s.webhookService.CreateWebhook(c.Request.Context(), &webhook); err != nil {
+		s.respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// handleUpdateWebhook reemplaza un webhook
+func (s *Server) handleUpdateWebhook(c *gin.Context) {
+	id, ok := s.parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	webhook := models.Webhook{Activo: true}
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Formato JSON inválido",
+		})
+		return
+	}
+	webhook.IDWebhook = id
+
+	if err := s.webhookService.UpdateWebhook(c.Request.Context(), &webhook); err != nil {
+		s.respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// handleDeleteWebhook elimina un webhook
+func (s *Server) handleDeleteWebhook(c *gin.Context) {
+	id, ok := s.parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := s.webhookService.DeleteWebhook(c.Request.Context(), id); err != nil {
+		s.respondWebhookError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleListDeliveries retorna el registro de entregas de un webhook.
+// Parámetros: status (pending|delivered|failed) y limit
+func (s *Server) handleListDeliveries(c *gin.Context) {
+	id, ok := s.parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	query := &models.DeliveryQuery{
+		IDWebhook: id,
+		Estado:    c.Query("status"),
+		Limit:     defaultDeliveriesLimit,
+	}
+
+	switch query.Estado {
+	case "", models.DeliveryStatusPending, models.DeliveryStatusDelivered, models.DeliveryStatusFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "parámetro status inválido: debe ser pending, delivered o failed",
+		})
+		return
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxDeliveriesLimit {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("parámetro limit inválido: debe estar entre 1 y %d", maxDeliveriesLimit),
+			})
+			return
+		}
+		query.Limit = limit
+	}
+
+	deliveries, err := s.webhookService.ListDeliveries(c.Request.Context(), query)
+	if err != nil {
+		s.respondWebhookError(c, err)
+		return
+	}
+
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"idWebhook": id,
+		"count":     len(deliveries),
+		"data":      deliveries,
+	})
+}
+
+// respondWebhookError traduce los errores del servicio de webhooks a respuestas HTTP
+func (s *Server) respondWebhookError(c *gin.Context, err error) {
+	var ve *service.ValidationErrors
+	switch {
+	case errors.As(err, &ve):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Validación fallida",
+			"fields": ve.GetErrors(),
+		})
+	case errors.Is(err, service.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Webhook no encontrado",
+		})
+	default:
+		s.logger.Error("Error en administración de webhooks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error al procesar la solicitud",
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDKey es la clave del ID de solicitud en el contexto
+type requestIDKey struct{}
+
+// NewRequestID genera un ID de solicitud aleatorio de 32 caracteres hexadecimales
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ContextWithRequestID retorna un contexto que transporta el ID de solicitud
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext retorna el ID de solicitud del contexto, o vacío si no tiene
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// contextAttrs retorna los campos de log presentes en el contexto: el ID de
+// solicitud y, si hay un span en curso, el ID de traza
+func contextAttrs(ctx context.Context) []any {
+	var args []any
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		args = append(args, KeyRequestID, requestID)
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		args = append(args, KeyTraceID, spanContext.TraceID().String())
+	}
+	return args
+}
@@ -0,0 +1,188 @@
+package logger
+
+import (
+	"container/list"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+)
+
+// deviceLog es el archivo de log abierto de un dispositivo
+type deviceLog struct {
+	identifier string
+	writer     *rotatingFile
+	logger     *slog.Logger
+	lastUsed   time.Time
+
+	// mu serializa las escrituras con el cierre; closed indica que el archivo fue
+	// cerrado y la entrada ya no está en el LRU
+	mu     sync.Mutex
+	closed bool
+}
+
+// close cierra el archivo del dispositivo, esperando la escritura en curso
+func (d *deviceLog) close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+	return d.writer.Close()
+}
+
+// deviceLogs mantiene abiertos los archivos de log de los dispositivos que
+// reportaron recientemente, a lo más config.DeviceMaxOpen. Al superarse se cierra
+// el usado hace más tiempo, y los que no reciben escrituras en DeviceIdleTimeout
+// se cierran periódicamente; se reabren en la siguiente escritura
+type deviceLogs struct {
+	config     *config.LoggingConfig
+	format     string
+	background *sync.WaitGroup
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	lru      *list.List // Frente: usado más recientemente
+	boundary time.Time  // Inicio del periodo de rotación en curso
+}
+
+// newDeviceLogs crea el conjunto de archivos de log de dispositivos
+func newDeviceLogs(cfg *config.LoggingConfig, boundary time.Time, background *sync.WaitGroup) *deviceLogs {
+	return &deviceLogs{
+		config:     cfg,
+		format:     cfg.Format,
+		background: background,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		boundary:   boundary,
+	}
+}
+
+// acquire retorna el log del dispositivo con su mutex tomado, abriendo el
+// archivo si no lo está. Quien lo obtiene debe liberar d.mu al terminar de escribir
+func (l *deviceLogs) acquire(identifier string) (*deviceLog, error) {
+	for {
+		d, err := l.get(identifier)
+		if err != nil {
+			return nil, err
+		}
+
+		d.mu.Lock()
+		if !d.closed {
+			return d, nil
+		}
+		// Cerrado entre get y Lock: se obtiene nuevamente
+		d.mu.Unlock()
+	}
+}
+
+// get obtiene o abre el log del dispositivo y lo marca como el más reciente
+func (l *deviceLogs) get(identifier string) (*deviceLog, error) {
+	l.mu.Lock()
+
+	if element, ok := l.entries[identifier]; ok {
+		d := element.Value.(*deviceLog)
+		d.lastUsed = time.Now()
+		l.lru.MoveToFront(element)
+		l.mu.Unlock()
+		return d, nil
+	}
+
+	path := filepath.Join(l.config.DeviceLogDir, fmt.Sprintf("%s.log", identifier))
+	writer, err := openRotatingFile(path, l.config, l.boundary, l.background)
+	if err != nil {
+		l.mu.Unlock()
+		return nil, fmt.Errorf("error al abrir archivo de log de dispositivo: %w", err)
+	}
+
+	d := &deviceLog{
+		identifier: identifier,
+		writer:     writer,
+		logger:     slog.New(newHandler(writer, l.format, slog.LevelInfo)),
+		lastUsed:   time.Now(),
+	}
+	l.entries[identifier] = l.lru.PushFront(d)
+
+	// Cerrar los menos usados si se superó el máximo de archivos abiertos
+	var evicted []*deviceLog
+	for l.lru.Len() > l.config.DeviceMaxOpen {
+		evicted = append(evicted, l.remove(l.lru.Back()))
+	}
+	l.mu.Unlock()
+
+	closeAll(evicted)
+	return d, nil
+}
+
+// closeIdle cierra los archivos sin escrituras desde hace más de DeviceIdleTimeout
+func (l *deviceLogs) closeIdle() {
+	cutoff := time.Now().Add(-l.config.DeviceIdleTimeout)
+
+	l.mu.Lock()
+	var idle []*deviceLog
+	for element := l.lru.Back(); element != nil; {
+		d := element.Value.(*deviceLog)
+		if d.lastUsed.After(cutoff) {
+			break
+		}
+		previous := element.Prev()
+		idle = append(idle, l.remove(element))
+		element = previous
+	}
+	l.mu.Unlock()
+
+	closeAll(idle)
+}
+
+// rotate rota los archivos abiertos al comenzar un nuevo periodo; los cerrados
+// se rotan al reabrirse
+func (l *deviceLogs) rotate(boundary time.Time) error {
+	l.mu.Lock()
+	l.boundary = boundary
+	open := make([]*deviceLog, 0, l.lru.Len())
+	for element := l.lru.Front(); element != nil; element = element.Next() {
+		open = append(open, element.Value.(*deviceLog))
+	}
+	l.mu.Unlock()
+
+	var errs []error
+	for _, d := range open {
+		d.mu.Lock()
+		if !d.closed {
+			if err := d.writer.Rotate(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		d.mu.Unlock()
+	}
+	return stderrors.Join(errs...)
+}
+
+// closeAllOpen cierra todos los archivos abiertos
+func (l *deviceLogs) closeAllOpen() {
+	l.mu.Lock()
+	open := make([]*deviceLog, 0, l.lru.Len())
+	for l.lru.Len() > 0 {
+		open = append(open, l.remove(l.lru.Back()))
+	}
+	l.mu.Unlock()
+
+	closeAll(open)
+}
+
+// remove quita un elemento del LRU; debe llamarse con l.mu tomado
+func (l *deviceLogs) remove(element *list.Element) *deviceLog {
+	d := l.lru.Remove(element).(*deviceLog)
+	delete(l.entries, d.identifier)
+	return d
+}
+
+// closeAll cierra los archivos indicados fuera del bloqueo del LRU
+func closeAll(logs []*deviceLog) {
+	for _, d := range logs {
+		d.close()
+	}
+}
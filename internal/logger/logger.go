@@ -1,187 +1,255 @@
 package logger
 
 import (
+	"context"
+	stderrors "errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
 )
 
-// Logger maneja todas las operaciones de logging para la aplicación
+// Claves de los campos estructurados comunes a todos los logs
+const (
+	KeyDevice    = "device"
+	KeyTransport = "transport"
+	KeyRequestID = "request_id"
+	KeyTraceID   = "trace_id"
+	KeyLatency   = "latency_ms"
+	KeyError     = "error"
+)
+
+// sinks son los destinos de log compartidos por un Logger y los derivados con With
+type sinks struct {
+	config *config.LoggingConfig
+
+	app           *slog.Logger
+	appWriter     *rotatingFile
+	invalid       *slog.Logger
+	invalidWriter *rotatingFile
+	devices       *deviceLogs
+
+	stopChan   chan struct{}
+	wg         sync.WaitGroup // Rotación periódica
+	background sync.WaitGroup // Compresión y depuración de archivos rotados
+	closeOnce  sync.Once
+}
+
+// Logger maneja todas las operaciones de logging para la aplicación. Cada línea
+// es un registro estructurado (JSON o clave=valor) con nivel, mensaje y los
+// campos agregados con With y WithContext
 type Logger struct {
-	appLogger     *log.Logger
-	invalidLogger *log.Logger
-	deviceLoggers map[string]*log.Logger
-	logDir        string
-	deviceLogDir  string
-	mu            sync.RWMutex
+	sinks *sinks
+	attrs []any
 }
 
-// New crea una nueva instancia de Logger
-func New(logDir, appLogFile, invalidLogFile, deviceLogDir string) (*Logger, error) {
+// New crea una nueva instancia de Logger. Los archivos se rotan al alcanzar
+// MaxSizeMB y al comenzar cada RotateInterval; debe llamarse a Close al terminar
+func New(cfg *config.LoggingConfig) (*Logger, error) {
 	// Crear directorios de logs
-	if err := os.MkdirAll(logDir, 0755); err != nil {
+	if err := os.MkdirAll(cfg.LogDir, 0755); err != nil {
 		return nil, fmt.Errorf("error al crear directorio de logs: %w", err)
 	}
 
-	if err := os.MkdirAll(deviceLogDir, 0755); err != nil {
+	if err := os.MkdirAll(cfg.DeviceLogDir, 0755); err != nil {
 		return nil, fmt.Errorf("error al crear directorio de logs de dispositivos: %w", err)
 	}
 
+	s := &sinks{
+		config:   cfg,
+		stopChan: make(chan struct{}),
+	}
+	boundary := rotationBoundary(time.Now(), cfg.RotateInterval)
+
 	// Crear archivo de log de aplicación
-	appLogPath := filepath.Join(logDir, appLogFile)
-	appFile, err := os.OpenFile(appLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	appWriter, err := openRotatingFile(filepath.Join(cfg.LogDir, cfg.AppLogFile), cfg, boundary, &s.background)
 	if err != nil {
 		return nil, fmt.Errorf("error al abrir archivo de log de aplicación: %w", err)
 	}
 
 	// Crear archivo de log de solicitudes inválidas
-	invalidLogPath := filepath.Join(logDir, invalidLogFile)
-	invalidFile, err := os.OpenFile(invalidLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	invalidWriter, err := openRotatingFile(filepath.Join(cfg.LogDir, cfg.InvalidLogFile), cfg, boundary, &s.background)
 	if err != nil {
+		appWriter.Close()
 		return nil, fmt.Errorf("error al abrir archivo de log de solicitudes inválidas: %w", err)
 	}
 
+	s.app = slog.New(newHandler(appWriter, cfg.Format, parseLevel(cfg.Level)))
+	s.appWriter = appWriter
+	s.invalid = slog.New(newHandler(invalidWriter, cfg.Format, slog.LevelInfo))
+	s.invalidWriter = invalidWriter
+	s.devices = newDeviceLogs(cfg, boundary, &s.background)
+
+	s.wg.Add(1)
+	go s.maintain(boundary)
+
+	return &Logger{sinks: s}, nil
+}
+
+// Close detiene la rotación periódica y cierra todos los archivos de log
+func (l *Logger) Close() error {
+	var err error
+	l.sinks.closeOnce.Do(func() {
+		close(l.sinks.stopChan)
+		l.sinks.wg.Wait()
+
+		l.sinks.devices.closeAllOpen()
+		err = stderrors.Join(l.sinks.appWriter.Close(), l.sinks.invalidWriter.Close())
+
+		// Esperar la compresión de los archivos rotados en curso
+		l.sinks.background.Wait()
+	})
+	return err
+}
+
+// With retorna un Logger que agrega los pares clave-valor indicados a cada línea
+func (l *Logger) With(args ...any) *Logger {
 	return &Logger{
-		appLogger:     log.New(appFile, "", log.LstdFlags),
-		invalidLogger: log.New(invalidFile, "", log.LstdFlags),
-		deviceLoggers: make(map[string]*log.Logger),
-		logDir:        logDir,
-		deviceLogDir:  deviceLogDir,
-	}, nil
+		sinks: l.sinks,
+		attrs: append(slices.Clip(l.attrs), args...),
+	}
+}
+
+// WithContext retorna un Logger que agrega a cada línea el ID de solicitud y el
+// ID de traza presentes en ctx
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	args := contextAttrs(ctx)
+	if len(args) == 0 {
+		return l
+	}
+	return l.With(args...)
+}
+
+// Debug registra un mensaje de depuración
+func (l *Logger) Debug(format string, v ...interface{}) {
+	l.log(slog.LevelDebug, format, v...)
 }
 
 // Info registra un mensaje informativo
 func (l *Logger) Info(format string, v ...interface{}) {
-	l.appLogger.Printf("[INFO] "+format, v...)
+	l.log(slog.LevelInfo, format, v...)
 }
 
 // Warning registra un mensaje de advertencia
 func (l *Logger) Warning(format string, v ...interface{}) {
-	l.appLogger.Printf("[WARNING] "+format, v...)
+	l.log(slog.LevelWarn, format, v...)
 }
 
 // Error registra un mensaje de error
 func (l *Logger) Error(format string, v ...interface{}) {
-	l.appLogger.Printf("[ERROR] "+format, v...)
+	l.log(slog.LevelError, format, v...)
 }
 
-// LogDeviceData registra datos de telemetría para un dispositivo específico
-func (l *Logger) LogDeviceData(identifier string, data *models.TelemetryRequest) error {
-	deviceLogger, err := l.getDeviceLogger(identifier)
-	if err != nil {
-		return fmt.Errorf("error al obtener logger de dispositivo: %w", err)
+// log formatea y registra un mensaje si el nivel está habilitado
+func (l *Logger) log(level slog.Level, format string, v ...interface{}) {
+	ctx := context.Background()
+	if !l.sinks.app.Enabled(ctx, level) {
+		return
 	}
+	l.sinks.app.Log(ctx, level, fmt.Sprintf(format, v...), l.attrs...)
+}
 
-	// Formatear entrada de log
-	logEntry := fmt.Sprintf("Identificador: %s, Latitud: %v, Longitud: %v",
-		identifier,
-		formatFloat(data.Latitud),
-		formatFloat(data.Longitud),
+// LogDeviceData registra datos de telemetría para un dispositivo específico
+func (l *Logger) LogDeviceData(identifier string, data *models.TelemetryRequest) error {
+	args := append(slices.Clip(l.attrs),
+		slog.String(KeyDevice, identifier),
+		floatAttr("latitud", data.Latitud),
+		floatAttr("longitud", data.Longitud),
 	)
 
-	// Agregar datos opcionales de sensores
-	if data.Sensor1 != nil {
-		logEntry += fmt.Sprintf(", Sensor_1: %.6f", *data.Sensor1)
+	// Agregar marca de tiempo del dispositivo si está presente
+	if data.Timestamp != nil {
+		args = append(args, slog.Time("timestamp", data.Timestamp.Time))
 	}
-	if data.Sensor2 != nil {
-		logEntry += fmt.Sprintf(", Sensor_2: %.6f", *data.Sensor2)
-	}
-	if data.Sensor3 != nil {
-		logEntry += fmt.Sprintf(", Sensor_3: %.6f", *data.Sensor3)
+
+	// Agregar datos opcionales de sensores
+	for i, value := range []*float64{data.Sensor1, data.Sensor2, data.Sensor3, data.Sensor4, data.Sensor5} {
+		if value != nil {
+			args = append(args, slog.Float64(fmt.Sprintf("sensor_%d", i+1), *value))
+		}
 	}
-	if data.Sensor4 != nil {
-		logEntry += fmt.Sprintf(", Sensor_4: %.6f", *data.Sensor4)
+
+	// Agregar sensores con nombre en orden alfabético
+	if len(data.Sensors) > 0 {
+		names := make([]string, 0, len(data.Sensors))
+		for name := range data.Sensors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		sensors := make([]any, 0, len(names))
+		for _, name := range names {
+			sensors = append(sensors, slog.Float64(name, float64(data.Sensors[name])))
+		}
+		args = append(args, slog.Group("sensores", sensors...))
 	}
-	if data.Sensor5 != nil {
-		logEntry += fmt.Sprintf(", Sensor_5: %.6f", *data.Sensor5)
+
+	deviceLog, err := l.sinks.devices.acquire(identifier)
+	if err != nil {
+		return fmt.Errorf("error al obtener logger de dispositivo: %w", err)
 	}
+	defer deviceLog.mu.Unlock()
 
-	deviceLogger.Println(logEntry)
+	deviceLog.logger.Info("medición", args...)
 	return nil
 }
 
 // LogInvalidRequest registra una solicitud con campos requeridos faltantes
 func (l *Logger) LogInvalidRequest(req *models.InvalidRequest) {
-	logEntry := fmt.Sprintf("IP: %s, Timestamp: %s",
-		req.IPAddress,
-		req.Timestamp.Format(time.RFC3339),
+	args := append(slices.Clip(l.attrs),
+		slog.String("ip", req.IPAddress),
+		slog.String(KeyDevice, req.Identificador),
+		floatAttr("latitud", req.Latitud),
+		floatAttr("longitud", req.Longitud),
 	)
 
-	// Agregar identificador si está presente
-	if req.Identificador != "" {
-		logEntry += fmt.Sprintf(", Identificador: %s", req.Identificador)
-	} else {
-		logEntry += ", Identificador: MISSING"
-	}
-
-	// Agregar coordenadas si están presentes
-	if req.Latitud != nil {
-		logEntry += fmt.Sprintf(", Latitud: %.6f", *req.Latitud)
-	} else {
-		logEntry += ", Latitud: MISSING"
-	}
-
-	if req.Longitud != nil {
-		logEntry += fmt.Sprintf(", Longitud: %.6f", *req.Longitud)
-	} else {
-		logEntry += ", Longitud: MISSING"
-	}
-
 	// Agregar errores de validación
 	if len(req.Errors) > 0 {
-		logEntry += ", Errors: ["
+		errors := make([]string, len(req.Errors))
 		for i, err := range req.Errors {
-			if i > 0 {
-				logEntry += ", "
-			}
-			logEntry += fmt.Sprintf("%s: %s", err.Field, err.Message)
+			errors[i] = fmt.Sprintf("%s: %s", err.Field, err.Message)
 		}
-		logEntry += "]"
+		args = append(args, slog.Any("errors", errors))
 	}
 
-	l.invalidLogger.Println(logEntry)
+	l.sinks.invalid.Info("solicitud inválida", args...)
 }
 
-// getDeviceLogger obtiene o crea un logger para un dispositivo específico
-func (l *Logger) getDeviceLogger(identifier string) (*log.Logger, error) {
-	l.mu.RLock()
-	if logger, exists := l.deviceLoggers[identifier]; exists {
-		l.mu.RUnlock()
-		return logger, nil
-	}
-	l.mu.RUnlock()
-
-	// Crear nuevo logger de dispositivo
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	// Verificar nuevamente después de adquirir el bloqueo de escritura
-	if logger, exists := l.deviceLoggers[identifier]; exists {
-		return logger, nil
+// floatAttr crea el campo de un valor opcional; nil se registra como nulo
+func floatAttr(key string, f *float64) slog.Attr {
+	if f == nil {
+		return slog.Any(key, nil)
 	}
+	return slog.Float64(key, *f)
+}
 
-	// Crear archivo de log de dispositivo
-	deviceLogPath := filepath.Join(l.deviceLogDir, fmt.Sprintf("%s.log", identifier))
-	deviceFile, err := os.OpenFile(deviceLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return nil, fmt.Errorf("error al abrir archivo de log de dispositivo: %w", err)
+// newHandler crea el handler de slog según el formato configurado
+func newHandler(w io.Writer, format string, level slog.Level) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == config.LogFormatText {
+		return slog.NewTextHandler(w, opts)
 	}
-
-	deviceLogger := log.New(deviceFile, "", log.LstdFlags)
-	l.deviceLoggers[identifier] = deviceLogger
-
-	return deviceLogger, nil
+	return slog.NewJSONHandler(w, opts)
 }
 
-// formatFloat formatea un puntero float para logging
-func formatFloat(f *float64) string {
-	if f == nil {
-		return "null"
+// parseLevel convierte el nivel configurado (LOG_LEVEL) a un nivel de slog
+func parseLevel(level string) slog.Level {
+	switch level {
+	case config.LogLevelDebug:
+		return slog.LevelDebug
+	case config.LogLevelWarning:
+		return slog.LevelWarn
+	case config.LogLevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
 	}
-	return fmt.Sprintf("%.6f", *f)
 }
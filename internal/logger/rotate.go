@@ -0,0 +1,363 @@
+package logger
+
+import (
+	"compress/gzip"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+)
+
+// pruneInterval es cada cuánto se eliminan los archivos rotados vencidos
+const pruneInterval = time.Hour
+
+// backupTimeFormat es la marca de tiempo agregada al nombre de un archivo rotado
+// (app-2006-01-02T15-04-05.000.log)
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// backupPattern reconoce los archivos rotados, opcionalmente comprimidos
+var backupPattern = regexp.MustCompile(`-\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}\.\d{3}\.log(\.gz)?$`)
+
+// rotatingFile es un archivo de log que se rota al alcanzar el tamaño máximo o
+// al llamar a Rotate. El archivo rotado se renombra con la fecha de rotación y
+// se comprime y depura en segundo plano según la retención configurada
+type rotatingFile struct {
+	path       string
+	config     *config.LoggingConfig
+	background *sync.WaitGroup // Compresión y depuración en curso
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// openRotatingFile abre un archivo de log rotativo. Si el archivo existente es
+// anterior a la última rotación periódica (since), se rota antes de escribir en
+// él, de modo que un archivo cerrado y reabierto no mezcle líneas de dos periodos
+func openRotatingFile(path string, cfg *config.LoggingConfig, since time.Time, background *sync.WaitGroup) (*rotatingFile, error) {
+	f := &rotatingFile{
+		path:       path,
+		config:     cfg,
+		background: background,
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 && !since.IsZero() && info.ModTime().Before(since) {
+		if err := f.backup(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write escribe p en el archivo, rotándolo antes si se superaría el tamaño máximo
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize() {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate rota el archivo si tiene contenido
+func (f *rotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil || f.size == 0 {
+		return nil
+	}
+	return f.rotate()
+}
+
+// Close cierra el archivo; una escritura posterior lo reabre
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open abre el archivo para agregar líneas al final
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error al abrir archivo de log %s: %w", f.path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error al leer archivo de log %s: %w", f.path, err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotate cierra el archivo, lo renombra y abre uno nuevo; debe llamarse con f.mu tomado
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("error al cerrar archivo de log %s: %w", f.path, err)
+	}
+	f.file = nil
+
+	if err := f.backup(); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+// backup renombra el archivo actual con la fecha de rotación y programa su
+// compresión y la depuración de los archivos rotados anteriores
+func (f *rotatingFile) backup() error {
+	ext := filepath.Ext(f.path)
+	name := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(f.path, ext), time.Now().Format(backupTimeFormat), ext)
+
+	if err := os.Rename(f.path, name); err != nil {
+		return fmt.Errorf("error al rotar archivo de log %s: %w", f.path, err)
+	}
+
+	f.background.Add(1)
+	go func() {
+		defer f.background.Done()
+
+		if f.config.Compress {
+			if err := compressFile(name); err != nil {
+				fmt.Fprintf(os.Stderr, "Error al comprimir archivo de log rotado: %v\n", err)
+			}
+		}
+		if err := f.removeOldBackups(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error al depurar archivos de log rotados: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// removeOldBackups elimina los archivos rotados de este log que exceden
+// MaxBackups o MaxAgeDays
+func (f *rotatingFile) removeOldBackups() error {
+	if f.config.MaxBackups == 0 && f.config.MaxAgeDays == 0 {
+		return nil
+	}
+
+	dir := filepath.Dir(f.path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("error al leer directorio de logs: %w", err)
+	}
+
+	// Los archivos rotados de este log son su nombre seguido de la fecha, por lo
+	// que el orden alfabético es el cronológico. El largo descarta los de otro log
+	// cuyo nombre comience igual (sensor-1 y sensor-1-b)
+	base := filepath.Base(f.path)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+	length := len(prefix) + len(backupTimeFormat) + len(ext)
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !backupPattern.MatchString(name) {
+			continue
+		}
+		if len(strings.TrimSuffix(name, ".gz")) == length {
+			backups = append(backups, name)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+
+	cutoff := time.Now().Add(-time.Duration(f.config.MaxAgeDays) * 24 * time.Hour)
+	var errs []error
+	for i, name := range backups {
+		path := filepath.Join(dir, name)
+
+		expired := f.config.MaxBackups > 0 && i >= f.config.MaxBackups
+		if !expired && f.config.MaxAgeDays > 0 {
+			if info, err := os.Stat(path); err == nil && info.ModTime().Before(cutoff) {
+				expired = true
+			}
+		}
+		if !expired {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// maxSize retorna el tamaño en bytes que provoca la rotación
+func (f *rotatingFile) maxSize() int64 {
+	return int64(f.config.MaxSizeMB) * 1024 * 1024
+}
+
+// compressFile comprime un archivo rotado con gzip y elimina el original
+func compressFile(name string) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("error al abrir archivo de log rotado: %w", err)
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("error al leer archivo de log rotado: %w", err)
+	}
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("error al crear archivo comprimido: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(name + ".gz")
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		return fmt.Errorf("error al comprimir archivo de log rotado: %w", err)
+	}
+	if err = gz.Close(); err != nil {
+		return fmt.Errorf("error al comprimir archivo de log rotado: %w", err)
+	}
+	if err = dst.Close(); err != nil {
+		return fmt.Errorf("error al cerrar archivo comprimido: %w", err)
+	}
+
+	// Conservar la fecha del original para la retención por antigüedad
+	os.Chtimes(name+".gz", info.ModTime(), info.ModTime())
+	return os.Remove(name)
+}
+
+// rotationBoundary retorna el inicio del periodo de rotación en curso, o la
+// fecha cero si la rotación periódica está deshabilitada
+func rotationBoundary(now time.Time, interval time.Duration) time.Time {
+	if interval <= 0 {
+		return time.Time{}
+	}
+	return now.Truncate(interval)
+}
+
+// pruneBackups elimina los archivos rotados de dir con más de maxAge de
+// antigüedad. La retención de cada archivo se aplica solo al rotarlo, por lo que
+// sin esto se conservarían los de dispositivos que dejaron de reportar
+func pruneBackups(dir string, maxAge time.Duration) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("error al leer directorio de logs: %w", err)
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	for _, entry := range entries {
+		if entry.IsDir() || !backupPattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error al eliminar archivo de log rotado: %w", err)
+		}
+	}
+	return nil
+}
+
+// maintain rota los archivos al comenzar cada periodo, cierra los archivos de
+// dispositivos inactivos y elimina los archivos rotados vencidos
+func (s *sinks) maintain(boundary time.Time) {
+	defer s.wg.Done()
+
+	// Revisar los archivos inactivos con la mitad del tiempo de inactividad,
+	// sin superar el intervalo de limpieza
+	ticker := time.NewTicker(min(s.config.DeviceIdleTimeout/2, pruneInterval))
+	defer ticker.Stop()
+
+	// Sin rotación periódica el temporizador nunca se activa
+	rotateTimer := time.NewTimer(time.Duration(math.MaxInt64))
+	if s.config.RotateInterval > 0 {
+		rotateTimer.Reset(time.Until(boundary.Add(s.config.RotateInterval)))
+	}
+	defer rotateTimer.Stop()
+
+	s.prune()
+	lastPrune := time.Now()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case now := <-rotateTimer.C:
+			boundary = rotationBoundary(now, s.config.RotateInterval)
+			s.rotate(boundary)
+			rotateTimer.Reset(time.Until(boundary.Add(s.config.RotateInterval)))
+		case now := <-ticker.C:
+			s.devices.closeIdle()
+			if now.Sub(lastPrune) >= pruneInterval {
+				s.prune()
+				lastPrune = now
+			}
+		}
+	}
+}
+
+// rotate rota todos los archivos abiertos al comenzar un nuevo periodo
+func (s *sinks) rotate(boundary time.Time) {
+	err := stderrors.Join(
+		s.appWriter.Rotate(),
+		s.invalidWriter.Rotate(),
+		s.devices.rotate(boundary),
+	)
+	if err != nil {
+		s.app.Error("Error al rotar archivos de log", KeyError, err)
+	}
+}
+
+// prune elimina los archivos rotados con más de MaxAgeDays de antigüedad
+func (s *sinks) prune() {
+	if s.config.MaxAgeDays <= 0 {
+		return
+	}
+
+	maxAge := time.Duration(s.config.MaxAgeDays) * 24 * time.Hour
+	for _, dir := range []string{s.config.LogDir, s.config.DeviceLogDir} {
+		if err := pruneBackups(dir, maxAge); err != nil {
+			s.app.Error("Error al limpiar archivos de log rotados", KeyError, err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+)
+
+// namespace es el prefijo de todas las métricas propias del servicio
+const namespace = "telemetria"
+
+// Transportes por los que llegan las mediciones
+const (
+	TransportHTTP  = "http"
+	TransportMQTT  = "mqtt"
+	TransportSpool = "spool" // Reenvío desde el spool local
+)
+
+// Resultados de la ingesta de una medición
+const (
+	OutcomeOK             = "ok"
+	OutcomeInvalid        = "invalid"
+	OutcomeUnknownDevice  = "unknown_device"
+	OutcomeDisabledDevice = "disabled_device"
+	OutcomeSpooled        = "spooled"
+	OutcomeRejected       = "rejected"  // Cola de ingesta llena o cerrada
+	OutcomeDuplicate      = "duplicate" // Repetición de un mensaje ya recibido
+	OutcomeDBError        = "db_error"
+)
+
+// registry contiene las métricas expuestas en /metrics
+var registry = prometheus.NewRegistry()
+
+var (
+	// IngestTotal cuenta las mediciones recibidas por transporte y resultado
+	IngestTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ingest_total",
+		Help:      "Mediciones recibidas por transporte y resultado.",
+	}, []string{"transport", "outcome"})
+
+	// ProcessDuration mide la duración del procesamiento de cada medición
+	ProcessDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "process_duration_seconds",
+		Help:      "Duración del procesamiento de una medición (ProcessTelemetryData).",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	})
+
+	// DeviceCacheTotal cuenta los aciertos y fallos del caché de dispositivos
+	DeviceCacheTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "device_cache_total",
+		Help:      "Búsquedas de dispositivos en el caché por resultado (hit, miss).",
+	}, []string{"result"})
+
+	// RateLimitRejectedTotal cuenta las solicitudes rechazadas por límite de tasa
+	RateLimitRejectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejected_total",
+		Help:      "Solicitudes HTTP rechazadas por límite de tasa, por ruta.",
+	}, []string{"route"})
+
+	// MQTTConnected indica si el cliente MQTT está conectado al broker
+	MQTTConnected = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "mqtt_connected",
+		Help:      "1 si el cliente MQTT está conectado al broker, 0 en otro caso.",
+	})
+
+	// WriterBatchSize mide la cantidad de mediciones de cada lote escrito
+	WriterBatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "writer_batch_size",
+		Help:      "Mediciones por lote escrito en la base de datos.",
+		Buckets:   []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000},
+	})
+
+	// WriterFlushDuration mide la duración de la escritura de cada lote
+	WriterFlushDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "writer_flush_duration_seconds",
+		Help:      "Duración de la escritura de un lote de mediciones.",
+		Buckets:   prometheus.DefBuckets,
+	})
+)
+
+// redisClient es el cliente Redis cuyas estadísticas de pool se exponen; nil
+// mientras no haya conexión
+var redisClient atomic.Pointer[redis.Client]
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		IngestTotal,
+		ProcessDuration,
+		DeviceCacheTotal,
+		RateLimitRejectedTotal,
+		MQTTConnected,
+		WriterBatchSize,
+		WriterFlushDuration,
+		redisPoolCollector{},
+	)
+}
+
+// Handler retorna el manejador HTTP que expone las métricas en formato Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RecordIngest cuenta una medición recibida por transporte y resultado
+func RecordIngest(transport, outcome string) {
+	IngestTotal.WithLabelValues(transport, outcome).Inc()
+}
+
+// RegisterDB expone las estadísticas del pool de conexiones de la base de datos
+// (sql.DB.Stats) como go_sql_* con la etiqueta db_name
+func RegisterDB(db *sql.DB, name string) {
+	registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// SetRedisClient define el cliente Redis cuyas estadísticas de pool se exponen
+func SetRedisClient(client *redis.Client) {
+	redisClient.Store(client)
+}
+
+// Descriptores de las estadísticas del pool de Redis
+var (
+	redisHitsDesc     = redisPoolDesc("hits_total", "Conexiones obtenidas del pool sin crear una nueva.")
+	redisMissesDesc   = redisPoolDesc("misses_total", "Conexiones que debieron crearse por no haber libres en el pool.")
+	redisTimeoutsDesc = redisPoolDesc("timeouts_total", "Esperas por una conexión del pool que agotaron el tiempo.")
+	redisTotalDesc    = redisPoolDesc("connections", "Conexiones abiertas en el pool.")
+	redisIdleDesc     = redisPoolDesc("idle_connections", "Conexiones libres en el pool.")
+	redisStaleDesc    = redisPoolDesc("stale_connections_total", "Conexiones obsoletas eliminadas del pool.")
+)
+
+// redisPoolDesc crea el descriptor de una estadística del pool de Redis
+func redisPoolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", name), help, nil, nil)
+}
+
+// redisPoolCollector expone las estadísticas del pool del cliente Redis al
+// momento de cada consulta, de modo que no requiere registrarse al conectar
+type redisPoolCollector struct{}
+
+// Describe envía los descriptores de las estadísticas del pool
+func (redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- redisHitsDesc
+	ch <- redisMissesDesc
+	ch <- redisTimeoutsDesc
+	ch <- redisTotalDesc
+	ch <- redisIdleDesc
+	ch <- redisStaleDesc
+}
+
+// Collect envía las estadísticas actuales del pool; sin cliente no envía nada
+func (redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	client := redisClient.Load()
+	if client == nil {
+		return
+	}
+
+	stats := client.PoolStats()
+	ch <- prometheus.MustNewConstMetric(redisHitsDesc, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(redisMissesDesc, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(redisTimeoutsDesc, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(redisTotalDesc, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(redisIdleDesc, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(redisStaleDesc, prometheus.CounterValue, float64(stats.StaleConns))
+}
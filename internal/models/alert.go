@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+)
+
+// Operadores de reglas de alerta
+const (
+	AlertOperatorGreater = "gt"      // Valor mayor al umbral
+	AlertOperatorLess    = "lt"      // Valor menor al umbral
+	AlertOperatorBetween = "between" // Valor dentro del rango [umbral, umbralMax]
+	AlertOperatorOutside = "outside" // Valor fuera del rango [umbral, umbralMax]
+	AlertOperatorRate    = "rate"    // Variación absoluta por minuto mayor al umbral
+)
+
+// Estados de una alerta
+const (
+	AlertStatusActive  = "active"
+	AlertStatusCleared = "cleared"
+)
+
+// AlertRule representa una regla de alerta sobre el valor de un sensor, asignada
+// a un dispositivo o a un grupo de dispositivos
+type AlertRule struct {
+	IDRegla       uint     `json:"idRegla"`
+	Nombre        string   `json:"nombre"`
+	IDTelemetria  *uint    `json:"idTelemetria,omitempty"`
+	Identificador string   `json:"identificador,omitempty"` // Identificador del dispositivo (alternativa a idTelemetria)
+	IDGrupo       *uint    `json:"idGrupo,omitempty"`
+	Sensor        string   `json:"sensor"` // sensor_1..sensor_5 o nombre de sensor
+	Operador      string   `json:"operador"`
+	Umbral        float64  `json:"umbral"`
+	UmbralMax     *float64 `json:"umbralMax,omitempty"` // Límite superior (between/outside)
+	Histeresis    float64  `json:"histeresis"`          // Margen para considerar la alerta resuelta
+	Duracion      int      `json:"duracion"`            // Segundos que la condición debe mantenerse antes de alertar
+	Activo        bool     `json:"activo"`
+}
+
+// AlertState representa el estado de evaluación de una regla para un dispositivo
+type AlertState struct {
+	Active       bool       `json:"active"`
+	IDAlerta     uint64     `json:"idAlerta,omitempty"`
+	PendingSince *time.Time `json:"pendingSince,omitempty"` // Inicio de la condición (duración)
+	LastValue    *float64   `json:"lastValue,omitempty"`    // Último valor (rate)
+	LastFecha    *time.Time `json:"lastFecha,omitempty"`    // Fecha del último valor (rate)
+}
+
+// Alert representa una alerta registrada en el historial
+type Alert struct {
+	IDAlerta        uint64     `json:"idAlerta"`
+	IDRegla         uint       `json:"idRegla"`
+	IDTelemetria    uint       `json:"idTelemetria"`
+	Sensor          string     `json:"sensor"`
+	Estado          string     `json:"estado"`
+	Valor           float64    `json:"valor"`
+	FechaActivacion time.Time  `json:"fechaActivacion"`
+	FechaResolucion *time.Time `json:"fechaResolucion"`
+	ValorResolucion *float64   `json:"valorResolucion"`
+	Descripcion     string     `json:"descripcion"`
+}
+
+// AlertQuery representa los filtros de una consulta de alertas
+type AlertQuery struct {
+	IDTelemetria uint
+	Estado       string // active, cleared o vacío para todas
+	From         *time.Time
+	To           *time.Time
+	Limit        int
+}
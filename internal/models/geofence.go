@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+)
+
+// Tipos de geocerca
+const (
+	GeofenceTypeCircle  = "circle"
+	GeofenceTypePolygon = "polygon"
+)
+
+// Tipos de evento de dispositivo
+const (
+	EventGeofenceEnter = "geofence_enter"
+	EventGeofenceExit  = "geofence_exit"
+	EventGeofenceDwell = "geofence_dwell"
+)
+
+// DeviceGroup representa un grupo de dispositivos (ej. flota o cliente)
+type DeviceGroup struct {
+	IDGrupo uint   `json:"idGrupo"`
+	Nombre  string `json:"nombre"`
+}
+
+// Geofence representa una geocerca circular o poligonal
+type Geofence struct {
+	IDGeocerca        uint         `json:"idGeocerca"`
+	Nombre            string       `json:"nombre"`
+	Tipo              string       `json:"tipo"`
+	Latitud           *float64     `json:"latitud,omitempty"`  // Centro (circle)
+	Longitud          *float64     `json:"longitud,omitempty"` // Centro (circle)
+	Radio             *float64     `json:"radio,omitempty"`    // Metros (circle)
+	Vertices          [][2]float64 `json:"vertices,omitempty"` // Pares [latitud, longitud] (polygon)
+	TiempoPermanencia int          `json:"tiempoPermanencia"`  // Segundos dentro para generar evento dwell (0 = sin evento)
+	Activo            bool         `json:"activo"`
+}
+
+// GeofenceAssignment asigna una geocerca a un dispositivo o a un grupo
+type GeofenceAssignment struct {
+	IDGeocerca   uint  `json:"idGeocerca"`
+	IDTelemetria *uint `json:"idTelemetria,omitempty"`
+	IDGrupo      *uint `json:"idGrupo,omitempty"`
+}
+
+// GeofenceState representa la permanencia de un dispositivo dentro de una geocerca
+type GeofenceState struct {
+	EnteredAt     time.Time `json:"enteredAt"`
+	DwellNotified bool      `json:"dwellNotified"`
+}
+
+// Event representa un evento de dispositivo (ej. entrada o salida de geocerca)
+type Event struct {
+	IDEvento     uint64    `json:"idEvento"`
+	IDTelemetria uint      `json:"idTelemetria"`
+	IDGeocerca   *uint     `json:"idGeocerca,omitempty"`
+	Tipo         string    `json:"tipo"`
+	Fecha        time.Time `json:"fecha"`
+	Latitud      *float64  `json:"latitud"`
+	Longitud     *float64  `json:"longitud"`
+	Descripcion  string    `json:"descripcion"`
+}
+
+// EventQuery representa los filtros de una consulta de eventos
+type EventQuery struct {
+	IDTelemetria uint
+	Tipo         string
+	From         *time.Time
+	To           *time.Time
+	Limit        int
+}
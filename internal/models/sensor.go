@@ -0,0 +1,64 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Tipos de dato de un sensor
+const (
+	SensorTypeFloat = "float"
+	SensorTypeInt   = "int"
+	SensorTypeBool  = "bool"
+)
+
+// LegacySensorSlots es la cantidad de columnas fijas Sensor_1..Sensor_5
+const LegacySensorSlots = 5
+
+// SensorDefinition representa la definición de un canal de sensor de un dispositivo
+type SensorDefinition struct {
+	IDSensor     uint   `json:"idSensor"`
+	IDTelemetria uint   `json:"idTelemetria"`
+	Nombre       string `json:"nombre"`
+	Unidad       string `json:"unidad"`
+	TipoDato     string `json:"tipoDato"`
+	Slot         *int   `json:"slot"` // Columna legacy Sensor_N asociada (1..5), opcional
+}
+
+// SensorValue representa el valor de un sensor con nombre.
+// Acepta números, booleanos (1/0) y números en cadena
+type SensorValue float64
+
+// UnmarshalJSON implementa json.Unmarshaler
+func (v *SensorValue) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case bytes.Equal(data, []byte("true")):
+		*v = 1
+		return nil
+	case bytes.Equal(data, []byte("false")):
+		*v = 0
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		f, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return fmt.Errorf("valor de sensor inválido: %s", str)
+		}
+		*v = SensorValue(f)
+		return nil
+	}
+
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("valor de sensor inválido: %s", string(data))
+	}
+	*v = SensorValue(f)
+	return nil
+}
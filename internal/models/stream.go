@@ -0,0 +1,9 @@
+package models
+
+// StreamMessage representa una medición publicada en el stream en vivo
+type StreamMessage struct {
+	Identificador string       `json:"identificador"`
+	IDGrupo       *uint        `json:"idGrupo,omitempty"`
+	Latest        bool         `json:"latest"` // false si es una lectura atrasada
+	Medicion      *Measurement `json:"medicion"`
+}
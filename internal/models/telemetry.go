@@ -1,6 +1,11 @@
 package models
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
 	"time"
 )
 
@@ -14,32 +19,104 @@ type TelemetryRequest struct {
 	Sensor3       *float64 `json:"sensor_3"`
 	Sensor4       *float64 `json:"sensor_4"`
 	Sensor5       *float64 `json:"sensor_5"`
+
+	// Sensores con nombre (ej. "temp_engine", "fuel_level")
+	Sensors map[string]SensorValue `json:"sensors,omitempty"`
+
+	// Marca de tiempo reportada por el dispositivo (opcional)
+	Timestamp *DeviceTime `json:"timestamp,omitempty"`
+
+	// ID único del mensaje asignado por el dispositivo (opcional), usado para
+	// descartar sus reenvíos
+	MessageID string `json:"messageId,omitempty"`
 }
 
 // Device representa un dispositivo de telemetría
 type Device struct {
-	IDTelemetria      uint      `json:"idTelemetria"`
-	Identificador     string    `json:"identificador"`
-	Nombre            string    `json:"nombre"`
-	UltimaConexion    time.Time `json:"ultimaConexion"`
-	TiempoFueraLinea  string    `json:"tiempoFueraLinea"` // Formato TIME HH:MM:SS
-	Latitud           *float64  `json:"latitud"`
-	Longitud          *float64  `json:"longitud"`
+	IDTelemetria     uint       `json:"idTelemetria"`
+	Identificador    string     `json:"identificador"`
+	Nombre           string     `json:"nombre"`
+	UltimaConexion   time.Time  `json:"ultimaConexion"`
+	TiempoFueraLinea string     `json:"tiempoFueraLinea"` // Formato TIME HH:MM:SS
+	Activo           bool       `json:"activo"`
+	IDGrupo          *uint      `json:"idGrupo"`
+	FueraLinea       bool       `json:"fueraLinea"`
+	FechaFueraLinea  *time.Time `json:"fechaFueraLinea"`
+	Latitud          *float64   `json:"latitud"`
+	Longitud         *float64   `json:"longitud"`
+}
+
+// DeviceInput representa los datos para crear o actualizar un dispositivo
+type DeviceInput struct {
+	Identificador    string  `json:"identificador"`
+	Nombre           string  `json:"nombre"`
+	TiempoFueraLinea *string `json:"tiempoFueraLinea"` // Formato HH:MM:SS
+	IDGrupo          *uint   `json:"idGrupo"`          // 0 quita el grupo
+}
+
+// DeviceCredential representa una credencial de acceso de un dispositivo.
+// Las credenciales de tipo token solo almacenan el hash SHA-256 del token;
+// las de tipo hmac almacenan el secreto compartido para verificar firmas
+type DeviceCredential struct {
+	IDCredencial    uint       `json:"idCredencial"`
+	IDTelemetria    uint       `json:"idTelemetria"`
+	Tipo            string     `json:"tipo"`
+	TokenHash       string     `json:"-"`
+	Secreto         string     `json:"-"`
+	Descripcion     string     `json:"descripcion"`
+	FechaExpiracion *time.Time `json:"fechaExpiracion"`
+}
+
+// Tipos de credencial de dispositivo
+const (
+	CredentialTypeToken = "token"
+	CredentialTypeHMAC  = "hmac"
+)
+
+// SignedEnvelope representa un mensaje firmado con HMAC-SHA256 (usado en MQTT,
+// donde no existen headers). La firma se calcula sobre los bytes exactos de Payload
+type SignedEnvelope struct {
+	Payload   json.RawMessage `json:"payload"`
+	Timestamp int64           `json:"timestamp"`
+	Nonce     string          `json:"nonce"`
+	Signature string          `json:"signature"`
 }
 
 // Measurement representa una medición de telemetría
 type Measurement struct {
-	IDMedicion   uint64    `json:"idMedicion"`
-	IDTelemetria uint      `json:"idTelemetria"`
-	Fecha        time.Time `json:"fecha"`
-	Latitud      *float64  `json:"latitud"`
-	Longitud     *float64  `json:"longitud"`
-	Distancia    *float64  `json:"distancia"`
-	Sensor1      *float64  `json:"sensor_1"`
-	Sensor2      *float64  `json:"sensor_2"`
-	Sensor3      *float64  `json:"sensor_3"`
-	Sensor4      *float64  `json:"sensor_4"`
-	Sensor5      *float64  `json:"sensor_5"`
+	IDMedicion       uint64     `json:"idMedicion"`
+	IDTelemetria     uint       `json:"idTelemetria"`
+	Fecha            time.Time  `json:"fecha"`            // Fecha efectiva de la medición
+	FechaDispositivo *time.Time `json:"fechaDispositivo"` // Fecha reportada por el dispositivo
+	FechaRecepcion   time.Time  `json:"fechaRecepcion"`   // Fecha de recepción en el servidor
+	Latitud          *float64   `json:"latitud"`
+	Longitud         *float64   `json:"longitud"`
+	Distancia        *float64   `json:"distancia"`
+	Sensor1          *float64   `json:"sensor_1"`
+	Sensor2          *float64   `json:"sensor_2"`
+	Sensor3          *float64   `json:"sensor_3"`
+	Sensor4          *float64   `json:"sensor_4"`
+	Sensor5          *float64   `json:"sensor_5"`
+
+	// Valores de sensores con nombre
+	Sensores map[string]float64 `json:"sensores,omitempty"`
+}
+
+// MeasurementQuery representa los filtros de una consulta de historial de mediciones
+type MeasurementQuery struct {
+	IDTelemetria   uint
+	From           *time.Time         // Fecha inicial inclusiva
+	To             *time.Time         // Fecha final exclusiva
+	Limit          int                // Cantidad máxima de resultados
+	Ascending      bool               // Orden cronológico ascendente (por defecto descendente)
+	Cursor         *MeasurementCursor // Posición desde la que continuar
+	IncludeSensors bool               // Incluir sensores con nombre
+}
+
+// MeasurementCursor identifica la última medición retornada en una página
+type MeasurementCursor struct {
+	Fecha      time.Time
+	IDMedicion uint64
 }
 
 // ErrorRecord representa una entrada de error
@@ -49,6 +126,7 @@ type ErrorRecord struct {
 	Identificador *string   `json:"identificador"`
 	Fecha         time.Time `json:"fecha"`
 	Descripcion   string    `json:"descripcion"`
+	RequestID     *string   `json:"requestId,omitempty"` // ID de la solicitud que originó el registro
 }
 
 // ValidationError representa errores de validación
@@ -66,3 +144,76 @@ type InvalidRequest struct {
 	Longitud      *float64
 	Errors        []ValidationError
 }
+
+// BatchItemResult representa el resultado del procesamiento de un elemento de un lote
+type BatchItemResult struct {
+	Index         int               `json:"index"`
+	Identificador string            `json:"identificador,omitempty"`
+	Status        string            `json:"status"`
+	Error         string            `json:"error,omitempty"`
+	Fields        []ValidationError `json:"fields,omitempty"`
+	Duplicate     bool              `json:"duplicate,omitempty"` // Repetición de un mensaje ya recibido
+}
+
+// DeviceTime representa una marca de tiempo enviada por el dispositivo.
+// Acepta cadenas RFC3339 y epoch en segundos o milisegundos (número o cadena)
+type DeviceTime struct {
+	time.Time
+}
+
+// epochMillisThreshold separa epoch en segundos de epoch en milisegundos
+// (1e12 segundos corresponde al año 33658, 1e12 milisegundos al año 2001)
+const epochMillisThreshold = 1e12
+
+// UnmarshalJSON implementa json.Unmarshaler
+func (t *DeviceTime) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	// Cadena: RFC3339 o epoch numérico entre comillas
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		if parsed, err := time.Parse(time.RFC3339Nano, str); err == nil {
+			t.Time = parsed
+			return nil
+		}
+		epoch, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return fmt.Errorf("formato de timestamp inválido: %s", str)
+		}
+		return t.setEpoch(epoch)
+	}
+
+	// Número: epoch en segundos o milisegundos
+	var epoch float64
+	if err := json.Unmarshal(data, &epoch); err != nil {
+		return fmt.Errorf("formato de timestamp inválido: %s", string(data))
+	}
+	return t.setEpoch(epoch)
+}
+
+// MarshalJSON implementa json.Marshaler usando RFC3339
+func (t DeviceTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Time.Format(time.RFC3339Nano))
+}
+
+// setEpoch asigna la marca de tiempo a partir de un epoch en segundos o milisegundos
+func (t *DeviceTime) setEpoch(epoch float64) error {
+	if epoch <= 0 || math.IsNaN(epoch) || math.IsInf(epoch, 0) {
+		return fmt.Errorf("timestamp fuera de rango: %v", epoch)
+	}
+
+	if epoch >= epochMillisThreshold {
+		t.Time = time.UnixMilli(int64(epoch))
+		return nil
+	}
+
+	sec, frac := math.Modf(epoch)
+	t.Time = time.Unix(int64(sec), int64(frac*1e9))
+	return nil
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDeviceTimeUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "RFC3339 UTC", input: `"2025-01-02T03:04:05Z"`, want: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{name: "RFC3339 con zona horaria", input: `"2025-01-02T00:04:05-03:00"`, want: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{name: "RFC3339 con fracción", input: `"2025-01-02T03:04:05.250Z"`, want: time.Date(2025, 1, 2, 3, 4, 5, 250e6, time.UTC)},
+		{name: "epoch en segundos", input: `1735787045`, want: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{name: "epoch en segundos con fracción", input: `1735787045.5`, want: time.Date(2025, 1, 2, 3, 4, 5, 500e6, time.UTC)},
+		{name: "epoch en milisegundos", input: `1735787045123`, want: time.Date(2025, 1, 2, 3, 4, 5, 123e6, time.UTC)},
+		{name: "epoch en segundos como cadena", input: `"1735787045"`, want: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{name: "epoch en milisegundos como cadena", input: `"1735787045123"`, want: time.Date(2025, 1, 2, 3, 4, 5, 123e6, time.UTC)},
+		{name: "espacios alrededor", input: ` 1735787045 `, want: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{name: "null", input: `null`},
+
+		// Límite entre segundos y milisegundos
+		{name: "justo bajo el umbral es segundos", input: `999999999999`, want: time.Unix(999999999999, 0)},
+		{name: "umbral es milisegundos", input: `1000000000000`, want: time.Date(2001, 9, 9, 1, 46, 40, 0, time.UTC)},
+
+		{name: "cadena inválida", input: `"ayer"`, wantErr: true},
+		{name: "cadena vacía", input: `""`, wantErr: true},
+		{name: "cero", input: `0`, wantErr: true},
+		{name: "negativo", input: `-5`, wantErr: true},
+		{name: "booleano", input: `true`, wantErr: true},
+		{name: "objeto", input: `{}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got DeviceTime
+			err := got.UnmarshalJSON([]byte(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("se esperaba error, se obtuvo %v", got.Time)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if !got.Time.Equal(tt.want) {
+				t.Errorf("se obtuvo %v, se esperaba %v", got.Time, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeviceTimeRoundTrip(t *testing.T) {
+	original := DeviceTime{Time: time.Date(2025, 1, 2, 3, 4, 5, 123456789, time.UTC)}
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("error al serializar: %v", err)
+	}
+
+	var decoded DeviceTime
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("error al deserializar %s: %v", data, err)
+	}
+	if !decoded.Time.Equal(original.Time) {
+		t.Errorf("se obtuvo %v, se esperaba %v", decoded.Time, original.Time)
+	}
+}
+
+func TestTelemetryRequestTimestamp(t *testing.T) {
+	var withTimestamp TelemetryRequest
+	if err := json.Unmarshal([]byte(`{"identificador":"D1","timestamp":1735787045}`), &withTimestamp); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if withTimestamp.Timestamp == nil || withTimestamp.Timestamp.Unix() != 1735787045 {
+		t.Errorf("timestamp incorrecto: %v", withTimestamp.Timestamp)
+	}
+
+	var withoutTimestamp TelemetryRequest
+	if err := json.Unmarshal([]byte(`{"identificador":"D1"}`), &withoutTimestamp); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if withoutTimestamp.Timestamp != nil {
+		t.Errorf("se esperaba timestamp nil, se obtuvo %v", withoutTimestamp.Timestamp)
+	}
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Tipos de notificación enviados a los webhooks. Los eventos de geocerca usan
+// los tipos de evento de dispositivo (geofence_enter, geofence_exit, geofence_dwell)
+const (
+	NotificationDeviceOffline      = "device_offline"
+	NotificationDeviceOnline       = "device_online"
+	NotificationMissingCoordinates = "missing_coordinates"
+	NotificationUnknownDevice      = "unknown_device"
+	NotificationAlertTriggered     = "alert_triggered"
+	NotificationAlertCleared       = "alert_cleared"
+)
+
+// Estados de una entrega de webhook
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+// Notification representa un evento del sistema notificado a los webhooks
+type Notification struct {
+	ID            string      `json:"id"`
+	Tipo          string      `json:"tipo"`
+	Identificador string      `json:"identificador"`
+	IDTelemetria  *uint       `json:"idTelemetria,omitempty"`
+	IDGrupo       *uint       `json:"idGrupo,omitempty"`
+	Fecha         time.Time   `json:"fecha"`
+	Descripcion   string      `json:"descripcion"`
+	Datos         interface{} `json:"datos,omitempty"` // Alerta o evento asociado
+}
+
+// Webhook representa un destino de notificaciones. Sin grupo recibe las
+// notificaciones de todos los dispositivos; sin eventos recibe todos los tipos
+type Webhook struct {
+	IDWebhook uint     `json:"idWebhook"`
+	Nombre    string   `json:"nombre"`
+	URL       string   `json:"url"`
+	Secreto   string   `json:"secreto,omitempty"` // Solo se recibe; no se retorna en consultas
+	IDGrupo   *uint    `json:"idGrupo,omitempty"`
+	Eventos   []string `json:"eventos"`
+	Activo    bool     `json:"activo"`
+}
+
+// WebhookDelivery representa una entrega de notificación en la cola persistente
+type WebhookDelivery struct {
+	IDEntrega       uint64          `json:"idEntrega"`
+	IDWebhook       uint            `json:"idWebhook"`
+	Tipo            string          `json:"tipo"`
+	Payload         json.RawMessage `json:"payload"`
+	Estado          string          `json:"estado"`
+	Intentos        int             `json:"intentos"`
+	ProximoIntento  time.Time       `json:"proximoIntento"`
+	UltimoError     string          `json:"ultimoError,omitempty"`
+	CodigoRespuesta *int            `json:"codigoRespuesta"`
+	FechaCreacion   time.Time       `json:"fechaCreacion"`
+	FechaEntrega    *time.Time      `json:"fechaEntrega"`
+}
+
+// DeliveryQuery representa los filtros de una consulta del registro de entregas
+type DeliveryQuery struct {
+	IDWebhook uint
+	Estado    string // pending, delivered, failed o vacío para todas
+	Limit     int
+}
@@ -1,106 +1,218 @@
 package mqtt
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"time"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/metrics"
 )
 
-// Client representa un cliente MQTT
+// Tiempos de conexión con el broker
+const (
+	connectTimeout    = 30 * time.Second
+	disconnectTimeout = 250 * time.Millisecond
+	keepAlive         = 30 // Segundos
+)
+
+// MessageHandler procesa un mensaje recibido del broker. Mientras no retorna no
+// se entregan nuevos mensajes, lo que aplica contrapresión al broker
+type MessageHandler func(msg *paho.Publish)
+
+// Client representa un cliente MQTT 5 con reconexión automática. La suscripción
+// al topic se renueva en cada conexión
 type Client struct {
-	client mqtt.Client
-	config *config.MQTTConfig
-	logger *logger.Logger
+	config  *config.MQTTConfig
+	handler MessageHandler
+	logger  *logger.Logger
+
+	clientConfig autopaho.ClientConfig
+	conn         *autopaho.ConnectionManager
+	cancel       context.CancelFunc
+
+	connected  atomic.Bool
+	subscribed chan error // Resultado de la primera suscripción, esperado por Connect
 }
 
-// NewClient crea un nuevo cliente MQTT
-func NewClient(cfg *config.MQTTConfig, log *logger.Logger) (*Client, error) {
-	opts := mqtt.NewClientOptions()
-	opts.AddBroker(cfg.BrokerURL)
-	opts.SetClientID(cfg.ClientID)
-	opts.SetCleanSession(cfg.CleanSession)
+// NewClient crea un nuevo cliente MQTT que entrega los mensajes del topic configurado al manejador
+func NewClient(cfg *config.MQTTConfig, handler MessageHandler, log *logger.Logger) (*Client, error) {
+	brokerURL, err := url.Parse(cfg.BrokerURL)
+	if err != nil {
+		return nil, fmt.Errorf("error al parsear MQTT_BROKER_URL: %w", err)
+	}
 
-	// Establecer credenciales si se proporcionan
-	if cfg.Username != "" {
-		opts.SetUsername(cfg.Username)
+	c := &Client{
+		config:     cfg,
+		handler:    handler,
+		logger:     log,
+		subscribed: make(chan error, 1),
 	}
-	if cfg.Password != "" {
-		opts.SetPassword(cfg.Password)
+
+	c.clientConfig = autopaho.ClientConfig{
+		ServerUrls:      []*url.URL{brokerURL},
+		KeepAlive:       keepAlive,
+		ConnectTimeout:  connectTimeout,
+		ConnectUsername: cfg.Username,
+		ConnectPassword: []byte(cfg.Password),
+
+		// Sin sesión limpia el broker conserva la sesión indefinidamente, como en MQTT 3.1.1
+		CleanStartOnInitialConnection: cfg.CleanSession,
+		SessionExpiryInterval:         sessionExpiry(cfg.CleanSession),
+
+		// Establecer manejadores de conexión
+		OnConnectionUp: c.onConnectionUp,
+		OnConnectError: func(err error) {
+			log.Warning("MQTT reconectando al broker: %v", err)
+		},
+
+		ClientConfig: paho.ClientConfig{
+			ClientID: cfg.ClientID,
+			OnPublishReceived: []func(paho.PublishReceived) (bool, error){
+				func(received paho.PublishReceived) (bool, error) {
+					c.handler(received.Packet)
+					return true, nil
+				},
+			},
+			OnClientError: func(err error) {
+				c.connectionLost(err)
+			},
+			OnServerDisconnect: func(disconnect *paho.Disconnect) {
+				c.connectionLost(fmt.Errorf("desconectado por el broker (código %d)", disconnect.ReasonCode))
+			},
+		},
 	}
 
 	// Configurar TLS si se usa conexión segura
-	if cfg.BrokerURL[:3] == "ssl" || cfg.BrokerURL[:3] == "tls" {
-		tlsConfig := &tls.Config{
+	switch strings.ToLower(brokerURL.Scheme) {
+	case "ssl", "tls", "mqtts":
+		c.clientConfig.TlsCfg = &tls.Config{
 			InsecureSkipVerify: false,
 		}
-		opts.SetTLSConfig(tlsConfig)
 	}
 
-	// Establecer manejadores de conexión
-	opts.SetConnectionLostHandler(func(client mqtt.Client, err error) {
-		log.Error("Conexión MQTT perdida: %v", err)
-	})
-
-	opts.SetOnConnectHandler(func(client mqtt.Client) {
-		log.Info("MQTT conectado al broker: %s", cfg.BrokerURL)
-	})
-
-	opts.SetReconnectingHandler(func(client mqtt.Client, opts *mqtt.ClientOptions) {
-		log.Warning("MQTT reconectando al broker...")
-	})
-
-	// Configuración de reconexión automática
-	opts.SetAutoReconnect(true)
-	opts.SetMaxReconnectInterval(10 * time.Second)
-
-	// Crear cliente
-	client := mqtt.NewClient(opts)
-
-	return &Client{
-		client: client,
-		config: cfg,
-		logger: log,
-	}, nil
+	return c, nil
 }
 
-// Connect conecta al broker MQTT
+// Connect conecta al broker MQTT y se suscribe al topic configurado
 func (c *Client) Connect() error {
 	c.logger.Info("Conectando al broker MQTT: %s", c.config.BrokerURL)
 
-	token := c.client.Connect()
-	if token.Wait() && token.Error() != nil {
-		return fmt.Errorf("error al conectar al broker MQTT: %w", token.Error())
+	ctx, cancel := context.WithCancel(context.Background())
+	conn, err := autopaho.NewConnection(ctx, c.clientConfig)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("error al conectar al broker MQTT: %w", err)
+	}
+	c.conn = conn
+	c.cancel = cancel
+
+	// Esperar la primera conexión y su suscripción
+	select {
+	case err := <-c.subscribed:
+		if err != nil {
+			c.Disconnect()
+			return err
+		}
+	case <-time.After(connectTimeout):
+		c.Disconnect()
+		return fmt.Errorf("error al conectar al broker MQTT: tiempo de espera agotado")
 	}
 
 	c.logger.Info("Conectado exitosamente al broker MQTT")
 	return nil
 }
 
-// Subscribe se suscribe a un topic con un manejador de mensajes
-func (c *Client) Subscribe(handler mqtt.MessageHandler) error {
+// Disconnect desconecta del broker MQTT
+func (c *Client) Disconnect() {
+	if c.conn == nil {
+		return
+	}
+
+	c.logger.Info("Desconectando del broker MQTT...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), disconnectTimeout)
+	defer cancel()
+	if err := c.conn.Disconnect(ctx); err != nil {
+		c.logger.Warning("Error al desconectar del broker MQTT: %v", err)
+	}
+	c.cancel()
+
+	c.connected.Store(false)
+	metrics.MQTTConnected.Set(0)
+	c.logger.Info("Desconectado del broker MQTT")
+}
+
+// IsConnected retorna si el cliente está conectado
+func (c *Client) IsConnected() bool {
+	return c.connected.Load()
+}
+
+// onConnectionUp se ejecuta en cada conexión (incluidas las reconexiones) y
+// renueva la suscripción, ya que el broker puede no haber conservado la sesión
+func (c *Client) onConnectionUp(conn *autopaho.ConnectionManager, connack *paho.Connack) {
+	c.connected.Store(true)
+	metrics.MQTTConnected.Set(1)
+	c.logger.Info("MQTT conectado al broker: %s", c.config.BrokerURL)
+
+	go func() {
+		err := c.subscribe(conn)
+		if err != nil {
+			c.logger.Error("%v", err)
+		}
+
+		// Solo Connect espera el resultado; en las reconexiones se descarta
+		select {
+		case c.subscribed <- err:
+		default:
+		}
+	}()
+}
+
+// subscribe se suscribe al topic configurado
+func (c *Client) subscribe(conn *autopaho.ConnectionManager) error {
 	c.logger.Info("Suscribiéndose al topic MQTT: %s (QoS: %d)", c.config.Topic, c.config.QoS)
 
-	token := c.client.Subscribe(c.config.Topic, c.config.QoS, handler)
-	if token.Wait() && token.Error() != nil {
-		return fmt.Errorf("error al suscribirse al topic: %w", token.Error())
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+
+	suback, err := conn.Subscribe(ctx, &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{
+			{Topic: c.config.Topic, QoS: c.config.QoS},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error al suscribirse al topic: %w", err)
+	}
+	// Los códigos de razón 0x80 o mayores indican que el broker rechazó la suscripción
+	if len(suback.Reasons) > 0 && suback.Reasons[0] >= 0x80 {
+		return fmt.Errorf("error al suscribirse al topic: rechazado por el broker (código %d)", suback.Reasons[0])
 	}
 
 	c.logger.Info("Suscrito exitosamente al topic: %s", c.config.Topic)
 	return nil
 }
 
-// Disconnect desconecta del broker MQTT
-func (c *Client) Disconnect() {
-	c.logger.Info("Desconectando del broker MQTT...")
-	c.client.Disconnect(250)
-	c.logger.Info("Desconectado del broker MQTT")
+// connectionLost registra la pérdida de la conexión; autopaho reconecta automáticamente
+func (c *Client) connectionLost(err error) {
+	c.connected.Store(false)
+	metrics.MQTTConnected.Set(0)
+	c.logger.Error("Conexión MQTT perdida: %v", err)
 }
 
-// IsConnected retorna si el cliente está conectado
-func (c *Client) IsConnected() bool {
-	return c.client.IsConnected()
+// sessionExpiry retorna el intervalo de expiración de la sesión MQTT 5 en segundos:
+// 0 (termina al desconectar) con sesión limpia y sin expiración en otro caso
+func sessionExpiry(cleanSession bool) uint32 {
+	if cleanSession {
+		return 0
+	}
+	return math.MaxUint32
 }
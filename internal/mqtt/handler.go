@@ -3,36 +3,94 @@ package mqtt
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/metrics"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/payload"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/service"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Handler maneja mensajes MQTT
 type Handler struct {
 	telemetryService *service.TelemetryService
+	ingestPipeline   *service.IngestPipeline // nil si la ingesta es síncrona
+	ingestConfig     *config.IngestConfig
+	dedupService     *service.DedupService
+	authService      *service.AuthService
+	authConfig       *config.AuthConfig
+	payloadConfig    *config.PayloadConfig
 	logger           *logger.Logger
 }
 
 // NewHandler crea un nuevo manejador de mensajes MQTT
-func NewHandler(telemetryService *service.TelemetryService, log *logger.Logger) *Handler {
+func NewHandler(telemetryService *service.TelemetryService, ingestPipeline *service.IngestPipeline, ingestCfg *config.IngestConfig, dedupService *service.DedupService, authService *service.AuthService, authCfg *config.AuthConfig, payloadCfg *config.PayloadConfig, log *logger.Logger) *Handler {
 	return &Handler{
 		telemetryService: telemetryService,
+		ingestPipeline:   ingestPipeline,
+		ingestConfig:     ingestCfg,
+		dedupService:     dedupService,
+		authService:      authService,
+		authConfig:       authCfg,
+		payloadConfig:    payloadCfg,
 		logger:           log,
 	}
 }
 
 // HandleMessage maneja mensajes MQTT entrantes
-func (h *Handler) HandleMessage(client mqtt.Client, msg mqtt.Message) {
-	h.logger.Info("Mensaje MQTT recibido en topic: %s", msg.Topic())
+func (h *Handler) HandleMessage(msg *paho.Publish) {
+	// Cada mensaje recibe su propio ID de solicitud para correlacionar sus logs
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	ctx = logger.ContextWithRequestID(ctx, logger.NewRequestID())
+
+	// Descomprimir y convertir a JSON los payloads binarios. Los dispositivos no
+	// siempre informan el tipo de contenido, por lo que el formato se reconoce por los primeros bytes
+	body, decodeErr := h.decodePayload(msg)
+
+	ctx, span := tracing.Start(extractTraceContext(ctx, msg, body), "Handler.HandleMessage",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("mqtt"),
+			semconv.MessagingDestinationName(msg.Topic),
+			attribute.Int("messaging.mqtt.qos", int(msg.QoS)),
+		),
+	)
+	defer span.End()
+
+	log := h.logger.WithContext(ctx).With(logger.KeyTransport, metrics.TransportMQTT)
+	log.Info("Mensaje MQTT recibido en topic: %s", msg.Topic)
+
+	if decodeErr != nil {
+		metrics.RecordIngest(metrics.TransportMQTT, metrics.OutcomeInvalid)
+		log.Warning("Payload MQTT inválido: %v", decodeErr)
+		h.logRejected(ctx, "payload", decodeErr.Error())
+		return
+	}
+
+	// Verificar firma HMAC si el mensaje viene en un sobre firmado
+	data, ok := h.verifyEnvelope(ctx, body)
+	if !ok {
+		metrics.RecordIngest(metrics.TransportMQTT, metrics.OutcomeInvalid)
+		return
+	}
 
 	// Parsear payload del mensaje
 	var req models.TelemetryRequest
-	if err := json.Unmarshal(msg.Payload(), &req); err != nil {
-		h.logger.Error("Error al parsear mensaje MQTT: %v", err)
+	if err := json.Unmarshal(data, &req); err != nil {
+		metrics.RecordIngest(metrics.TransportMQTT, metrics.OutcomeInvalid)
+		log.Error("Error al parsear mensaje MQTT: %v", err)
 
 		// Registrar solicitud inválida
 		invalidReq := &models.InvalidRequest{
@@ -45,13 +103,14 @@ func (h *Handler) HandleMessage(client mqtt.Client, msg mqtt.Message) {
 				},
 			},
 		}
-		h.logger.LogInvalidRequest(invalidReq)
+		log.LogInvalidRequest(invalidReq)
 		return
 	}
 
 	// Validar campos requeridos
 	if err := service.ValidateRequiredFields(&req); err != nil {
-		h.logger.Warning("Validación de mensaje MQTT fallida: %v", err)
+		metrics.RecordIngest(metrics.TransportMQTT, metrics.OutcomeInvalid)
+		log.Warning("Validación de mensaje MQTT fallida: %v", err)
 
 		// Extraer errores de validación
 		var validationErrors []models.ValidationError
@@ -68,18 +127,169 @@ func (h *Handler) HandleMessage(client mqtt.Client, msg mqtt.Message) {
 			Longitud:      req.Longitud,
 			Errors:        validationErrors,
 		}
-		h.logger.LogInvalidRequest(invalidReq)
+		log.LogInvalidRequest(invalidReq)
 		return
 	}
 
-	// Procesar datos de telemetría
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	log = log.With(logger.KeyDevice, req.Identificador)
+
+	// Descartar las entregas repetidas (QoS 1) y los reenvíos del dispositivo
+	claim, _, err := h.dedupService.Claim(ctx, &req)
+	if errors.Is(err, service.ErrDuplicate) {
+		metrics.RecordIngest(metrics.TransportMQTT, metrics.OutcomeDuplicate)
+		log.Info("Mensaje MQTT duplicado de %s descartado", req.Identificador)
+		return
+	}
 
-	if err := h.telemetryService.ProcessTelemetryData(ctx, &req); err != nil {
-		h.logger.Error("Error al procesar datos de telemetría MQTT: %v", err)
+	// Encolar para procesamiento asíncrono. Sin espacio en la cola se espera, lo que
+	// detiene la lectura de mensajes y aplica contrapresión al broker. La cola
+	// completa la reserva del mensaje con el resultado de su procesamiento
+	if h.ingestPipeline != nil {
+		waitCtx, cancelWait := context.WithTimeout(ctx, h.ingestConfig.MQTTWait)
+		defer cancelWait()
+
+		if err := h.ingestPipeline.EnqueueWait(waitCtx, &req, "MQTT", claim); err != nil {
+			h.dedupService.Release(ctx, claim)
+			metrics.RecordIngest(metrics.TransportMQTT, metrics.OutcomeRejected)
+			span.SetStatus(codes.Error, err.Error())
+			log.Error("Mensaje MQTT de %s descartado: %v", req.Identificador, err)
+			return
+		}
 		return
 	}
 
-	h.logger.Info("Datos de telemetría MQTT procesados exitosamente para dispositivo: %s", req.Identificador)
+	// Procesar datos de telemetría
+	err = h.telemetryService.ProcessTelemetryData(ctx, &req)
+	metrics.RecordIngest(metrics.TransportMQTT, service.IngestOutcome(err))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		if errors.Is(err, service.ErrSpooled) {
+			h.dedupService.Complete(ctx, claim, service.MessageAccepted)
+			log.Warning("Datos de telemetría MQTT de %s almacenados en el spool", req.Identificador)
+			return
+		}
+		h.dedupService.Release(ctx, claim)
+		log.Error("Error al procesar datos de telemetría MQTT: %v", err)
+		return
+	}
+
+	h.dedupService.Complete(ctx, claim, service.MessageProcessed)
+	log.Info("Datos de telemetría MQTT procesados exitosamente para dispositivo: %s", req.Identificador)
+}
+
+// decodePayload descomprime el payload (gzip o zlib) y lo convierte a JSON si
+// viene en CBOR, MessagePack o Protocol Buffers. El formato se toma de la
+// propiedad de tipo de contenido (MQTT 5) o, sin ella, se reconoce por el
+// payload. Los mensajes firmados deben ser JSON, aunque pueden llegar comprimidos
+func (h *Handler) decodePayload(msg *paho.Publish) ([]byte, error) {
+	if len(msg.Payload) > h.payloadConfig.MaxBodyMB<<20 {
+		return nil, fmt.Errorf("el payload excede el tamaño máximo de %d MB", h.payloadConfig.MaxBodyMB)
+	}
+
+	maxSize := int64(h.payloadConfig.MaxDecompressedMB) << 20
+	body, err := payload.Decompress(msg.Payload, payload.DetectEncoding(msg.Payload), maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	format := ""
+	if msg.Properties != nil {
+		if format, err = payload.FormatFromContentType(msg.Properties.ContentType); err != nil {
+			return nil, err
+		}
+	}
+	if format == "" {
+		if format, err = payload.DetectFormat(body); err != nil {
+			return nil, err
+		}
+	}
+	return payload.ToJSON(body, format, false)
+}
+
+// extractTraceContext obtiene el contexto de traza W3C de las propiedades de
+// usuario traceparent y tracestate del mensaje (MQTT 5). Los dispositivos que
+// publican con MQTT 3.1.1 no disponen de propiedades de usuario y pueden enviar
+// los mismos campos en el JSON (en el sobre firmado o en el payload sin firma);
+// sin ninguno de ellos se inicia una traza nueva
+func extractTraceContext(ctx context.Context, msg *paho.Publish, raw []byte) context.Context {
+	if msg.Properties != nil {
+		if traceparent := msg.Properties.User.Get("traceparent"); traceparent != "" {
+			return tracing.Extract(ctx, propagation.MapCarrier{
+				"traceparent": traceparent,
+				"tracestate":  msg.Properties.User.Get("tracestate"),
+			})
+		}
+	}
+
+	var carrier struct {
+		Traceparent string `json:"traceparent"`
+		Tracestate  string `json:"tracestate"`
+	}
+	if err := json.Unmarshal(raw, &carrier); err != nil || carrier.Traceparent == "" {
+		return ctx
+	}
+
+	return tracing.Extract(ctx, propagation.MapCarrier{
+		"traceparent": carrier.Traceparent,
+		"tracestate":  carrier.Tracestate,
+	})
+}
+
+// verifyEnvelope verifica la firma de un mensaje firmado (models.SignedEnvelope) y retorna
+// el payload interno. Los mensajes sin firma se retornan tal cual, salvo que la
+// configuración exija firma. El segundo valor indica si el mensaje debe procesarse
+func (h *Handler) verifyEnvelope(ctx context.Context, raw []byte) ([]byte, bool) {
+	log := h.logger.WithContext(ctx).With(logger.KeyTransport, metrics.TransportMQTT)
+
+	var envelope models.SignedEnvelope
+	if err := json.Unmarshal(raw, &envelope); err != nil || envelope.Signature == "" || len(envelope.Payload) == 0 {
+		if h.authConfig.MQTTRequireSignature {
+			log.Warning("Mensaje MQTT sin firma rechazado")
+			h.logRejected(ctx, "signature", "Firma requerida")
+			return nil, false
+		}
+		return raw, true
+	}
+
+	// Obtener identificador del payload firmado
+	var identified struct {
+		Identificador string `json:"identificador"`
+	}
+	if err := json.Unmarshal(envelope.Payload, &identified); err != nil || identified.Identificador == "" {
+		log.Warning("Mensaje MQTT firmado sin identificador válido")
+		h.logRejected(ctx, "identificador", "El identificador es requerido")
+		return nil, false
+	}
+
+	signature := &service.Signature{
+		Timestamp: envelope.Timestamp,
+		Nonce:     envelope.Nonce,
+		Value:     envelope.Signature,
+	}
+	err := h.authService.VerifySignature(ctx, identified.Identificador, envelope.Payload, signature, "MQTT")
+	if errors.Is(err, service.ErrUnauthorized) {
+		log.Warning("Firma de mensaje MQTT rechazada para dispositivo: %s", identified.Identificador)
+		return nil, false
+	}
+	if err != nil {
+		log.Error("Error al verificar firma de mensaje MQTT: %v", err)
+		return nil, false
+	}
+
+	return envelope.Payload, true
+}
+
+// logRejected registra un mensaje MQTT rechazado antes de parsear su contenido
+func (h *Handler) logRejected(ctx context.Context, field, message string) {
+	invalidReq := &models.InvalidRequest{
+		Timestamp: time.Now(),
+		IPAddress: "MQTT",
+		Errors: []models.ValidationError{
+			{
+				Field:   field,
+				Message: message,
+			},
+		},
+	}
+	h.logger.WithContext(ctx).LogInvalidRequest(invalidReq)
 }
@@ -0,0 +1,96 @@
+package mqtt
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func init() {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+func TestExtractTraceContext(t *testing.T) {
+	const (
+		propertyTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+		jsonTrace     = "0af7651916cd43dd8448eb211c80319c"
+	)
+	traceparent := func(traceID string) string { return "00-" + traceID + "-00f067aa0ba902b7-01" }
+	withProperties := func(props ...string) *paho.Publish {
+		msg := &paho.Publish{Properties: &paho.PublishProperties{}}
+		for i := 0; i < len(props); i += 2 {
+			msg.Properties.User.Add(props[i], props[i+1])
+		}
+		return msg
+	}
+
+	tests := []struct {
+		name string
+		msg  *paho.Publish
+		body string
+		want string // ID de traza esperado, vacío si no hay traza
+	}{
+		{name: "propiedad de usuario", msg: withProperties("traceparent", traceparent(propertyTrace)), body: `{"identificador":"D1"}`, want: propertyTrace},
+		{name: "propiedad de usuario con prioridad sobre el JSON", msg: withProperties("traceparent", traceparent(propertyTrace)), body: `{"traceparent":"` + traceparent(jsonTrace) + `"}`, want: propertyTrace},
+		{name: "campo del JSON sin propiedades (MQTT 3.1.1)", msg: &paho.Publish{}, body: `{"traceparent":"` + traceparent(jsonTrace) + `"}`, want: jsonTrace},
+		{name: "campo del JSON con otras propiedades", msg: withProperties("origen", "gateway"), body: `{"traceparent":"` + traceparent(jsonTrace) + `"}`, want: jsonTrace},
+		{name: "sin contexto de traza", msg: &paho.Publish{}, body: `{"identificador":"D1"}`, want: ""},
+		{name: "payload no JSON", msg: &paho.Publish{}, body: "\x0a\x02D1", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := trace.SpanContextFromContext(extractTraceContext(context.Background(), tt.msg, []byte(tt.body)))
+
+			got := ""
+			if sc.IsValid() {
+				got = sc.TraceID().String()
+			}
+			if got != tt.want {
+				t.Errorf("se obtuvo %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodePayload(t *testing.T) {
+	h := &Handler{payloadConfig: &config.PayloadConfig{MaxBodyMB: 1, MaxDecompressedMB: 1}}
+	reading := `{"identificador":"DEVICE001"}`
+	cbor := "\xa1\x6didentificador\x69DEVICE001"
+	withContentType := func(contentType, body string) *paho.Publish {
+		return &paho.Publish{Payload: []byte(body), Properties: &paho.PublishProperties{ContentType: contentType}}
+	}
+
+	tests := []struct {
+		name    string
+		msg     *paho.Publish
+		want    string
+		wantErr bool
+	}{
+		{name: "JSON sin propiedades", msg: &paho.Publish{Payload: []byte(reading)}, want: reading},
+		{name: "CBOR reconocido por el contenido", msg: &paho.Publish{Payload: []byte(cbor)}, want: reading},
+		{name: "CBOR con tipo de contenido", msg: withContentType("application/cbor", cbor), want: reading},
+		{name: "propiedades sin tipo de contenido", msg: withContentType("", cbor), want: reading},
+		{name: "tipo de contenido no soportado", msg: withContentType("text/xml", "<telemetria/>"), wantErr: true},
+		{name: "formato no reconocido", msg: &paho.Publish{Payload: []byte("hola")}, wantErr: true},
+		{name: "excede el tamaño máximo", msg: &paho.Publish{Payload: []byte(reading + strings.Repeat(" ", 1<<20))}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.decodePayload(tt.msg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, se esperaba error: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("se obtuvo %s, se esperaba %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package payload
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"reflect"
+	"strings"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Formatos de payload admitidos. Todos se convierten a JSON para seguir el
+// mismo camino de validación y procesamiento
+const (
+	FormatJSON     = "json"
+	FormatCBOR     = "cbor"
+	FormatMsgPack  = "msgpack"
+	FormatProtobuf = "protobuf"
+)
+
+// Codificaciones de compresión admitidas (Content-Encoding)
+const (
+	EncodingIdentity = ""
+	EncodingGzip     = "gzip"
+	EncodingDeflate  = "deflate" // zlib (RFC 1950) o deflate sin encabezado
+)
+
+var (
+	// ErrUnsupportedEncoding indica una compresión no soportada
+	ErrUnsupportedEncoding = errors.New("codificación de contenido no soportada")
+
+	// ErrUnsupportedFormat indica un tipo de contenido no soportado o un payload
+	// cuyo formato no se reconoce
+	ErrUnsupportedFormat = errors.New("formato de payload no soportado")
+
+	// ErrTooLarge indica que el payload descomprimido supera el tamaño máximo
+	ErrTooLarge = errors.New("el payload descomprimido excede el tamaño máximo")
+)
+
+// contentTypes asocia los tipos de contenido admitidos a su formato
+var contentTypes = map[string]string{
+	"application/json":                FormatJSON,
+	"text/json":                       FormatJSON,
+	"application/x-ndjson":            FormatJSON,
+	"application/ndjson":              FormatJSON,
+	"application/jsonl":               FormatJSON,
+	"application/x-jsonlines":         FormatJSON,
+	"application/cbor":                FormatCBOR,
+	"application/msgpack":             FormatMsgPack,
+	"application/x-msgpack":           FormatMsgPack,
+	"application/vnd.msgpack":         FormatMsgPack,
+	"application/protobuf":            FormatProtobuf,
+	"application/x-protobuf":          FormatProtobuf,
+	"application/vnd.google.protobuf": FormatProtobuf,
+}
+
+// Handles de CBOR y MessagePack para decodificar sin esquema: los mapas se
+// decodifican con claves de texto para poder convertirlos a JSON
+var (
+	cborHandle    = &codec.CborHandle{}
+	msgpackHandle = &codec.MsgpackHandle{}
+)
+
+func init() {
+	mapType := reflect.TypeOf(map[string]interface{}(nil))
+	cborHandle.MapType = mapType
+	msgpackHandle.MapType = mapType
+	msgpackHandle.RawToString = true
+}
+
+// FormatFromContentType retorna el formato correspondiente al tipo de
+// contenido. Sin tipo de contenido, o con application/octet-stream, retorna ""
+// para que el formato se reconozca por el contenido con DetectFormat
+func FormatFromContentType(contentType string) (string, error) {
+	if strings.TrimSpace(contentType) == "" {
+		return "", nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, contentType)
+	}
+	if format, ok := contentTypes[mediaType]; ok {
+		return format, nil
+	}
+	switch {
+	case mediaType == "application/octet-stream":
+		return "", nil
+	case strings.HasSuffix(mediaType, "+json"):
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, mediaType)
+}
+
+// Decompress descomprime un payload según su codificación (valor de
+// Content-Encoding). El resultado no puede superar maxSize bytes
+func Decompress(body []byte, encoding string, maxSize int64) ([]byte, error) {
+	var reader io.Reader
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case EncodingIdentity, "identity":
+		return body, nil
+	case EncodingGzip, "x-gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("error al leer payload gzip: %w", err)
+		}
+		reader = gz
+	case EncodingDeflate:
+		// Algunos clientes envían deflate sin el encabezado zlib
+		if zr, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+			reader = zr
+		} else {
+			reader = flate.NewReader(bytes.NewReader(body))
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, encoding)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error al descomprimir payload: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+// DetectEncoding identifica la compresión de un payload sin metadatos (MQTT
+// 3.1.1) por su encabezado: gzip (1f 8b) o zlib. El deflate sin encabezado no
+// se puede reconocer
+func DetectEncoding(body []byte) string {
+	if len(body) < 2 {
+		return EncodingIdentity
+	}
+	if body[0] == 0x1f && body[1] == 0x8b {
+		return EncodingGzip
+	}
+	// Encabezado zlib: método 8 (deflate), ventana de hasta 32 KB y verificación módulo 31
+	if body[0]&0x0f == 8 && body[0]>>4 <= 7 && (uint16(body[0])<<8|uint16(body[1]))%31 == 0 {
+		return EncodingDeflate
+	}
+	return EncodingIdentity
+}
+
+// DetectFormat identifica el formato de una medición sin tipo de contenido:
+// JSON comienza con '{' o '[' (admitiendo espacios iniciales), un mapa CBOR con
+// 0xa0-0xbf (o la etiqueta de autodescripción d9 d9 f7), un mapa MessagePack con
+// 0x80-0x8f, 0xde o 0xdf y Protocol Buffers con la etiqueta de un campo de
+// TelemetryRequest y su tipo de codificación. Cualquier otro contenido retorna
+// ErrUnsupportedFormat
+func DetectFormat(body []byte) (string, error) {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	switch {
+	case len(trimmed) == 0:
+		return FormatJSON, nil
+	case trimmed[0] == '{' || trimmed[0] == '[':
+		// La etiqueta del identificador en Protocol Buffers (0x0a) es un salto de
+		// línea: con un identificador de 91 o 123 bytes el mensaje parece JSON
+		if body[0] == 0x0a && !json.Valid(body) && startsWithField(body) {
+			return FormatProtobuf, nil
+		}
+		return FormatJSON, nil
+	case trimmed[0] >= 0xa0 && trimmed[0] <= 0xbf, bytes.HasPrefix(trimmed, []byte{0xd9, 0xd9, 0xf7}):
+		return FormatCBOR, nil
+	case trimmed[0] >= 0x80 && trimmed[0] <= 0x8f, trimmed[0] == 0xde, trimmed[0] == 0xdf:
+		return FormatMsgPack, nil
+	case startsWithField(body):
+		return FormatProtobuf, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+// ToJSON convierte un payload del formato indicado a JSON. Con batch, un
+// payload Protocol Buffers se decodifica como TelemetryBatch (los demás
+// formatos representan los lotes como arreglos)
+func ToJSON(body []byte, format string, batch bool) ([]byte, error) {
+	var value interface{}
+	switch format {
+	case FormatJSON:
+		return body, nil
+	case FormatCBOR:
+		decoded, err := decodeSchemaless(body, cborHandle)
+		if err != nil {
+			return nil, fmt.Errorf("payload CBOR inválido: %w", err)
+		}
+		value = decoded
+	case FormatMsgPack:
+		decoded, err := decodeSchemaless(body, msgpackHandle)
+		if err != nil {
+			return nil, fmt.Errorf("payload MessagePack inválido: %w", err)
+		}
+		value = decoded
+	case FormatProtobuf:
+		var err error
+		if batch {
+			value, err = decodeBatch(body)
+		} else {
+			value, err = decodeTelemetry(body)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("payload Protocol Buffers inválido: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("error al convertir payload %s a JSON: %w", format, err)
+	}
+	return data, nil
+}
+
+// decodeSchemaless decodifica un único valor CBOR o MessagePack que debe
+// ocupar todo el payload
+func decodeSchemaless(body []byte, handle codec.Handle) (interface{}, error) {
+	var value interface{}
+	decoder := codec.NewDecoderBytes(body, handle)
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.NumBytesRead() != len(body) {
+		return nil, errors.New("datos adicionales después del valor")
+	}
+	return value, nil
+}
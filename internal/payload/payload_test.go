@@ -0,0 +1,334 @@
+package payload
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"github.com/ugorji/go/codec"
+)
+
+// testMap es una medición con los mismos campos que el JSON de la API
+var testMap = map[string]interface{}{
+	"identificador": "DEVICE001",
+	"latitud":       -33.45,
+	"longitud":      -70.66,
+	"sensor_1":      23.5,
+	"sensors":       map[string]interface{}{"temp_engine": 90},
+}
+
+// encode codifica value con el handle de CBOR o MessagePack
+func encode(t *testing.T, value interface{}, handle codec.Handle) []byte {
+	t.Helper()
+
+	var b []byte
+	if err := codec.NewEncoderBytes(&b, handle).Encode(value); err != nil {
+		t.Fatalf("error al codificar: %v", err)
+	}
+	return b
+}
+
+// compress comprime body con la codificación indicada
+func compress(t *testing.T, body []byte, encoding string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	case "raw":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			t.Fatalf("error al crear compresor: %v", err)
+		}
+		w = fw
+	}
+	if _, err := w.Write(body); err != nil {
+		t.Fatalf("error al comprimir: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("error al comprimir: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// checkReading verifica que el JSON resultante sea la medición de testMap
+func checkReading(t *testing.T, data []byte) {
+	t.Helper()
+
+	var req models.TelemetryRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatalf("JSON inválido %s: %v", data, err)
+	}
+	if req.Identificador != "DEVICE001" || req.Latitud == nil || *req.Latitud != -33.45 ||
+		req.Sensor1 == nil || *req.Sensor1 != 23.5 || req.Sensors["temp_engine"] != 90 {
+		t.Errorf("se obtuvo %s", data)
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	cbor := encode(t, testMap, cborHandle)
+	msgpack := encode(t, testMap, msgpackHandle)
+	protobuf := pbSensor(pbReading(23.5), "temp_engine", 90)
+
+	tests := []struct {
+		name   string
+		body   []byte
+		format string
+	}{
+		{name: "JSON", body: []byte(`{"identificador":"DEVICE001","latitud":-33.45,"longitud":-70.66,"sensor_1":23.5,"sensors":{"temp_engine":90}}`), format: FormatJSON},
+		{name: "CBOR", body: cbor, format: FormatCBOR},
+		{name: "MessagePack", body: msgpack, format: FormatMsgPack},
+		{name: "Protocol Buffers", body: protobuf, format: FormatProtobuf},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ToJSON(tt.body, tt.format, false)
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			checkReading(t, data)
+		})
+	}
+}
+
+func TestToJSONBatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   []byte
+		format string
+	}{
+		{name: "CBOR", body: encode(t, []interface{}{testMap, testMap}, cborHandle), format: FormatCBOR},
+		{name: "MessagePack", body: encode(t, []interface{}{testMap, testMap}, msgpackHandle), format: FormatMsgPack},
+		{name: "Protocol Buffers", body: pbMessage(pbMessage(nil, fieldBatchItems, pbSensor(pbReading(23.5), "temp_engine", 90)),
+			fieldBatchItems, pbSensor(pbReading(23.5), "temp_engine", 90)), format: FormatProtobuf},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ToJSON(tt.body, tt.format, true)
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			var items []json.RawMessage
+			if err := json.Unmarshal(data, &items); err != nil || len(items) != 2 {
+				t.Fatalf("se obtuvo %s, se esperaba un arreglo de 2 elementos", data)
+			}
+			for _, item := range items {
+				checkReading(t, item)
+			}
+		})
+	}
+}
+
+func TestToJSONMalformed(t *testing.T) {
+	cbor := encode(t, testMap, cborHandle)
+	msgpack := encode(t, testMap, msgpackHandle)
+
+	tests := []struct {
+		name    string
+		body    []byte
+		format  string
+		wantErr error // Error esperado con errors.Is, nil para cualquier error
+	}{
+		{name: "CBOR truncado", body: cbor[:len(cbor)-4], format: FormatCBOR},
+		{name: "CBOR con datos adicionales", body: append(append([]byte{}, cbor...), 0x01), format: FormatCBOR},
+		{name: "CBOR vacío", body: nil, format: FormatCBOR},
+		{name: "MessagePack truncado", body: msgpack[:len(msgpack)-4], format: FormatMsgPack},
+		{name: "MessagePack con datos adicionales", body: append(append([]byte{}, msgpack...), 0xc0), format: FormatMsgPack},
+		{name: "Protocol Buffers truncado", body: pbReading(1)[:5], format: FormatProtobuf},
+		{name: "formato desconocido", body: []byte("<xml/>"), format: "xml", wantErr: ErrUnsupportedFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ToJSON(tt.body, tt.format, false)
+			if err == nil {
+				t.Fatal("se esperaba un error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("se obtuvo %v, se esperaba %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	body := []byte(strings.Repeat(`{"identificador":"DEVICE001"}`, 10))
+
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+	}{
+		{name: "sin compresión", body: body, encoding: EncodingIdentity},
+		{name: "identity", body: body, encoding: "identity"},
+		{name: "gzip", body: compress(t, body, "gzip"), encoding: EncodingGzip},
+		{name: "x-gzip en mayúsculas", body: compress(t, body, "gzip"), encoding: "X-GZIP"},
+		{name: "deflate zlib", body: compress(t, body, "zlib"), encoding: EncodingDeflate},
+		{name: "deflate sin encabezado", body: compress(t, body, "raw"), encoding: EncodingDeflate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Decompress(tt.body, tt.encoding, 1<<20)
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if !bytes.Equal(data, body) {
+				t.Errorf("se obtuvo %q, se esperaba %q", data, body)
+			}
+		})
+	}
+}
+
+func TestDecompressErrors(t *testing.T) {
+	body := bytes.Repeat([]byte{'a'}, 1000)
+	gz := compress(t, body, "gzip")
+
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		maxSize  int64
+		wantErr  error // Error esperado con errors.Is, nil para cualquier error
+	}{
+		{name: "compresión no soportada", body: body, encoding: "br", maxSize: 1 << 20, wantErr: ErrUnsupportedEncoding},
+		{name: "gzip excede el tamaño máximo", body: gz, encoding: EncodingGzip, maxSize: 999, wantErr: ErrTooLarge},
+		{name: "deflate excede el tamaño máximo", body: compress(t, body, "zlib"), encoding: EncodingDeflate, maxSize: 999, wantErr: ErrTooLarge},
+		{name: "gzip sin encabezado", body: body, encoding: EncodingGzip, maxSize: 1 << 20},
+		{name: "gzip truncado", body: gz[:len(gz)/2], encoding: EncodingGzip, maxSize: 1 << 20},
+		{name: "gzip con suma de verificación inválida", body: append(append([]byte{}, gz[:len(gz)-8]...), 0, 0, 0, 0, 0, 0, 0, 0), encoding: EncodingGzip, maxSize: 1 << 20},
+		{name: "deflate corrupto", body: []byte{0xff, 0xff, 0xff}, encoding: EncodingDeflate, maxSize: 1 << 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decompress(tt.body, tt.encoding, tt.maxSize)
+			if err == nil {
+				t.Fatal("se esperaba un error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("se obtuvo %v, se esperaba %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Exactamente en el límite se acepta
+	if _, err := Decompress(gz, EncodingGzip, 1000); err != nil {
+		t.Errorf("en el límite: error inesperado %v", err)
+	}
+}
+
+func TestDetectEncoding(t *testing.T) {
+	body := []byte(`{"identificador":"DEVICE001"}`)
+
+	tests := []struct {
+		name string
+		body []byte
+		want string
+	}{
+		{name: "gzip", body: compress(t, body, "gzip"), want: EncodingGzip},
+		{name: "zlib", body: compress(t, body, "zlib"), want: EncodingDeflate},
+		{name: "JSON", body: body, want: EncodingIdentity},
+		{name: "un byte", body: []byte{0x1f}, want: EncodingIdentity},
+		{name: "vacío", body: nil, want: EncodingIdentity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectEncoding(tt.body); got != tt.want {
+				t.Errorf("se obtuvo %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	// Un identificador de 123 bytes codifica su longitud como '{'
+	longID := pbString(nil, fieldIdentificador, strings.Repeat("D", '{'))
+
+	tests := []struct {
+		name    string
+		body    []byte
+		want    string
+		wantErr bool
+	}{
+		{name: "JSON", body: []byte(`{"identificador":"DEVICE001"}`), want: FormatJSON},
+		{name: "arreglo JSON", body: []byte(`[{"identificador":"DEVICE001"}]`), want: FormatJSON},
+		{name: "JSON con espacios iniciales", body: []byte("\n\t {\"identificador\":\"DEVICE001\"}"), want: FormatJSON},
+		{name: "vacío", body: nil, want: FormatJSON},
+		{name: "CBOR", body: encode(t, testMap, cborHandle), want: FormatCBOR},
+		{name: "CBOR autodescrito", body: append([]byte{0xd9, 0xd9, 0xf7}, encode(t, testMap, cborHandle)...), want: FormatCBOR},
+		{name: "CBOR con espacios iniciales", body: append([]byte(" "), encode(t, testMap, cborHandle)...), want: FormatCBOR},
+		{name: "MessagePack", body: encode(t, testMap, msgpackHandle), want: FormatMsgPack},
+		{name: "Protocol Buffers", body: pbReading(23.5), want: FormatProtobuf},
+		{name: "Protocol Buffers desde la marca de tiempo", body: pbVarint(nil, fieldTimestamp, 1), want: FormatProtobuf},
+		{name: "Protocol Buffers con identificador que parece JSON", body: longID, want: FormatProtobuf},
+		{name: "texto", body: []byte("hola"), wantErr: true},
+		{name: "XML", body: []byte("<telemetria/>"), wantErr: true},
+		{name: "campo Protocol Buffers con otro tipo", body: pbVarint(nil, fieldIdentificador, 1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFormat(tt.body)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsupportedFormat) {
+					t.Errorf("se obtuvo (%q, %v), se esperaba %v", got, err, ErrUnsupportedFormat)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("se obtuvo (%q, %v), se esperaba %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatFromContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+		wantErr     bool
+	}{
+		{contentType: "application/json", want: FormatJSON},
+		{contentType: "application/json; charset=utf-8", want: FormatJSON},
+		{contentType: "application/x-ndjson", want: FormatJSON},
+		{contentType: "application/vnd.telemetria+json", want: FormatJSON},
+		{contentType: "application/cbor", want: FormatCBOR},
+		{contentType: "application/x-msgpack", want: FormatMsgPack},
+		{contentType: "application/x-protobuf", want: FormatProtobuf},
+		{contentType: "", want: ""},
+		{contentType: "application/octet-stream", want: ""},
+		{contentType: "text/xml", wantErr: true},
+		{contentType: "application/x-www-form-urlencoded", wantErr: true},
+		{contentType: "no es un tipo", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			got, err := FormatFromContentType(tt.contentType)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsupportedFormat) {
+					t.Errorf("se obtuvo (%q, %v), se esperaba %v", got, err, ErrUnsupportedFormat)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("se obtuvo (%q, %v), se esperaba %q", got, err, tt.want)
+			}
+		})
+	}
+}
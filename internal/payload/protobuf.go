@@ -0,0 +1,245 @@
+package payload
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Números de campo del mensaje TelemetryRequest (ver telemetry.proto)
+const (
+	fieldIdentificador protowire.Number = 1
+	fieldLatitud       protowire.Number = 2
+	fieldLongitud      protowire.Number = 3
+	fieldSensor1       protowire.Number = 4
+	fieldSensor5       protowire.Number = 8
+	fieldSensors       protowire.Number = 9
+	fieldTimestamp     protowire.Number = 10
+	fieldMessageID     protowire.Number = 11
+
+	fieldBatchItems protowire.Number = 1 // TelemetryBatch.items
+	fieldMapKey     protowire.Number = 1 // Entrada de map<string, double>
+	fieldMapValue   protowire.Number = 2
+)
+
+// errInvalidField indica un campo conocido con un tipo de codificación distinto
+// al del esquema o con un string que no es UTF-8
+var errInvalidField = errors.New("tipo de codificación o valor inválido")
+
+// invalidField es el código que retornan las funciones consume* para un campo
+// inválido, distinto de los códigos de error de protowire
+const invalidField = -100
+
+// decodeTelemetry decodifica un mensaje TelemetryRequest. Los campos
+// desconocidos se ignoran para admitir versiones posteriores del esquema
+func decodeTelemetry(b []byte) (*models.TelemetryRequest, error) {
+	req := &models.TelemetryRequest{}
+	sensors := []**float64{&req.Sensor1, &req.Sensor2, &req.Sensor3, &req.Sensor4, &req.Sensor5}
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		switch {
+		case num == fieldIdentificador || num == fieldMessageID:
+			value, n := consumeString(b, typ)
+			if n < 0 {
+				return nil, fieldError(num, n)
+			}
+			if num == fieldIdentificador {
+				req.Identificador = value
+			} else {
+				req.MessageID = value
+			}
+			b = b[n:]
+
+		case num == fieldLatitud || num == fieldLongitud || (num >= fieldSensor1 && num <= fieldSensor5):
+			value, n := consumeDouble(b, typ)
+			if n < 0 {
+				return nil, fieldError(num, n)
+			}
+			switch num {
+			case fieldLatitud:
+				req.Latitud = &value
+			case fieldLongitud:
+				req.Longitud = &value
+			default:
+				*sensors[num-fieldSensor1] = &value
+			}
+			b = b[n:]
+
+		case num == fieldSensors:
+			if typ != protowire.BytesType {
+				return nil, fieldError(num, invalidField)
+			}
+			entry, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, fieldError(num, n)
+			}
+			name, value, err := decodeSensorEntry(entry)
+			if err != nil {
+				return nil, fmt.Errorf("campo %d: %w", num, err)
+			}
+			if req.Sensors == nil {
+				req.Sensors = make(map[string]models.SensorValue)
+			}
+			req.Sensors[name] = models.SensorValue(value)
+			b = b[n:]
+
+		case num == fieldTimestamp:
+			if typ != protowire.VarintType {
+				return nil, fieldError(num, invalidField)
+			}
+			value, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil, fieldError(num, n)
+			}
+			// Epoch en milisegundos; 0 (valor por defecto) equivale a sin marca de tiempo
+			if millis := int64(value); millis != 0 {
+				req.Timestamp = &models.DeviceTime{Time: time.UnixMilli(millis)}
+			}
+			b = b[n:]
+
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, fieldError(num, n)
+			}
+			b = b[n:]
+		}
+	}
+
+	return req, nil
+}
+
+// decodeBatch decodifica un mensaje TelemetryBatch
+func decodeBatch(b []byte) ([]*models.TelemetryRequest, error) {
+	items := []*models.TelemetryRequest{}
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if num != fieldBatchItems {
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, fieldError(num, n)
+			}
+			b = b[n:]
+			continue
+		}
+
+		if typ != protowire.BytesType {
+			return nil, fieldError(num, invalidField)
+		}
+		raw, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, fieldError(num, n)
+		}
+		item, err := decodeTelemetry(raw)
+		if err != nil {
+			return nil, fmt.Errorf("elemento %d: %w", len(items), err)
+		}
+		items = append(items, item)
+		b = b[n:]
+	}
+
+	return items, nil
+}
+
+// decodeSensorEntry decodifica una entrada del mapa sensors (clave 1, valor 2)
+func decodeSensorEntry(b []byte) (string, float64, error) {
+	var (
+		name  string
+		value float64
+	)
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return "", 0, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		switch num {
+		case fieldMapKey:
+			name, n = consumeString(b, typ)
+		case fieldMapValue:
+			value, n = consumeDouble(b, typ)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return "", 0, fieldError(num, n)
+		}
+		b = b[n:]
+	}
+
+	return name, value, nil
+}
+
+// consumeString lee un campo string; retorna n < 0 si es inválido
+func consumeString(b []byte, typ protowire.Type) (string, int) {
+	if typ != protowire.BytesType {
+		return "", invalidField
+	}
+	value, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return "", n
+	}
+	if !utf8.Valid(value) {
+		return "", invalidField
+	}
+	return string(value), n
+}
+
+// consumeDouble lee un campo double; retorna n < 0 si es inválido
+func consumeDouble(b []byte, typ protowire.Type) (float64, int) {
+	if typ != protowire.Fixed64Type {
+		return 0, invalidField
+	}
+	bits, n := protowire.ConsumeFixed64(b)
+	if n < 0 {
+		return 0, n
+	}
+	return math.Float64frombits(bits), n
+}
+
+// fieldError describe el error de decodificación de un campo. n es el código de
+// error de protowire o invalidField
+func fieldError(num protowire.Number, n int) error {
+	if n == invalidField {
+		return fmt.Errorf("campo %d: %w", num, errInvalidField)
+	}
+	return fmt.Errorf("campo %d: %w", num, protowire.ParseError(n))
+}
+
+// startsWithField indica si b comienza con la etiqueta de un campo de
+// TelemetryRequest codificado con el tipo del esquema, lo que permite reconocer
+// un mensaje Protocol Buffers sin tipo de contenido
+func startsWithField(b []byte) bool {
+	num, typ, n := protowire.ConsumeTag(b)
+	if n < 0 {
+		return false
+	}
+	switch {
+	case num == fieldIdentificador || num == fieldSensors || num == fieldMessageID:
+		return typ == protowire.BytesType
+	case num == fieldLatitud || num == fieldLongitud || (num >= fieldSensor1 && num <= fieldSensor5):
+		return typ == protowire.Fixed64Type
+	case num == fieldTimestamp:
+		return typ == protowire.VarintType
+	}
+	return false
+}
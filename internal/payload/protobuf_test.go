@@ -0,0 +1,197 @@
+package payload
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Funciones para codificar mensajes de prueba campo a campo, como lo haría protoc
+
+func pbString(b []byte, num protowire.Number, value string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func pbDouble(b []byte, num protowire.Number, value float64) []byte {
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(value))
+}
+
+func pbVarint(b []byte, num protowire.Number, value uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+func pbMessage(b []byte, num protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+// pbSensor codifica una entrada del mapa sensors
+func pbSensor(b []byte, name string, value float64) []byte {
+	return pbMessage(b, fieldSensors, pbDouble(pbString(nil, fieldMapKey, name), fieldMapValue, value))
+}
+
+// pbReading codifica una medición de DEVICE001 con posición y sensor_1
+func pbReading(sensor1 float64) []byte {
+	b := pbString(nil, fieldIdentificador, "DEVICE001")
+	b = pbDouble(b, fieldLatitud, -33.45)
+	b = pbDouble(b, fieldLongitud, -70.66)
+	return pbDouble(b, fieldSensor1, sensor1)
+}
+
+func TestDecodeTelemetry(t *testing.T) {
+	ts := time.Date(2024, 5, 10, 12, 30, 0, 0, time.UTC)
+
+	b := pbDouble(pbReading(23.5), fieldSensor5, -1)
+	b = pbSensor(b, "temp_engine", 90)
+	b = pbSensor(b, "fuel_level", 55.5)
+	b = pbVarint(b, fieldTimestamp, uint64(ts.UnixMilli()))
+	b = pbString(b, fieldMessageID, "DEVICE001-000123")
+
+	req, err := decodeTelemetry(b)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	if req.Identificador != "DEVICE001" || req.MessageID != "DEVICE001-000123" {
+		t.Errorf("identificador y mensaje: se obtuvo %q y %q", req.Identificador, req.MessageID)
+	}
+	if req.Latitud == nil || *req.Latitud != -33.45 || req.Longitud == nil || *req.Longitud != -70.66 {
+		t.Errorf("posición: se obtuvo %v, %v", req.Latitud, req.Longitud)
+	}
+	if req.Sensor1 == nil || *req.Sensor1 != 23.5 || req.Sensor5 == nil || *req.Sensor5 != -1 {
+		t.Errorf("sensores: se obtuvo %v y %v", req.Sensor1, req.Sensor5)
+	}
+	// Un campo optional ausente no equivale a cero
+	if req.Sensor2 != nil {
+		t.Errorf("sensor_2 ausente: se obtuvo %v, se esperaba nil", *req.Sensor2)
+	}
+	if len(req.Sensors) != 2 || req.Sensors["temp_engine"] != 90 || req.Sensors["fuel_level"] != 55.5 {
+		t.Errorf("sensores con nombre: se obtuvo %v", req.Sensors)
+	}
+	if req.Timestamp == nil || !req.Timestamp.Equal(ts) {
+		t.Errorf("marca de tiempo: se obtuvo %v, se esperaba %v", req.Timestamp, ts)
+	}
+}
+
+func TestDecodeTelemetryValid(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		wantSensor1 float64
+	}{
+		{name: "marca de tiempo cero equivale a sin marca", body: pbVarint(pbReading(1), fieldTimestamp, 0), wantSensor1: 1},
+		{name: "campos desconocidos se ignoran", body: pbVarint(pbString(pbReading(1), 20, "versión posterior"), 21, 7), wantSensor1: 1},
+		{name: "el último valor de un campo repetido prevalece", body: pbDouble(pbReading(1), fieldSensor1, 2), wantSensor1: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := decodeTelemetry(tt.body)
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if req.Sensor1 == nil || *req.Sensor1 != tt.wantSensor1 {
+				t.Errorf("sensor_1: se obtuvo %v, se esperaba %v", req.Sensor1, tt.wantSensor1)
+			}
+			if req.Timestamp != nil {
+				t.Errorf("marca de tiempo: se obtuvo %v, se esperaba nil", req.Timestamp)
+			}
+		})
+	}
+}
+
+func TestDecodeTelemetryMalformed(t *testing.T) {
+	valid := pbReading(23.5)
+
+	tests := []struct {
+		name    string
+		body    []byte
+		wantErr error // Error esperado con errors.Is, nil para cualquier error
+	}{
+		{name: "identificador como varint", body: pbVarint(nil, fieldIdentificador, 1), wantErr: errInvalidField},
+		{name: "latitud como string", body: pbString(nil, fieldLatitud, "-33.45"), wantErr: errInvalidField},
+		{name: "marca de tiempo como double", body: pbDouble(nil, fieldTimestamp, 1), wantErr: errInvalidField},
+		{name: "sensores como double", body: pbDouble(nil, fieldSensors, 1), wantErr: errInvalidField},
+		{name: "clave de sensor como double", body: pbMessage(nil, fieldSensors, pbDouble(nil, fieldMapKey, 1)), wantErr: errInvalidField},
+		{name: "identificador no UTF-8", body: pbString(nil, fieldIdentificador, "DEV\xff"), wantErr: errInvalidField},
+		{name: "mensaje truncado", body: valid[:len(valid)-3]},
+		{name: "longitud de string truncada", body: []byte{0x0a, 0x80}},
+		{name: "string más corto que su longitud", body: []byte{0x0a, 0x05, 'D', 'E'}},
+		{name: "etiqueta inválida", body: []byte{0x00}},
+		{name: "entrada de sensor truncada", body: pbMessage(nil, fieldSensors, []byte{0x11, 0x00})},
+		{name: "campo desconocido truncado", body: append(valid, 0xa2, 0x01, 0x05, 'x')},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeTelemetry(tt.body)
+			if err == nil {
+				t.Fatal("se esperaba un error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("se obtuvo %v, se esperaba %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeBatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      []byte
+		wantItems int
+		wantErr   bool
+	}{
+		{name: "lote vacío", body: nil, wantItems: 0},
+		{name: "dos mediciones", body: pbMessage(pbMessage(nil, fieldBatchItems, pbReading(1)), fieldBatchItems, pbReading(2)), wantItems: 2},
+		{name: "campo desconocido se ignora", body: pbVarint(pbMessage(nil, fieldBatchItems, pbReading(1)), 2, 7), wantItems: 1},
+		{name: "elemento como varint", body: pbVarint(nil, fieldBatchItems, 1), wantErr: true},
+		{name: "elemento inválido", body: pbMessage(nil, fieldBatchItems, pbVarint(nil, fieldLatitud, 1)), wantErr: true},
+		{name: "elemento truncado", body: pbMessage(nil, fieldBatchItems, pbReading(1))[:10], wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := decodeBatch(tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, se esperaba error: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(items) != tt.wantItems {
+				t.Errorf("se obtuvieron %d elementos, se esperaban %d", len(items), tt.wantItems)
+			}
+		})
+	}
+}
+
+func TestStartsWithField(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+		want bool
+	}{
+		{name: "identificador", body: pbString(nil, fieldIdentificador, "DEVICE001"), want: true},
+		{name: "latitud", body: pbDouble(nil, fieldLatitud, 1), want: true},
+		{name: "sensores", body: pbSensor(nil, "temp_engine", 90), want: true},
+		{name: "marca de tiempo", body: pbVarint(nil, fieldTimestamp, 1), want: true},
+		{name: "ID de mensaje", body: pbString(nil, fieldMessageID, "M1"), want: true},
+		{name: "identificador como varint", body: pbVarint(nil, fieldIdentificador, 1), want: false},
+		{name: "latitud como string", body: pbString(nil, fieldLatitud, "1"), want: false},
+		{name: "campo desconocido", body: pbString(nil, 20, "x"), want: false},
+		{name: "vacío", body: nil, want: false},
+		{name: "texto", body: []byte("hola"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := startsWithField(tt.body); got != tt.want {
+				t.Errorf("se obtuvo %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,549 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// ErrAlertRuleNotFound indica que la regla de alerta no existe
+var ErrAlertRuleNotFound = errors.New("regla de alerta no encontrada")
+
+// alertRuleCacheEntry almacena localmente las reglas de un dispositivo
+type alertRuleCacheEntry struct {
+	rules   []models.AlertRule
+	expires time.Time
+}
+
+// AlertService administra reglas de alerta y las evalúa sobre los valores de
+// sensores de cada medición. Implementa MeasurementObserver
+type AlertService struct {
+	repo     database.Repository
+	cache    database.Cache
+	config   *config.AlertConfig
+	notifier Notifier
+	logger   *logger.Logger
+
+	mu    sync.Mutex
+	rules map[uint]alertRuleCacheEntry // Reglas por idTelemetria
+}
+
+// NewAlertService crea un nuevo servicio de alertas
+func NewAlertService(repo database.Repository, cache database.Cache, cfg *config.AlertConfig, notifier Notifier, log *logger.Logger) *AlertService {
+	return &AlertService{
+		repo:     repo,
+		cache:    cache,
+		config:   cfg,
+		notifier: notifier,
+		logger:   log,
+		rules:    make(map[uint]alertRuleCacheEntry),
+	}
+}
+
+// OnMeasurement evalúa las reglas de alerta del dispositivo con los valores de la medición.
+// Una alerta se activa una sola vez cuando la condición se mantiene durante la duración
+// de la regla, y se resuelve una sola vez cuando el valor vuelve más allá de la histéresis
+func (s *AlertService) OnMeasurement(ctx context.Context, event *MeasurementEvent) {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, event.Device.Identificador)
+
+	// Las lecturas atrasadas no alteran el estado de las alertas
+	if !event.Latest {
+		return
+	}
+
+	device := event.Device
+	measurement := event.Measurement
+
+	rules := s.deviceRules(ctx, device)
+	if len(rules) == 0 {
+		return
+	}
+
+	states, err := s.cache.GetAlertStates(ctx, device.Identificador)
+	if err != nil {
+		log.Warning("Error al obtener estado de alertas del dispositivo %s: %v", device.Identificador, err)
+		states = map[uint]models.AlertState{}
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+
+		value, ok := sensorValue(measurement, rule.Sensor)
+		if !ok {
+			continue
+		}
+
+		state := states[rule.IDRegla]
+		if s.evaluateRule(ctx, device, rule, &state, measurement.Fecha, value) {
+			s.saveState(ctx, device, rule, &state)
+		}
+	}
+}
+
+// evaluateRule actualiza el estado de una regla con un nuevo valor.
+// Retorna true si el estado cambió y debe almacenarse
+func (s *AlertService) evaluateRule(ctx context.Context, device *models.Device, rule *models.AlertRule, state *models.AlertState, ts time.Time, value float64) bool {
+	metric := value
+
+	// Las reglas de variación comparan contra el valor anterior
+	if rule.Operador == models.AlertOperatorRate {
+		previous, previousAt := state.LastValue, state.LastFecha
+		v, t := value, ts
+		state.LastValue, state.LastFecha = &v, &t
+
+		if previous == nil || previousAt == nil || !ts.After(*previousAt) {
+			return true
+		}
+		metric = (value - *previous) / ts.Sub(*previousAt).Minutes()
+	}
+
+	if state.Active {
+		if !alertCleared(rule, metric) {
+			return rule.Operador == models.AlertOperatorRate
+		}
+		s.clearAlert(ctx, device, rule, state, ts, value)
+		return true
+	}
+
+	if !alertTriggered(rule, metric) {
+		if state.PendingSince == nil {
+			return rule.Operador == models.AlertOperatorRate
+		}
+		state.PendingSince = nil
+		return true
+	}
+
+	if state.PendingSince == nil {
+		t := ts
+		state.PendingSince = &t
+	}
+	if ts.Sub(*state.PendingSince) >= time.Duration(rule.Duracion)*time.Second {
+		s.fireAlert(ctx, device, rule, state, ts, value)
+	}
+
+	return true
+}
+
+// fireAlert registra la activación de una alerta
+func (s *AlertService) fireAlert(ctx context.Context, device *models.Device, rule *models.AlertRule, state *models.AlertState, ts time.Time, value float64) {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, device.Identificador)
+
+	state.Active = true
+	state.PendingSince = nil
+
+	// Si el estado en caché se perdió, reutilizar la alerta aún abierta
+	open, err := s.repo.GetOpenAlert(ctx, rule.IDRegla, device.IDTelemetria)
+	if err != nil {
+		log.Warning("Error al consultar alerta abierta de la regla %d: %v", rule.IDRegla, err)
+	}
+	if open != nil {
+		state.IDAlerta = open.IDAlerta
+		return
+	}
+
+	alert := &models.Alert{
+		IDRegla:         rule.IDRegla,
+		IDTelemetria:    device.IDTelemetria,
+		Sensor:          rule.Sensor,
+		Estado:          models.AlertStatusActive,
+		Valor:           value,
+		FechaActivacion: ts,
+		Descripcion:     describeAlert(rule, value),
+	}
+	if err := s.repo.InsertAlert(ctx, alert); err != nil {
+		log.Error("Error al insertar alerta: %v", err)
+		return
+	}
+
+	state.IDAlerta = alert.IDAlerta
+	log.Warning("Alerta activada para dispositivo %s: %s", device.Identificador, alert.Descripcion)
+
+	s.notifier.Notify(ctx, deviceNotification(device, models.NotificationAlertTriggered, ts, alert.Descripcion, alert))
+}
+
+// clearAlert registra la resolución de una alerta
+func (s *AlertService) clearAlert(ctx context.Context, device *models.Device, rule *models.AlertRule, state *models.AlertState, ts time.Time, value float64) {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, device.Identificador)
+
+	if state.IDAlerta != 0 {
+		if err := s.repo.ResolveAlert(ctx, state.IDAlerta, ts, value); err != nil {
+			log.Error("Error al resolver alerta: %v", err)
+		}
+	}
+
+	alert := &models.Alert{
+		IDAlerta:        state.IDAlerta,
+		IDRegla:         rule.IDRegla,
+		IDTelemetria:    device.IDTelemetria,
+		Sensor:          rule.Sensor,
+		Estado:          models.AlertStatusCleared,
+		FechaResolucion: &ts,
+		ValorResolucion: &value,
+		Descripcion:     fmt.Sprintf("%s: %s = %g, alerta resuelta", rule.Nombre, rule.Sensor, value),
+	}
+
+	state.Active = false
+	state.IDAlerta = 0
+	log.Info("Alerta resuelta para dispositivo %s: %s (valor %.2f)", device.Identificador, rule.Nombre, value)
+
+	s.notifier.Notify(ctx, deviceNotification(device, models.NotificationAlertCleared, ts, alert.Descripcion, alert))
+}
+
+// saveState almacena el estado de una regla; los estados vacíos se eliminan
+func (s *AlertService) saveState(ctx context.Context, device *models.Device, rule *models.AlertRule, state *models.AlertState) {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, device.Identificador)
+
+	if !state.Active && state.PendingSince == nil && state.LastValue == nil {
+		if err := s.cache.DeleteAlertState(ctx, device.Identificador, rule.IDRegla); err != nil {
+			log.Warning("Error al eliminar estado de alerta del caché: %v", err)
+		}
+		return
+	}
+
+	if err := s.cache.SetAlertState(ctx, device.Identificador, rule.IDRegla, state); err != nil {
+		log.Warning("Error al almacenar estado de alerta en caché: %v", err)
+	}
+}
+
+// deviceRules obtiene las reglas del dispositivo, con caché local de corta duración
+func (s *AlertService) deviceRules(ctx context.Context, device *models.Device) []models.AlertRule {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, device.Identificador)
+
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.rules[device.IDTelemetria]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.rules
+	}
+
+	rules, err := s.repo.GetDeviceAlertRules(ctx, device.IDTelemetria, device.IDGrupo)
+	if err != nil {
+		log.Warning("Error al consultar reglas de alerta del dispositivo %s: %v", device.Identificador, err)
+		return nil
+	}
+
+	s.mu.Lock()
+	s.rules[device.IDTelemetria] = alertRuleCacheEntry{
+		rules:   rules,
+		expires: now.Add(s.config.CacheTTL),
+	}
+	s.mu.Unlock()
+
+	return rules
+}
+
+// invalidate descarta las reglas cacheadas localmente
+func (s *AlertService) invalidate() {
+	s.mu.Lock()
+	s.rules = make(map[uint]alertRuleCacheEntry)
+	s.mu.Unlock()
+}
+
+// ListAlertRules obtiene todas las reglas de alerta
+func (s *AlertService) ListAlertRules(ctx context.Context) ([]models.AlertRule, error) {
+	rules, err := s.repo.ListAlertRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar reglas de alerta: %w", err)
+	}
+	return rules, nil
+}
+
+// GetAlertRule obtiene una regla de alerta por ID
+func (s *AlertService) GetAlertRule(ctx context.Context, ruleID uint) (*models.AlertRule, error) {
+	rule, err := s.repo.GetAlertRule(ctx, ruleID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener regla de alerta: %w", err)
+	}
+	if rule == nil {
+		return nil, ErrAlertRuleNotFound
+	}
+	return rule, nil
+}
+
+// CreateAlertRule registra una nueva regla de alerta
+func (s *AlertService) CreateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	if err := s.prepareRule(ctx, rule); err != nil {
+		return err
+	}
+
+	if err := s.repo.CreateAlertRule(ctx, rule); err != nil {
+		if errors.Is(err, database.ErrInvalidReference) {
+			return errGroupNotFound
+		}
+		return fmt.Errorf("error al crear regla de alerta: %w", err)
+	}
+
+	s.invalidate()
+	s.logger.Info("Regla de alerta creada: %s (id %d)", rule.Nombre, rule.IDRegla)
+	return nil
+}
+
+// UpdateAlertRule actualiza una regla de alerta
+func (s *AlertService) UpdateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	if err := s.prepareRule(ctx, rule); err != nil {
+		return err
+	}
+
+	found, err := s.repo.UpdateAlertRule(ctx, rule)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidReference) {
+			return errGroupNotFound
+		}
+		return fmt.Errorf("error al actualizar regla de alerta: %w", err)
+	}
+	if !found {
+		return ErrAlertRuleNotFound
+	}
+
+	s.invalidate()
+	s.logger.Info("Regla de alerta actualizada: %s (id %d)", rule.Nombre, rule.IDRegla)
+	return nil
+}
+
+// DeleteAlertRule elimina una regla de alerta
+func (s *AlertService) DeleteAlertRule(ctx context.Context, ruleID uint) error {
+	found, err := s.repo.DeleteAlertRule(ctx, ruleID)
+	if err != nil {
+		return fmt.Errorf("error al eliminar regla de alerta: %w", err)
+	}
+	if !found {
+		return ErrAlertRuleNotFound
+	}
+
+	s.invalidate()
+	s.logger.Info("Regla de alerta eliminada: %d", ruleID)
+	return nil
+}
+
+// ListAlerts consulta el historial de alertas de un dispositivo
+func (s *AlertService) ListAlerts(ctx context.Context, identifier string, query *models.AlertQuery) ([]models.Alert, error) {
+	device, err := s.repo.GetDeviceByIdentifier(ctx, identifier)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener dispositivo: %w", err)
+	}
+	if device == nil {
+		return nil, ErrDeviceNotFound
+	}
+
+	query.IDTelemetria = device.IDTelemetria
+	alerts, err := s.repo.ListAlerts(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar alertas: %w", err)
+	}
+
+	return alerts, nil
+}
+
+// prepareRule valida la regla y resuelve el identificador del dispositivo
+func (s *AlertService) prepareRule(ctx context.Context, rule *models.AlertRule) error {
+	if err := validateAlertRule(rule); err != nil {
+		return err
+	}
+
+	if rule.IDGrupo != nil {
+		rule.IDTelemetria = nil
+		rule.Identificador = ""
+		return nil
+	}
+
+	device, err := s.repo.GetDeviceByIdentifier(ctx, rule.Identificador)
+	if err != nil {
+		return fmt.Errorf("error al obtener dispositivo: %w", err)
+	}
+	if device == nil {
+		return ErrDeviceNotFound
+	}
+	rule.IDTelemetria = &device.IDTelemetria
+
+	return nil
+}
+
+// validateAlertRule valida los campos de una regla de alerta
+func validateAlertRule(rule *models.AlertRule) error {
+	var errors []models.ValidationError
+
+	rule.Nombre = strings.TrimSpace(rule.Nombre)
+	if rule.Nombre == "" {
+		errors = append(errors, models.ValidationError{
+			Field:   "nombre",
+			Message: "El nombre es requerido",
+		})
+	}
+
+	rule.Identificador = strings.TrimSpace(rule.Identificador)
+	if (rule.Identificador == "") == (rule.IDGrupo == nil) {
+		errors = append(errors, models.ValidationError{
+			Field:   "identificador",
+			Message: "Se debe indicar identificador o idGrupo (solo uno)",
+		})
+	}
+
+	if !sensorNamePattern.MatchString(rule.Sensor) {
+		errors = append(errors, models.ValidationError{
+			Field:   "sensor",
+			Message: "Nombre de sensor inválido (sensor_1..sensor_5 o nombre del catálogo)",
+		})
+	}
+
+	switch rule.Operador {
+	case models.AlertOperatorGreater, models.AlertOperatorLess:
+		rule.UmbralMax = nil
+	case models.AlertOperatorRate:
+		rule.UmbralMax = nil
+		if rule.Umbral <= 0 {
+			errors = append(errors, models.ValidationError{
+				Field:   "umbral",
+				Message: "El umbral de variación debe ser mayor a cero (unidades por minuto)",
+			})
+		}
+	case models.AlertOperatorBetween, models.AlertOperatorOutside:
+		if rule.UmbralMax == nil || *rule.UmbralMax <= rule.Umbral {
+			errors = append(errors, models.ValidationError{
+				Field:   "umbralMax",
+				Message: "El umbral máximo debe ser mayor al umbral",
+			})
+		}
+	default:
+		errors = append(errors, models.ValidationError{
+			Field:   "operador",
+			Message: "El operador debe ser gt, lt, between, outside o rate",
+		})
+	}
+
+	if rule.Histeresis < 0 {
+		errors = append(errors, models.ValidationError{
+			Field:   "histeresis",
+			Message: "La histéresis no puede ser negativa",
+		})
+	} else if message := hysteresisError(rule); message != "" {
+		errors = append(errors, models.ValidationError{
+			Field:   "histeresis",
+			Message: message,
+		})
+	}
+
+	if rule.Duracion < 0 {
+		errors = append(errors, models.ValidationError{
+			Field:   "duracion",
+			Message: "La duración no puede ser negativa",
+		})
+	}
+
+	if len(errors) > 0 {
+		return &ValidationErrors{Errors: errors}
+	}
+
+	return nil
+}
+
+// hysteresisError verifica que la alerta pueda resolverse con la histéresis de
+// la regla (ver alertCleared). Retorna el mensaje de error o "" si es válida.
+// gt, lt y between siempre se resuelven: basta con alejarse lo suficiente del
+// umbral o del rango
+func hysteresisError(rule *models.AlertRule) string {
+	h := rule.Histeresis
+	if h == 0 {
+		return ""
+	}
+
+	switch rule.Operador {
+	case models.AlertOperatorRate:
+		// La variación absoluta nunca es negativa: con h >= umbral la alerta no se resolvería
+		if h >= rule.Umbral {
+			return "La histéresis debe ser menor al umbral de variación"
+		}
+	case models.AlertOperatorOutside:
+		// Se resuelve dentro del rango reducido en h por ambos lados
+		if rule.UmbralMax != nil && h >= (*rule.UmbralMax-rule.Umbral)/2 {
+			return "La histéresis debe ser menor a la mitad del rango"
+		}
+	}
+	return ""
+}
+
+// sensorValue obtiene el valor de un sensor de la medición: sensor_1..sensor_5
+// o un sensor con nombre
+func sensorValue(measurement *models.Measurement, name string) (float64, bool) {
+	if value, ok := measurement.Sensores[name]; ok {
+		return value, true
+	}
+
+	legacy := map[string]*float64{
+		"sensor_1": measurement.Sensor1,
+		"sensor_2": measurement.Sensor2,
+		"sensor_3": measurement.Sensor3,
+		"sensor_4": measurement.Sensor4,
+		"sensor_5": measurement.Sensor5,
+	}
+	if value := legacy[name]; value != nil {
+		return *value, true
+	}
+
+	return 0, false
+}
+
+// alertTriggered indica si el valor cumple la condición de la regla
+func alertTriggered(rule *models.AlertRule, value float64) bool {
+	switch rule.Operador {
+	case models.AlertOperatorGreater:
+		return value > rule.Umbral
+	case models.AlertOperatorLess:
+		return value < rule.Umbral
+	case models.AlertOperatorBetween:
+		return rule.UmbralMax != nil && value >= rule.Umbral && value <= *rule.UmbralMax
+	case models.AlertOperatorOutside:
+		return rule.UmbralMax != nil && (value < rule.Umbral || value > *rule.UmbralMax)
+	case models.AlertOperatorRate:
+		return math.Abs(value) > rule.Umbral
+	}
+	return false
+}
+
+// alertCleared indica si el valor salió de la condición más allá de la histéresis
+func alertCleared(rule *models.AlertRule, value float64) bool {
+	h := rule.Histeresis
+
+	switch rule.Operador {
+	case models.AlertOperatorGreater:
+		return value <= rule.Umbral-h
+	case models.AlertOperatorLess:
+		return value >= rule.Umbral+h
+	case models.AlertOperatorBetween:
+		return rule.UmbralMax == nil || value < rule.Umbral-h || value > *rule.UmbralMax+h
+	case models.AlertOperatorOutside:
+		return rule.UmbralMax == nil || (value >= rule.Umbral+h && value <= *rule.UmbralMax-h)
+	case models.AlertOperatorRate:
+		return math.Abs(value) <= rule.Umbral-h
+	}
+	return true
+}
+
+// describeAlert construye la descripción de una alerta activada
+func describeAlert(rule *models.AlertRule, value float64) string {
+	var condition string
+	switch rule.Operador {
+	case models.AlertOperatorGreater:
+		condition = fmt.Sprintf("mayor a %g", rule.Umbral)
+	case models.AlertOperatorLess:
+		condition = fmt.Sprintf("menor a %g", rule.Umbral)
+	case models.AlertOperatorBetween:
+		condition = fmt.Sprintf("entre %g y %g", rule.Umbral, *rule.UmbralMax)
+	case models.AlertOperatorOutside:
+		condition = fmt.Sprintf("fuera de %g y %g", rule.Umbral, *rule.UmbralMax)
+	case models.AlertOperatorRate:
+		condition = fmt.Sprintf("con variación mayor a %g por minuto", rule.Umbral)
+	}
+
+	return fmt.Sprintf("%s: %s = %g %s", rule.Nombre, rule.Sensor, value, condition)
+}
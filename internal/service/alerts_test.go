@@ -0,0 +1,100 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+func TestValidateAlertRuleHysteresis(t *testing.T) {
+	max := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name       string
+		operador   string
+		umbral     float64
+		umbralMax  *float64
+		histeresis float64
+		wantErr    bool
+	}{
+		{name: "gt sin histéresis", operador: models.AlertOperatorGreater, umbral: 8},
+		{name: "gt con histéresis menor al umbral", operador: models.AlertOperatorGreater, umbral: 8, histeresis: 0.5},
+		{name: "gt con histéresis igual al umbral", operador: models.AlertOperatorGreater, umbral: 8, histeresis: 8},
+		{name: "gt con umbral cero", operador: models.AlertOperatorGreater, umbral: 0, histeresis: 0.5},
+		{name: "lt con umbral negativo", operador: models.AlertOperatorLess, umbral: -20, histeresis: 2},
+		{name: "lt con histéresis mayor al umbral", operador: models.AlertOperatorLess, umbral: -20, histeresis: 25},
+		{name: "lt con umbral cero", operador: models.AlertOperatorLess, umbral: 0, histeresis: 0.5},
+		{name: "rate con histéresis menor al umbral", operador: models.AlertOperatorRate, umbral: 5, histeresis: 1},
+		{name: "rate con histéresis igual al umbral", operador: models.AlertOperatorRate, umbral: 5, histeresis: 5, wantErr: true},
+		{name: "rate con histéresis mayor al umbral", operador: models.AlertOperatorRate, umbral: 5, histeresis: 10, wantErr: true},
+		{name: "between con histéresis menor a la mitad", operador: models.AlertOperatorBetween, umbral: 2, umbralMax: max(8), histeresis: 2.5},
+		{name: "between con histéresis igual a la mitad", operador: models.AlertOperatorBetween, umbral: 2, umbralMax: max(8), histeresis: 3},
+		{name: "between con histéresis mayor al rango", operador: models.AlertOperatorBetween, umbral: 2, umbralMax: max(8), histeresis: 10},
+		{name: "outside con histéresis menor a la mitad", operador: models.AlertOperatorOutside, umbral: 2, umbralMax: max(8), histeresis: 2.5},
+		{name: "outside con histéresis igual a la mitad", operador: models.AlertOperatorOutside, umbral: 2, umbralMax: max(8), histeresis: 3, wantErr: true},
+		{name: "outside con histéresis mayor a la mitad", operador: models.AlertOperatorOutside, umbral: 2, umbralMax: max(8), histeresis: 4, wantErr: true},
+		{name: "histéresis negativa", operador: models.AlertOperatorGreater, umbral: 8, histeresis: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &models.AlertRule{
+				Nombre:        "Regla",
+				Identificador: "DEVICE001",
+				Sensor:        "sensor_1",
+				Operador:      tt.operador,
+				Umbral:        tt.umbral,
+				UmbralMax:     tt.umbralMax,
+				Histeresis:    tt.histeresis,
+			}
+
+			err := validateAlertRule(rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, se esperaba error: %v", err, tt.wantErr)
+			}
+
+			// Toda regla válida debe poder resolverse
+			if err == nil && !clearable(rule) {
+				t.Errorf("la regla es válida pero la alerta nunca se resuelve")
+			}
+		})
+	}
+}
+
+// clearable indica si existe algún valor que resuelva la alerta de la regla
+func clearable(rule *models.AlertRule) bool {
+	for v := -1000.0; v <= 1000; v += 0.25 {
+		if alertCleared(rule, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAlertCleared(t *testing.T) {
+	max := 8.0
+
+	tests := []struct {
+		name  string
+		rule  models.AlertRule
+		value float64
+		want  bool
+	}{
+		{name: "gt dentro del margen", rule: models.AlertRule{Operador: models.AlertOperatorGreater, Umbral: 8, Histeresis: 0.5}, value: 7.8, want: false},
+		{name: "gt bajo el margen", rule: models.AlertRule{Operador: models.AlertOperatorGreater, Umbral: 8, Histeresis: 0.5}, value: 7.5, want: true},
+		{name: "lt sobre el margen", rule: models.AlertRule{Operador: models.AlertOperatorLess, Umbral: 2, Histeresis: 0.5}, value: 2.5, want: true},
+		{name: "rate dentro del margen", rule: models.AlertRule{Operador: models.AlertOperatorRate, Umbral: 5, Histeresis: 1}, value: -4.5, want: false},
+		{name: "rate bajo el margen", rule: models.AlertRule{Operador: models.AlertOperatorRate, Umbral: 5, Histeresis: 1}, value: -3, want: true},
+		{name: "between fuera del rango con margen", rule: models.AlertRule{Operador: models.AlertOperatorBetween, Umbral: 2, UmbralMax: &max, Histeresis: 1}, value: 9.5, want: true},
+		{name: "outside dentro del rango con margen", rule: models.AlertRule{Operador: models.AlertOperatorOutside, Umbral: 2, UmbralMax: &max, Histeresis: 1}, value: 5, want: true},
+		{name: "outside en el margen", rule: models.AlertRule{Operador: models.AlertOperatorOutside, Umbral: 2, UmbralMax: &max, Histeresis: 1}, value: 2.5, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := alertCleared(&tt.rule, tt.value); got != tt.want {
+				t.Errorf("se obtuvo %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
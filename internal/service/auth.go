@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"golang.org/x/time/rate"
+)
+
+// ErrUnauthorized indica que el token no es válido para el dispositivo reportado
+var ErrUnauthorized = errors.New("credenciales inválidas para el dispositivo")
+
+// Signature representa la firma HMAC-SHA256 de una solicitud
+type Signature struct {
+	Timestamp int64  // Epoch en segundos
+	Nonce     string // Valor único por solicitud
+	Value     string // HMAC-SHA256 en hexadecimal
+}
+
+// rejectionRecordBurst es la ráfaga de rechazos que se registra en la tabla de
+// errores antes de aplicar AUTH_REJECTION_RECORD_RATE
+const rejectionRecordBurst = 10
+
+// AuthService maneja la autenticación de dispositivos mediante API keys y firmas HMAC
+type AuthService struct {
+	repo          database.Repository
+	cache         database.Cache
+	config        *config.AuthConfig
+	recordLimiter *rate.Limiter // Limita las escrituras de rechazos en la base de datos
+	logger        *logger.Logger
+}
+
+// NewAuthService crea un nuevo servicio de autenticación
+func NewAuthService(repo database.Repository, cache database.Cache, cfg *config.AuthConfig, log *logger.Logger) *AuthService {
+	burst := rejectionRecordBurst
+	if cfg.RejectionRecordRate <= 0 {
+		burst = 0
+	}
+
+	return &AuthService{
+		repo:          repo,
+		cache:         cache,
+		config:        cfg,
+		recordLimiter: rate.NewLimiter(rate.Limit(cfg.RejectionRecordRate), burst),
+		logger:        log,
+	}
+}
+
+// Authenticate verifica que el token pertenezca al dispositivo indicado.
+// Los rechazos se registran en la tabla de errores con el origen de la solicitud
+func (s *AuthService) Authenticate(ctx context.Context, identifier, token, origin string) error {
+	if token == "" {
+		s.recordRejection(ctx, identifier, nil, fmt.Sprintf("Autenticación rechazada: token ausente (origen: %s)", origin))
+		return ErrUnauthorized
+	}
+
+	credentials, err := s.getCredentials(ctx, identifier)
+	if err != nil {
+		return fmt.Errorf("error al obtener credenciales: %w", err)
+	}
+
+	tokenHash := HashToken(token)
+	now := time.Now()
+	var deviceID *uint
+
+	for i := range credentials {
+		credential := &credentials[i]
+		deviceID = &credential.IDTelemetria
+
+		if credential.Tipo != models.CredentialTypeToken || isExpired(credential, now) {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(tokenHash), []byte(credential.TokenHash)) == 1 {
+			return nil
+		}
+	}
+
+	s.recordRejection(ctx, identifier, deviceID, fmt.Sprintf("Autenticación rechazada: token inválido (origen: %s)", origin))
+	return ErrUnauthorized
+}
+
+// VerifySignature verifica una solicitud firmada con HMAC-SHA256.
+// La firma se calcula sobre "timestamp\nnonce\nbody" con el secreto del dispositivo;
+// el timestamp debe estar dentro de la ventana configurada y el nonce no debe haberse usado
+func (s *AuthService) VerifySignature(ctx context.Context, identifier string, body []byte, sig *Signature, origin string) error {
+	if sig.Nonce == "" || sig.Value == "" || sig.Timestamp == 0 {
+		s.recordRejection(ctx, identifier, nil, fmt.Sprintf("Firma rechazada: firma incompleta (origen: %s)", origin))
+		return ErrUnauthorized
+	}
+
+	// Verificar ventana de tiempo
+	now := time.Now()
+	signedAt := time.Unix(sig.Timestamp, 0)
+	if skew := now.Sub(signedAt); skew > s.config.SignatureWindow || -skew > s.config.SignatureWindow {
+		s.recordRejection(ctx, identifier, nil, fmt.Sprintf("Firma rechazada: timestamp %s fuera de la ventana de %s (origen: %s)",
+			signedAt.Format(time.RFC3339), s.config.SignatureWindow.String(), origin))
+		return ErrUnauthorized
+	}
+
+	credentials, err := s.getCredentials(ctx, identifier)
+	if err != nil {
+		return fmt.Errorf("error al obtener credenciales: %w", err)
+	}
+
+	expected, err := hex.DecodeString(sig.Value)
+	if err != nil {
+		s.recordRejection(ctx, identifier, nil, fmt.Sprintf("Firma rechazada: formato de firma inválido (origen: %s)", origin))
+		return ErrUnauthorized
+	}
+
+	var deviceID *uint
+	valid := false
+	for i := range credentials {
+		credential := &credentials[i]
+		deviceID = &credential.IDTelemetria
+
+		if credential.Tipo != models.CredentialTypeHMAC || isExpired(credential, now) {
+			continue
+		}
+
+		if hmac.Equal(expected, ComputeSignature(credential.Secreto, sig.Timestamp, sig.Nonce, body)) {
+			valid = true
+			break
+		}
+	}
+
+	if !valid {
+		s.recordRejection(ctx, identifier, deviceID, fmt.Sprintf("Firma rechazada: firma inválida (origen: %s)", origin))
+		return ErrUnauthorized
+	}
+
+	// Registrar nonce; el TTL cubre la ventana hacia ambos lados del timestamp
+	fresh, err := s.cache.RegisterNonce(ctx, identifier, sig.Nonce, 2*s.config.SignatureWindow)
+	if err != nil {
+		return fmt.Errorf("error al registrar nonce: %w", err)
+	}
+	if !fresh {
+		s.recordRejection(ctx, identifier, deviceID, fmt.Sprintf("Firma rechazada: nonce repetido %s (origen: %s)", sig.Nonce, origin))
+		return ErrUnauthorized
+	}
+
+	return nil
+}
+
+// getCredentials obtiene las credenciales desde caché o base de datos
+func (s *AuthService) getCredentials(ctx context.Context, identifier string) ([]models.DeviceCredential, error) {
+	// Intentar caché primero
+	credentials, found, err := s.cache.GetDeviceCredentials(ctx, identifier)
+	if err != nil {
+		s.logger.Warning("Error al obtener credenciales del caché: %v", err)
+	}
+
+	if found {
+		return credentials, nil
+	}
+
+	// Respaldo a base de datos
+	credentials, err = s.repo.GetDeviceCredentials(ctx, identifier)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar credenciales desde la base de datos: %w", err)
+	}
+
+	// Poblar caché (también sin credenciales, para no consultar la base de datos en cada intento)
+	if err := s.cache.SetDeviceCredentials(ctx, identifier, credentials); err != nil {
+		s.logger.Warning("Error al almacenar credenciales en caché: %v", err)
+	}
+
+	return credentials, nil
+}
+
+// recordRejection registra un intento de autenticación rechazado. Todos se
+// escriben en el log; en la tabla de errores solo hasta AUTH_REJECTION_RECORD_RATE
+// por segundo, para que las solicitudes no autenticadas no se traduzcan en
+// escrituras ilimitadas en la base de datos
+func (s *AuthService) recordRejection(ctx context.Context, identifier string, deviceID *uint, description string) {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, identifier)
+
+	log.Warning("Dispositivo %s: %s", identifier, description)
+
+	if !s.recordLimiter.Allow() {
+		return
+	}
+
+	errorRecord := &models.ErrorRecord{
+		IDTelemetria:  deviceID,
+		Identificador: &identifier,
+		Fecha:         time.Now(),
+		Descripcion:   description,
+		RequestID:     requestIDOf(ctx),
+	}
+	if err := s.repo.InsertError(ctx, errorRecord); err != nil {
+		log.Error("Error al insertar registro de error: %v", err)
+	}
+}
+
+// isExpired indica si la credencial expiró (pudo expirar mientras estaba en caché)
+func isExpired(credential *models.DeviceCredential, now time.Time) bool {
+	return credential.FechaExpiracion != nil && !credential.FechaExpiracion.After(now)
+}
+
+// ComputeSignature calcula el HMAC-SHA256 de "timestamp\nnonce\nbody" con el secreto dado
+func ComputeSignature(secret string, timestamp int64, nonce string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d\n%s\n", timestamp, nonce)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// HashToken retorna el hash SHA-256 en hexadecimal de un token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+const (
+	testIdentifier = "DEVICE001"
+	testSecret     = "secreto-hmac"
+	testToken      = "token-secreto"
+)
+
+// newTestAuthService crea un servicio de autenticación con un token y un
+// secreto HMAC vigentes para testIdentifier
+func newTestAuthService(t *testing.T, cfg *config.AuthConfig) (*AuthService, *fakeRepository) {
+	t.Helper()
+
+	repo := &fakeRepository{
+		credentials: map[string][]models.DeviceCredential{
+			testIdentifier: {
+				{IDCredencial: 1, IDTelemetria: 1, Tipo: models.CredentialTypeToken, TokenHash: HashToken(testToken)},
+				{IDCredencial: 2, IDTelemetria: 1, Tipo: models.CredentialTypeHMAC, Secreto: testSecret},
+			},
+		},
+	}
+	return NewAuthService(repo, newTestCache(t), cfg, newTestLogger(t)), repo
+}
+
+// sign firma body con el secreto de prueba
+func sign(timestamp int64, nonce string, body []byte) *Signature {
+	return &Signature{
+		Timestamp: timestamp,
+		Nonce:     nonce,
+		Value:     hex.EncodeToString(ComputeSignature(testSecret, timestamp, nonce, body)),
+	}
+}
+
+func TestComputeSignature(t *testing.T) {
+	body := []byte(`{"identificador":"DEVICE001"}`)
+
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte("1733405400\n7f3a9c1e\n"))
+	mac.Write(body)
+	if got, want := ComputeSignature(testSecret, 1733405400, "7f3a9c1e", body), mac.Sum(nil); !hmac.Equal(got, want) {
+		t.Fatalf("firma incorrecta: %x, se esperaba %x", got, want)
+	}
+
+	// Cada componente firmado cambia la firma
+	base := ComputeSignature(testSecret, 1733405400, "7f3a9c1e", body)
+	variants := map[string][]byte{
+		"secreto":   ComputeSignature("otro", 1733405400, "7f3a9c1e", body),
+		"timestamp": ComputeSignature(testSecret, 1733405401, "7f3a9c1e", body),
+		"nonce":     ComputeSignature(testSecret, 1733405400, "7f3a9c1f", body),
+		"cuerpo":    ComputeSignature(testSecret, 1733405400, "7f3a9c1e", []byte(`{"identificador":"DEVICE002"}`)),
+	}
+	for name, variant := range variants {
+		if hmac.Equal(base, variant) {
+			t.Errorf("cambiar %s no cambia la firma", name)
+		}
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"identificador":"DEVICE001","latitud":-33.45,"longitud":-70.66}`)
+	now := time.Now().Unix()
+	window := 5 * time.Minute
+
+	tests := []struct {
+		name       string
+		identifier string
+		body       []byte
+		sig        *Signature
+		wantErr    error
+	}{
+		{name: "firma válida", identifier: testIdentifier, body: body, sig: sign(now, "n-valida", body)},
+		{name: "en el borde de la ventana", identifier: testIdentifier, body: body, sig: sign(now-int64(window.Seconds())+5, "n-borde", body)},
+		{name: "cuerpo alterado", identifier: testIdentifier, body: []byte(`{"identificador":"DEVICE001","latitud":0,"longitud":0}`), sig: sign(now, "n-alterado", body), wantErr: ErrUnauthorized},
+		{name: "timestamp antiguo", identifier: testIdentifier, body: body, sig: sign(now-int64(window.Seconds())-60, "n-antiguo", body), wantErr: ErrUnauthorized},
+		{name: "timestamp futuro", identifier: testIdentifier, body: body, sig: sign(now+int64(window.Seconds())+60, "n-futuro", body), wantErr: ErrUnauthorized},
+		{name: "sin nonce", identifier: testIdentifier, body: body, sig: sign(now, "", body), wantErr: ErrUnauthorized},
+		{name: "sin timestamp", identifier: testIdentifier, body: body, sig: &Signature{Nonce: "n", Value: "00"}, wantErr: ErrUnauthorized},
+		{name: "firma no hexadecimal", identifier: testIdentifier, body: body, sig: &Signature{Timestamp: now, Nonce: "n-hex", Value: "zz"}, wantErr: ErrUnauthorized},
+		{name: "otro dispositivo", identifier: "DEVICE002", body: body, sig: sign(now, "n-otro", body), wantErr: ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, _ := newTestAuthService(t, &config.AuthConfig{SignatureWindow: window, RejectionRecordRate: 1})
+
+			err := auth.VerifySignature(context.Background(), tt.identifier, tt.body, tt.sig, "test")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("se obtuvo %v, se esperaba %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifySignatureNonceReplay(t *testing.T) {
+	auth, _ := newTestAuthService(t, &config.AuthConfig{SignatureWindow: 5 * time.Minute, RejectionRecordRate: 1})
+	body := []byte(`{"identificador":"DEVICE001"}`)
+	sig := sign(time.Now().Unix(), "nonce-unico", body)
+
+	if err := auth.VerifySignature(context.Background(), testIdentifier, body, sig, "test"); err != nil {
+		t.Fatalf("primera solicitud rechazada: %v", err)
+	}
+	if err := auth.VerifySignature(context.Background(), testIdentifier, body, sig, "test"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("solicitud repetida: se obtuvo %v, se esperaba %v", err, ErrUnauthorized)
+	}
+}
+
+func TestVerifySignatureExpiredCredential(t *testing.T) {
+	auth, repo := newTestAuthService(t, &config.AuthConfig{SignatureWindow: 5 * time.Minute, RejectionRecordRate: 1})
+	expired := time.Now().Add(-time.Hour)
+	repo.credentials[testIdentifier][1].FechaExpiracion = &expired
+
+	body := []byte(`{"identificador":"DEVICE001"}`)
+	err := auth.VerifySignature(context.Background(), testIdentifier, body, sign(time.Now().Unix(), "n", body), "test")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("se obtuvo %v, se esperaba %v", err, ErrUnauthorized)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "token válido", token: testToken},
+		{name: "token inválido", token: "otro", wantErr: ErrUnauthorized},
+		{name: "sin token", token: "", wantErr: ErrUnauthorized},
+		{name: "el secreto HMAC no sirve como token", token: testSecret, wantErr: ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, _ := newTestAuthService(t, &config.AuthConfig{RejectionRecordRate: 1})
+
+			err := auth.Authenticate(context.Background(), testIdentifier, tt.token, "test")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("se obtuvo %v, se esperaba %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRejectionRecordRate(t *testing.T) {
+	tests := []struct {
+		name string
+		rate float64
+		want int
+	}{
+		{name: "limitado a la ráfaga", rate: 1, want: rejectionRecordBurst},
+		{name: "solo en el log", rate: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, repo := newTestAuthService(t, &config.AuthConfig{RejectionRecordRate: tt.rate})
+
+			for i := 0; i < 3*rejectionRecordBurst; i++ {
+				if err := auth.Authenticate(context.Background(), testIdentifier, "invalido", "test"); !errors.Is(err, ErrUnauthorized) {
+					t.Fatalf("se obtuvo %v, se esperaba %v", err, ErrUnauthorized)
+				}
+			}
+			if got := repo.errorCount(); got != tt.want {
+				t.Errorf("se registraron %d rechazos, se esperaban %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math"
+	"regexp"
+	"sort"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// ErrDuplicate indica que el mensaje ya fue recibido dentro de la ventana de deduplicación
+var ErrDuplicate = errors.New("mensaje duplicado")
+
+// Resultados guardados de un mensaje, retornados a sus repeticiones
+const (
+	MessageProcessed = "success"  // Procesado y almacenado
+	MessageAccepted  = "accepted" // Almacenado en el spool
+)
+
+// messageIDPattern valida el ID de mensaje enviado por el dispositivo
+// (hasta 128 caracteres ASCII imprimibles sin espacios)
+var messageIDPattern = regexp.MustCompile(`^[\x21-\x7e]{1,128}$`)
+
+// MessageClaim es la reserva de un mensaje mientras se procesa. Debe
+// completarse con Complete o liberarse con Release
+type MessageClaim struct {
+	identifier string
+	key        string
+}
+
+// DedupService descarta los mensajes repetidos (reenvíos tras un timeout,
+// entregas duplicadas de MQTT QoS 1). Un mensaje se reconoce por el ID enviado
+// por el dispositivo o, sin él, por la huella de su identificador, marca de
+// tiempo y valores. Las mediciones sin ID ni marca de tiempo no se deduplican,
+// ya que dos lecturas iguales pueden ser legítimas
+type DedupService struct {
+	cache  database.Cache
+	config *config.DedupConfig
+	logger *logger.Logger
+}
+
+// NewDedupService crea un nuevo servicio de deduplicación de mensajes
+func NewDedupService(cache database.Cache, cfg *config.DedupConfig, log *logger.Logger) *DedupService {
+	return &DedupService{
+		cache:  cache,
+		config: cfg,
+		logger: log,
+	}
+}
+
+// Claim reserva el mensaje durante la ventana de deduplicación. Si el mensaje es
+// una repetición retorna ErrDuplicate junto con el resultado del original
+// ("" mientras aún se procesa). Retorna una reserva nil si el mensaje no se puede
+// reconocer, la deduplicación está deshabilitada o el caché falla: ante la duda
+// el mensaje se procesa
+func (s *DedupService) Claim(ctx context.Context, req *models.TelemetryRequest) (*MessageClaim, string, error) {
+	if !s.config.Enabled {
+		return nil, "", nil
+	}
+
+	key := messageKey(req)
+	if key == "" {
+		return nil, "", nil
+	}
+
+	claimed, result, err := s.cache.ClaimMessage(ctx, req.Identificador, key, s.config.Window)
+	if err != nil {
+		s.logger.WithContext(ctx).With(logger.KeyDevice, req.Identificador).Warning("Error al verificar mensaje duplicado: %v", err)
+		return nil, "", nil
+	}
+	if !claimed {
+		return nil, result, ErrDuplicate
+	}
+
+	return &MessageClaim{identifier: req.Identificador, key: key}, "", nil
+}
+
+// Complete guarda el resultado del mensaje para responderlo a sus repeticiones
+func (s *DedupService) Complete(ctx context.Context, claim *MessageClaim, result string) {
+	if claim == nil {
+		return
+	}
+
+	if err := s.cache.CompleteMessage(ctx, claim.identifier, claim.key, result, s.config.Window); err != nil {
+		s.logger.WithContext(ctx).With(logger.KeyDevice, claim.identifier).Warning("Error al guardar resultado del mensaje: %v", err)
+	}
+}
+
+// Release libera la reserva de un mensaje que no se procesó, para que el
+// dispositivo pueda reintentarlo
+func (s *DedupService) Release(ctx context.Context, claim *MessageClaim) {
+	if claim == nil {
+		return
+	}
+
+	if err := s.cache.ReleaseMessage(ctx, claim.identifier, claim.key); err != nil {
+		s.logger.WithContext(ctx).With(logger.KeyDevice, claim.identifier).Warning("Error al liberar mensaje: %v", err)
+	}
+}
+
+// messageKey retorna la clave de deduplicación del mensaje: su ID o la huella
+// de la medición, o "" si no tiene ID ni marca de tiempo
+func messageKey(req *models.TelemetryRequest) string {
+	if req.MessageID != "" {
+		return "id:" + req.MessageID
+	}
+	if req.Timestamp == nil {
+		return ""
+	}
+	return "fp:" + fingerprint(req)
+}
+
+// fingerprint calcula el SHA-256 del identificador, la marca de tiempo y los
+// valores de una medición. La marca de tiempo se toma como instante, por lo que
+// la misma hora en distinta zona horaria produce la misma huella
+func fingerprint(req *models.TelemetryRequest) string {
+	h := sha256.New()
+	var buf [8]byte
+
+	writeUint := func(v uint64) {
+		binary.BigEndian.PutUint64(buf[:], v)
+		h.Write(buf[:])
+	}
+	writeString := func(v string) {
+		writeUint(uint64(len(v)))
+		h.Write([]byte(v))
+	}
+	writeFloat := func(v *float64) {
+		if v == nil {
+			h.Write([]byte{0})
+			return
+		}
+		h.Write([]byte{1})
+		writeUint(math.Float64bits(*v))
+	}
+
+	writeString(req.Identificador)
+	writeUint(uint64(req.Timestamp.UnixNano()))
+	for _, v := range []*float64{req.Latitud, req.Longitud, req.Sensor1, req.Sensor2, req.Sensor3, req.Sensor4, req.Sensor5} {
+		writeFloat(v)
+	}
+
+	// Sensores con nombre en orden alfabético
+	names := make([]string, 0, len(req.Sensors))
+	for name := range req.Sensors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := float64(req.Sensors[name])
+		writeString(name)
+		writeFloat(&value)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// testReading crea una medición de DEVICE001 con la marca de tiempo y el ID indicados
+func testReading(timestamp *time.Time, messageID string, sensor1 float64) *models.TelemetryRequest {
+	lat, lng := -33.45, -70.66
+	req := &models.TelemetryRequest{
+		Identificador: "DEVICE001",
+		Latitud:       &lat,
+		Longitud:      &lng,
+		Sensor1:       &sensor1,
+		Sensors:       map[string]models.SensorValue{"temp_engine": 90, "fuel_level": 55.5},
+		MessageID:     messageID,
+	}
+	if timestamp != nil {
+		req.Timestamp = &models.DeviceTime{Time: *timestamp}
+	}
+	return req
+}
+
+func TestMessageKey(t *testing.T) {
+	ts := time.Date(2024, 5, 10, 12, 30, 0, 0, time.UTC)
+	sameInstant := ts.In(time.FixedZone("CLT", -4*3600))
+	later := ts.Add(time.Second)
+
+	base := messageKey(testReading(&ts, "", 23.5))
+
+	tests := []struct {
+		name     string
+		req      *models.TelemetryRequest
+		wantKey  string // Clave exacta esperada, si se conoce
+		prefix   string
+		sameAsFp bool // Debe coincidir con la huella de la medición base
+	}{
+		{name: "ID de mensaje", req: testReading(&ts, "DEVICE001-000123", 23.5), wantKey: "id:DEVICE001-000123"},
+		{name: "ID de mensaje sin marca de tiempo", req: testReading(nil, "DEVICE001-000123", 23.5), wantKey: "id:DEVICE001-000123"},
+		{name: "sin ID ni marca de tiempo no se deduplica", req: testReading(nil, "", 23.5), wantKey: ""},
+		{name: "huella con marca de tiempo", req: testReading(&ts, "", 23.5), prefix: "fp:", sameAsFp: true},
+		{name: "mismo instante en otra zona horaria", req: testReading(&sameInstant, "", 23.5), prefix: "fp:", sameAsFp: true},
+		{name: "otra marca de tiempo", req: testReading(&later, "", 23.5), prefix: "fp:"},
+		{name: "otro valor de sensor", req: testReading(&ts, "", 23.6), prefix: "fp:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := messageKey(tt.req)
+
+			if tt.prefix == "" {
+				if key != tt.wantKey {
+					t.Errorf("se obtuvo %q, se esperaba %q", key, tt.wantKey)
+				}
+				return
+			}
+
+			if !strings.HasPrefix(key, tt.prefix) || len(key) != len(tt.prefix)+64 {
+				t.Fatalf("se obtuvo %q, se esperaba %s seguido de un SHA-256 hexadecimal", key, tt.prefix)
+			}
+			if (key == base) != tt.sameAsFp {
+				t.Errorf("coincide con la huella base: %v, se esperaba %v", key == base, tt.sameAsFp)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	ts := time.Date(2024, 5, 10, 12, 30, 0, 0, time.UTC)
+
+	// El orden de un mapa no está definido; la huella debe ser estable
+	first := fingerprint(testReading(&ts, "", 23.5))
+	for i := 0; i < 20; i++ {
+		if got := fingerprint(testReading(&ts, "", 23.5)); got != first {
+			t.Fatalf("huella inestable: %s != %s", got, first)
+		}
+	}
+
+	// Un sensor ausente no equivale a un sensor en cero
+	withoutSensor := testReading(&ts, "", 23.5)
+	withoutSensor.Sensor1 = nil
+	zeroSensor := testReading(&ts, "", 0)
+	if fingerprint(withoutSensor) == fingerprint(zeroSensor) {
+		t.Error("un sensor ausente y un sensor en cero producen la misma huella")
+	}
+}
+
+// failingCache es un caché cuya reserva de mensajes siempre falla
+type failingCache struct {
+	database.Cache
+}
+
+func (failingCache) ClaimMessage(ctx context.Context, identifier, messageID string, ttl time.Duration) (bool, string, error) {
+	return false, "", errors.New("redis caído")
+}
+
+func TestDedupClaim(t *testing.T) {
+	ctx := context.Background()
+	enabled := &config.DedupConfig{Enabled: true, Window: time.Minute}
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s *DedupService, req *models.TelemetryRequest)
+	}{
+		{
+			name: "repetición en curso",
+			run: func(t *testing.T, s *DedupService, req *models.TelemetryRequest) {
+				s.Claim(ctx, req)
+				_, original, err := s.Claim(ctx, req)
+				if !errors.Is(err, ErrDuplicate) || original != "" {
+					t.Errorf("se obtuvo (%q, %v), se esperaba (\"\", %v)", original, err, ErrDuplicate)
+				}
+			},
+		},
+		{
+			name: "repetición de un mensaje completado",
+			run: func(t *testing.T, s *DedupService, req *models.TelemetryRequest) {
+				claim, _, _ := s.Claim(ctx, req)
+				s.Complete(ctx, claim, MessageAccepted)
+				_, original, err := s.Claim(ctx, req)
+				if !errors.Is(err, ErrDuplicate) || original != MessageAccepted {
+					t.Errorf("se obtuvo (%q, %v), se esperaba (%q, %v)", original, err, MessageAccepted, ErrDuplicate)
+				}
+			},
+		},
+		{
+			name: "reserva liberada tras un error",
+			run: func(t *testing.T, s *DedupService, req *models.TelemetryRequest) {
+				claim, _, _ := s.Claim(ctx, req)
+				s.Release(ctx, claim)
+
+				// El dispositivo puede reintentar el mensaje que falló
+				claim, _, err := s.Claim(ctx, req)
+				if err != nil || claim == nil {
+					t.Errorf("reintento tras liberar: reserva %v, error %v", claim, err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewDedupService(newTestCache(t), enabled, newTestLogger(t))
+			req := testReading(nil, "DEVICE001-000123", 23.5)
+
+			claim, _, err := s.Claim(ctx, req)
+			if err != nil || claim == nil {
+				t.Fatalf("primera reserva: reserva %v, error %v", claim, err)
+			}
+			s.Release(ctx, claim)
+
+			tt.run(t, s, req)
+		})
+	}
+}
+
+func TestDedupClaimWithoutKey(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Now()
+
+	tests := []struct {
+		name  string
+		cfg   config.DedupConfig
+		cache database.Cache
+		req   *models.TelemetryRequest
+	}{
+		{name: "deduplicación deshabilitada", cfg: config.DedupConfig{Window: time.Minute}, req: testReading(&ts, "DEVICE001-000123", 23.5)},
+		{name: "sin ID ni marca de tiempo", cfg: config.DedupConfig{Enabled: true, Window: time.Minute}, req: testReading(nil, "", 23.5)},
+		{name: "error del caché", cfg: config.DedupConfig{Enabled: true, Window: time.Minute}, cache: failingCache{}, req: testReading(&ts, "DEVICE001-000123", 23.5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := tt.cache
+			if cache == nil {
+				cache = newTestCache(t)
+			}
+			s := NewDedupService(cache, &tt.cfg, newTestLogger(t))
+
+			// Ante la duda el mensaje se procesa, siempre
+			for i := 0; i < 2; i++ {
+				claim, _, err := s.Claim(ctx, tt.req)
+				if err != nil || claim != nil {
+					t.Fatalf("intento %d: se obtuvo reserva %v y error %v, se esperaba procesar sin reserva", i+1, claim, err)
+				}
+				// Completar o liberar una reserva nil no hace nada
+				s.Complete(ctx, claim, MessageProcessed)
+				s.Release(ctx, claim)
+			}
+		})
+	}
+}
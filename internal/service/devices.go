@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// ErrDeviceExists indica que ya existe un dispositivo con el mismo identificador
+var ErrDeviceExists = errors.New("ya existe un dispositivo con ese identificador")
+
+// errGroupNotFound se retorna cuando el grupo indicado para el dispositivo no existe
+var errGroupNotFound = &ValidationErrors{Errors: []models.ValidationError{
+	{Field: "idGrupo", Message: "El grupo no existe"},
+}}
+
+// defaultTiempoFueraLinea es el tiempo fuera de línea por defecto (igual al esquema)
+const defaultTiempoFueraLinea = "00:00:00"
+
+// DeviceService maneja la administración de dispositivos
+type DeviceService struct {
+	repo   database.Repository
+	cache  database.Cache
+	logger *logger.Logger
+}
+
+// NewDeviceService crea un nuevo servicio de administración de dispositivos
+func NewDeviceService(repo database.Repository, cache database.Cache, log *logger.Logger) *DeviceService {
+	return &DeviceService{
+		repo:   repo,
+		cache:  cache,
+		logger: log,
+	}
+}
+
+// ListDevices obtiene todos los dispositivos registrados
+func (s *DeviceService) ListDevices(ctx context.Context) ([]models.Device, error) {
+	devices, err := s.repo.ListDevices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar dispositivos: %w", err)
+	}
+	return devices, nil
+}
+
+// GetDevice obtiene un dispositivo desde la base de datos
+func (s *DeviceService) GetDevice(ctx context.Context, identifier string) (*models.Device, error) {
+	device, err := s.repo.GetDeviceByIdentifier(ctx, identifier)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener dispositivo: %w", err)
+	}
+	if device == nil {
+		return nil, ErrDeviceNotFound
+	}
+	return device, nil
+}
+
+// CreateDevice registra un nuevo dispositivo
+func (s *DeviceService) CreateDevice(ctx context.Context, input *models.DeviceInput) (*models.Device, error) {
+	if err := validateDeviceInput(input, true); err != nil {
+		return nil, err
+	}
+
+	device := &models.Device{
+		Identificador:    strings.TrimSpace(input.Identificador),
+		Nombre:           strings.TrimSpace(input.Nombre),
+		UltimaConexion:   time.Now(),
+		TiempoFueraLinea: defaultTiempoFueraLinea,
+		Activo:           true,
+	}
+	if input.TiempoFueraLinea != nil {
+		device.TiempoFueraLinea = *input.TiempoFueraLinea
+	}
+	applyDeviceGroup(device, input)
+
+	if err := s.repo.CreateDevice(ctx, device); err != nil {
+		switch {
+		case errors.Is(err, database.ErrDuplicateKey):
+			return nil, ErrDeviceExists
+		case errors.Is(err, database.ErrInvalidReference):
+			return nil, errGroupNotFound
+		}
+		return nil, fmt.Errorf("error al crear dispositivo: %w", err)
+	}
+
+	// Un intento previo con este identificador pudo dejar credenciales o sensores vacíos en caché
+	s.invalidateCache(ctx, device.Identificador)
+
+	s.logger.Info("Dispositivo creado: %s (id %d)", device.Identificador, device.IDTelemetria)
+	return device, nil
+}
+
+// UpdateDevice actualiza un dispositivo; los campos vacíos no se modifican
+func (s *DeviceService) UpdateDevice(ctx context.Context, identifier string, input *models.DeviceInput) (*models.Device, error) {
+	if err := validateDeviceInput(input, false); err != nil {
+		return nil, err
+	}
+
+	device, err := s.GetDevice(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if v := strings.TrimSpace(input.Identificador); v != "" {
+		device.Identificador = v
+	}
+	if v := strings.TrimSpace(input.Nombre); v != "" {
+		device.Nombre = v
+	}
+	if input.TiempoFueraLinea != nil {
+		device.TiempoFueraLinea = *input.TiempoFueraLinea
+	}
+	applyDeviceGroup(device, input)
+
+	if err := s.repo.UpdateDevice(ctx, device); err != nil {
+		switch {
+		case errors.Is(err, database.ErrDuplicateKey):
+			return nil, ErrDeviceExists
+		case errors.Is(err, database.ErrInvalidReference):
+			return nil, errGroupNotFound
+		}
+		return nil, fmt.Errorf("error al actualizar dispositivo: %w", err)
+	}
+
+	s.invalidateCache(ctx, identifier)
+	if device.Identificador != identifier {
+		s.invalidateCache(ctx, device.Identificador)
+	}
+
+	s.logger.Info("Dispositivo actualizado: %s", device.Identificador)
+	return device, nil
+}
+
+// SetDeviceActive habilita o deshabilita un dispositivo
+func (s *DeviceService) SetDeviceActive(ctx context.Context, identifier string, active bool) (*models.Device, error) {
+	device, err := s.GetDevice(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetDeviceActive(ctx, device.IDTelemetria, active); err != nil {
+		return nil, fmt.Errorf("error al actualizar estado del dispositivo: %w", err)
+	}
+	device.Activo = active
+
+	s.invalidateCache(ctx, identifier)
+
+	s.logger.Info("Dispositivo %s: activo=%t", identifier, active)
+	return device, nil
+}
+
+// DeleteDevice elimina un dispositivo
+func (s *DeviceService) DeleteDevice(ctx context.Context, identifier string) error {
+	device, err := s.GetDevice(ctx, identifier)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteDevice(ctx, device.IDTelemetria); err != nil {
+		return fmt.Errorf("error al eliminar dispositivo: %w", err)
+	}
+
+	s.invalidateCache(ctx, identifier)
+
+	s.logger.Info("Dispositivo eliminado: %s", identifier)
+	return nil
+}
+
+// applyDeviceGroup asigna el grupo indicado al dispositivo; idGrupo 0 lo quita del grupo
+func applyDeviceGroup(device *models.Device, input *models.DeviceInput) {
+	if input.IDGrupo == nil {
+		return
+	}
+	if *input.IDGrupo == 0 {
+		device.IDGrupo = nil
+		return
+	}
+	groupID := *input.IDGrupo
+	device.IDGrupo = &groupID
+}
+
+// invalidateCache elimina del caché el dispositivo y sus datos asociados para
+// que los cambios tengan efecto inmediato sin esperar el TTL
+func (s *DeviceService) invalidateCache(ctx context.Context, identifier string) {
+	if err := s.cache.DeleteDevice(ctx, identifier); err != nil {
+		s.logger.Warning("Error al invalidar dispositivo en caché: %v", err)
+	}
+	if err := s.cache.DeleteDeviceCredentials(ctx, identifier); err != nil {
+		s.logger.Warning("Error al invalidar credenciales en caché: %v", err)
+	}
+	if err := s.cache.DeleteDeviceSensors(ctx, identifier); err != nil {
+		s.logger.Warning("Error al invalidar sensores en caché: %v", err)
+	}
+}
+
+// validateDeviceInput valida los datos de un dispositivo; en creación
+// identificador y nombre son requeridos
+func validateDeviceInput(input *models.DeviceInput, create bool) error {
+	var errors []models.ValidationError
+
+	identifier := strings.TrimSpace(input.Identificador)
+	if create && identifier == "" {
+		errors = append(errors, models.ValidationError{
+			Field:   "identificador",
+			Message: "El identificador es requerido",
+		})
+	}
+	if len(identifier) > 255 {
+		errors = append(errors, models.ValidationError{
+			Field:   "identificador",
+			Message: "El identificador no puede superar 255 caracteres",
+		})
+	}
+
+	name := strings.TrimSpace(input.Nombre)
+	if create && name == "" {
+		errors = append(errors, models.ValidationError{
+			Field:   "nombre",
+			Message: "El nombre es requerido",
+		})
+	}
+	if len(name) > 255 {
+		errors = append(errors, models.ValidationError{
+			Field:   "nombre",
+			Message: "El nombre no puede superar 255 caracteres",
+		})
+	}
+
+	if input.TiempoFueraLinea != nil {
+		if _, err := parseTimeDuration(*input.TiempoFueraLinea); err != nil {
+			errors = append(errors, models.ValidationError{
+				Field:   "tiempoFueraLinea",
+				Message: "El tiempo fuera de línea debe tener formato HH:MM:SS",
+			})
+		}
+	}
+
+	if len(errors) > 0 {
+		return &ValidationErrors{Errors: errors}
+	}
+
+	return nil
+}
@@ -0,0 +1,501 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// Errores de geocercas retornados por el servicio
+var (
+	// ErrGeofenceNotFound indica que la geocerca no existe
+	ErrGeofenceNotFound = errors.New("geocerca no encontrada")
+
+	// ErrAssignmentNotFound indica que la asignación de geocerca no existe
+	ErrAssignmentNotFound = errors.New("asignación de geocerca no encontrada")
+
+	// ErrAssignmentExists indica que la geocerca ya está asignada
+	ErrAssignmentExists = errors.New("la geocerca ya está asignada")
+
+	// ErrGroupNotFound indica que el grupo no existe
+	ErrGroupNotFound = errors.New("grupo no encontrado")
+)
+
+// AssignmentInput representa la asignación de una geocerca a un dispositivo o a un grupo
+type AssignmentInput struct {
+	Identificador string `json:"identificador"`
+	IDGrupo       *uint  `json:"idGrupo"`
+}
+
+// geofenceCacheEntry almacena localmente las geocercas de un dispositivo
+type geofenceCacheEntry struct {
+	geofences []models.Geofence
+	expires   time.Time
+}
+
+// GeofenceService administra geocercas y evalúa las entradas, salidas y permanencias
+// de los dispositivos. Implementa MeasurementObserver
+type GeofenceService struct {
+	repo     database.Repository
+	cache    database.Cache
+	config   *config.GeofenceConfig
+	notifier Notifier
+	logger   *logger.Logger
+
+	mu        sync.Mutex
+	geofences map[uint]geofenceCacheEntry // Geocercas por idTelemetria
+}
+
+// NewGeofenceService crea un nuevo servicio de geocercas
+func NewGeofenceService(repo database.Repository, cache database.Cache, cfg *config.GeofenceConfig, notifier Notifier, log *logger.Logger) *GeofenceService {
+	return &GeofenceService{
+		repo:      repo,
+		cache:     cache,
+		config:    cfg,
+		notifier:  notifier,
+		logger:    log,
+		geofences: make(map[uint]geofenceCacheEntry),
+	}
+}
+
+// OnMeasurement evalúa la posición de la medición contra las geocercas del dispositivo,
+// comparándola con la posición anterior en caché
+func (s *GeofenceService) OnMeasurement(ctx context.Context, event *MeasurementEvent) {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, event.Device.Identificador)
+
+	device := event.Device
+	measurement := event.Measurement
+
+	// Las lecturas atrasadas o sin coordenadas no cambian la posición actual
+	if !event.Latest || measurement.Latitud == nil || measurement.Longitud == nil {
+		return
+	}
+
+	geofences := s.deviceGeofences(ctx, device)
+	if len(geofences) == 0 {
+		return
+	}
+
+	states, err := s.cache.GetGeofenceStates(ctx, device.Identificador)
+	if err != nil {
+		log.Warning("Error al obtener estado de geocercas del dispositivo %s: %v", device.Identificador, err)
+		states = map[uint]models.GeofenceState{}
+	}
+
+	lat, lon := *measurement.Latitud, *measurement.Longitud
+	hasPrevious := device.Latitud != nil && device.Longitud != nil
+
+	for i := range geofences {
+		geofence := &geofences[i]
+		state, tracked := states[geofence.IDGeocerca]
+
+		insideNow := ContainsPoint(geofence, lat, lon)
+		insideBefore := tracked
+		if hasPrevious {
+			insideBefore = ContainsPoint(geofence, *device.Latitud, *device.Longitud)
+		}
+
+		switch {
+		case insideNow && !insideBefore:
+			s.recordEvent(ctx, device, geofence, measurement, models.EventGeofenceEnter,
+				fmt.Sprintf("Entrada a geocerca %s", geofence.Nombre))
+			state = models.GeofenceState{EnteredAt: measurement.Fecha}
+			s.saveState(ctx, device, geofence, &state)
+
+		case !insideNow && insideBefore:
+			description := fmt.Sprintf("Salida de geocerca %s", geofence.Nombre)
+			if tracked {
+				description += fmt.Sprintf(" tras %s", measurement.Fecha.Sub(state.EnteredAt).Round(time.Second))
+			}
+			s.recordEvent(ctx, device, geofence, measurement, models.EventGeofenceExit, description)
+			s.deleteState(ctx, device, geofence)
+			continue
+
+		case !insideNow:
+			// Estado obsoleto (ej. la posición en caché expiró)
+			if tracked {
+				s.deleteState(ctx, device, geofence)
+			}
+			continue
+
+		case !tracked:
+			// Dentro sin estado registrado: comenzar a medir la permanencia
+			state = models.GeofenceState{EnteredAt: measurement.Fecha}
+			s.saveState(ctx, device, geofence, &state)
+		}
+
+		// Evento de permanencia, una vez por estadía
+		dwell := time.Duration(geofence.TiempoPermanencia) * time.Second
+		if dwell > 0 && !state.DwellNotified && measurement.Fecha.Sub(state.EnteredAt) >= dwell {
+			s.recordEvent(ctx, device, geofence, measurement, models.EventGeofenceDwell,
+				fmt.Sprintf("Permanencia en geocerca %s superior a %s", geofence.Nombre, dwell))
+			state.DwellNotified = true
+			s.saveState(ctx, device, geofence, &state)
+		}
+	}
+}
+
+// deviceGeofences obtiene las geocercas del dispositivo, con caché local de corta duración
+func (s *GeofenceService) deviceGeofences(ctx context.Context, device *models.Device) []models.Geofence {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, device.Identificador)
+
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.geofences[device.IDTelemetria]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.geofences
+	}
+
+	geofences, err := s.repo.GetDeviceGeofences(ctx, device.IDTelemetria, device.IDGrupo)
+	if err != nil {
+		log.Warning("Error al consultar geocercas del dispositivo %s: %v", device.Identificador, err)
+		return nil
+	}
+
+	s.mu.Lock()
+	s.geofences[device.IDTelemetria] = geofenceCacheEntry{
+		geofences: geofences,
+		expires:   now.Add(s.config.CacheTTL),
+	}
+	s.mu.Unlock()
+
+	return geofences
+}
+
+// recordEvent registra un evento de geocerca
+func (s *GeofenceService) recordEvent(ctx context.Context, device *models.Device, geofence *models.Geofence, measurement *models.Measurement, eventType, description string) {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, device.Identificador)
+
+	log.Info("Dispositivo %s: %s", device.Identificador, description)
+
+	event := &models.Event{
+		IDTelemetria: device.IDTelemetria,
+		IDGeocerca:   &geofence.IDGeocerca,
+		Tipo:         eventType,
+		Fecha:        measurement.Fecha,
+		Latitud:      measurement.Latitud,
+		Longitud:     measurement.Longitud,
+		Descripcion:  description,
+	}
+	if err := s.repo.InsertEvent(ctx, event); err != nil {
+		log.Error("Error al insertar evento de geocerca: %v", err)
+	}
+
+	s.notifier.Notify(ctx, deviceNotification(device, eventType, measurement.Fecha, description, event))
+}
+
+// saveState almacena la permanencia del dispositivo en una geocerca
+func (s *GeofenceService) saveState(ctx context.Context, device *models.Device, geofence *models.Geofence, state *models.GeofenceState) {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, device.Identificador)
+
+	if err := s.cache.SetGeofenceState(ctx, device.Identificador, geofence.IDGeocerca, state); err != nil {
+		log.Warning("Error al almacenar estado de geocerca en caché: %v", err)
+	}
+}
+
+// deleteState elimina la permanencia del dispositivo en una geocerca
+func (s *GeofenceService) deleteState(ctx context.Context, device *models.Device, geofence *models.Geofence) {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, device.Identificador)
+
+	if err := s.cache.DeleteGeofenceState(ctx, device.Identificador, geofence.IDGeocerca); err != nil {
+		log.Warning("Error al eliminar estado de geocerca del caché: %v", err)
+	}
+}
+
+// invalidate descarta las geocercas cacheadas localmente
+func (s *GeofenceService) invalidate() {
+	s.mu.Lock()
+	s.geofences = make(map[uint]geofenceCacheEntry)
+	s.mu.Unlock()
+}
+
+// ListGroups obtiene todos los grupos de dispositivos
+func (s *GeofenceService) ListGroups(ctx context.Context) ([]models.DeviceGroup, error) {
+	groups, err := s.repo.ListGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar grupos: %w", err)
+	}
+	return groups, nil
+}
+
+// CreateGroup registra un nuevo grupo de dispositivos
+func (s *GeofenceService) CreateGroup(ctx context.Context, group *models.DeviceGroup) error {
+	group.Nombre = strings.TrimSpace(group.Nombre)
+	if group.Nombre == "" || len(group.Nombre) > 255 {
+		return &ValidationErrors{Errors: []models.ValidationError{
+			{Field: "nombre", Message: "El nombre es requerido y no puede superar 255 caracteres"},
+		}}
+	}
+
+	if err := s.repo.CreateGroup(ctx, group); err != nil {
+		return fmt.Errorf("error al crear grupo: %w", err)
+	}
+
+	s.logger.Info("Grupo creado: %s (id %d)", group.Nombre, group.IDGrupo)
+	return nil
+}
+
+// DeleteGroup elimina un grupo; sus dispositivos quedan sin grupo
+func (s *GeofenceService) DeleteGroup(ctx context.Context, groupID uint) error {
+	found, err := s.repo.DeleteGroup(ctx, groupID)
+	if err != nil {
+		return fmt.Errorf("error al eliminar grupo: %w", err)
+	}
+	if !found {
+		return ErrGroupNotFound
+	}
+
+	s.invalidate()
+	s.logger.Info("Grupo eliminado: %d", groupID)
+	return nil
+}
+
+// ListGeofences obtiene todas las geocercas
+func (s *GeofenceService) ListGeofences(ctx context.Context) ([]models.Geofence, error) {
+	geofences, err := s.repo.ListGeofences(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar geocercas: %w", err)
+	}
+	return geofences, nil
+}
+
+// GetGeofence obtiene una geocerca por ID
+func (s *GeofenceService) GetGeofence(ctx context.Context, geofenceID uint) (*models.Geofence, error) {
+	geofence, err := s.repo.GetGeofence(ctx, geofenceID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener geocerca: %w", err)
+	}
+	if geofence == nil {
+		return nil, ErrGeofenceNotFound
+	}
+	return geofence, nil
+}
+
+// CreateGeofence registra una nueva geocerca
+func (s *GeofenceService) CreateGeofence(ctx context.Context, geofence *models.Geofence) error {
+	if err := validateGeofence(geofence); err != nil {
+		return err
+	}
+
+	if err := s.repo.CreateGeofence(ctx, geofence); err != nil {
+		return fmt.Errorf("error al crear geocerca: %w", err)
+	}
+
+	s.invalidate()
+	s.logger.Info("Geocerca creada: %s (id %d)", geofence.Nombre, geofence.IDGeocerca)
+	return nil
+}
+
+// UpdateGeofence actualiza una geocerca
+func (s *GeofenceService) UpdateGeofence(ctx context.Context, geofence *models.Geofence) error {
+	if err := validateGeofence(geofence); err != nil {
+		return err
+	}
+
+	found, err := s.repo.UpdateGeofence(ctx, geofence)
+	if err != nil {
+		return fmt.Errorf("error al actualizar geocerca: %w", err)
+	}
+	if !found {
+		return ErrGeofenceNotFound
+	}
+
+	s.invalidate()
+	s.logger.Info("Geocerca actualizada: %s (id %d)", geofence.Nombre, geofence.IDGeocerca)
+	return nil
+}
+
+// DeleteGeofence elimina una geocerca
+func (s *GeofenceService) DeleteGeofence(ctx context.Context, geofenceID uint) error {
+	found, err := s.repo.DeleteGeofence(ctx, geofenceID)
+	if err != nil {
+		return fmt.Errorf("error al eliminar geocerca: %w", err)
+	}
+	if !found {
+		return ErrGeofenceNotFound
+	}
+
+	s.invalidate()
+	s.logger.Info("Geocerca eliminada: %d", geofenceID)
+	return nil
+}
+
+// AssignGeofence asigna una geocerca a un dispositivo o a un grupo
+func (s *GeofenceService) AssignGeofence(ctx context.Context, geofenceID uint, input *AssignmentInput) error {
+	assignment, err := s.resolveAssignment(ctx, geofenceID, input)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.AssignGeofence(ctx, assignment); err != nil {
+		switch {
+		case errors.Is(err, database.ErrDuplicateKey):
+			return ErrAssignmentExists
+		case errors.Is(err, database.ErrInvalidReference):
+			return &ValidationErrors{Errors: []models.ValidationError{
+				{Field: "idGrupo", Message: "La geocerca o el grupo no existe"},
+			}}
+		}
+		return fmt.Errorf("error al asignar geocerca: %w", err)
+	}
+
+	s.invalidate()
+	return nil
+}
+
+// UnassignGeofence quita la asignación de una geocerca a un dispositivo o a un grupo
+func (s *GeofenceService) UnassignGeofence(ctx context.Context, geofenceID uint, input *AssignmentInput) error {
+	assignment, err := s.resolveAssignment(ctx, geofenceID, input)
+	if err != nil {
+		return err
+	}
+
+	found, err := s.repo.UnassignGeofence(ctx, assignment)
+	if err != nil {
+		return fmt.Errorf("error al quitar asignación de geocerca: %w", err)
+	}
+	if !found {
+		return ErrAssignmentNotFound
+	}
+
+	s.invalidate()
+	return nil
+}
+
+// resolveAssignment valida la asignación y obtiene el ID del dispositivo indicado
+func (s *GeofenceService) resolveAssignment(ctx context.Context, geofenceID uint, input *AssignmentInput) (*models.GeofenceAssignment, error) {
+	identifier := strings.TrimSpace(input.Identificador)
+	if (identifier == "") == (input.IDGrupo == nil) {
+		return nil, &ValidationErrors{Errors: []models.ValidationError{
+			{Field: "identificador", Message: "Se debe indicar identificador o idGrupo (solo uno)"},
+		}}
+	}
+
+	assignment := &models.GeofenceAssignment{
+		IDGeocerca: geofenceID,
+		IDGrupo:    input.IDGrupo,
+	}
+
+	if identifier != "" {
+		device, err := s.repo.GetDeviceByIdentifier(ctx, identifier)
+		if err != nil {
+			return nil, fmt.Errorf("error al obtener dispositivo: %w", err)
+		}
+		if device == nil {
+			return nil, ErrDeviceNotFound
+		}
+		assignment.IDTelemetria = &device.IDTelemetria
+	}
+
+	return assignment, nil
+}
+
+// validateGeofence valida la forma de una geocerca
+func validateGeofence(geofence *models.Geofence) error {
+	var errors []models.ValidationError
+
+	geofence.Nombre = strings.TrimSpace(geofence.Nombre)
+	if geofence.Nombre == "" {
+		errors = append(errors, models.ValidationError{
+			Field:   "nombre",
+			Message: "El nombre es requerido",
+		})
+	}
+
+	switch geofence.Tipo {
+	case models.GeofenceTypeCircle:
+		if geofence.Latitud == nil || geofence.Longitud == nil || !validCoordinate(*geofence.Latitud, *geofence.Longitud) {
+			errors = append(errors, models.ValidationError{
+				Field:   "latitud",
+				Message: "Una geocerca circular requiere un centro válido (latitud y longitud)",
+			})
+		}
+		if geofence.Radio == nil || *geofence.Radio <= 0 {
+			errors = append(errors, models.ValidationError{
+				Field:   "radio",
+				Message: "Una geocerca circular requiere un radio mayor a cero (metros)",
+			})
+		}
+		geofence.Vertices = nil
+
+	case models.GeofenceTypePolygon:
+		if len(geofence.Vertices) < 3 {
+			errors = append(errors, models.ValidationError{
+				Field:   "vertices",
+				Message: "Una geocerca poligonal requiere al menos 3 vértices",
+			})
+		}
+		for _, vertex := range geofence.Vertices {
+			if !validCoordinate(vertex[0], vertex[1]) {
+				errors = append(errors, models.ValidationError{
+					Field:   "vertices",
+					Message: "Los vértices deben ser pares [latitud, longitud] válidos",
+				})
+				break
+			}
+		}
+		geofence.Latitud, geofence.Longitud, geofence.Radio = nil, nil, nil
+
+	default:
+		errors = append(errors, models.ValidationError{
+			Field:   "tipo",
+			Message: "El tipo debe ser circle o polygon",
+		})
+	}
+
+	if geofence.TiempoPermanencia < 0 {
+		errors = append(errors, models.ValidationError{
+			Field:   "tiempoPermanencia",
+			Message: "El tiempo de permanencia no puede ser negativo",
+		})
+	}
+
+	if len(errors) > 0 {
+		return &ValidationErrors{Errors: errors}
+	}
+
+	return nil
+}
+
+// validCoordinate verifica que latitud y longitud estén en rango
+func validCoordinate(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+// ContainsPoint indica si una coordenada está dentro de la geocerca.
+// Los círculos usan la distancia de Haversine; los polígonos, el algoritmo de
+// ray casting en el plano latitud/longitud (adecuado para áreas pequeñas)
+func ContainsPoint(geofence *models.Geofence, lat, lon float64) bool {
+	switch geofence.Tipo {
+	case models.GeofenceTypeCircle:
+		if geofence.Latitud == nil || geofence.Longitud == nil || geofence.Radio == nil {
+			return false
+		}
+		return CalculateDistance(*geofence.Latitud, *geofence.Longitud, lat, lon) <= *geofence.Radio
+
+	case models.GeofenceTypePolygon:
+		vertices := geofence.Vertices
+		inside := false
+		for i, j := 0, len(vertices)-1; i < len(vertices); j, i = i, i+1 {
+			latI, lonI := vertices[i][0], vertices[i][1]
+			latJ, lonJ := vertices[j][0], vertices[j][1]
+			if (latI > lat) != (latJ > lat) &&
+				lon < (lonJ-lonI)*(lat-latI)/(latJ-latI)+lonI {
+				inside = !inside
+			}
+		}
+		return inside
+	}
+
+	return false
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// geofenceRepository retorna geocercas fijas y registra los eventos insertados
+type geofenceRepository struct {
+	database.Repository
+
+	geofences []models.Geofence
+
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *geofenceRepository) GetDeviceGeofences(ctx context.Context, deviceID uint, groupID *uint) ([]models.Geofence, error) {
+	return r.geofences, nil
+}
+
+func (r *geofenceRepository) InsertEvent(ctx context.Context, event *models.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event.Tipo)
+	return nil
+}
+
+// testPolygon crea una geocerca poligonal con vértices [latitud, longitud]
+func testPolygon(vertices ...[2]float64) *models.Geofence {
+	return &models.Geofence{IDGeocerca: 1, Nombre: "Polígono", Tipo: models.GeofenceTypePolygon, Vertices: vertices, Activo: true}
+}
+
+// testCircle crea una geocerca circular con radio en metros
+func testCircle(lat, lon, radius float64) *models.Geofence {
+	return &models.Geofence{IDGeocerca: 1, Nombre: "Círculo", Tipo: models.GeofenceTypeCircle, Latitud: &lat, Longitud: &lon, Radio: &radius, Activo: true}
+}
+
+func TestContainsPointPolygon(t *testing.T) {
+	square := testPolygon([2]float64{0, 0}, [2]float64{0, 1}, [2]float64{1, 1}, [2]float64{1, 0})
+	// Rombo con vértices a la misma latitud que los puntos de prueba
+	diamond := testPolygon([2]float64{0, 1}, [2]float64{1, 2}, [2]float64{2, 1}, [2]float64{1, 0})
+	// Polígono cóncavo en forma de U, abierto hacia el norte entre las longitudes 1 y 2
+	concave := testPolygon([2]float64{0, 0}, [2]float64{0, 3}, [2]float64{3, 3}, [2]float64{3, 2},
+		[2]float64{1, 2}, [2]float64{1, 1}, [2]float64{3, 1}, [2]float64{3, 0})
+
+	tests := []struct {
+		name     string
+		geofence *models.Geofence
+		lat, lon float64
+		want     bool
+	}{
+		{name: "centro del cuadrado", geofence: square, lat: 0.5, lon: 0.5, want: true},
+		{name: "al norte del cuadrado", geofence: square, lat: 1.5, lon: 0.5, want: false},
+		{name: "al oeste del cuadrado", geofence: square, lat: 0.5, lon: -0.5, want: false},
+		{name: "al este del cuadrado", geofence: square, lat: 0.5, lon: 1.5, want: false},
+		// En los bordes la regla es semiabierta: los bordes sur y oeste
+		// pertenecen al polígono y los bordes norte y este no
+		{name: "borde sur", geofence: square, lat: 0, lon: 0.5, want: true},
+		{name: "borde oeste", geofence: square, lat: 0.5, lon: 0, want: true},
+		{name: "borde norte", geofence: square, lat: 1, lon: 0.5, want: false},
+		{name: "borde este", geofence: square, lat: 0.5, lon: 1, want: false},
+		{name: "vértice suroeste", geofence: square, lat: 0, lon: 0, want: true},
+		{name: "vértice noreste", geofence: square, lat: 1, lon: 1, want: false},
+		// El rayo que pasa por un vértice no cuenta dos cruces
+		{name: "rayo por dos vértices desde dentro", geofence: diamond, lat: 1, lon: 0.5, want: true},
+		{name: "rayo por dos vértices desde fuera", geofence: diamond, lat: 1, lon: -0.5, want: false},
+		{name: "más allá del último vértice", geofence: diamond, lat: 1, lon: 2.5, want: false},
+		{name: "vértice sur del rombo", geofence: diamond, lat: 0, lon: 0.5, want: false},
+		{name: "brazo del cóncavo", geofence: concave, lat: 2, lon: 0.5, want: true},
+		{name: "hueco del cóncavo", geofence: concave, lat: 2, lon: 1.5, want: false},
+		{name: "base del cóncavo", geofence: concave, lat: 0.5, lon: 1.5, want: true},
+		{name: "polígono sin vértices", geofence: testPolygon(), lat: 0, lon: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContainsPoint(tt.geofence, tt.lat, tt.lon); got != tt.want {
+				t.Errorf("se obtuvo %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainsPointSharedEdge(t *testing.T) {
+	west := testPolygon([2]float64{0, 0}, [2]float64{0, 1}, [2]float64{1, 1}, [2]float64{1, 0})
+	east := testPolygon([2]float64{0, 1}, [2]float64{0, 2}, [2]float64{1, 2}, [2]float64{1, 1})
+
+	// Un punto del borde compartido pertenece a una sola de las dos geocercas
+	for _, lat := range []float64{0, 0.25, 0.5, 0.75} {
+		inWest, inEast := ContainsPoint(west, lat, 1), ContainsPoint(east, lat, 1)
+		if inWest == inEast {
+			t.Errorf("latitud %v: oeste %v, este %v, se esperaba exactamente una", lat, inWest, inEast)
+		}
+	}
+}
+
+func TestContainsPointCircle(t *testing.T) {
+	// Radio de 1 km en Santiago; 0,01° de latitud son unos 1.112 m y 0,01° de
+	// longitud, a esta latitud, unos 928 m
+	santiago := testCircle(-33.45, -70.66, 1000)
+	// Círculo sobre el antimeridiano
+	antimeridian := testCircle(0, 179.999, 1000)
+	withoutRadius := testCircle(0, 0, 1000)
+	withoutRadius.Radio = nil
+
+	tests := []struct {
+		name     string
+		geofence *models.Geofence
+		lat, lon float64
+		want     bool
+	}{
+		{name: "centro", geofence: santiago, lat: -33.45, lon: -70.66, want: true},
+		{name: "890 m al norte", geofence: santiago, lat: -33.442, lon: -70.66, want: true},
+		{name: "1.112 m al norte", geofence: santiago, lat: -33.44, lon: -70.66, want: false},
+		{name: "928 m al este", geofence: santiago, lat: -33.45, lon: -70.65, want: true},
+		{name: "1.113 m al este", geofence: santiago, lat: -33.45, lon: -70.648, want: false},
+		{name: "al otro lado del antimeridiano", geofence: antimeridian, lat: 0, lon: -179.999, want: true},
+		{name: "lejos del antimeridiano", geofence: antimeridian, lat: 0, lon: 179.9, want: false},
+		{name: "sin radio", geofence: withoutRadius, lat: 0, lon: 0, want: false},
+		{name: "tipo desconocido", geofence: &models.Geofence{Tipo: "rectangle"}, lat: 0, lon: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContainsPoint(tt.geofence, tt.lat, tt.lon); got != tt.want {
+				t.Errorf("se obtuvo %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeofenceOnMeasurement(t *testing.T) {
+	// Cuadrado de unos 2 km de lado en Santiago
+	square := testPolygon([2]float64{-33.46, -70.67}, [2]float64{-33.46, -70.65}, [2]float64{-33.44, -70.65}, [2]float64{-33.44, -70.67})
+	dwell := *square
+	dwell.TiempoPermanencia = 60
+	circle := testCircle(-33.45, -70.66, 500)
+
+	inside := [2]float64{-33.45, -70.66}
+	outside := [2]float64{-33.43, -70.66}
+
+	// step es una medición del recorrido, at segundos después del inicio
+	type step struct {
+		pos  [2]float64
+		at   int
+		late bool // Lectura atrasada
+	}
+
+	tests := []struct {
+		name           string
+		geofence       *models.Geofence
+		steps          []step
+		forgetPosition bool // Sin posición previa en caché (p. ej. expiró)
+		want           []string
+	}{
+		{
+			name:     "entra y sale",
+			geofence: square,
+			steps:    []step{{pos: outside}, {pos: inside, at: 10}, {pos: inside, at: 20}, {pos: outside, at: 30}, {pos: outside, at: 40}},
+			want:     []string{models.EventGeofenceEnter, models.EventGeofenceExit},
+		},
+		{
+			name:     "entra dos veces",
+			geofence: square,
+			steps:    []step{{pos: outside}, {pos: inside, at: 10}, {pos: outside, at: 20}, {pos: inside, at: 30}},
+			want:     []string{models.EventGeofenceEnter, models.EventGeofenceExit, models.EventGeofenceEnter},
+		},
+		{
+			name:     "primera medición dentro",
+			geofence: square,
+			steps:    []step{{pos: inside}, {pos: inside, at: 10}},
+			want:     []string{models.EventGeofenceEnter},
+		},
+		{
+			name:     "siempre fuera",
+			geofence: square,
+			steps:    []step{{pos: outside}, {pos: outside, at: 10}},
+			want:     nil,
+		},
+		{
+			name:     "lectura atrasada no cambia la posición",
+			geofence: square,
+			steps:    []step{{pos: outside, at: 10}, {pos: inside, late: true}, {pos: outside, at: 20}},
+			want:     nil,
+		},
+		{
+			name:           "sin posición previa usa el estado guardado",
+			geofence:       square,
+			forgetPosition: true,
+			steps:          []step{{pos: inside}, {pos: inside, at: 10}, {pos: outside, at: 20}, {pos: outside, at: 30}},
+			want:           []string{models.EventGeofenceEnter, models.EventGeofenceExit},
+		},
+		{
+			name:     "permanencia una vez por estadía",
+			geofence: &dwell,
+			steps:    []step{{pos: outside}, {pos: inside, at: 10}, {pos: inside, at: 40}, {pos: inside, at: 70}, {pos: inside, at: 200}, {pos: outside, at: 210}},
+			want:     []string{models.EventGeofenceEnter, models.EventGeofenceDwell, models.EventGeofenceExit},
+		},
+		{
+			name:     "círculo",
+			geofence: circle,
+			steps:    []step{{pos: outside}, {pos: inside, at: 10}, {pos: outside, at: 20}},
+			want:     []string{models.EventGeofenceEnter, models.EventGeofenceExit},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := &geofenceRepository{geofences: []models.Geofence{*tt.geofence}}
+			s := NewGeofenceService(repo, newTestCache(t), &config.GeofenceConfig{CacheTTL: time.Minute}, nopNotifier{}, newTestLogger(t))
+
+			start := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+			var previous *[2]float64
+			for _, st := range tt.steps {
+				device := &models.Device{IDTelemetria: 1, Identificador: "DEVICE001", Activo: true}
+				if previous != nil && !tt.forgetPosition {
+					device.Latitud, device.Longitud = &previous[0], &previous[1]
+				}
+
+				pos := st.pos
+				s.OnMeasurement(ctx, &MeasurementEvent{
+					Device:      device,
+					Measurement: &models.Measurement{IDTelemetria: 1, Fecha: start.Add(time.Duration(st.at) * time.Second), Latitud: &pos[0], Longitud: &pos[1]},
+					Latest:      !st.late,
+				})
+				if !st.late {
+					previous = &pos
+				}
+			}
+
+			if !reflect.DeepEqual(repo.events, tt.want) {
+				t.Errorf("se obtuvo %v, se esperaba %v", repo.events, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+)
+
+// ErrDegraded indica que un componente responde pero opera con un respaldo
+// (por ejemplo, el caché en memoria mientras Redis no está disponible)
+var ErrDegraded = stderrors.New("operando en modo degradado")
+
+// Estados de un componente y del servicio en /health/ready
+const (
+	ComponentUp       = "up"
+	ComponentDegraded = "degraded"
+	ComponentDown     = "down"
+
+	ReadinessOK          = "ok"
+	ReadinessDegraded    = "degraded"
+	ReadinessUnavailable = "unavailable"
+)
+
+// HealthCheck verifica un componente; retorna nil si está disponible
+type HealthCheck func(ctx context.Context) error
+
+// ComponentStatus es el resultado de la verificación de un componente
+type ComponentStatus struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Readiness es el resultado de verificar todos los componentes
+type Readiness struct {
+	Status     string                     `json:"status"`
+	Ready      bool                       `json:"ready"`
+	Timestamp  string                     `json:"timestamp"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// HealthService verifica las dependencias del servicio (base de datos, caché,
+// broker MQTT) para la sonda de disponibilidad
+type HealthService struct {
+	config *config.HealthConfig
+	logger *logger.Logger
+
+	mu     sync.RWMutex
+	checks map[string]HealthCheck
+}
+
+// NewHealthService crea un nuevo servicio de verificación de salud
+func NewHealthService(cfg *config.HealthConfig, log *logger.Logger) *HealthService {
+	return &HealthService{
+		config: cfg,
+		logger: log,
+		checks: make(map[string]HealthCheck),
+	}
+}
+
+// AddCheck registra la verificación de un componente
+func (s *HealthService) AddCheck(name string, check HealthCheck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks[name] = check
+}
+
+// Readiness verifica en paralelo todos los componentes, cada uno con el tiempo
+// máximo configurado. El servicio no está listo si algún componente crítico está
+// caído; los componentes degradados o no críticos caídos solo lo marcan como degradado
+func (s *HealthService) Readiness(ctx context.Context) *Readiness {
+	s.mu.RLock()
+	checks := make(map[string]HealthCheck, len(s.checks))
+	for name, check := range s.checks {
+		checks[name] = check
+	}
+	s.mu.RUnlock()
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	components := make(map[string]ComponentStatus, len(checks))
+
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check HealthCheck) {
+			defer wg.Done()
+
+			status := s.runCheck(ctx, check)
+			status.Critical = slices.Contains(s.config.Critical, name)
+
+			mu.Lock()
+			components[name] = status
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	readiness := &Readiness{
+		Status:     ReadinessOK,
+		Ready:      true,
+		Timestamp:  time.Now().Format(time.RFC3339),
+		Components: components,
+	}
+	for name, component := range components {
+		if component.Status == ComponentUp {
+			continue
+		}
+		if component.Status == ComponentDown && component.Critical {
+			readiness.Status = ReadinessUnavailable
+			readiness.Ready = false
+			s.logger.Warning("Componente crítico %s no disponible: %s", name, component.Error)
+		} else if readiness.Ready {
+			readiness.Status = ReadinessDegraded
+		}
+	}
+
+	return readiness
+}
+
+// runCheck ejecuta una verificación con tiempo máximo y mide su latencia. Si la
+// verificación no respeta el contexto, se informa como caída al agotarse el tiempo
+func (s *HealthService) runCheck(ctx context.Context, check HealthCheck) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		result <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = fmt.Errorf("tiempo de verificación agotado: %w", ctx.Err())
+	}
+
+	status := ComponentStatus{
+		Status:    ComponentUp,
+		LatencyMs: milliseconds(time.Since(start)),
+	}
+
+	switch {
+	case err == nil:
+	case stderrors.Is(err, ErrDegraded):
+		status.Status = ComponentDegraded
+		status.Error = err.Error()
+	default:
+		status.Status = ComponentDown
+		status.Error = err.Error()
+	}
+	return status
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database/memory"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// newTestLogger crea un logger que escribe en un directorio temporal de la prueba
+func newTestLogger(t *testing.T) *logger.Logger {
+	t.Helper()
+
+	dir := t.TempDir()
+	log, err := logger.New(&config.LoggingConfig{
+		LogDir:            dir,
+		AppLogFile:        "app.log",
+		InvalidLogFile:    "invalid_requests.log",
+		DeviceLogDir:      filepath.Join(dir, "devices"),
+		Level:             config.LogLevelDebug,
+		Format:            config.LogFormatJSON,
+		MaxSizeMB:         10,
+		DeviceMaxOpen:     4,
+		DeviceIdleTimeout: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("error al crear logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+	return log
+}
+
+// newTestCache crea un caché en memoria que se cierra al terminar la prueba
+func newTestCache(t *testing.T) *memory.Cache {
+	t.Helper()
+
+	cache := memory.NewCache(time.Minute, 100)
+	t.Cleanup(func() { cache.Close() })
+	return cache
+}
+
+// fakeRepository implementa database.Repository para pruebas. Solo redefine los
+// métodos que usan los servicios probados; los demás provocan un panic
+type fakeRepository struct {
+	database.Repository
+
+	mu          sync.Mutex
+	credentials map[string][]models.DeviceCredential
+	errors      []*models.ErrorRecord
+}
+
+func (r *fakeRepository) GetDeviceCredentials(ctx context.Context, identifier string) ([]models.DeviceCredential, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.credentials[identifier], nil
+}
+
+// GetDeviceByIdentifier no encuentra dispositivos: los de las pruebas se cargan en el caché
+func (r *fakeRepository) GetDeviceByIdentifier(ctx context.Context, identifier string) (*models.Device, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) InsertError(ctx context.Context, errorRecord *models.ErrorRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, errorRecord)
+	return nil
+}
+
+// errorCount retorna la cantidad de registros de error insertados
+func (r *fakeRepository) errorCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.errors)
+}
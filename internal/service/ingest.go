@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/metrics"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ingestJobTimeout es el tiempo máximo de procesamiento de cada medición encolada
+const ingestJobTimeout = 30 * time.Second
+
+// Errores de la cola de ingesta
+var (
+	// ErrQueueFull indica que la cola de ingesta está llena
+	ErrQueueFull = stderrors.New("cola de ingesta llena")
+
+	// ErrQueueClosed indica que la cola de ingesta se está vaciando por apagado
+	ErrQueueClosed = stderrors.New("cola de ingesta cerrada")
+)
+
+// ingestJob es una medición encolada con su fecha de recepción, su origen y el
+// ID de solicitud y contexto de traza de la solicitud que la recibió
+type ingestJob struct {
+	req         *models.TelemetryRequest
+	receivedAt  time.Time
+	source      string
+	requestID   string
+	spanContext trace.SpanContext
+	claim       *MessageClaim // Reserva de deduplicación que se completa según el resultado
+	result      chan error    // Si no es nil, recibe el resultado del procesamiento
+}
+
+// newIngestJob crea el trabajo de una medición recibida ahora. Del contexto solo
+// se conservan el ID de solicitud y la traza, ya que la solicitud suele terminar
+// antes de procesarse
+func newIngestJob(ctx context.Context, req *models.TelemetryRequest, source string) *ingestJob {
+	return &ingestJob{
+		req:         req,
+		receivedAt:  time.Now(),
+		source:      source,
+		requestID:   logger.RequestIDFromContext(ctx),
+		spanContext: trace.SpanContextFromContext(ctx),
+	}
+}
+
+// IngestStats contiene el estado de la cola de ingesta
+type IngestStats struct {
+	Encolados int `json:"encolados"`
+	Capacidad int `json:"capacidad"`
+	Workers   int `json:"workers"`
+}
+
+// IngestPipeline desacopla la recepción (HTTP, MQTT) del procesamiento de
+// mediciones mediante una cola acotada atendida por un grupo de workers. Cada
+// worker tiene su propia cola y las mediciones de un dispositivo siempre van al
+// mismo worker, por lo que se procesan en orden de llegada y nunca en paralelo
+type IngestPipeline struct {
+	telemetry *TelemetryService
+	dedup     *DedupService
+	config    *config.IngestConfig
+	logger    *logger.Logger
+
+	queues []chan *ingestJob
+
+	mu     sync.RWMutex
+	closed bool
+
+	closing chan struct{}  // Se cierra al iniciar el apagado, antes que las colas
+	waiting sync.WaitGroup // EnqueueWait en espera de espacio en la cola
+
+	abort chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewIngestPipeline crea una nueva cola de ingesta
+func NewIngestPipeline(telemetry *TelemetryService, dedup *DedupService, cfg *config.IngestConfig, log *logger.Logger) *IngestPipeline {
+	// La capacidad total se reparte entre las colas de los workers
+	capacity := (cfg.QueueSize + cfg.Workers - 1) / cfg.Workers
+
+	queues := make([]chan *ingestJob, cfg.Workers)
+	for i := range queues {
+		queues[i] = make(chan *ingestJob, capacity)
+	}
+
+	return &IngestPipeline{
+		telemetry: telemetry,
+		dedup:     dedup,
+		config:    cfg,
+		logger:    log,
+		queues:    queues,
+		closing:   make(chan struct{}),
+		abort:     make(chan struct{}),
+	}
+}
+
+// Start inicia los workers de la cola
+func (p *IngestPipeline) Start() {
+	for _, queue := range p.queues {
+		p.wg.Add(1)
+		go p.worker(queue)
+	}
+
+	p.logger.Info("Cola de ingesta iniciada (workers: %d, capacidad: %d)", p.config.Workers, p.config.QueueSize)
+}
+
+// Enqueue encola una medición sin esperar. ctx solo aporta el ID de solicitud y la traza.
+// La reserva de deduplicación, si la hay, se completa o libera al procesarse la
+// medición. Retorna ErrQueueFull si la cola está llena y ErrQueueClosed si se
+// está apagando; en ese caso la reserva queda a cargo de quien llama
+func (p *IngestPipeline) Enqueue(ctx context.Context, req *models.TelemetryRequest, source string, claim *MessageClaim) error {
+	job := newIngestJob(ctx, req, source)
+	job.claim = claim
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrQueueClosed
+	}
+
+	select {
+	case p.queueFor(req.Identificador) <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// EnqueueResult encola una medición sin esperar, igual que Enqueue, y retorna un
+// canal que recibe el resultado de su procesamiento: nil, ErrSpooled si quedó en
+// el spool o el error del servicio de telemetría. Toda medición encolada recibe
+// un resultado, incluso si el apagado la descarta
+func (p *IngestPipeline) EnqueueResult(ctx context.Context, req *models.TelemetryRequest, source string) (<-chan error, error) {
+	job := newIngestJob(ctx, req, source)
+	job.result = make(chan error, 1)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return nil, ErrQueueClosed
+	}
+
+	select {
+	case p.queueFor(req.Identificador) <- job:
+		return job.result, nil
+	default:
+		return nil, ErrQueueFull
+	}
+}
+
+// EnqueueWait encola una medición esperando espacio en la cola hasta que se
+// cancele el contexto, lo que aplica contrapresión a quien la envía (MQTT). La
+// reserva de deduplicación se trata igual que en Enqueue
+func (p *IngestPipeline) EnqueueWait(ctx context.Context, req *models.TelemetryRequest, source string, claim *MessageClaim) error {
+	job := newIngestJob(ctx, req, source)
+	job.claim = claim
+
+	// La espera no retiene el lock, ya que bloquearía a Stop; Stop espera a los
+	// EnqueueWait en curso antes de cerrar las colas
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return ErrQueueClosed
+	}
+	p.waiting.Add(1)
+	p.mu.RUnlock()
+	defer p.waiting.Done()
+
+	select {
+	case p.queueFor(req.Identificador) <- job:
+		return nil
+	case <-p.closing:
+		return ErrQueueClosed
+	case <-ctx.Done():
+		return ErrQueueFull
+	}
+}
+
+// Stats retorna la ocupación de la cola
+func (p *IngestPipeline) Stats() IngestStats {
+	stats := IngestStats{Workers: len(p.queues)}
+	for _, queue := range p.queues {
+		stats.Encolados += len(queue)
+		stats.Capacidad += cap(queue)
+	}
+	return stats
+}
+
+// Stop cierra la cola y espera a que los workers procesen las mediciones
+// encoladas durante a lo más DrainTimeout. Las que no alcanzan a procesarse se
+// almacenan en el spool si está habilitado; en otro caso se pierden
+func (p *IngestPipeline) Stop() {
+	// Esperar a que terminen los Enqueue en curso y despertar a los EnqueueWait
+	// en espera antes de cerrar los canales
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.closing)
+	p.mu.Unlock()
+
+	p.waiting.Wait()
+	for _, queue := range p.queues {
+		close(queue)
+	}
+
+	p.logger.Info("Vaciando cola de ingesta (%d mediciones pendientes)...", p.Stats().Encolados)
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(p.config.DrainTimeout):
+		p.logger.Warning("Tiempo de vaciado de la cola de ingesta agotado, %d mediciones sin procesar", p.Stats().Encolados)
+		close(p.abort)
+		<-done
+	}
+
+	p.logger.Info("Cola de ingesta detenida")
+}
+
+// worker procesa mediciones de su cola hasta que se cierre
+func (p *IngestPipeline) worker(queue chan *ingestJob) {
+	defer p.wg.Done()
+
+	for job := range queue {
+		select {
+		case <-p.abort:
+			p.discard(job)
+		default:
+			p.process(job)
+		}
+	}
+}
+
+// queueFor retorna la cola del worker asignado al dispositivo
+func (p *IngestPipeline) queueFor(identifier string) chan *ingestJob {
+	h := fnv.New32a()
+	h.Write([]byte(identifier))
+	return p.queues[h.Sum32()%uint32(len(p.queues))]
+}
+
+// process procesa una medición encolada, en un span hijo de la solicitud que la
+// recibió que incluye el tiempo de espera en la cola
+func (p *IngestPipeline) process(job *ingestJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), ingestJobTimeout)
+	defer cancel()
+
+	ctx = logger.ContextWithRequestID(trace.ContextWithSpanContext(ctx, job.spanContext), job.requestID)
+	ctx, span := tracing.Start(ctx, "IngestPipeline.process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithTimestamp(job.receivedAt),
+		trace.WithAttributes(
+			attribute.String("telemetria.identificador", job.req.Identificador),
+			attribute.String("telemetria.origen", job.source),
+		),
+	)
+
+	err := p.telemetry.ProcessTelemetryDataAt(ctx, job.req, job.receivedAt)
+	tracing.End(span, err)
+	p.settle(ctx, job, err)
+	job.reply(err)
+	metrics.RecordIngest(strings.ToLower(job.source), IngestOutcome(err))
+
+	log := p.logger.WithContext(ctx).With(logger.KeyDevice, job.req.Identificador, logger.KeyTransport, strings.ToLower(job.source))
+	switch {
+	case err == nil:
+	case stderrors.Is(err, ErrSpooled):
+		log.Warning("Datos de telemetría %s de %s almacenados en el spool", job.source, job.req.Identificador)
+	default:
+		log.Error("Error al procesar datos de telemetría %s de %s: %v", job.source, job.req.Identificador, err)
+	}
+}
+
+// discard almacena en el spool una medición que no alcanzó a procesarse al apagar
+func (p *IngestPipeline) discard(job *ingestJob) {
+	if p.telemetry.spool == nil {
+		p.logger.Error("Medición de %s descartada al apagar: cola de ingesta no vaciada a tiempo", job.req.Identificador)
+		p.settle(context.Background(), job, ErrQueueClosed)
+		job.reply(ErrQueueClosed)
+		return
+	}
+
+	if err := p.telemetry.spoolRequest(job.req, job.receivedAt); err != nil {
+		p.logger.Error("Error al almacenar en el spool medición de %s al apagar: %v", job.req.Identificador, err)
+		p.settle(context.Background(), job, err)
+		job.reply(err)
+		return
+	}
+	p.settle(context.Background(), job, ErrSpooled)
+	job.reply(ErrSpooled)
+}
+
+// settle completa la reserva de deduplicación de la medición según el resultado
+// de su procesamiento, o la libera si falló para que el dispositivo pueda
+// reintentarla
+func (p *IngestPipeline) settle(ctx context.Context, job *ingestJob, err error) {
+	if job.claim == nil {
+		return
+	}
+
+	switch {
+	case err == nil:
+		p.dedup.Complete(ctx, job.claim, MessageProcessed)
+	case stderrors.Is(err, ErrSpooled):
+		p.dedup.Complete(ctx, job.claim, MessageAccepted)
+	default:
+		p.dedup.Release(ctx, job.claim)
+	}
+}
+
+// reply entrega el resultado del procesamiento a quien espera la medición, si lo hay
+func (j *ingestJob) reply(err error) {
+	if j.result != nil {
+		j.result <- err
+	}
+}
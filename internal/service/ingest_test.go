@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// nopNotifier descarta las notificaciones
+type nopNotifier struct{}
+
+func (nopNotifier) Notify(ctx context.Context, notification *models.Notification) {}
+
+// newTestPipeline crea una cola de ingesta sin iniciar sobre un servicio de
+// telemetría sin spool, con un dispositivo deshabilitado en caché
+func newTestPipeline(t *testing.T, workers, queueSize int) *IngestPipeline {
+	t.Helper()
+
+	log := newTestLogger(t)
+	cache := newTestCache(t)
+	if err := cache.SetDevice(context.Background(), &models.Device{IDTelemetria: 1, Identificador: "INACTIVO", Activo: false}); err != nil {
+		t.Fatalf("error al escribir dispositivo en caché: %v", err)
+	}
+
+	telemetry := NewTelemetryService(&fakeRepository{}, cache, &config.TelemetryConfig{}, &config.SpoolConfig{}, nil, nil, nopNotifier{}, log)
+	return NewIngestPipeline(telemetry, nil, &config.IngestConfig{Workers: workers, QueueSize: queueSize, DrainTimeout: time.Second}, log)
+}
+
+func TestEnqueueResult(t *testing.T) {
+	p := newTestPipeline(t, 2, 10)
+	p.Start()
+	defer p.Stop()
+
+	lat, lng := -33.45, -70.66
+	tests := []struct {
+		name    string
+		req     *models.TelemetryRequest
+		wantErr error
+	}{
+		{name: "medición inválida", req: &models.TelemetryRequest{Identificador: "D1"}, wantErr: &ValidationErrors{}},
+		{name: "dispositivo deshabilitado", req: &models.TelemetryRequest{Identificador: "INACTIVO", Latitud: &lat, Longitud: &lng}, wantErr: ErrDeviceDisabled},
+	}
+
+	// Encolar todas las mediciones antes de esperar los resultados, como un lote
+	results := make([]<-chan error, len(tests))
+	for i, tt := range tests {
+		done, err := p.EnqueueResult(context.Background(), tt.req, "HTTP")
+		if err != nil {
+			t.Fatalf("%s: error al encolar: %v", tt.name, err)
+		}
+		results[i] = done
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := <-results[i]
+			var ve *ValidationErrors
+			if errors.As(tt.wantErr, &ve) {
+				if !errors.As(err, &ve) {
+					t.Errorf("se obtuvo %v, se esperaba un error de validación", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("se obtuvo %v, se esperaba %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnqueueResultQueueErrors(t *testing.T) {
+	p := newTestPipeline(t, 1, 1)
+	req := &models.TelemetryRequest{Identificador: "D1"}
+
+	// Sin workers iniciados la segunda medición no cabe en la cola
+	done, err := p.EnqueueResult(context.Background(), req, "HTTP")
+	if err != nil {
+		t.Fatalf("error al encolar: %v", err)
+	}
+	if _, err := p.EnqueueResult(context.Background(), req, "HTTP"); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("con la cola llena: se obtuvo %v, se esperaba %v", err, ErrQueueFull)
+	}
+
+	// La medición encolada recibe su resultado al vaciar la cola en el apagado
+	p.Start()
+	p.Stop()
+	var ve *ValidationErrors
+	if err := <-done; !errors.As(err, &ve) {
+		t.Errorf("resultado de la medición encolada: se obtuvo %v, se esperaba un error de validación", err)
+	}
+
+	if _, err := p.EnqueueResult(context.Background(), req, "HTTP"); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("con la cola cerrada: se obtuvo %v, se esperaba %v", err, ErrQueueClosed)
+	}
+}
+
+func TestEnqueueWaitStop(t *testing.T) {
+	p := newTestPipeline(t, 1, 1)
+	req := &models.TelemetryRequest{Identificador: "D1"}
+
+	// Sin workers iniciados la cola se llena y la siguiente medición espera
+	if err := p.Enqueue(context.Background(), req, "MQTT", nil); err != nil {
+		t.Fatalf("error al encolar: %v", err)
+	}
+
+	waitErr := make(chan error, 1)
+	go func() {
+		waitErr <- p.EnqueueWait(context.Background(), req, "MQTT", nil)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// El apagado no queda bloqueado por la medición en espera, que se rechaza
+	stopped := make(chan struct{})
+	go func() {
+		p.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case err := <-waitErr:
+		if !errors.Is(err, ErrQueueClosed) {
+			t.Errorf("se obtuvo %v, se esperaba %v", err, ErrQueueClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("EnqueueWait sigue bloqueado tras Stop")
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop sigue bloqueado")
+	}
+}
+
+func TestIngestSettle(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Now()
+
+	tests := []struct {
+		name         string
+		err          error
+		wantOriginal string // "" si la reserva debe quedar liberada
+	}{
+		{name: "procesada", err: nil, wantOriginal: MessageProcessed},
+		{name: "almacenada en el spool", err: ErrSpooled, wantOriginal: MessageAccepted},
+		{name: "dispositivo deshabilitado", err: ErrDeviceDisabled},
+		{name: "descartada al apagar", err: ErrQueueClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPipeline(t, 1, 1)
+			p.dedup = NewDedupService(newTestCache(t), &config.DedupConfig{Enabled: true, Window: time.Minute}, newTestLogger(t))
+			req := testReading(&ts, "DEVICE001-000123", 23.5)
+
+			claim, _, err := p.dedup.Claim(ctx, req)
+			if err != nil || claim == nil {
+				t.Fatalf("primera reserva: reserva %v, error %v", claim, err)
+			}
+			p.settle(ctx, &ingestJob{req: req, claim: claim}, tt.err)
+
+			claim, original, err := p.dedup.Claim(ctx, req)
+			if tt.wantOriginal == "" {
+				if err != nil || claim == nil {
+					t.Errorf("reintento tras liberar: reserva %v, error %v", claim, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrDuplicate) || original != tt.wantOriginal {
+				t.Errorf("se obtuvo (%q, %v), se esperaba (%q, %v)", original, err, tt.wantOriginal, ErrDuplicate)
+			}
+		})
+	}
+}
+
+func TestEnqueueReleasesClaimOnFailure(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Now()
+
+	p := newTestPipeline(t, 1, 1)
+	p.dedup = NewDedupService(newTestCache(t), &config.DedupConfig{Enabled: true, Window: time.Minute}, newTestLogger(t))
+	req := testReading(&ts, "INACTIVO-000001", 23.5)
+	req.Identificador = "INACTIVO"
+
+	claim, _, err := p.dedup.Claim(ctx, req)
+	if err != nil || claim == nil {
+		t.Fatalf("primera reserva: reserva %v, error %v", claim, err)
+	}
+	if err := p.Enqueue(ctx, req, "HTTP", claim); err != nil {
+		t.Fatalf("error al encolar: %v", err)
+	}
+
+	// Mientras está en la cola el mensaje sigue reservado
+	if _, original, err := p.dedup.Claim(ctx, req); !errors.Is(err, ErrDuplicate) || original != "" {
+		t.Fatalf("en la cola: se obtuvo (%q, %v), se esperaba una reserva en curso", original, err)
+	}
+
+	// El dispositivo deshabilitado falla y la reserva se libera para el reintento
+	p.Start()
+	p.Stop()
+	if claim, _, err := p.dedup.Claim(ctx, req); err != nil || claim == nil {
+		t.Errorf("reintento tras el fallo: reserva %v, error %v", claim, err)
+	}
+}
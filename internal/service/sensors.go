@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// sensorNamePattern define los nombres de sensor permitidos (ej. "temp_engine")
+var sensorNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// resolvedSensors contiene los valores de sensores listos para almacenar
+type resolvedSensors struct {
+	legacy [models.LegacySensorSlots]*float64 // Columnas Sensor_1..Sensor_5
+	named  map[string]float64                 // Sensores con nombre
+}
+
+// resolveSensors combina los sensores legacy y los sensores con nombre según el catálogo
+// del dispositivo. Un sensor con nombre asociado a un slot también se almacena en la
+// columna Sensor_N correspondiente, y un valor legacy de un slot catalogado también se
+// almacena con su nombre. Retorna las descripciones de los valores descartados
+func (s *TelemetryService) resolveSensors(ctx context.Context, device *models.Device, req *models.TelemetryRequest) (*resolvedSensors, []string) {
+	resolved := &resolvedSensors{
+		legacy: [models.LegacySensorSlots]*float64{req.Sensor1, req.Sensor2, req.Sensor3, req.Sensor4, req.Sensor5},
+		named:  make(map[string]float64),
+	}
+
+	// Sin sensores con nombre ni catálogo no hay nada que resolver
+	catalog := s.getSensorCatalog(ctx, device)
+	if len(req.Sensors) == 0 && len(catalog) == 0 {
+		return resolved, nil
+	}
+
+	var errors []string
+
+	// Procesar en orden para que los errores sean deterministas
+	names := make([]string, 0, len(req.Sensors))
+	for name := range req.Sensors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := float64(req.Sensors[name])
+
+		def, defined := catalog[name]
+		if !defined {
+			// Sin catálogo se aceptan todos los sensores
+			if len(catalog) > 0 {
+				errors = append(errors, fmt.Sprintf("Sensor no definido en el catálogo: %s", name))
+				continue
+			}
+			resolved.named[name] = value
+			continue
+		}
+
+		if err := checkSensorType(def, value); err != "" {
+			errors = append(errors, err)
+			continue
+		}
+
+		resolved.named[name] = value
+		if slot := def.Slot; slot != nil && *slot >= 1 && *slot <= models.LegacySensorSlots && resolved.legacy[*slot-1] == nil {
+			v := value
+			resolved.legacy[*slot-1] = &v
+		}
+	}
+
+	// Completar nombres a partir de valores legacy de slots catalogados
+	for name, def := range catalog {
+		if def.Slot == nil || *def.Slot < 1 || *def.Slot > models.LegacySensorSlots {
+			continue
+		}
+		if _, exists := resolved.named[name]; exists {
+			continue
+		}
+		if legacy := resolved.legacy[*def.Slot-1]; legacy != nil {
+			resolved.named[name] = *legacy
+		}
+	}
+
+	return resolved, errors
+}
+
+// getSensorCatalog obtiene el catálogo de sensores del dispositivo (caché -> base de datos)
+// indexado por nombre. Ante un error se continúa sin catálogo
+func (s *TelemetryService) getSensorCatalog(ctx context.Context, device *models.Device) map[string]models.SensorDefinition {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, device.Identificador)
+
+	sensors, found, err := s.cache.GetDeviceSensors(ctx, device.Identificador)
+	if err != nil {
+		log.Warning("Error al obtener sensores del caché: %v", err)
+	}
+
+	if !found {
+		sensors, err = s.repo.GetDeviceSensors(ctx, device.IDTelemetria)
+		if err != nil {
+			log.Warning("Error al consultar sensores del dispositivo %s: %v", device.Identificador, err)
+			return nil
+		}
+
+		if err := s.cache.SetDeviceSensors(ctx, device.Identificador, sensors); err != nil {
+			log.Warning("Error al almacenar sensores en caché: %v", err)
+		}
+	}
+
+	catalog := make(map[string]models.SensorDefinition, len(sensors))
+	for _, sensor := range sensors {
+		catalog[sensor.Nombre] = sensor
+	}
+
+	return catalog
+}
+
+// checkSensorType verifica que el valor corresponda al tipo de dato del sensor
+func checkSensorType(def models.SensorDefinition, value float64) string {
+	switch def.TipoDato {
+	case models.SensorTypeInt:
+		if value != math.Trunc(value) {
+			return fmt.Sprintf("Valor inválido para sensor %s: se esperaba un entero, se recibió %v", def.Nombre, value)
+		}
+	case models.SensorTypeBool:
+		if value != 0 && value != 1 {
+			return fmt.Sprintf("Valor inválido para sensor %s: se esperaba un booleano, se recibió %v", def.Nombre, value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/metrics"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// spoolPingTimeout es el tiempo máximo de la verificación de la base de datos
+const spoolPingTimeout = 5 * time.Second
+
+// spoolReplayTimeout es el tiempo máximo de procesamiento de cada medición reenviada
+const spoolReplayTimeout = 30 * time.Second
+
+// spooledRequest es una medición almacenada en el spool con su fecha de recepción original
+type spooledRequest struct {
+	FechaRecepcion time.Time                `json:"fechaRecepcion"`
+	Request        *models.TelemetryRequest `json:"request"`
+}
+
+// SpoolStats contiene el estado del spool local de mediciones
+type SpoolStats struct {
+	Enabled    bool  `json:"habilitado"`
+	Pendientes int   `json:"pendientes"`
+	Bytes      int64 `json:"bytes"`
+}
+
+// SpoolStats retorna la cantidad y el tamaño de las mediciones pendientes en el spool
+func (s *TelemetryService) SpoolStats() SpoolStats {
+	if s.spool == nil {
+		return SpoolStats{}
+	}
+
+	return SpoolStats{
+		Enabled:    true,
+		Pendientes: s.spool.Depth(),
+		Bytes:      s.spool.Size(),
+	}
+}
+
+// Start inicia el reenvío en segundo plano de las mediciones del spool
+func (s *TelemetryService) Start() {
+	if s.spool == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.spoolConfig.RetryInterval)
+		defer ticker.Stop()
+
+		for {
+			s.replaySpool(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	if depth := s.spool.Depth(); depth > 0 {
+		s.logger.Info("Spool de mediciones iniciado con %d mediciones pendientes", depth)
+	} else {
+		s.logger.Info("Spool de mediciones iniciado")
+	}
+}
+
+// Stop detiene el reenvío del spool; las mediciones pendientes se conservan en disco
+func (s *TelemetryService) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+
+	s.logger.Info("Spool de mediciones detenido con %d mediciones pendientes", s.spool.Depth())
+}
+
+// replaySpool reenvía en orden las mediciones del spool mientras la base de datos
+// esté disponible. Las mediciones rechazadas por otros motivos (validación,
+// dispositivo desconocido) se descartan, igual que si hubieran llegado en línea
+func (s *TelemetryService) replaySpool(ctx context.Context) {
+	depth := s.spool.Depth()
+	if depth == 0 || !s.repositoryAvailable(ctx) {
+		return
+	}
+
+	s.logger.Info("Base de datos disponible, reenviando %d mediciones del spool", depth)
+
+	replayed := 0
+	for ctx.Err() == nil {
+		data, err := s.spool.Peek()
+		if err != nil {
+			s.logger.Error("Error al leer el spool de mediciones: %v", err)
+			return
+		}
+		if data == nil {
+			break
+		}
+
+		var item spooledRequest
+		if err := json.Unmarshal(data, &item); err != nil || item.Request == nil {
+			metrics.RecordIngest(metrics.TransportSpool, metrics.OutcomeInvalid)
+			s.logger.Error("Medición del spool inválida, descartada: %v", err)
+		} else if err := s.replayRequest(ctx, &item); err != nil {
+			if !s.repositoryAvailable(ctx) {
+				s.logger.Warning("Base de datos no disponible, reenvío del spool pausado con %d mediciones pendientes", s.spool.Depth())
+				return
+			}
+			metrics.RecordIngest(metrics.TransportSpool, IngestOutcome(err))
+			s.logger.Error("Error al reenviar medición del spool de %s, descartada: %v", item.Request.Identificador, err)
+		} else {
+			metrics.RecordIngest(metrics.TransportSpool, metrics.OutcomeOK)
+			replayed++
+		}
+
+		if err := s.spool.Ack(); err != nil {
+			s.logger.Error("Error al confirmar medición del spool: %v", err)
+			return
+		}
+	}
+
+	s.logger.Info("Reenvío del spool finalizado: %d mediciones procesadas, %d pendientes", replayed, s.spool.Depth())
+}
+
+// replayRequest procesa una medición del spool con su fecha de recepción original
+func (s *TelemetryService) replayRequest(ctx context.Context, item *spooledRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, spoolReplayTimeout)
+	defer cancel()
+
+	return s.processTelemetryData(ctx, item.Request, item.FechaRecepcion)
+}
+
+// spoolRequest almacena una medición en el spool
+func (s *TelemetryService) spoolRequest(req *models.TelemetryRequest, receivedAt time.Time) error {
+	data, err := json.Marshal(&spooledRequest{
+		FechaRecepcion: receivedAt,
+		Request:        req,
+	})
+	if err != nil {
+		return fmt.Errorf("error al codificar medición para el spool: %w", err)
+	}
+
+	return s.spool.Append(data)
+}
+
+// spoolable indica si una medición que falló debe almacenarse en el spool: solo
+// si el error no es propio de la medición y la base de datos no responde
+func (s *TelemetryService) spoolable(ctx context.Context, err error) bool {
+	var ve *ValidationErrors
+	if stderrors.As(err, &ve) || stderrors.Is(err, ErrDeviceNotFound) || stderrors.Is(err, ErrDeviceDisabled) {
+		return false
+	}
+
+	return !s.repositoryAvailable(ctx)
+}
+
+// repositoryAvailable verifica si la base de datos responde
+func (s *TelemetryService) repositoryAvailable(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, spoolPingTimeout)
+	defer cancel()
+
+	return s.repo.Ping(ctx) == nil
+}
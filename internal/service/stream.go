@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// streamChannel es el canal pub/sub por el que se distribuyen las mediciones entre instancias
+const streamChannel = "telemetry:measurements"
+
+// streamRetryInterval es la espera antes de reintentar la suscripción al canal
+const streamRetryInterval = 5 * time.Second
+
+// StreamFilter limita los mensajes recibidos por un suscriptor. Sin filtros recibe todos
+type StreamFilter struct {
+	Identifiers map[string]bool // Identificadores de dispositivos
+	IDGrupo     *uint           // Grupo de dispositivos
+}
+
+// matches indica si el mensaje cumple el filtro
+func (f *StreamFilter) matches(msg *models.StreamMessage) bool {
+	if len(f.Identifiers) > 0 && !f.Identifiers[msg.Identificador] {
+		return false
+	}
+	if f.IDGrupo != nil && (msg.IDGrupo == nil || *msg.IDGrupo != *f.IDGrupo) {
+		return false
+	}
+	return true
+}
+
+// StreamSubscription representa un cliente suscrito al stream en vivo
+type StreamSubscription struct {
+	C      <-chan []byte // Mensajes JSON; se cierra al detener el hub
+	ch     chan []byte
+	filter StreamFilter
+}
+
+// StreamHub distribuye las mediciones almacenadas a los clientes del stream en vivo.
+// Cada instancia publica sus mediciones en Redis pub/sub y reenvía lo recibido del
+// canal a sus suscriptores locales. Implementa MeasurementObserver
+type StreamHub struct {
+	cache  database.Cache
+	config *config.StreamConfig
+	logger *logger.Logger
+
+	mu          sync.RWMutex
+	subscribers map[*StreamSubscription]struct{}
+	subscribed  bool // Suscripción a Redis activa
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewStreamHub crea un nuevo hub del stream en vivo
+func NewStreamHub(cache database.Cache, cfg *config.StreamConfig, log *logger.Logger) *StreamHub {
+	return &StreamHub{
+		cache:       cache,
+		config:      cfg,
+		logger:      log,
+		subscribers: make(map[*StreamSubscription]struct{}),
+	}
+}
+
+// OnMeasurement publica la medición almacenada en el canal compartido
+func (h *StreamHub) OnMeasurement(ctx context.Context, event *MeasurementEvent) {
+	msg := &models.StreamMessage{
+		Identificador: event.Device.Identificador,
+		IDGrupo:       event.Device.IDGrupo,
+		Latest:        event.Latest,
+		Medicion:      event.Measurement,
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		h.logger.Error("Error al codificar mensaje del stream: %v", err)
+		return
+	}
+
+	h.mu.RLock()
+	subscribed := h.subscribed
+	h.mu.RUnlock()
+
+	// Sin suscripción a Redis se entrega localmente para no perder a los clientes de esta instancia
+	if !subscribed {
+		h.broadcast(data)
+		return
+	}
+
+	if err := h.cache.Publish(ctx, streamChannel, data); err != nil {
+		h.logger.Warning("Error al publicar medición en el stream: %v", err)
+		h.broadcast(data)
+	}
+}
+
+// Start inicia la suscripción al canal compartido en segundo plano
+func (h *StreamHub) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+
+		for {
+			messages, err := h.cache.Subscribe(ctx, streamChannel)
+			if err != nil {
+				h.logger.Warning("Error al suscribirse al stream compartido, reintentando en %s: %v", streamRetryInterval, err)
+			} else {
+				h.setSubscribed(true)
+				for data := range messages {
+					h.broadcast(data)
+				}
+				h.setSubscribed(false)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(streamRetryInterval):
+			}
+		}
+	}()
+
+	h.logger.Info("Stream de telemetría en vivo iniciado")
+}
+
+// Stop detiene la suscripción y cierra los canales de todos los suscriptores
+func (h *StreamHub) Stop() {
+	if h.cancel == nil {
+		return
+	}
+	h.cancel()
+	h.wg.Wait()
+
+	h.mu.Lock()
+	for sub := range h.subscribers {
+		close(sub.ch)
+		delete(h.subscribers, sub)
+	}
+	h.mu.Unlock()
+
+	h.logger.Info("Stream de telemetría en vivo detenido")
+}
+
+// Subscribe registra un nuevo suscriptor con el filtro dado
+func (h *StreamHub) Subscribe(filter StreamFilter) *StreamSubscription {
+	ch := make(chan []byte, h.config.BufferSize)
+	sub := &StreamSubscription{C: ch, ch: ch, filter: filter}
+
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	total := len(h.subscribers)
+	h.mu.Unlock()
+
+	h.logger.Info("Cliente suscrito al stream (total: %d)", total)
+	return sub
+}
+
+// Unsubscribe elimina un suscriptor
+func (h *StreamHub) Unsubscribe(sub *StreamSubscription) {
+	h.mu.Lock()
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.ch)
+	}
+	total := len(h.subscribers)
+	h.mu.Unlock()
+
+	h.logger.Info("Cliente desuscrito del stream (total: %d)", total)
+}
+
+// broadcast entrega un mensaje a los suscriptores cuyo filtro coincide. Si un cliente
+// no consume a tiempo, el mensaje se descarta para no bloquear al resto
+func (h *StreamHub) broadcast(data []byte) {
+	var msg models.StreamMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		h.logger.Warning("Mensaje inválido en el stream: %v", err)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers {
+		if !sub.filter.matches(&msg) {
+			continue
+		}
+		select {
+		case sub.ch <- data:
+		default:
+			h.logger.Warning("Cliente del stream lento, mensaje de %s descartado", msg.Identificador)
+		}
+	}
+}
+
+// setSubscribed actualiza el estado de la suscripción compartida
+func (h *StreamHub) setSubscribed(subscribed bool) {
+	h.mu.Lock()
+	h.subscribed = subscribed
+	h.mu.Unlock()
+}
@@ -2,67 +2,225 @@ package service
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
-	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database/redis"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/metrics"
 	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/spool"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 )
 
+// Errores de dispositivo retornados por el servicio
+var (
+	// ErrDeviceNotFound indica que el identificador no corresponde a un dispositivo registrado
+	ErrDeviceNotFound = stderrors.New("dispositivo no encontrado")
+
+	// ErrDeviceDisabled indica que el dispositivo fue deshabilitado
+	ErrDeviceDisabled = stderrors.New("dispositivo deshabilitado")
+
+	// ErrSpooled indica que la base de datos no está disponible y la medición
+	// quedó en el spool local para procesarse cuando se recupere
+	ErrSpooled = stderrors.New("medición almacenada en el spool local")
+)
+
+// unknownDeviceBurst es la ráfaga de identificadores desconocidos que se registra
+// y notifica antes de aplicar TELEMETRY_UNKNOWN_DEVICE_RATE
+const unknownDeviceBurst = 10
+
+// unknownDeviceKey es el espacio de nombres con el que se registra en el caché
+// (como un nonce) la notificación de cada identificador desconocido
+const unknownDeviceKey = "dispositivo-desconocido"
+
+// MeasurementEvent contiene una medición recién almacenada y el estado del
+// dispositivo previo a ella (última posición y última conexión)
+type MeasurementEvent struct {
+	Device      *models.Device
+	Measurement *models.Measurement
+	Request     *models.TelemetryRequest
+	Latest      bool // false si la medición es anterior a la última conexión (lectura atrasada)
+}
+
+// MeasurementObserver recibe cada medición almacenada exitosamente
+type MeasurementObserver interface {
+	OnMeasurement(ctx context.Context, event *MeasurementEvent)
+}
+
 // TelemetryService maneja el procesamiento de datos de telemetría
 type TelemetryService struct {
-	repo   database.Repository
-	cache  database.Cache
-	rCache *redis.Cache // Para operaciones adicionales de caché
-	logger *logger.Logger
+	repo        database.Repository
+	cache       database.Cache
+	config      *config.TelemetryConfig
+	spoolConfig *config.SpoolConfig
+	spool       *spool.Spool       // nil si el spool está deshabilitado
+	writer      *MeasurementWriter // nil si la escritura por lotes está deshabilitada
+	notifier    Notifier
+	logger      *logger.Logger
+	observers   []MeasurementObserver
+
+	unknownLimiter *rate.Limiter // Limita los registros y notificaciones de dispositivos desconocidos
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
-// NewTelemetryService crea un nuevo servicio de telemetría
-func NewTelemetryService(repo database.Repository, cache database.Cache, rCache *redis.Cache, log *logger.Logger) *TelemetryService {
+// NewTelemetryService crea un nuevo servicio de telemetría. Con sp nil las
+// mediciones que no se pueden almacenar retornan error en lugar de ir al spool
+func NewTelemetryService(repo database.Repository, cache database.Cache, cfg *config.TelemetryConfig, spoolCfg *config.SpoolConfig, sp *spool.Spool, writer *MeasurementWriter, notifier Notifier, log *logger.Logger) *TelemetryService {
+	burst := unknownDeviceBurst
+	if cfg.UnknownDeviceRate <= 0 {
+		burst = 0
+	}
+
 	return &TelemetryService{
-		repo:   repo,
-		cache:  cache,
-		rCache: rCache,
-		logger: log,
+		repo:           repo,
+		cache:          cache,
+		config:         cfg,
+		spoolConfig:    spoolCfg,
+		spool:          sp,
+		writer:         writer,
+		notifier:       notifier,
+		logger:         log,
+		unknownLimiter: rate.NewLimiter(rate.Limit(cfg.UnknownDeviceRate), burst),
 	}
 }
 
-// ProcessTelemetryData procesa los datos de telemetría entrantes
+// AddObserver registra un observador de mediciones. Debe llamarse antes de procesar datos
+func (s *TelemetryService) AddObserver(observer MeasurementObserver) {
+	s.observers = append(s.observers, observer)
+}
+
+// ProcessTelemetryData procesa los datos de telemetría entrantes. Si la base de
+// datos no está disponible la medición se almacena en el spool y se retorna ErrSpooled
 func (s *TelemetryService) ProcessTelemetryData(ctx context.Context, req *models.TelemetryRequest) error {
+	return s.ProcessTelemetryDataAt(ctx, req, time.Now())
+}
+
+// ProcessTelemetryDataAt procesa datos de telemetría recibidos en receivedAt
+// (por ejemplo, al salir de la cola de ingesta), igual que ProcessTelemetryData
+func (s *TelemetryService) ProcessTelemetryDataAt(ctx context.Context, req *models.TelemetryRequest, receivedAt time.Time) (err error) {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, req.Identificador)
+
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "TelemetryService.ProcessTelemetryData",
+		trace.WithAttributes(attribute.String("telemetria.identificador", req.Identificador)))
+	defer func() {
+		metrics.ProcessDuration.Observe(time.Since(start).Seconds())
+		tracing.End(span, err)
+	}()
+
+	if s.spool == nil {
+		return s.processTelemetryData(ctx, req, receivedAt)
+	}
+
+	// Mientras haya mediciones pendientes en el spool, las nuevas se encolan detrás
+	// para conservar el orden, una vez validadas para no aceptar lo que la
+	// reproducción descartaría
+	if s.spool.Depth() > 0 {
+		if err := s.validateBeforeSpool(ctx, req, receivedAt); err != nil {
+			return err
+		}
+		if err := s.spoolRequest(req, receivedAt); err == nil {
+			return ErrSpooled
+		}
+	}
+
+	err = s.processTelemetryData(ctx, req, receivedAt)
+	if err == nil || !s.spoolable(ctx, err) {
+		return err
+	}
+
+	if spoolErr := s.spoolRequest(req, receivedAt); spoolErr != nil {
+		log.Error("Error al almacenar medición en el spool: %v", spoolErr)
+		return err
+	}
+
+	log.Warning("Base de datos no disponible, medición de %s almacenada en el spool: %v", req.Identificador, err)
+	return ErrSpooled
+}
+
+// IngestOutcome clasifica el resultado de ProcessTelemetryData para las métricas de ingesta
+func IngestOutcome(err error) string {
+	var ve *ValidationErrors
+	switch {
+	case err == nil:
+		return metrics.OutcomeOK
+	case stderrors.As(err, &ve):
+		return metrics.OutcomeInvalid
+	case stderrors.Is(err, ErrDeviceNotFound):
+		return metrics.OutcomeUnknownDevice
+	case stderrors.Is(err, ErrDeviceDisabled):
+		return metrics.OutcomeDisabledDevice
+	case stderrors.Is(err, ErrSpooled):
+		return metrics.OutcomeSpooled
+	default:
+		return metrics.OutcomeDBError
+	}
+}
+
+// processTelemetryData procesa una medición recibida en receivedAt
+func (s *TelemetryService) processTelemetryData(ctx context.Context, req *models.TelemetryRequest, receivedAt time.Time) error {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, req.Identificador)
+
 	// Validar campos requeridos
 	if err := ValidateRequiredFields(req); err != nil {
 		return fmt.Errorf("validación fallida: %w", err)
 	}
 
+	// Determinar la fecha de la medición (dispositivo -> recepción)
+	measuredAt, skewError, err := s.resolveMeasurementTime(ctx, req, receivedAt)
+	if err != nil {
+		return fmt.Errorf("validación fallida: %w", err)
+	}
+
 	// Obtener información del dispositivo (caché -> respaldo de base de datos)
-	device, err := s.getDevice(ctx, req.Identificador)
+	stepCtx, span := tracing.Start(ctx, "TelemetryService.getDevice")
+	device, err := s.getDevice(stepCtx, req.Identificador)
+	tracing.End(span, err)
 	if err != nil {
 		return fmt.Errorf("error al obtener dispositivo: %w", err)
 	}
 
-	if device == nil {
-		// Dispositivo no encontrado - registrar error
-		s.logger.Warning("Dispositivo no encontrado: %s", req.Identificador)
-		errorRecord := &models.ErrorRecord{
-			Identificador: &req.Identificador,
-			Fecha:         time.Now(),
-			Descripcion:   fmt.Sprintf("Identificador no existe en la base de datos: %s", req.Identificador),
-		}
-		if err := s.repo.InsertError(ctx, errorRecord); err != nil {
-			s.logger.Error("Error al insertar registro de error: %v", err)
-		}
-		return fmt.Errorf("dispositivo no encontrado: %s", req.Identificador)
+	if err := s.checkDevice(ctx, req, device); err != nil {
+		return err
 	}
 
+	// Una lectura atrasada no debe alterar la última posición ni la última conexión
+	isLatest := !measuredAt.Before(device.UltimaConexion)
+
 	// Validar tiempo fuera de línea
-	errors := s.validateOfflineTime(ctx, device)
+	var errors []string
+	if isLatest {
+		errors = s.validateOfflineTime(ctx, device, measuredAt)
+	}
+
+	// Registrar desfase de reloj del dispositivo
+	if skewError != "" {
+		errors = append(errors, skewError)
+	}
 
 	// Validar coordenadas faltantes
 	coordinateErrors := s.validateCoordinates(req)
 	errors = append(errors, coordinateErrors...)
+	if len(coordinateErrors) > 0 {
+		s.notifier.Notify(ctx, deviceNotification(device, models.NotificationMissingCoordinates, measuredAt,
+			strings.Join(coordinateErrors, "; "), nil))
+	}
+
+	// Resolver sensores legacy y con nombre según el catálogo del dispositivo
+	stepCtx, span = tracing.Start(ctx, "TelemetryService.resolveSensors")
+	sensors, sensorErrors := s.resolveSensors(stepCtx, device, req)
+	span.End()
+	errors = append(errors, sensorErrors...)
 
 	// Si hay errores, registrarlos
 	if len(errors) > 0 {
@@ -72,75 +230,252 @@ func (s *TelemetryService) ProcessTelemetryData(ctx context.Context, req *models
 			Identificador: &req.Identificador,
 			Fecha:         time.Now(),
 			Descripcion:   errorDesc,
+			RequestID:     requestIDOf(ctx),
 		}
 		if err := s.repo.InsertError(ctx, errorRecord); err != nil {
-			s.logger.Error("Error al insertar registro de error: %v", err)
+			log.Error("Error al insertar registro de error: %v", err)
 		}
 	}
 
 	// Calcular distancia desde la ubicación anterior
 	var distance *float64
-	if device.Latitud != nil && device.Longitud != nil && req.Latitud != nil && req.Longitud != nil {
+	if isLatest && device.Latitud != nil && device.Longitud != nil && req.Latitud != nil && req.Longitud != nil {
 		dist := CalculateDistance(*device.Latitud, *device.Longitud, *req.Latitud, *req.Longitud)
 		distance = &dist
 	}
 
 	// Crear registro de medición
 	measurement := &models.Measurement{
-		IDTelemetria: device.IDTelemetria,
-		Fecha:        time.Now(),
-		Latitud:      req.Latitud,
-		Longitud:     req.Longitud,
-		Distancia:    distance,
-		Sensor1:      req.Sensor1,
-		Sensor2:      req.Sensor2,
-		Sensor3:      req.Sensor3,
-		Sensor4:      req.Sensor4,
-		Sensor5:      req.Sensor5,
+		IDTelemetria:   device.IDTelemetria,
+		Fecha:          measuredAt,
+		FechaRecepcion: receivedAt,
+		Latitud:        req.Latitud,
+		Longitud:       req.Longitud,
+		Distancia:      distance,
+		Sensor1:        sensors.legacy[0],
+		Sensor2:        sensors.legacy[1],
+		Sensor3:        sensors.legacy[2],
+		Sensor4:        sensors.legacy[3],
+		Sensor5:        sensors.legacy[4],
+		Sensores:       sensors.named,
+	}
+	if req.Timestamp != nil {
+		deviceTime := req.Timestamp.Time
+		measurement.FechaDispositivo = &deviceTime
 	}
 
 	// Insertar medición
-	if err := s.repo.InsertMeasurement(ctx, measurement); err != nil {
+	stepCtx, span = tracing.Start(ctx, "TelemetryService.insertMeasurement")
+	err = s.insertMeasurement(stepCtx, measurement)
+	tracing.End(span, err)
+	if err != nil {
 		return fmt.Errorf("error al insertar medición: %w", err)
 	}
 
-	// Actualizar tiempo de conexión del dispositivo
-	now := time.Now()
-	if err := s.repo.UpdateDeviceConnection(ctx, device.IDTelemetria, now); err != nil {
-		s.logger.Warning("Error al actualizar conexión del dispositivo: %v", err)
-	}
+	// Las lecturas atrasadas se almacenan pero no modifican el estado actual del dispositivo
+	if isLatest {
+		stepCtx, span = tracing.Start(ctx, "TelemetryService.updateDeviceState")
 
-	// Actualizar caché con nueva ubicación y marca de tiempo
-	if req.Latitud != nil && req.Longitud != nil {
-		if err := s.rCache.UpdateDeviceLocation(ctx, req.Identificador, *req.Latitud, *req.Longitud, now); err != nil {
-			s.logger.Warning("Error al actualizar ubicación del dispositivo en caché: %v", err)
+		// Actualizar tiempo de conexión del dispositivo
+		if err := s.repo.UpdateDeviceConnection(stepCtx, device.IDTelemetria, measuredAt); err != nil {
+			log.Warning("Error al actualizar conexión del dispositivo: %v", err)
 		}
+
+		// Registrar reconexión si el watchdog lo había marcado fuera de línea
+		s.markOnline(stepCtx, device, measuredAt)
+
+		// Actualizar caché con nueva ubicación y marca de tiempo
+		if req.Latitud != nil && req.Longitud != nil {
+			if err := s.cache.UpdateDeviceLocation(stepCtx, req.Identificador, *req.Latitud, *req.Longitud, measuredAt); err != nil {
+				log.Warning("Error al actualizar ubicación del dispositivo en caché: %v", err)
+			}
+		}
+
+		span.End()
 	}
 
+	// Notificar a los observadores (geocercas, etc.)
+	event := &MeasurementEvent{
+		Device:      device,
+		Measurement: measurement,
+		Request:     req,
+		Latest:      isLatest,
+	}
+	stepCtx, span = tracing.Start(ctx, "TelemetryService.notifyObservers")
+	for _, observer := range s.observers {
+		observer.OnMeasurement(stepCtx, event)
+	}
+	span.End()
+
 	// Registrar datos del dispositivo
-	if err := s.logger.LogDeviceData(req.Identificador, req); err != nil {
-		s.logger.Warning("Error al registrar datos del dispositivo: %v", err)
+	_, span = tracing.Start(ctx, "TelemetryService.logDeviceData")
+	err = s.logger.WithContext(ctx).LogDeviceData(req.Identificador, req)
+	tracing.End(span, err)
+	if err != nil {
+		log.Warning("Error al registrar datos del dispositivo: %v", err)
+	}
+
+	log.Info("Datos de telemetría procesados para dispositivo: %s", req.Identificador)
+	return nil
+}
+
+// validateBeforeSpool aplica a una medición que va al spool las validaciones de
+// processTelemetryData: campos requeridos, desfase de reloj y dispositivo
+// registrado y habilitado. Si el dispositivo no se puede consultar la medición
+// se acepta y se valida al reproducirla
+func (s *TelemetryService) validateBeforeSpool(ctx context.Context, req *models.TelemetryRequest, receivedAt time.Time) error {
+	if err := ValidateRequiredFields(req); err != nil {
+		return fmt.Errorf("validación fallida: %w", err)
+	}
+	if _, _, err := s.resolveMeasurementTime(ctx, req, receivedAt); err != nil {
+		return fmt.Errorf("validación fallida: %w", err)
+	}
+
+	device, err := s.getDevice(ctx, req.Identificador)
+	if err != nil {
+		s.logger.WithContext(ctx).With(logger.KeyDevice, req.Identificador).
+			Warning("No se pudo verificar el dispositivo antes de almacenar en el spool: %v", err)
+		return nil
+	}
+	return s.checkDevice(ctx, req, device)
+}
+
+// checkDevice verifica que el dispositivo de la medición exista y esté
+// habilitado, registrando el error en caso contrario
+func (s *TelemetryService) checkDevice(ctx context.Context, req *models.TelemetryRequest, device *models.Device) error {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, req.Identificador)
+
+	if device == nil {
+		// Dispositivo no encontrado - registrar error
+		log.Warning("Dispositivo no encontrado: %s", req.Identificador)
+		s.recordUnknownDevice(ctx, req.Identificador)
+		return fmt.Errorf("%w: %s", ErrDeviceNotFound, req.Identificador)
+	}
+
+	if !device.Activo {
+		// Dispositivo deshabilitado - registrar error
+		log.Warning("Dispositivo deshabilitado: %s", req.Identificador)
+		errorRecord := &models.ErrorRecord{
+			IDTelemetria:  &device.IDTelemetria,
+			Identificador: &req.Identificador,
+			Fecha:         time.Now(),
+			Descripcion:   fmt.Sprintf("Dispositivo deshabilitado: %s", req.Identificador),
+			RequestID:     requestIDOf(ctx),
+		}
+		if err := s.repo.InsertError(ctx, errorRecord); err != nil {
+			log.Error("Error al insertar registro de error: %v", err)
+		}
+		return fmt.Errorf("%w: %s", ErrDeviceDisabled, req.Identificador)
 	}
 
-	s.logger.Info("Datos de telemetría procesados para dispositivo: %s", req.Identificador)
 	return nil
 }
 
+// recordUnknownDevice registra un identificador desconocido en la tabla de
+// errores y lo notifica. Cualquier cliente puede enviar identificadores
+// inventados, así que se registran hasta TELEMETRY_UNKNOWN_DEVICE_RATE por
+// segundo y cada identificador se notifica una sola vez por
+// TELEMETRY_UNKNOWN_DEVICE_WINDOW; el resto queda solo en el log
+func (s *TelemetryService) recordUnknownDevice(ctx context.Context, identifier string) {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, identifier)
+
+	if !s.unknownLimiter.Allow() {
+		return
+	}
+
+	errorRecord := &models.ErrorRecord{
+		Identificador: &identifier,
+		Fecha:         time.Now(),
+		Descripcion:   fmt.Sprintf("Identificador no existe en la base de datos: %s", identifier),
+		RequestID:     requestIDOf(ctx),
+	}
+	if err := s.repo.InsertError(ctx, errorRecord); err != nil {
+		log.Error("Error al insertar registro de error: %v", err)
+	}
+
+	// El registro en el caché es compartido por todas las instancias
+	first, err := s.cache.RegisterNonce(ctx, unknownDeviceKey, identifier, s.config.UnknownDeviceWindow)
+	if err != nil {
+		log.Warning("Error al registrar dispositivo desconocido en caché: %v", err)
+		return
+	}
+	if !first {
+		return
+	}
+
+	s.notifier.Notify(ctx, &models.Notification{
+		Tipo:          models.NotificationUnknownDevice,
+		Identificador: identifier,
+		Descripcion:   errorRecord.Descripcion,
+	})
+}
+
+// ListOfflineDevices obtiene los dispositivos actualmente fuera de línea
+func (s *TelemetryService) ListOfflineDevices(ctx context.Context) ([]models.Device, error) {
+	devices, err := s.repo.ListOfflineDevices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar dispositivos fuera de línea: %w", err)
+	}
+	return devices, nil
+}
+
+// ListMeasurements consulta el historial de mediciones de un dispositivo
+func (s *TelemetryService) ListMeasurements(ctx context.Context, identifier string, query *models.MeasurementQuery) ([]models.Measurement, error) {
+	device, err := s.getDevice(ctx, identifier)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener dispositivo: %w", err)
+	}
+	if device == nil {
+		return nil, ErrDeviceNotFound
+	}
+
+	query.IDTelemetria = device.IDTelemetria
+	measurements, err := s.repo.ListMeasurements(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar mediciones: %w", err)
+	}
+
+	return measurements, nil
+}
+
+// ListEvents consulta los eventos de un dispositivo
+func (s *TelemetryService) ListEvents(ctx context.Context, identifier string, query *models.EventQuery) ([]models.Event, error) {
+	device, err := s.getDevice(ctx, identifier)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener dispositivo: %w", err)
+	}
+	if device == nil {
+		return nil, ErrDeviceNotFound
+	}
+
+	query.IDTelemetria = device.IDTelemetria
+	events, err := s.repo.ListEvents(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar eventos: %w", err)
+	}
+
+	return events, nil
+}
+
 // getDevice obtiene información del dispositivo desde caché o base de datos
 func (s *TelemetryService) getDevice(ctx context.Context, identifier string) (*models.Device, error) {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, identifier)
+
 	// Intentar caché primero
 	device, err := s.cache.GetDevice(ctx, identifier)
 	if err != nil {
-		s.logger.Warning("Error al obtener dispositivo del caché: %v", err)
+		log.Warning("Error al obtener dispositivo del caché: %v", err)
 	}
 
 	if device != nil {
-		s.logger.Info("Dispositivo encontrado en caché: %s", identifier)
+		metrics.DeviceCacheTotal.WithLabelValues("hit").Inc()
+		log.Info("Dispositivo encontrado en caché: %s", identifier)
 		return device, nil
 	}
+	metrics.DeviceCacheTotal.WithLabelValues("miss").Inc()
 
 	// Respaldo a base de datos
-	s.logger.Info("Dispositivo no está en caché, consultando base de datos: %s", identifier)
+	log.Info("Dispositivo no está en caché, consultando base de datos: %s", identifier)
 	device, err = s.repo.GetDeviceByIdentifier(ctx, identifier)
 	if err != nil {
 		return nil, fmt.Errorf("error al consultar dispositivo desde la base de datos: %w", err)
@@ -152,27 +487,71 @@ func (s *TelemetryService) getDevice(ctx context.Context, identifier string) (*m
 
 	// Poblar caché
 	if err := s.cache.SetDevice(ctx, device); err != nil {
-		s.logger.Warning("Error al almacenar dispositivo en caché: %v", err)
+		log.Warning("Error al almacenar dispositivo en caché: %v", err)
 	} else {
-		s.logger.Info("Dispositivo almacenado en caché: %s", identifier)
+		log.Info("Dispositivo almacenado en caché: %s", identifier)
 	}
 
 	return device, nil
 }
 
+// resolveMeasurementTime determina la fecha efectiva de una medición.
+// Si el dispositivo no envía timestamp se usa la fecha de recepción. Si el desfase
+// supera los límites configurados, la lectura se rechaza o se marca según la política,
+// retornando en ese caso la descripción del desfase para registrarla como error
+func (s *TelemetryService) resolveMeasurementTime(ctx context.Context, req *models.TelemetryRequest, receivedAt time.Time) (time.Time, string, error) {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, req.Identificador)
+
+	if req.Timestamp == nil {
+		return receivedAt, "", nil
+	}
+
+	deviceTime := req.Timestamp.Time
+	skew := deviceTime.Sub(receivedAt)
+
+	var skewError string
+	switch {
+	case s.config.MaxClockSkew > 0 && skew > s.config.MaxClockSkew:
+		skewError = fmt.Sprintf("Desfase de reloj excedido: timestamp del dispositivo %s adelantado %s (máximo permitido %s)",
+			deviceTime.Format(time.RFC3339), skew.String(), s.config.MaxClockSkew.String())
+	case s.config.MaxBackfillAge > 0 && -skew > s.config.MaxBackfillAge:
+		skewError = fmt.Sprintf("Desfase de reloj excedido: timestamp del dispositivo %s atrasado %s (máximo permitido %s)",
+			deviceTime.Format(time.RFC3339), (-skew).String(), s.config.MaxBackfillAge.String())
+	default:
+		return deviceTime, "", nil
+	}
+
+	log.Warning("Dispositivo %s: %s", req.Identificador, skewError)
+
+	if s.config.ClockSkewPolicy == config.ClockSkewPolicyReject {
+		return time.Time{}, "", &ValidationErrors{Errors: []models.ValidationError{
+			{
+				Field:   "timestamp",
+				Message: skewError,
+			},
+		}}
+	}
+
+	// Política "flag": se almacena con la fecha de recepción
+	return receivedAt, skewError, nil
+}
+
 // validateOfflineTime verifica si el dispositivo ha estado fuera de línea más tiempo del permitido
-func (s *TelemetryService) validateOfflineTime(ctx context.Context, device *models.Device) []string {
+// hasta la fecha de la medición recibida
+func (s *TelemetryService) validateOfflineTime(ctx context.Context, device *models.Device, measuredAt time.Time) []string {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, device.Identificador)
+
 	var errors []string
 
 	// Parsear TiempoFueraLinea (formato: HH:MM:SS)
 	maxOfflineDuration, err := parseTimeDuration(device.TiempoFueraLinea)
 	if err != nil {
-		s.logger.Warning("Error al parsear TiempoFueraLinea para dispositivo %s: %v", device.Identificador, err)
+		log.Warning("Error al parsear TiempoFueraLinea para dispositivo %s: %v", device.Identificador, err)
 		return errors
 	}
 
 	// Calcular tiempo desde la última conexión
-	timeSinceLastConnection := time.Since(device.UltimaConexion)
+	timeSinceLastConnection := measuredAt.Sub(device.UltimaConexion)
 
 	// Verificar si se excedió el tiempo fuera de línea
 	if timeSinceLastConnection > maxOfflineDuration {
@@ -182,12 +561,45 @@ func (s *TelemetryService) validateOfflineTime(ctx context.Context, device *mode
 			timeSinceLastConnection.String(),
 		)
 		errors = append(errors, errorMsg)
-		s.logger.Warning("Dispositivo %s tiempo fuera de línea excedido: %s", device.Identificador, errorMsg)
+		log.Warning("Dispositivo %s tiempo fuera de línea excedido: %s", device.Identificador, errorMsg)
 	}
 
 	return errors
 }
 
+// markOnline quita la marca fuera de línea del dispositivo y registra el evento de reconexión
+func (s *TelemetryService) markOnline(ctx context.Context, device *models.Device, measuredAt time.Time) {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, device.Identificador)
+
+	reconnected, err := s.repo.MarkDeviceOnline(ctx, device.IDTelemetria)
+	if err != nil {
+		log.Warning("Error al actualizar estado en línea del dispositivo: %v", err)
+		return
+	}
+	if !reconnected {
+		return
+	}
+
+	description := fmt.Sprintf("Dispositivo en línea nuevamente: última conexión anterior %s, reconexión %s",
+		device.UltimaConexion.Format(time.RFC3339),
+		measuredAt.Format(time.RFC3339),
+	)
+	log.Info("Dispositivo %s: %s", device.Identificador, description)
+
+	errorRecord := &models.ErrorRecord{
+		IDTelemetria:  &device.IDTelemetria,
+		Identificador: &device.Identificador,
+		Fecha:         time.Now(),
+		Descripcion:   description,
+		RequestID:     requestIDOf(ctx),
+	}
+	if err := s.repo.InsertError(ctx, errorRecord); err != nil {
+		log.Error("Error al insertar registro de error: %v", err)
+	}
+
+	s.notifier.Notify(ctx, deviceNotification(device, models.NotificationDeviceOnline, measuredAt, description, nil))
+}
+
 // validateCoordinates verifica si faltan coordenadas
 func (s *TelemetryService) validateCoordinates(req *models.TelemetryRequest) []string {
 	var errors []string
@@ -222,3 +634,13 @@ func parseTimeDuration(timeStr string) (time.Duration, error) {
 
 	return duration, nil
 }
+
+// requestIDOf retorna el ID de solicitud del contexto para los registros de
+// error, o nil si la medición no proviene de una solicitud (ej. reenvío del spool)
+func requestIDOf(ctx context.Context) *string {
+	requestID := logger.RequestIDFromContext(ctx)
+	if requestID == "" {
+		return nil
+	}
+	return &requestID
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/spool"
+)
+
+func TestProcessWithPendingSpool(t *testing.T) {
+	ctx := context.Background()
+	skewed := time.Now().Add(time.Hour)
+
+	reading := func(identifier string, timestamp *time.Time) *models.TelemetryRequest {
+		req := testReading(timestamp, "", 23.5)
+		req.Identificador = identifier
+		return req
+	}
+	withoutPosition := reading("DEVICE001", nil)
+	withoutPosition.Latitud = nil
+
+	tests := []struct {
+		name      string
+		req       *models.TelemetryRequest
+		wantErr   error // ErrSpooled si la medición debe quedar en el spool
+		wantDepth int
+	}{
+		{name: "dispositivo registrado", req: reading("DEVICE001", nil), wantErr: ErrSpooled, wantDepth: 2},
+		{name: "dispositivo desconocido", req: reading("DESCONOCIDO", nil), wantErr: ErrDeviceNotFound, wantDepth: 1},
+		{name: "dispositivo deshabilitado", req: reading("INACTIVO", nil), wantErr: ErrDeviceDisabled, wantDepth: 1},
+		{name: "medición inválida", req: withoutPosition, wantErr: &ValidationErrors{}, wantDepth: 1},
+		{name: "desfase de reloj rechazado", req: reading("DEVICE001", &skewed), wantErr: &ValidationErrors{}, wantDepth: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp, err := spool.Open(&config.SpoolConfig{Dir: t.TempDir(), SegmentSizeMB: 1})
+			if err != nil {
+				t.Fatalf("error al abrir spool: %v", err)
+			}
+			defer sp.Close()
+			// Una medición pendiente obliga a encolar las nuevas detrás
+			if err := sp.Append([]byte(`{}`)); err != nil {
+				t.Fatalf("error al escribir en el spool: %v", err)
+			}
+
+			cache := newTestCache(t)
+			for _, device := range []*models.Device{
+				{IDTelemetria: 1, Identificador: "DEVICE001", Activo: true},
+				{IDTelemetria: 2, Identificador: "INACTIVO", Activo: false},
+			} {
+				if err := cache.SetDevice(ctx, device); err != nil {
+					t.Fatalf("error al escribir dispositivo en caché: %v", err)
+				}
+			}
+
+			repo := &fakeRepository{}
+			telemetryCfg := &config.TelemetryConfig{MaxClockSkew: time.Minute, ClockSkewPolicy: config.ClockSkewPolicyReject}
+			s := NewTelemetryService(repo, cache, telemetryCfg, &config.SpoolConfig{}, sp, nil, nopNotifier{}, newTestLogger(t))
+
+			err = s.ProcessTelemetryData(ctx, tt.req)
+			var ve *ValidationErrors
+			if errors.As(tt.wantErr, &ve) {
+				if !errors.As(err, &ve) {
+					t.Errorf("se obtuvo %v, se esperaba un error de validación", err)
+				}
+			} else if !errors.Is(err, tt.wantErr) {
+				t.Errorf("se obtuvo %v, se esperaba %v", err, tt.wantErr)
+			}
+
+			if depth := sp.Depth(); depth != tt.wantDepth {
+				t.Errorf("mediciones en el spool: se obtuvo %d, se esperaba %d", depth, tt.wantDepth)
+			}
+		})
+	}
+}
+
+// countingNotifier cuenta las notificaciones por identificador
+type countingNotifier struct {
+	mu    sync.Mutex
+	count map[string]int
+}
+
+func (n *countingNotifier) Notify(ctx context.Context, notification *models.Notification) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.count == nil {
+		n.count = make(map[string]int)
+	}
+	n.count[notification.Tipo+":"+notification.Identificador]++
+}
+
+func (n *countingNotifier) get(notificationType, identifier string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.count[notificationType+":"+identifier]
+}
+
+func TestUnknownDeviceNotification(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		rate        float64
+		identifiers []string
+		wantRecords int
+		wantNotify  map[string]int
+	}{
+		{
+			name:        "una notificación por identificador",
+			rate:        1000,
+			identifiers: []string{"FALSO1", "FALSO1", "FALSO2", "FALSO1", "FALSO2"},
+			wantRecords: 5,
+			wantNotify:  map[string]int{"FALSO1": 1, "FALSO2": 1},
+		},
+		{
+			name:        "registros limitados por segundo",
+			rate:        0.001,
+			identifiers: []string{"F01", "F02", "F03", "F04", "F05", "F06", "F07", "F08", "F09", "F10", "F11", "F12"},
+			wantRecords: unknownDeviceBurst,
+			wantNotify:  map[string]int{"F01": 1, "F10": 1, "F11": 0, "F12": 0},
+		},
+		{
+			name:        "registros deshabilitados",
+			rate:        0,
+			identifiers: []string{"FALSO1", "FALSO2"},
+			wantRecords: 0,
+			wantNotify:  map[string]int{"FALSO1": 0, "FALSO2": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{}
+			notifier := &countingNotifier{}
+			telemetryCfg := &config.TelemetryConfig{UnknownDeviceWindow: time.Hour, UnknownDeviceRate: tt.rate}
+			s := NewTelemetryService(repo, newTestCache(t), telemetryCfg, &config.SpoolConfig{}, nil, nil, notifier, newTestLogger(t))
+
+			for _, identifier := range tt.identifiers {
+				req := testReading(nil, "", 23.5)
+				req.Identificador = identifier
+				if err := s.ProcessTelemetryData(ctx, req); !errors.Is(err, ErrDeviceNotFound) {
+					t.Fatalf("%s: se obtuvo %v, se esperaba %v", identifier, err, ErrDeviceNotFound)
+				}
+			}
+
+			if got := repo.errorCount(); got != tt.wantRecords {
+				t.Errorf("registros de error: se obtuvo %d, se esperaba %d", got, tt.wantRecords)
+			}
+			for identifier, want := range tt.wantNotify {
+				if got := notifier.get(models.NotificationUnknownDevice, identifier); got != want {
+					t.Errorf("notificaciones de %s: se obtuvo %d, se esperaba %d", identifier, got, want)
+				}
+			}
+		})
+	}
+}
@@ -31,6 +31,23 @@ func ValidateRequiredFields(req *models.TelemetryRequest) error {
 		})
 	}
 
+	if req.MessageID != "" && !messageIDPattern.MatchString(req.MessageID) {
+		errors = append(errors, models.ValidationError{
+			Field:   "messageId",
+			Message: "ID de mensaje inválido (caracteres ASCII imprimibles sin espacios, máximo 128)",
+		})
+	}
+
+	// Validar nombres de sensores
+	for name := range req.Sensors {
+		if !sensorNamePattern.MatchString(name) {
+			errors = append(errors, models.ValidationError{
+				Field:   "sensors." + name,
+				Message: "Nombre de sensor inválido (minúsculas, dígitos y '_', máximo 64 caracteres)",
+			})
+		}
+	}
+
 	if len(errors) > 0 {
 		return &ValidationErrors{Errors: errors}
 	}
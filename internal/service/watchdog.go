@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// OfflineWatchdog revisa periódicamente los dispositivos que dejaron de reportar.
+// Registra un evento fuera de línea una única vez por corte, aunque el dispositivo
+// no vuelva a enviar datos
+type OfflineWatchdog struct {
+	repo     database.Repository
+	config   *config.WatchdogConfig
+	notifier Notifier
+	logger   *logger.Logger
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewOfflineWatchdog crea un nuevo watchdog de dispositivos fuera de línea
+func NewOfflineWatchdog(repo database.Repository, cfg *config.WatchdogConfig, notifier Notifier, log *logger.Logger) *OfflineWatchdog {
+	return &OfflineWatchdog{
+		repo:     repo,
+		config:   cfg,
+		notifier: notifier,
+		logger:   log,
+	}
+}
+
+// Start inicia la revisión periódica en segundo plano
+func (w *OfflineWatchdog) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			w.scan(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	w.logger.Info("Watchdog de dispositivos fuera de línea iniciado (intervalo: %s)", w.config.Interval)
+}
+
+// Stop detiene el watchdog y espera a que termine la revisión en curso
+func (w *OfflineWatchdog) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	w.wg.Wait()
+	w.logger.Info("Watchdog de dispositivos fuera de línea detenido")
+}
+
+// scan marca fuera de línea los dispositivos cuyo tiempo permitido ya transcurrió
+func (w *OfflineWatchdog) scan(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, w.config.Interval)
+	defer cancel()
+
+	now := time.Now()
+	devices, err := w.repo.ListOverdueDevices(ctx, now)
+	if err != nil {
+		w.logger.Error("Error al consultar dispositivos fuera de línea: %v", err)
+		return
+	}
+
+	for i := range devices {
+		device := &devices[i]
+
+		threshold, err := parseTimeDuration(device.TiempoFueraLinea)
+		if err != nil {
+			w.logger.Error("Error al parsear TiempoFueraLinea para dispositivo %s: %v", device.Identificador, err)
+			continue
+		}
+
+		// Solo una instancia logra marcar el dispositivo, evitando eventos duplicados.
+		// Si el dispositivo se conectó después de la consulta, su última conexión ya
+		// no es anterior al plazo y no se marca
+		marked, err := w.repo.MarkDeviceOffline(ctx, device.IDTelemetria, now, now.Add(-threshold))
+		if err != nil {
+			w.logger.Error("Error al marcar dispositivo %s fuera de línea: %v", device.Identificador, err)
+			continue
+		}
+		if !marked {
+			continue
+		}
+
+		description := fmt.Sprintf("Dispositivo fuera de línea: sin conexión desde %s, tiempo máximo permitido %s",
+			device.UltimaConexion.Format(time.RFC3339),
+			device.TiempoFueraLinea,
+		)
+		w.logger.Warning("Dispositivo %s: %s", device.Identificador, description)
+
+		errorRecord := &models.ErrorRecord{
+			IDTelemetria:  &device.IDTelemetria,
+			Identificador: &device.Identificador,
+			Fecha:         now,
+			Descripcion:   description,
+		}
+		if err := w.repo.InsertError(ctx, errorRecord); err != nil {
+			w.logger.Error("Error al insertar registro de error: %v", err)
+		}
+
+		w.notifier.Notify(ctx, deviceNotification(device, models.NotificationDeviceOffline, now, description, nil))
+	}
+}
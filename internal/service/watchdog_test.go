@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// watchdogRepository simula la tabla de dispositivos. lastSeen es la última
+// conexión al momento de marcar, que puede ser posterior a la de la consulta
+type watchdogRepository struct {
+	database.Repository
+
+	devices  []models.Device
+	lastSeen map[uint]time.Time
+
+	mu      sync.Mutex
+	offline map[uint]bool
+	cutoffs map[uint]time.Time
+}
+
+func (r *watchdogRepository) ListOverdueDevices(ctx context.Context, now time.Time) ([]models.Device, error) {
+	return r.devices, nil
+}
+
+func (r *watchdogRepository) MarkDeviceOffline(ctx context.Context, deviceID uint, timestamp, cutoff time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cutoffs[deviceID] = cutoff
+	if r.offline[deviceID] || !r.lastSeen[deviceID].Before(cutoff) {
+		return false, nil
+	}
+	r.offline[deviceID] = true
+	return true, nil
+}
+
+func (r *watchdogRepository) InsertError(ctx context.Context, errorRecord *models.ErrorRecord) error {
+	return nil
+}
+
+func TestWatchdogScan(t *testing.T) {
+	lastSeen := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		reconnect  bool // El dispositivo se conecta entre la consulta y la marca
+		offline    bool // Ya marcado por otra instancia
+		wantNotify int
+	}{
+		{name: "dispositivo sin conexión", wantNotify: 1},
+		{name: "reconectado durante la revisión", reconnect: true, wantNotify: 0},
+		{name: "ya marcado por otra instancia", offline: true, wantNotify: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := models.Device{IDTelemetria: 1, Identificador: "DEVICE001", UltimaConexion: lastSeen, TiempoFueraLinea: "00:30:00", Activo: true}
+			repo := &watchdogRepository{
+				devices:  []models.Device{device},
+				lastSeen: map[uint]time.Time{1: lastSeen},
+				offline:  map[uint]bool{1: tt.offline},
+				cutoffs:  map[uint]time.Time{},
+			}
+			if tt.reconnect {
+				repo.lastSeen[1] = time.Now()
+			}
+			notifier := &countingNotifier{}
+			w := NewOfflineWatchdog(repo, &config.WatchdogConfig{Interval: time.Minute}, notifier, newTestLogger(t))
+
+			before := time.Now()
+			w.scan(context.Background())
+
+			// El plazo es la hora de la revisión menos el tiempo fuera de línea permitido
+			cutoff := repo.cutoffs[1]
+			if cutoff.Before(before.Add(-30*time.Minute)) || cutoff.After(time.Now().Add(-30*time.Minute)) {
+				t.Errorf("plazo: se obtuvo %v, se esperaba unos 30 minutos antes de %v", cutoff, before)
+			}
+			// Se vuelve a revisar sin notificar otra vez el mismo corte
+			w.scan(context.Background())
+
+			if got := notifier.get(models.NotificationDeviceOffline, "DEVICE001"); got != tt.wantNotify {
+				t.Errorf("notificaciones: se obtuvo %d, se esperaba %d", got, tt.wantNotify)
+			}
+		})
+	}
+}
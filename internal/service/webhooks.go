@@ -0,0 +1,496 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// ErrWebhookNotFound indica que el webhook no existe
+var ErrWebhookNotFound = errors.New("webhook no encontrado")
+
+// Headers de las solicitudes de webhook
+const (
+	headerWebhookEvent     = "X-Webhook-Event"
+	headerWebhookDelivery  = "X-Webhook-Delivery"
+	headerWebhookTimestamp = "X-Webhook-Timestamp"
+	headerWebhookSignature = "X-Webhook-Signature"
+)
+
+// notificationTypes son los tipos de notificación a los que se puede suscribir un webhook
+var notificationTypes = map[string]bool{
+	models.NotificationDeviceOffline:      true,
+	models.NotificationDeviceOnline:       true,
+	models.NotificationMissingCoordinates: true,
+	models.NotificationUnknownDevice:      true,
+	models.NotificationAlertTriggered:     true,
+	models.NotificationAlertCleared:       true,
+	models.EventGeofenceEnter:             true,
+	models.EventGeofenceExit:              true,
+	models.EventGeofenceDwell:             true,
+}
+
+// Notifier recibe las notificaciones de eventos del sistema
+type Notifier interface {
+	Notify(ctx context.Context, notification *models.Notification)
+}
+
+// WebhookService administra webhooks y entrega las notificaciones mediante una
+// cola persistente en base de datos, con firma HMAC y reintentos con backoff exponencial
+type WebhookService struct {
+	repo   database.Repository
+	config *config.WebhookConfig
+	logger *logger.Logger
+	client *http.Client
+
+	mu       sync.Mutex
+	webhooks []models.Webhook // Webhooks activos cacheados
+	expires  time.Time
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWebhookService crea un nuevo servicio de webhooks
+func NewWebhookService(repo database.Repository, cfg *config.WebhookConfig, log *logger.Logger) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		config: cfg,
+		logger: log,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Notify encola la notificación para cada webhook activo suscrito a su tipo y grupo
+func (s *WebhookService) Notify(ctx context.Context, notification *models.Notification) {
+	log := s.logger.WithContext(ctx).With(logger.KeyDevice, notification.Identificador)
+
+	if !s.config.Enabled {
+		return
+	}
+
+	webhooks := s.activeWebhooks(ctx)
+	if len(webhooks) == 0 {
+		return
+	}
+
+	if notification.ID == "" {
+		notification.ID = newNotificationID()
+	}
+	if notification.Fecha.IsZero() {
+		notification.Fecha = time.Now()
+	}
+
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		log.Error("Error al codificar notificación: %v", err)
+		return
+	}
+
+	now := time.Now()
+	var deliveries []models.WebhookDelivery
+	for i := range webhooks {
+		if !webhookMatches(&webhooks[i], notification) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			IDWebhook:      webhooks[i].IDWebhook,
+			Tipo:           notification.Tipo,
+			Payload:        payload,
+			Estado:         models.DeliveryStatusPending,
+			ProximoIntento: now,
+			FechaCreacion:  now,
+		})
+	}
+
+	if err := s.repo.EnqueueDeliveries(ctx, deliveries); err != nil {
+		log.Error("Error al encolar notificación %s: %v", notification.Tipo, err)
+	}
+}
+
+// Start inicia el envío periódico de las entregas pendientes en segundo plano
+func (s *WebhookService) Start() {
+	if !s.config.Enabled {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.config.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			s.dispatch(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	s.logger.Info("Despachador de webhooks iniciado (intervalo: %s)", s.config.PollInterval)
+}
+
+// Stop detiene el despachador y espera a que termine la entrega en curso.
+// Las entregas no enviadas permanecen en la cola
+func (s *WebhookService) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+	s.logger.Info("Despachador de webhooks detenido")
+}
+
+// dispatch envía las entregas pendientes vencidas
+func (s *WebhookService) dispatch(ctx context.Context) {
+	// La reserva cubre el envío secuencial de todo el lote
+	lease := s.config.Timeout*time.Duration(s.config.BatchSize) + time.Minute
+
+	deliveries, err := s.repo.ClaimDeliveries(ctx, time.Now(), lease, s.config.BatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error("Error al obtener entregas de webhook pendientes: %v", err)
+		}
+		return
+	}
+
+	webhooks := make(map[uint]*models.Webhook)
+	active := s.activeWebhooks(ctx)
+	for i := range active {
+		webhooks[active[i].IDWebhook] = &active[i]
+	}
+
+	for i := range deliveries {
+		// Las entregas no procesadas se liberan al vencer la reserva
+		if ctx.Err() != nil {
+			return
+		}
+		s.deliver(&deliveries[i], webhooks[deliveries[i].IDWebhook])
+	}
+}
+
+// deliver realiza un intento de entrega y registra su resultado
+func (s *WebhookService) deliver(delivery *models.WebhookDelivery, webhook *models.Webhook) {
+	// Se usa un contexto propio para no interrumpir un envío en curso al apagar
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout+5*time.Second)
+	defer cancel()
+
+	delivery.Intentos++
+	delivery.CodigoRespuesta = nil
+
+	var err error
+	if webhook == nil {
+		err = errors.New("webhook inactivo o eliminado")
+		delivery.Intentos = s.config.MaxAttempts
+	} else {
+		delivery.CodigoRespuesta, err = s.send(ctx, webhook, delivery)
+	}
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Estado = models.DeliveryStatusDelivered
+		delivery.UltimoError = ""
+		delivery.FechaEntrega = &now
+	case delivery.Intentos >= s.config.MaxAttempts:
+		delivery.Estado = models.DeliveryStatusFailed
+		delivery.UltimoError = err.Error()
+		s.logger.Error("Entrega de webhook %d fallida tras %d intentos: %v", delivery.IDEntrega, delivery.Intentos, err)
+	default:
+		delivery.UltimoError = err.Error()
+		delivery.ProximoIntento = now.Add(s.backoff(delivery.Intentos))
+		s.logger.Warning("Entrega de webhook %d fallida (intento %d), reintento a las %s: %v",
+			delivery.IDEntrega, delivery.Intentos, delivery.ProximoIntento.Format(time.RFC3339), err)
+	}
+
+	if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+		s.logger.Error("Error al actualizar entrega de webhook %d: %v", delivery.IDEntrega, err)
+	}
+}
+
+// send envía la notificación firmada al webhook. Retorna el código de respuesta si lo hubo
+func (s *WebhookService) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return nil, fmt.Errorf("error al crear solicitud: %w", err)
+	}
+
+	deliveryID := strconv.FormatUint(delivery.IDEntrega, 10)
+	timestamp := time.Now().Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "telemetria-endpoint-webhook")
+	req.Header.Set(headerWebhookEvent, delivery.Tipo)
+	req.Header.Set(headerWebhookDelivery, deliveryID)
+	req.Header.Set(headerWebhookTimestamp, strconv.FormatInt(timestamp, 10))
+	if webhook.Secreto != "" {
+		signature := ComputeSignature(webhook.Secreto, timestamp, deliveryID, delivery.Payload)
+		req.Header.Set(headerWebhookSignature, "sha256="+hex.EncodeToString(signature))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error al enviar solicitud: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	code := resp.StatusCode
+	if code < 200 || code > 299 {
+		return &code, fmt.Errorf("respuesta HTTP %d", code)
+	}
+
+	return &code, nil
+}
+
+// backoff calcula la espera antes del siguiente intento: base * 2^(intentos-1), con máximo
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	wait := s.config.BackoffBase
+	for i := 1; i < attempts && wait < s.config.BackoffMax; i++ {
+		wait *= 2
+	}
+	if s.config.BackoffMax > 0 && wait > s.config.BackoffMax {
+		wait = s.config.BackoffMax
+	}
+	return wait
+}
+
+// activeWebhooks obtiene los webhooks activos, con caché local de corta duración
+func (s *WebhookService) activeWebhooks(ctx context.Context) []models.Webhook {
+	now := time.Now()
+
+	s.mu.Lock()
+	if now.Before(s.expires) {
+		webhooks := s.webhooks
+		s.mu.Unlock()
+		return webhooks
+	}
+	s.mu.Unlock()
+
+	all, err := s.repo.ListWebhooks(ctx)
+	if err != nil {
+		s.logger.Warning("Error al consultar webhooks: %v", err)
+		return nil
+	}
+
+	var webhooks []models.Webhook
+	for _, webhook := range all {
+		if webhook.Activo {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+
+	s.mu.Lock()
+	s.webhooks = webhooks
+	s.expires = now.Add(s.config.CacheTTL)
+	s.mu.Unlock()
+
+	return webhooks
+}
+
+// invalidate descarta los webhooks cacheados localmente
+func (s *WebhookService) invalidate() {
+	s.mu.Lock()
+	s.expires = time.Time{}
+	s.mu.Unlock()
+}
+
+// ListWebhooks obtiene todos los webhooks, sin sus secretos
+func (s *WebhookService) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	webhooks, err := s.repo.ListWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar webhooks: %w", err)
+	}
+	for i := range webhooks {
+		webhooks[i].Secreto = ""
+	}
+	return webhooks, nil
+}
+
+// GetWebhook obtiene un webhook por ID, sin su secreto
+func (s *WebhookService) GetWebhook(ctx context.Context, webhookID uint) (*models.Webhook, error) {
+	webhook, err := s.repo.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener webhook: %w", err)
+	}
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+	webhook.Secreto = ""
+	return webhook, nil
+}
+
+// CreateWebhook registra un nuevo webhook
+func (s *WebhookService) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	if err := validateWebhook(webhook, true); err != nil {
+		return err
+	}
+
+	if err := s.repo.CreateWebhook(ctx, webhook); err != nil {
+		if errors.Is(err, database.ErrInvalidReference) {
+			return errGroupNotFound
+		}
+		return fmt.Errorf("error al crear webhook: %w", err)
+	}
+
+	s.invalidate()
+	s.logger.Info("Webhook creado: %s (id %d)", webhook.Nombre, webhook.IDWebhook)
+	webhook.Secreto = ""
+	return nil
+}
+
+// UpdateWebhook actualiza un webhook; un secreto vacío conserva el actual
+func (s *WebhookService) UpdateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	if err := validateWebhook(webhook, false); err != nil {
+		return err
+	}
+
+	found, err := s.repo.UpdateWebhook(ctx, webhook)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidReference) {
+			return errGroupNotFound
+		}
+		return fmt.Errorf("error al actualizar webhook: %w", err)
+	}
+	if !found {
+		return ErrWebhookNotFound
+	}
+
+	s.invalidate()
+	s.logger.Info("Webhook actualizado: %s (id %d)", webhook.Nombre, webhook.IDWebhook)
+	webhook.Secreto = ""
+	return nil
+}
+
+// DeleteWebhook elimina un webhook y su registro de entregas
+func (s *WebhookService) DeleteWebhook(ctx context.Context, webhookID uint) error {
+	found, err := s.repo.DeleteWebhook(ctx, webhookID)
+	if err != nil {
+		return fmt.Errorf("error al eliminar webhook: %w", err)
+	}
+	if !found {
+		return ErrWebhookNotFound
+	}
+
+	s.invalidate()
+	s.logger.Info("Webhook eliminado: %d", webhookID)
+	return nil
+}
+
+// ListDeliveries consulta el registro de entregas de un webhook
+func (s *WebhookService) ListDeliveries(ctx context.Context, query *models.DeliveryQuery) ([]models.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, query.IDWebhook); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.repo.ListDeliveries(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar entregas de webhook: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// webhookMatches indica si el webhook está suscrito al tipo y grupo de la notificación
+func webhookMatches(webhook *models.Webhook, notification *models.Notification) bool {
+	if webhook.IDGrupo != nil && (notification.IDGrupo == nil || *notification.IDGrupo != *webhook.IDGrupo) {
+		return false
+	}
+	if len(webhook.Eventos) == 0 {
+		return true
+	}
+	for _, event := range webhook.Eventos {
+		if event == notification.Tipo {
+			return true
+		}
+	}
+	return false
+}
+
+// validateWebhook valida los campos de un webhook; en creación el secreto es requerido
+func validateWebhook(webhook *models.Webhook, create bool) error {
+	var errors []models.ValidationError
+
+	webhook.Nombre = strings.TrimSpace(webhook.Nombre)
+	if webhook.Nombre == "" {
+		errors = append(errors, models.ValidationError{
+			Field:   "nombre",
+			Message: "El nombre es requerido",
+		})
+	}
+
+	if u, err := url.Parse(webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errors = append(errors, models.ValidationError{
+			Field:   "url",
+			Message: "La URL debe ser una dirección http o https válida",
+		})
+	}
+
+	if create && webhook.Secreto == "" {
+		errors = append(errors, models.ValidationError{
+			Field:   "secreto",
+			Message: "El secreto para firmar las notificaciones es requerido",
+		})
+	}
+
+	for _, event := range webhook.Eventos {
+		if !notificationTypes[event] {
+			errors = append(errors, models.ValidationError{
+				Field:   "eventos",
+				Message: fmt.Sprintf("Tipo de evento desconocido: %s", event),
+			})
+		}
+	}
+
+	if len(errors) > 0 {
+		return &ValidationErrors{Errors: errors}
+	}
+
+	return nil
+}
+
+// deviceNotification construye una notificación asociada a un dispositivo registrado
+func deviceNotification(device *models.Device, notificationType string, ts time.Time, description string, data interface{}) *models.Notification {
+	return &models.Notification{
+		Tipo:          notificationType,
+		Identificador: device.Identificador,
+		IDTelemetria:  &device.IDTelemetria,
+		IDGrupo:       device.IDGrupo,
+		Fecha:         ts,
+		Descripcion:   description,
+		Datos:         data,
+	}
+}
+
+// newNotificationID genera un identificador aleatorio de notificación
+func newNotificationID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+// webhookRepository simula la tabla de webhooks y la cola de entregas
+type webhookRepository struct {
+	database.Repository
+
+	mu         sync.Mutex
+	webhooks   []models.Webhook
+	deliveries []models.WebhookDelivery
+	updated    []models.WebhookDelivery
+}
+
+func (r *webhookRepository) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	return r.webhooks, nil
+}
+
+func (r *webhookRepository) EnqueueDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries = append(r.deliveries, deliveries...)
+	return nil
+}
+
+func (r *webhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.updated = append(r.updated, *delivery)
+	return nil
+}
+
+// testWebhookConfig es la configuración de webhooks de las pruebas
+func testWebhookConfig() *config.WebhookConfig {
+	return &config.WebhookConfig{
+		Enabled:     true,
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		BackoffBase: time.Second,
+		BackoffMax:  30 * time.Second,
+		BatchSize:   10,
+		CacheTTL:    time.Minute,
+	}
+}
+
+func TestWebhookMatches(t *testing.T) {
+	group := func(id uint) *uint { return &id }
+
+	tests := []struct {
+		name         string
+		webhook      models.Webhook
+		notification models.Notification
+		want         bool
+	}{
+		{name: "sin grupo ni eventos recibe todo", webhook: models.Webhook{}, notification: models.Notification{Tipo: models.NotificationDeviceOffline, IDGrupo: group(1)}, want: true},
+		{name: "sin grupo recibe dispositivos desconocidos", webhook: models.Webhook{}, notification: models.Notification{Tipo: models.NotificationUnknownDevice}, want: true},
+		{name: "evento suscrito", webhook: models.Webhook{Eventos: []string{models.NotificationAlertTriggered}}, notification: models.Notification{Tipo: models.NotificationAlertTriggered}, want: true},
+		{name: "evento no suscrito", webhook: models.Webhook{Eventos: []string{models.NotificationAlertTriggered}}, notification: models.Notification{Tipo: models.NotificationAlertCleared}, want: false},
+		{name: "mismo grupo", webhook: models.Webhook{IDGrupo: group(1)}, notification: models.Notification{Tipo: models.NotificationDeviceOnline, IDGrupo: group(1)}, want: true},
+		{name: "otro grupo", webhook: models.Webhook{IDGrupo: group(1)}, notification: models.Notification{Tipo: models.NotificationDeviceOnline, IDGrupo: group(2)}, want: false},
+		{name: "con grupo no recibe dispositivos sin grupo", webhook: models.Webhook{IDGrupo: group(1)}, notification: models.Notification{Tipo: models.NotificationUnknownDevice}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := webhookMatches(&tt.webhook, &tt.notification); got != tt.want {
+				t.Errorf("se obtuvo %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookNotify(t *testing.T) {
+	group := uint(1)
+	repo := &webhookRepository{webhooks: []models.Webhook{
+		{IDWebhook: 1, Activo: true},
+		{IDWebhook: 2, Activo: true, IDGrupo: &group, Eventos: []string{models.NotificationAlertTriggered}},
+		{IDWebhook: 3, Activo: false},
+		{IDWebhook: 4, Activo: true, Eventos: []string{models.NotificationDeviceOffline}},
+	}}
+	s := NewWebhookService(repo, testWebhookConfig(), newTestLogger(t))
+
+	s.Notify(context.Background(), &models.Notification{Tipo: models.NotificationAlertTriggered, Identificador: "DEVICE001", IDGrupo: &group})
+
+	var ids []uint
+	for _, delivery := range repo.deliveries {
+		ids = append(ids, delivery.IDWebhook)
+		if delivery.Estado != models.DeliveryStatusPending || delivery.Tipo != models.NotificationAlertTriggered || len(delivery.Payload) == 0 {
+			t.Errorf("entrega %d: se obtuvo %+v", delivery.IDWebhook, delivery)
+		}
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("webhooks notificados: se obtuvo %v, se esperaba [1 2]", ids)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	s := NewWebhookService(&webhookRepository{}, testWebhookConfig(), newTestLogger(t))
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 3, want: 4 * time.Second},
+		{attempts: 5, want: 16 * time.Second},
+		{attempts: 6, want: 30 * time.Second},
+		{attempts: 50, want: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.attempts), func(t *testing.T) {
+			if got := s.backoff(tt.attempts); got != tt.want {
+				t.Errorf("se obtuvo %s, se esperaba %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookDeliver(t *testing.T) {
+	const secret = "webhook-secret"
+	payload := []byte(`{"tipo":"device_offline","identificador":"DEVICE001"}`)
+
+	var (
+		mu      sync.Mutex
+		status  = http.StatusOK
+		headers http.Header
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		headers = r.Header.Clone()
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		status       int
+		attempts     int // Intentos previos de la entrega
+		webhook      *models.Webhook
+		wantEstado   string
+		wantRetry    bool // Se espera un reintento programado según el backoff
+		wantAttempts int
+	}{
+		{name: "entregada", status: http.StatusOK, webhook: &models.Webhook{URL: server.URL, Secreto: secret}, wantEstado: models.DeliveryStatusDelivered, wantAttempts: 1},
+		{name: "error con reintento", status: http.StatusInternalServerError, attempts: 1, webhook: &models.Webhook{URL: server.URL, Secreto: secret}, wantEstado: models.DeliveryStatusPending, wantRetry: true, wantAttempts: 2},
+		{name: "último intento fallido", status: http.StatusBadGateway, attempts: 2, webhook: &models.Webhook{URL: server.URL, Secreto: secret}, wantEstado: models.DeliveryStatusFailed, wantAttempts: 3},
+		{name: "webhook eliminado", webhook: nil, wantEstado: models.DeliveryStatusFailed, wantAttempts: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			status, headers = tt.status, nil
+			mu.Unlock()
+
+			repo := &webhookRepository{}
+			s := NewWebhookService(repo, testWebhookConfig(), newTestLogger(t))
+			delivery := &models.WebhookDelivery{
+				IDEntrega: 42,
+				Tipo:      models.NotificationDeviceOffline,
+				Payload:   payload,
+				Estado:    models.DeliveryStatusPending,
+				Intentos:  tt.attempts,
+			}
+
+			before := time.Now()
+			s.deliver(delivery, tt.webhook)
+
+			if len(repo.updated) != 1 {
+				t.Fatalf("se registraron %d actualizaciones, se esperaba 1", len(repo.updated))
+			}
+			got := repo.updated[0]
+			if got.Estado != tt.wantEstado || got.Intentos != tt.wantAttempts {
+				t.Errorf("se obtuvo estado %q e intentos %d, se esperaba %q y %d", got.Estado, got.Intentos, tt.wantEstado, tt.wantAttempts)
+			}
+			if tt.wantRetry {
+				wait := got.ProximoIntento.Sub(before)
+				if want := s.backoff(tt.wantAttempts); wait < want || wait > want+time.Second {
+					t.Errorf("próximo intento en %s, se esperaba %s", wait, want)
+				}
+			}
+			if tt.wantEstado == models.DeliveryStatusDelivered && (got.FechaEntrega == nil || got.UltimoError != "") {
+				t.Errorf("entrega exitosa: se obtuvo %+v", got)
+			}
+			if tt.wantEstado != models.DeliveryStatusDelivered && got.UltimoError == "" {
+				t.Error("entrega fallida sin error registrado")
+			}
+			if tt.webhook == nil {
+				return
+			}
+
+			// La firma cubre timestamp, ID de entrega y cuerpo, como en las solicitudes de dispositivos
+			mu.Lock()
+			defer mu.Unlock()
+			if headers.Get(headerWebhookEvent) != models.NotificationDeviceOffline || headers.Get(headerWebhookDelivery) != "42" {
+				t.Errorf("headers: se obtuvo %v", headers)
+			}
+			timestamp, err := strconv.ParseInt(headers.Get(headerWebhookTimestamp), 10, 64)
+			if err != nil {
+				t.Fatalf("timestamp inválido: %v", err)
+			}
+			want := "sha256=" + hex.EncodeToString(ComputeSignature(secret, timestamp, "42", payload))
+			if got := headers.Get(headerWebhookSignature); got != want {
+				t.Errorf("firma: se obtuvo %q, se esperaba %q", got, want)
+			}
+		})
+	}
+}
+
+func TestWebhookDisabled(t *testing.T) {
+	cfg := testWebhookConfig()
+	cfg.Enabled = false
+	repo := &webhookRepository{webhooks: []models.Webhook{{IDWebhook: 1, Activo: true}}}
+
+	NewWebhookService(repo, cfg, newTestLogger(t)).Notify(context.Background(), &models.Notification{Tipo: models.NotificationUnknownDevice})
+	if len(repo.deliveries) != 0 {
+		t.Errorf("con webhooks deshabilitados se encolaron %d entregas", len(repo.deliveries))
+	}
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/logger"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/metrics"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// writerFlushTimeout es el tiempo máximo de escritura de cada lote
+const writerFlushTimeout = 30 * time.Second
+
+// writeRequest es una medición pendiente de escritura, el canal de su resultado y
+// el contexto de traza de quien la envió
+type writeRequest struct {
+	measurement *models.Measurement
+	result      chan error
+	spanContext trace.SpanContext
+}
+
+// WriterStats contiene las métricas de la escritura por lotes de mediciones
+type WriterStats struct {
+	Enabled            bool    `json:"habilitado"`
+	Lotes              int64   `json:"lotes"`
+	Mediciones         int64   `json:"mediciones"`
+	Errores            int64   `json:"errores"`
+	TamanoPromedio     float64 `json:"tamanoPromedio"`
+	TamanoMaximo       int     `json:"tamanoMaximo"`
+	LatenciaUltimaMs   float64 `json:"latenciaUltimaMs"`
+	LatenciaPromedioMs float64 `json:"latenciaPromedioMs"`
+}
+
+// MeasurementWriter agrupa las mediciones de llamadas concurrentes en lotes que
+// se escriben con un único INSERT de múltiples filas. Un lote se escribe al
+// alcanzar BatchSize mediciones o FlushInterval desde la primera en llegar, y
+// cada llamada recibe el resultado de su propia medición
+type MeasurementWriter struct {
+	repo   database.Repository
+	config *config.WriterConfig
+	logger *logger.Logger
+
+	requests chan *writeRequest
+
+	mu     sync.RWMutex
+	closed bool
+
+	statsMu      sync.Mutex
+	batches      int64
+	written      int64
+	failed       int64
+	maxBatch     int
+	lastLatency  time.Duration
+	totalLatency time.Duration
+
+	wg sync.WaitGroup
+}
+
+// NewMeasurementWriter crea un nuevo escritor de mediciones por lotes
+func NewMeasurementWriter(repo database.Repository, cfg *config.WriterConfig, log *logger.Logger) *MeasurementWriter {
+	return &MeasurementWriter{
+		repo:     repo,
+		config:   cfg,
+		logger:   log,
+		requests: make(chan *writeRequest, cfg.BatchSize),
+	}
+}
+
+// Start inicia la escritura de lotes en segundo plano
+func (w *MeasurementWriter) Start() {
+	w.wg.Add(1)
+	go w.run()
+
+	w.logger.Info("Escritura por lotes de mediciones iniciada (lote: %d, intervalo: %v)", w.config.BatchSize, w.config.FlushInterval)
+}
+
+// Stop deja de aceptar mediciones, escribe el lote pendiente y espera a que termine
+func (w *MeasurementWriter) Stop() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.requests)
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	w.logger.Info("Escritura por lotes de mediciones detenida")
+}
+
+// Write agrega una medición al lote en curso y espera a que se escriba,
+// retornando el resultado de esa medición. Una vez encolada se espera su
+// resultado aunque se cancele ctx, para no informar como fallida una medición
+// que igualmente se almacena. Detenido el escritor, la medición se inserta directamente
+func (w *MeasurementWriter) Write(ctx context.Context, measurement *models.Measurement) error {
+	req := &writeRequest{
+		measurement: measurement,
+		result:      make(chan error, 1),
+		spanContext: trace.SpanContextFromContext(ctx),
+	}
+
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return w.repo.InsertMeasurement(ctx, measurement)
+	}
+
+	select {
+	case w.requests <- req:
+	case <-ctx.Done():
+		w.mu.RUnlock()
+		return ctx.Err()
+	}
+	w.mu.RUnlock()
+
+	return <-req.result
+}
+
+// Stats retorna las métricas acumuladas de los lotes escritos
+func (w *MeasurementWriter) Stats() WriterStats {
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+
+	stats := WriterStats{
+		Enabled:          true,
+		Lotes:            w.batches,
+		Mediciones:       w.written,
+		Errores:          w.failed,
+		TamanoMaximo:     w.maxBatch,
+		LatenciaUltimaMs: milliseconds(w.lastLatency),
+	}
+	if w.batches > 0 {
+		stats.TamanoPromedio = float64(w.written+w.failed) / float64(w.batches)
+		stats.LatenciaPromedioMs = milliseconds(w.totalLatency) / float64(w.batches)
+	}
+	return stats
+}
+
+// run acumula mediciones y escribe cada lote por tamaño o por tiempo
+func (w *MeasurementWriter) run() {
+	defer w.wg.Done()
+
+	batch := make([]*writeRequest, 0, w.config.BatchSize)
+	var deadline <-chan time.Time
+
+	for {
+		select {
+		case req, ok := <-w.requests:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+
+			batch = append(batch, req)
+			if len(batch) == 1 {
+				deadline = time.After(w.config.FlushInterval)
+			}
+			if len(batch) < w.config.BatchSize {
+				continue
+			}
+		case <-deadline:
+		}
+
+		w.flush(batch)
+		batch = batch[:0]
+		deadline = nil
+	}
+}
+
+// flush escribe un lote y entrega a cada llamada su resultado. Si el lote falla
+// se reintenta medición por medición, de modo que una medición inválida no
+// haga fallar al resto y cada llamada reciba su propio error
+func (w *MeasurementWriter) flush(batch []*writeRequest) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writerFlushTimeout)
+	defer cancel()
+
+	// El lote es una traza propia enlazada a la de cada medición que contiene
+	measurements := make([]*models.Measurement, len(batch))
+	links := make([]trace.Link, 0, len(batch))
+	for i, req := range batch {
+		measurements[i] = req.measurement
+		if req.spanContext.IsValid() {
+			links = append(links, trace.Link{SpanContext: req.spanContext})
+		}
+	}
+
+	ctx, span := tracing.Start(ctx, "MeasurementWriter.flush",
+		trace.WithLinks(links...),
+		trace.WithAttributes(attribute.Int("telemetria.lote.tamano", len(batch))),
+	)
+	defer span.End()
+
+	start := time.Now()
+	err := w.repo.InsertMeasurements(ctx, measurements)
+	if err == nil {
+		w.record(len(batch), 0, time.Since(start))
+		for _, req := range batch {
+			req.result <- nil
+		}
+		return
+	}
+
+	if len(batch) > 1 {
+		w.logger.Warning("Error al escribir lote de %d mediciones, reintentando individualmente: %v", len(batch), err)
+	}
+
+	failed := 0
+	for _, req := range batch {
+		if len(batch) > 1 {
+			err = w.repo.InsertMeasurement(ctx, req.measurement)
+		}
+		if err != nil {
+			failed++
+		}
+		req.result <- err
+	}
+	span.SetAttributes(attribute.Int("telemetria.lote.errores", failed))
+	w.record(len(batch), failed, time.Since(start))
+}
+
+// record acumula las métricas de un lote escrito y las publica en Prometheus
+func (w *MeasurementWriter) record(size, failed int, latency time.Duration) {
+	metrics.WriterBatchSize.Observe(float64(size))
+	metrics.WriterFlushDuration.Observe(latency.Seconds())
+
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+
+	w.batches++
+	w.written += int64(size - failed)
+	w.failed += int64(failed)
+	w.maxBatch = max(w.maxBatch, size)
+	w.lastLatency = latency
+	w.totalLatency += latency
+}
+
+// milliseconds convierte una duración a milisegundos con decimales
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// WriterStats retorna las métricas de la escritura por lotes de mediciones
+func (s *TelemetryService) WriterStats() WriterStats {
+	if s.writer == nil {
+		return WriterStats{}
+	}
+	return s.writer.Stats()
+}
+
+// insertMeasurement almacena una medición a través del escritor por lotes si
+// está habilitado, o directamente en el repositorio en otro caso
+func (s *TelemetryService) insertMeasurement(ctx context.Context, measurement *models.Measurement) error {
+	if s.writer == nil {
+		return s.repo.InsertMeasurement(ctx, measurement)
+	}
+	return s.writer.Write(ctx, measurement)
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/database"
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/models"
+)
+
+var errInvalidMeasurement = errors.New("medición rechazada por la base de datos")
+
+// batchRepository simula una base de datos cuyo INSERT de múltiples filas falla
+// si el lote contiene alguna medición del dispositivo inválido
+type batchRepository struct {
+	database.Repository
+
+	mu      sync.Mutex
+	batches int
+	singles int
+	stored  []uint
+}
+
+// invalidDevice es el dispositivo cuyas mediciones rechaza batchRepository
+const invalidDevice = 99
+
+func (r *batchRepository) InsertMeasurements(ctx context.Context, measurements []*models.Measurement) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.batches++
+	for _, m := range measurements {
+		if m.IDTelemetria == invalidDevice {
+			return errInvalidMeasurement
+		}
+	}
+	for _, m := range measurements {
+		r.stored = append(r.stored, m.IDTelemetria)
+	}
+	return nil
+}
+
+func (r *batchRepository) InsertMeasurement(ctx context.Context, measurement *models.Measurement) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.singles++
+	if measurement.IDTelemetria == invalidDevice {
+		return errInvalidMeasurement
+	}
+	r.stored = append(r.stored, measurement.IDTelemetria)
+	return nil
+}
+
+// writeConcurrently escribe una medición por dispositivo en paralelo y retorna el resultado de cada una
+func writeConcurrently(w *MeasurementWriter, devices []uint) []error {
+	results := make([]error, len(devices))
+	var wg sync.WaitGroup
+	for i, device := range devices {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = w.Write(context.Background(), &models.Measurement{IDTelemetria: device, Fecha: time.Now()})
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func TestMeasurementWriterBatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		devices     []uint
+		wantSingles int
+		wantStored  int
+		wantFailed  int64
+	}{
+		{name: "lote válido en un solo INSERT", devices: []uint{1, 2, 3}, wantSingles: 0, wantStored: 3, wantFailed: 0},
+		{name: "lote con una medición inválida se reintenta una a una", devices: []uint{1, invalidDevice, 3}, wantSingles: 3, wantStored: 2, wantFailed: 1},
+		{name: "medición única inválida no se reintenta", devices: []uint{invalidDevice}, wantSingles: 0, wantStored: 0, wantFailed: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &batchRepository{}
+			// El intervalo largo obliga a escribir el lote al completarse
+			w := NewMeasurementWriter(repo, &config.WriterConfig{BatchSize: len(tt.devices), FlushInterval: time.Minute}, newTestLogger(t))
+			w.Start()
+			defer w.Stop()
+
+			results := writeConcurrently(w, tt.devices)
+
+			// Cada medición recibe su propio resultado
+			for i, device := range tt.devices {
+				wantErr := device == invalidDevice
+				if (results[i] != nil) != wantErr {
+					t.Errorf("dispositivo %d: error = %v, se esperaba error: %v", device, results[i], wantErr)
+				}
+			}
+
+			if repo.batches != 1 {
+				t.Errorf("INSERT de múltiples filas: se obtuvo %d, se esperaba 1", repo.batches)
+			}
+			if repo.singles != tt.wantSingles {
+				t.Errorf("INSERT individuales: se obtuvo %d, se esperaba %d", repo.singles, tt.wantSingles)
+			}
+			if len(repo.stored) != tt.wantStored {
+				t.Errorf("mediciones almacenadas: se obtuvo %v, se esperaban %d", repo.stored, tt.wantStored)
+			}
+
+			stats := w.Stats()
+			if stats.Lotes != 1 || stats.Errores != tt.wantFailed || stats.Mediciones != int64(tt.wantStored) {
+				t.Errorf("métricas: se obtuvo %+v", stats)
+			}
+		})
+	}
+}
+
+func TestMeasurementWriterFlushInterval(t *testing.T) {
+	repo := &batchRepository{}
+	w := NewMeasurementWriter(repo, &config.WriterConfig{BatchSize: 100, FlushInterval: 10 * time.Millisecond}, newTestLogger(t))
+	w.Start()
+	defer w.Stop()
+
+	// Un lote incompleto se escribe al cumplirse el intervalo
+	for i, err := range writeConcurrently(w, []uint{1, 2}) {
+		if err != nil {
+			t.Errorf("medición %d: error inesperado %v", i, err)
+		}
+	}
+	if len(repo.stored) != 2 {
+		t.Errorf("mediciones almacenadas: se obtuvo %v, se esperaban 2", repo.stored)
+	}
+}
+
+func TestMeasurementWriterStopped(t *testing.T) {
+	repo := &batchRepository{}
+	w := NewMeasurementWriter(repo, &config.WriterConfig{BatchSize: 10, FlushInterval: time.Minute}, newTestLogger(t))
+	w.Start()
+	w.Stop()
+
+	// Detenido el escritor, la medición se inserta directamente
+	if err := w.Write(context.Background(), &models.Measurement{IDTelemetria: 1}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if repo.singles != 1 || repo.batches != 0 {
+		t.Errorf("se obtuvieron %d INSERT individuales y %d de múltiples filas, se esperaba 1 y 0", repo.singles, repo.batches)
+	}
+}
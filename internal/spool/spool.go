@@ -0,0 +1,467 @@
+package spool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+)
+
+// Formato de cada registro en un segmento: longitud (4 bytes) | CRC32 de los datos (4 bytes) | datos
+const headerSize = 8
+
+// maxRecordSize es el tamaño máximo de un registro; uno mayor indica un segmento corrupto
+const maxRecordSize = 16 << 20
+
+// Nombres de archivos del spool
+const (
+	segmentExt     = ".spool"
+	checkpointFile = "checkpoint"
+)
+
+// Formato del checkpoint: segmento (8 bytes) | posición (8 bytes) | CRC32 de los 16 bytes anteriores
+const checkpointSize = 20
+
+// ErrFull indica que el spool alcanzó su tamaño máximo
+var ErrFull = errors.New("spool lleno")
+
+// Spool es una cola persistente en disco (write-ahead) de registros opacos.
+// Los registros se agregan al final del segmento más reciente y se consumen en
+// orden desde el más antiguo; la posición de lectura se guarda en un checkpoint,
+// por lo que la cola sobrevive a reinicios del proceso. Tras un corte de energía
+// un registro ya consumido puede entregarse nuevamente (al menos una vez): el
+// checkpoint solo se sincroniza a disco con SPOOL_SYNC, y perder su última
+// actualización, o encontrarlo corrupto, solo retrocede la lectura, nunca la adelanta
+type Spool struct {
+	dir         string
+	segmentSize int64
+	maxSize     int64
+	sync        bool
+
+	mu         sync.Mutex
+	segments   []segment // El primero se lee, el último se escribe
+	writer     *os.File
+	reader     *os.File
+	readOffset int64
+	pending    int
+	checkpoint *os.File
+}
+
+// segment es un archivo del spool y su tamaño válido
+type segment struct {
+	id   uint64
+	size int64
+}
+
+// Open abre (o crea) el spool en el directorio configurado y recupera los
+// registros pendientes. Un registro incompleto al final de un segmento (escritura
+// interrumpida) se descarta
+func Open(cfg *config.SpoolConfig) (*Spool, error) {
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("error al crear directorio del spool: %w", err)
+	}
+
+	s := &Spool{
+		dir:         cfg.Dir,
+		segmentSize: int64(cfg.SegmentSizeMB) << 20,
+		maxSize:     int64(cfg.MaxSizeMB) << 20,
+		sync:        cfg.Sync,
+	}
+
+	if err := s.recover(); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Append agrega un registro al final del spool
+func (s *Spool) Append(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.writer == nil {
+		return fmt.Errorf("spool cerrado")
+	}
+
+	recordSize := int64(headerSize + len(data))
+	if s.maxSize > 0 && s.size()+recordSize > s.maxSize {
+		return ErrFull
+	}
+
+	last := &s.segments[len(s.segments)-1]
+	if last.size > 0 && last.size+recordSize > s.segmentSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+		last = &s.segments[len(s.segments)-1]
+	}
+
+	record := make([]byte, recordSize)
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(data))
+	copy(record[headerSize:], data)
+
+	if _, err := s.writer.Write(record); err != nil {
+		// Descartar una escritura parcial para no dejar un registro corrupto
+		s.writer.Truncate(last.size)
+		return fmt.Errorf("error al escribir en el spool: %w", err)
+	}
+	if s.sync {
+		if err := s.writer.Sync(); err != nil {
+			return fmt.Errorf("error al sincronizar el spool: %w", err)
+		}
+	}
+
+	last.size += recordSize
+	s.pending++
+
+	return nil
+}
+
+// Peek retorna el registro pendiente más antiguo sin consumirlo, o nil si el spool está vacío
+func (s *Spool) Peek() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending == 0 {
+		return nil, nil
+	}
+
+	// Saltar segmentos ya leídos por completo
+	for s.readOffset >= s.segments[0].size && len(s.segments) > 1 {
+		if err := s.removeFirst(); err != nil {
+			return nil, err
+		}
+	}
+
+	data, _, err := s.readRecord()
+	return data, err
+}
+
+// Ack consume el registro pendiente más antiguo (el último retornado por Peek)
+func (s *Spool) Ack() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending == 0 {
+		return nil
+	}
+
+	_, recordSize, err := s.readRecord()
+	if err != nil {
+		return err
+	}
+
+	s.readOffset += recordSize
+	s.pending--
+
+	if s.readOffset >= s.segments[0].size {
+		if len(s.segments) > 1 {
+			if err := s.removeFirst(); err != nil {
+				return err
+			}
+		} else if s.pending == 0 {
+			// Spool vacío: reutilizar el segmento desde el inicio
+			if err := s.writer.Truncate(0); err != nil {
+				return fmt.Errorf("error al vaciar el spool: %w", err)
+			}
+			s.segments[0].size = 0
+			s.readOffset = 0
+		}
+	}
+
+	return s.saveCheckpoint()
+}
+
+// Depth retorna la cantidad de registros pendientes
+func (s *Spool) Depth() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
+
+// Size retorna el tamaño en bytes de los registros pendientes
+func (s *Spool) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size()
+}
+
+// Close cierra los archivos del spool; los registros pendientes se conservan en disco
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for _, f := range []*os.File{s.writer, s.reader, s.checkpoint} {
+		if f == nil {
+			continue
+		}
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.writer, s.reader, s.checkpoint = nil, nil, nil
+
+	return firstErr
+}
+
+// recover carga los segmentos existentes, descarta los ya consumidos según el
+// checkpoint y cuenta los registros pendientes
+func (s *Spool) recover() error {
+	ids, err := s.listSegments()
+	if err != nil {
+		return err
+	}
+
+	s.checkpoint, err = os.OpenFile(filepath.Join(s.dir, checkpointFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("error al abrir checkpoint del spool: %w", err)
+	}
+
+	checkpointID, checkpointOffset, err := s.loadCheckpoint()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		// Segmentos anteriores al checkpoint ya fueron consumidos
+		if id < checkpointID {
+			if err := os.Remove(s.segmentPath(id)); err != nil {
+				return fmt.Errorf("error al eliminar segmento consumido del spool: %w", err)
+			}
+			continue
+		}
+
+		var from int64
+		if len(s.segments) == 0 && id == checkpointID {
+			from = checkpointOffset
+		}
+
+		size, count, err := s.scanSegment(id, from)
+		if err != nil {
+			return err
+		}
+		if len(s.segments) == 0 {
+			s.readOffset = min(from, size)
+		}
+
+		s.segments = append(s.segments, segment{id: id, size: size})
+		s.pending += count
+	}
+
+	// Segmento de escritura
+	if len(s.segments) == 0 {
+		s.segments = append(s.segments, segment{id: checkpointID + 1})
+	}
+	last := s.segments[len(s.segments)-1]
+	s.writer, err = os.OpenFile(s.segmentPath(last.id), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error al abrir segmento del spool: %w", err)
+	}
+
+	return nil
+}
+
+// scanSegment recorre un segmento desde la posición indicada y retorna el tamaño
+// válido y la cantidad de registros. Un final incompleto o corrupto se trunca
+func (s *Spool) scanSegment(id uint64, from int64) (int64, int, error) {
+	path := s.segmentPath(id)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error al abrir segmento del spool: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, fmt.Errorf("error al leer segmento del spool: %w", err)
+	}
+	if from > info.Size() {
+		from = info.Size()
+	}
+	if _, err := f.Seek(from, io.SeekStart); err != nil {
+		return 0, 0, fmt.Errorf("error al leer segmento del spool: %w", err)
+	}
+
+	r := bufio.NewReader(f)
+	offset := from
+	count := 0
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			break
+		}
+		length := binary.BigEndian.Uint32(header[0:4])
+		if length > maxRecordSize {
+			break
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:8]) {
+			break
+		}
+
+		offset += int64(headerSize) + int64(length)
+		count++
+	}
+
+	if offset < info.Size() {
+		if err := os.Truncate(path, offset); err != nil {
+			return 0, 0, fmt.Errorf("error al truncar segmento corrupto del spool: %w", err)
+		}
+	}
+
+	return offset, count, nil
+}
+
+// readRecord lee el registro en la posición de lectura actual y retorna sus datos
+// y su tamaño total en el segmento
+func (s *Spool) readRecord() ([]byte, int64, error) {
+	if s.reader == nil {
+		reader, err := os.Open(s.segmentPath(s.segments[0].id))
+		if err != nil {
+			return nil, 0, fmt.Errorf("error al abrir segmento del spool: %w", err)
+		}
+		s.reader = reader
+	}
+
+	header := make([]byte, headerSize)
+	if _, err := s.reader.ReadAt(header, s.readOffset); err != nil {
+		return nil, 0, fmt.Errorf("error al leer registro del spool: %w", err)
+	}
+
+	data := make([]byte, binary.BigEndian.Uint32(header[0:4]))
+	if _, err := s.reader.ReadAt(data, s.readOffset+headerSize); err != nil {
+		return nil, 0, fmt.Errorf("error al leer registro del spool: %w", err)
+	}
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, fmt.Errorf("registro del spool corrupto en segmento %d, posición %d", s.segments[0].id, s.readOffset)
+	}
+
+	return data, int64(headerSize + len(data)), nil
+}
+
+// rotate cierra el segmento de escritura y crea uno nuevo
+func (s *Spool) rotate() error {
+	if err := s.writer.Sync(); err != nil {
+		return fmt.Errorf("error al sincronizar el spool: %w", err)
+	}
+	if err := s.writer.Close(); err != nil {
+		return fmt.Errorf("error al cerrar segmento del spool: %w", err)
+	}
+
+	id := s.segments[len(s.segments)-1].id + 1
+	writer, err := os.OpenFile(s.segmentPath(id), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		s.writer = nil
+		return fmt.Errorf("error al crear segmento del spool: %w", err)
+	}
+
+	s.writer = writer
+	s.segments = append(s.segments, segment{id: id})
+	return nil
+}
+
+// removeFirst elimina el segmento de lectura, ya consumido, y avanza al siguiente
+func (s *Spool) removeFirst() error {
+	if s.reader != nil {
+		s.reader.Close()
+		s.reader = nil
+	}
+
+	if err := os.Remove(s.segmentPath(s.segments[0].id)); err != nil {
+		return fmt.Errorf("error al eliminar segmento consumido del spool: %w", err)
+	}
+
+	s.segments = s.segments[1:]
+	s.readOffset = 0
+	return s.saveCheckpoint()
+}
+
+// loadCheckpoint lee el segmento y la posición de lectura guardados. Un
+// checkpoint ausente, incompleto o corrupto se ignora y la lectura comienza en el
+// primer segmento, ya que una posición inválida podría truncar registros pendientes
+func (s *Spool) loadCheckpoint() (uint64, int64, error) {
+	buf := make([]byte, checkpointSize)
+	n, err := s.checkpoint.ReadAt(buf, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, 0, fmt.Errorf("error al leer checkpoint del spool: %w", err)
+	}
+	if n < len(buf) || crc32.ChecksumIEEE(buf[0:16]) != binary.BigEndian.Uint32(buf[16:20]) {
+		return 0, 0, nil
+	}
+
+	return binary.BigEndian.Uint64(buf[0:8]), int64(binary.BigEndian.Uint64(buf[8:16])), nil
+}
+
+// saveCheckpoint guarda el segmento y la posición de lectura actuales
+func (s *Spool) saveCheckpoint() error {
+	buf := make([]byte, checkpointSize)
+	binary.BigEndian.PutUint64(buf[0:8], s.segments[0].id)
+	binary.BigEndian.PutUint64(buf[8:16], uint64(s.readOffset))
+	binary.BigEndian.PutUint32(buf[16:20], crc32.ChecksumIEEE(buf[0:16]))
+
+	if _, err := s.checkpoint.WriteAt(buf, 0); err != nil {
+		return fmt.Errorf("error al guardar checkpoint del spool: %w", err)
+	}
+	if s.sync {
+		if err := s.checkpoint.Sync(); err != nil {
+			return fmt.Errorf("error al sincronizar checkpoint del spool: %w", err)
+		}
+	}
+	return nil
+}
+
+// listSegments retorna los identificadores de los segmentos existentes en orden
+func (s *Spool) listSegments() ([]uint64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer directorio del spool: %w", err)
+	}
+
+	var ids []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// segmentPath retorna la ruta del archivo de un segmento
+func (s *Spool) segmentPath(id uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", id, segmentExt))
+}
+
+// size retorna el tamaño de los registros pendientes. Debe llamarse con el mutex tomado
+func (s *Spool) size() int64 {
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+	return total - s.readOffset
+}
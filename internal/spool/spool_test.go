@@ -0,0 +1,294 @@
+package spool
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tenshi98/telemetria-endpoint-GOLANG/internal/config"
+)
+
+// openTestSpool abre un spool en el directorio indicado y lo cierra al terminar la prueba
+func openTestSpool(t *testing.T, dir string, segmentSize int64) *Spool {
+	t.Helper()
+
+	s, err := Open(&config.SpoolConfig{Dir: dir, SegmentSizeMB: 1})
+	if err != nil {
+		t.Fatalf("error al abrir spool: %v", err)
+	}
+	if segmentSize > 0 {
+		s.segmentSize = segmentSize
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// appendRecords agrega los registros "registro-<i>" para i en [from, to)
+func appendRecords(t *testing.T, s *Spool, from, to int) {
+	t.Helper()
+
+	for i := from; i < to; i++ {
+		if err := s.Append([]byte(fmt.Sprintf("registro-%d", i))); err != nil {
+			t.Fatalf("error al agregar registro %d: %v", i, err)
+		}
+	}
+}
+
+// drain consume todos los registros pendientes y los retorna en orden
+func drain(t *testing.T, s *Spool) []string {
+	t.Helper()
+
+	var records []string
+	for {
+		data, err := s.Peek()
+		if err != nil {
+			t.Fatalf("error al leer registro: %v", err)
+		}
+		if data == nil {
+			return records
+		}
+		records = append(records, string(data))
+		if err := s.Ack(); err != nil {
+			t.Fatalf("error al confirmar registro: %v", err)
+		}
+	}
+}
+
+// expectRecords verifica que los registros sean "registro-<i>" para i en [from, to)
+func expectRecords(t *testing.T, got []string, from, to int) {
+	t.Helper()
+
+	if len(got) != to-from {
+		t.Fatalf("se obtuvieron %d registros, se esperaban %d: %v", len(got), to-from, got)
+	}
+	for i, record := range got {
+		if want := fmt.Sprintf("registro-%d", from+i); record != want {
+			t.Errorf("registro %d: se obtuvo %q, se esperaba %q", i, record, want)
+		}
+	}
+}
+
+// segmentFiles retorna las rutas de los segmentos del directorio en orden
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		t.Fatalf("error al listar segmentos: %v", err)
+	}
+	return files
+}
+
+func TestRecordFormat(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestSpool(t, dir, 0)
+
+	data := []byte("medición")
+	if err := s.Append(data); err != nil {
+		t.Fatalf("error al agregar registro: %v", err)
+	}
+	s.Close()
+
+	files := segmentFiles(t, dir)
+	if len(files) != 1 {
+		t.Fatalf("se obtuvieron %d segmentos, se esperaba 1", len(files))
+	}
+	raw, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("error al leer segmento: %v", err)
+	}
+
+	if len(raw) != headerSize+len(data) {
+		t.Fatalf("tamaño del registro: se obtuvo %d, se esperaba %d", len(raw), headerSize+len(data))
+	}
+	if got := binary.BigEndian.Uint32(raw[0:4]); got != uint32(len(data)) {
+		t.Errorf("largo: se obtuvo %d, se esperaba %d", got, len(data))
+	}
+	if got := binary.BigEndian.Uint32(raw[4:8]); got != crc32.ChecksumIEEE(data) {
+		t.Errorf("CRC32: se obtuvo %08x, se esperaba %08x", got, crc32.ChecksumIEEE(data))
+	}
+	if got := string(raw[headerSize:]); got != string(data) {
+		t.Errorf("datos: se obtuvo %q, se esperaba %q", got, data)
+	}
+}
+
+func TestReplayOrderAcrossSegments(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestSpool(t, dir, 64)
+
+	appendRecords(t, s, 0, 20)
+	if n := len(segmentFiles(t, dir)); n < 2 {
+		t.Fatalf("se obtuvieron %d segmentos, se esperaba rotación", n)
+	}
+	if s.Depth() != 20 {
+		t.Fatalf("pendientes: se obtuvo %d, se esperaba 20", s.Depth())
+	}
+
+	expectRecords(t, drain(t, s), 0, 20)
+
+	// Los segmentos consumidos se eliminan y el último se reutiliza
+	if n := len(segmentFiles(t, dir)); n != 1 {
+		t.Errorf("segmentos tras vaciar el spool: se obtuvo %d, se esperaba 1", n)
+	}
+	if s.Size() != 0 {
+		t.Errorf("tamaño tras vaciar el spool: se obtuvo %d, se esperaba 0", s.Size())
+	}
+}
+
+func TestReopenResumesFromCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestSpool(t, dir, 64)
+
+	appendRecords(t, s, 0, 10)
+	for i := 0; i < 4; i++ {
+		if _, err := s.Peek(); err != nil {
+			t.Fatalf("error al leer registro: %v", err)
+		}
+		if err := s.Ack(); err != nil {
+			t.Fatalf("error al confirmar registro: %v", err)
+		}
+	}
+	s.Close()
+
+	s = openTestSpool(t, dir, 64)
+	if s.Depth() != 6 {
+		t.Fatalf("pendientes tras reabrir: se obtuvo %d, se esperaba 6", s.Depth())
+	}
+
+	// Los registros nuevos quedan detrás de los pendientes
+	appendRecords(t, s, 10, 12)
+	expectRecords(t, drain(t, s), 4, 12)
+}
+
+func TestReopenDropsTruncatedLastRecord(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestSpool(t, dir, 0)
+	appendRecords(t, s, 0, 3)
+	s.Close()
+
+	// Simular una escritura interrumpida del último registro
+	path := segmentFiles(t, dir)[0]
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("error al leer segmento: %v", err)
+	}
+	if err := os.Truncate(path, info.Size()-3); err != nil {
+		t.Fatalf("error al truncar segmento: %v", err)
+	}
+
+	s = openTestSpool(t, dir, 0)
+	if s.Depth() != 2 {
+		t.Fatalf("pendientes tras reabrir: se obtuvo %d, se esperaba 2", s.Depth())
+	}
+
+	// El segmento se trunca al último registro válido y admite nuevas escrituras
+	appendRecords(t, s, 3, 4)
+	got := drain(t, s)
+	want := []string{"registro-0", "registro-1", "registro-3"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("se obtuvo %v, se esperaba %v", got, want)
+	}
+}
+
+func TestReopenDropsCorruptTail(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(raw []byte, last int) []byte
+	}{
+		{
+			name: "CRC inválido",
+			corrupt: func(raw []byte, last int) []byte {
+				raw[len(raw)-1] ^= 0xff
+				return raw
+			},
+		},
+		{
+			name: "largo fuera de rango",
+			corrupt: func(raw []byte, last int) []byte {
+				binary.BigEndian.PutUint32(raw[last:last+4], maxRecordSize+1)
+				return raw
+			},
+		},
+		{
+			name: "cabecera incompleta",
+			corrupt: func(raw []byte, last int) []byte {
+				return raw[:last+headerSize/2]
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := openTestSpool(t, dir, 0)
+			appendRecords(t, s, 0, 3)
+			last := s.Size() - int64(headerSize+len("registro-2"))
+			s.Close()
+
+			path := segmentFiles(t, dir)[0]
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("error al leer segmento: %v", err)
+			}
+			if err := os.WriteFile(path, tt.corrupt(raw, int(last)), 0644); err != nil {
+				t.Fatalf("error al escribir segmento: %v", err)
+			}
+
+			s = openTestSpool(t, dir, 0)
+			if s.Size() != last {
+				t.Errorf("tamaño tras reabrir: se obtuvo %d, se esperaba %d", s.Size(), last)
+			}
+			expectRecords(t, drain(t, s), 0, 2)
+		})
+	}
+}
+
+func TestCorruptCheckpointReplaysFromStart(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestSpool(t, dir, 0)
+	appendRecords(t, s, 0, 5)
+	for i := 0; i < 3; i++ {
+		s.Peek()
+		if err := s.Ack(); err != nil {
+			t.Fatalf("error al confirmar registro: %v", err)
+		}
+	}
+	s.Close()
+
+	// Un checkpoint dañado no debe adelantar la lectura ni truncar registros pendientes
+	path := filepath.Join(dir, checkpointFile)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error al leer checkpoint: %v", err)
+	}
+	raw[15] ^= 0x01
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		t.Fatalf("error al escribir checkpoint: %v", err)
+	}
+
+	s = openTestSpool(t, dir, 0)
+	expectRecords(t, drain(t, s), 0, 5)
+}
+
+func TestAppendFull(t *testing.T) {
+	s, err := Open(&config.SpoolConfig{Dir: t.TempDir(), SegmentSizeMB: 1, MaxSizeMB: 1})
+	if err != nil {
+		t.Fatalf("error al abrir spool: %v", err)
+	}
+	defer s.Close()
+
+	data := make([]byte, 600<<10)
+	if err := s.Append(data); err != nil {
+		t.Fatalf("error al agregar registro: %v", err)
+	}
+	if err := s.Append(data); !errors.Is(err, ErrFull) {
+		t.Fatalf("se obtuvo %v, se esperaba %v", err, ErrFull)
+	}
+	if s.Depth() != 1 {
+		t.Errorf("pendientes: se obtuvo %d, se esperaba 1", s.Depth())
+	}
+}
@@ -0,0 +1,16 @@
+-- ============================================================================
+-- Migración: fechas de dispositivo y de recepción en mediciones
+-- Motor: MySQL 5.7+
+-- Descripción: Fecha pasa a ser la fecha efectiva de la medición (la enviada
+--              por el dispositivo cuando es válida). Se agregan la fecha
+--              reportada por el dispositivo y la fecha de recepción.
+-- ============================================================================
+
+USE telemetria;
+
+ALTER TABLE equipos_telemetria_datos
+    ADD COLUMN FechaDispositivo TIMESTAMP NULL DEFAULT NULL AFTER Fecha,
+    ADD COLUMN FechaRecepcion TIMESTAMP DEFAULT CURRENT_TIMESTAMP AFTER FechaDispositivo;
+
+-- Las mediciones existentes fueron registradas con la hora de llegada
+UPDATE equipos_telemetria_datos SET FechaRecepcion = Fecha;
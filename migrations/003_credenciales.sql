@@ -0,0 +1,34 @@
+-- ============================================================================
+-- Migración: credenciales de autenticación por dispositivo
+-- Motor: MySQL 5.7+
+-- ============================================================================
+
+USE telemetria;
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_credenciales
+-- Descripción: Credenciales (API keys) de los dispositivos. Solo se almacena
+--              el hash SHA-256 del token en hexadecimal
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_credenciales (
+    idCredencial INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    idTelemetria INT UNSIGNED NOT NULL,
+    TokenHash CHAR(64) NOT NULL,
+    Descripcion VARCHAR(255),
+    Activo TINYINT(1) NOT NULL DEFAULT 1,
+    FechaCreacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FechaExpiracion TIMESTAMP NULL DEFAULT NULL,
+
+    PRIMARY KEY (idCredencial),
+    UNIQUE KEY uk_token_hash (TokenHash),
+    INDEX idx_telemetria_activo (idTelemetria, Activo),
+
+    CONSTRAINT fk_credenciales_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE equipos_telemetria_credenciales
+    COMMENT = 'Credenciales de autenticación de los dispositivos';
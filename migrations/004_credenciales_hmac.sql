@@ -0,0 +1,11 @@
+-- ============================================================================
+-- Migración: credenciales HMAC para solicitudes firmadas
+-- Motor: MySQL 5.7+
+-- ============================================================================
+
+USE telemetria;
+
+ALTER TABLE equipos_telemetria_credenciales
+    ADD COLUMN Tipo ENUM('token', 'hmac') NOT NULL DEFAULT 'token' AFTER idTelemetria,
+    MODIFY COLUMN TokenHash CHAR(64) NULL,
+    ADD COLUMN Secreto VARCHAR(255) NULL AFTER TokenHash;
@@ -0,0 +1,55 @@
+-- ============================================================================
+-- Migración: catálogo de sensores y sensores con nombre
+-- Motor: MySQL 5.7+
+-- ============================================================================
+
+USE telemetria;
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_sensores
+-- Descripción: Catálogo de canales de sensores por dispositivo. Slot asocia
+--              el sensor a una columna legacy Sensor_1..Sensor_5 (opcional)
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_sensores (
+    idSensor INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    idTelemetria INT UNSIGNED NOT NULL,
+    Nombre VARCHAR(64) NOT NULL,
+    Unidad VARCHAR(32),
+    TipoDato ENUM('float', 'int', 'bool') NOT NULL DEFAULT 'float',
+    Slot TINYINT UNSIGNED NULL,
+    Activo TINYINT(1) NOT NULL DEFAULT 1,
+
+    PRIMARY KEY (idSensor),
+    UNIQUE KEY uk_telemetria_nombre (idTelemetria, Nombre),
+
+    CONSTRAINT fk_sensores_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_datos_sensores
+-- Descripción: Valores de sensores con nombre de cada medición
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_datos_sensores (
+    idMedicion BIGINT UNSIGNED NOT NULL,
+    Nombre VARCHAR(64) NOT NULL,
+    Valor DOUBLE NOT NULL,
+
+    PRIMARY KEY (idMedicion, Nombre),
+    INDEX idx_nombre (Nombre),
+
+    CONSTRAINT fk_datos_sensores_medicion
+        FOREIGN KEY (idMedicion)
+        REFERENCES equipos_telemetria_datos(idMedicion)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE equipos_telemetria_sensores
+    COMMENT = 'Catálogo de canales de sensores por dispositivo';
+
+ALTER TABLE equipos_telemetria_datos_sensores
+    COMMENT = 'Valores de sensores con nombre de cada medición';
@@ -0,0 +1,9 @@
+-- ============================================================================
+-- Migración: habilitar/deshabilitar dispositivos
+-- Motor: MySQL 5.7+
+-- ============================================================================
+
+USE telemetria;
+
+ALTER TABLE equipos_telemetria
+    ADD COLUMN Activo TINYINT(1) NOT NULL DEFAULT 1 AFTER TiempoFueraLinea;
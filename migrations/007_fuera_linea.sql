@@ -0,0 +1,11 @@
+-- ============================================================================
+-- Migración: estado fuera de línea de dispositivos (watchdog)
+-- Motor: MySQL 5.7+
+-- ============================================================================
+
+USE telemetria;
+
+ALTER TABLE equipos_telemetria
+    ADD COLUMN FueraLinea TINYINT(1) NOT NULL DEFAULT 0 AFTER Activo,
+    ADD COLUMN FechaFueraLinea TIMESTAMP NULL DEFAULT NULL AFTER FueraLinea,
+    ADD INDEX idx_fuera_linea (FueraLinea, Activo);
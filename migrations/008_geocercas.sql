@@ -0,0 +1,93 @@
+-- ============================================================================
+-- Migración: grupos de dispositivos, geocercas y eventos
+-- Motor: MySQL 5.7+
+-- ============================================================================
+
+USE telemetria;
+
+CREATE TABLE IF NOT EXISTS grupos_telemetria (
+    idGrupo INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    Nombre VARCHAR(255) NOT NULL,
+
+    PRIMARY KEY (idGrupo)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE equipos_telemetria
+    ADD COLUMN idGrupo INT UNSIGNED NULL AFTER FechaFueraLinea,
+    ADD CONSTRAINT fk_telemetria_grupo
+        FOREIGN KEY (idGrupo)
+        REFERENCES grupos_telemetria(idGrupo)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE;
+
+CREATE TABLE IF NOT EXISTS geocercas (
+    idGeocerca INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    Nombre VARCHAR(255) NOT NULL,
+    Tipo ENUM('circle', 'polygon') NOT NULL,
+    Latitud DECIMAL(9, 6) NULL,
+    Longitud DECIMAL(9, 6) NULL,
+    Radio DOUBLE NULL,
+    Vertices TEXT NULL,
+    TiempoPermanencia INT UNSIGNED NOT NULL DEFAULT 0,
+    Activo TINYINT(1) NOT NULL DEFAULT 1,
+
+    PRIMARY KEY (idGeocerca)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS geocercas_asignaciones (
+    idAsignacion INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    idGeocerca INT UNSIGNED NOT NULL,
+    idTelemetria INT UNSIGNED NULL,
+    idGrupo INT UNSIGNED NULL,
+
+    PRIMARY KEY (idAsignacion),
+    UNIQUE KEY uk_geocerca_telemetria (idGeocerca, idTelemetria),
+    UNIQUE KEY uk_geocerca_grupo (idGeocerca, idGrupo),
+    INDEX idx_telemetria (idTelemetria),
+    INDEX idx_grupo (idGrupo),
+
+    CONSTRAINT fk_asignaciones_geocerca
+        FOREIGN KEY (idGeocerca)
+        REFERENCES geocercas(idGeocerca)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+
+    CONSTRAINT fk_asignaciones_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+
+    CONSTRAINT fk_asignaciones_grupo
+        FOREIGN KEY (idGrupo)
+        REFERENCES grupos_telemetria(idGrupo)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS equipos_telemetria_eventos (
+    idEvento BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    idTelemetria INT UNSIGNED NOT NULL,
+    idGeocerca INT UNSIGNED NULL,
+    Tipo VARCHAR(32) NOT NULL,
+    Fecha TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    Latitud DECIMAL(9, 6),
+    Longitud DECIMAL(9, 6),
+    Descripcion TEXT,
+
+    PRIMARY KEY (idEvento),
+    INDEX idx_telemetria_fecha (idTelemetria, Fecha),
+    INDEX idx_tipo (Tipo),
+
+    CONSTRAINT fk_eventos_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+
+    CONSTRAINT fk_eventos_geocerca
+        FOREIGN KEY (idGeocerca)
+        REFERENCES geocercas(idGeocerca)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
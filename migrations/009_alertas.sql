@@ -0,0 +1,64 @@
+-- ============================================================================
+-- Migración: reglas de alerta e historial de alertas
+-- Motor: MySQL 5.7+
+-- ============================================================================
+
+USE telemetria;
+
+CREATE TABLE IF NOT EXISTS alertas_reglas (
+    idRegla INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    Nombre VARCHAR(255) NOT NULL,
+    idTelemetria INT UNSIGNED NULL,
+    idGrupo INT UNSIGNED NULL,
+    Sensor VARCHAR(64) NOT NULL,
+    Operador ENUM('gt', 'lt', 'between', 'outside', 'rate') NOT NULL,
+    Umbral DOUBLE NOT NULL,
+    UmbralMax DOUBLE NULL,
+    Histeresis DOUBLE NOT NULL DEFAULT 0,
+    Duracion INT UNSIGNED NOT NULL DEFAULT 0,
+    Activo TINYINT(1) NOT NULL DEFAULT 1,
+
+    PRIMARY KEY (idRegla),
+    INDEX idx_telemetria (idTelemetria),
+    INDEX idx_grupo (idGrupo),
+
+    CONSTRAINT fk_reglas_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+
+    CONSTRAINT fk_reglas_grupo
+        FOREIGN KEY (idGrupo)
+        REFERENCES grupos_telemetria(idGrupo)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS equipos_telemetria_alertas (
+    idAlerta BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    idRegla INT UNSIGNED NOT NULL,
+    idTelemetria INT UNSIGNED NOT NULL,
+    Sensor VARCHAR(64) NOT NULL,
+    Valor DOUBLE NOT NULL,
+    FechaActivacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FechaResolucion TIMESTAMP NULL DEFAULT NULL,
+    ValorResolucion DOUBLE NULL,
+    Descripcion TEXT,
+
+    PRIMARY KEY (idAlerta),
+    INDEX idx_telemetria_fecha (idTelemetria, FechaActivacion),
+    INDEX idx_regla_abierta (idRegla, idTelemetria, FechaResolucion),
+
+    CONSTRAINT fk_alertas_regla
+        FOREIGN KEY (idRegla)
+        REFERENCES alertas_reglas(idRegla)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+
+    CONSTRAINT fk_alertas_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,50 @@
+-- ============================================================================
+-- Migración: webhooks salientes y cola de entregas
+-- Motor: MySQL 5.7+
+-- ============================================================================
+
+USE telemetria;
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    idWebhook INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    Nombre VARCHAR(255) NOT NULL,
+    URL VARCHAR(2048) NOT NULL,
+    Secreto VARCHAR(255) NOT NULL,
+    idGrupo INT UNSIGNED NULL,
+    Eventos TEXT NULL,
+    Activo TINYINT(1) NOT NULL DEFAULT 1,
+
+    PRIMARY KEY (idWebhook),
+
+    CONSTRAINT fk_webhooks_grupo
+        FOREIGN KEY (idGrupo)
+        REFERENCES grupos_telemetria(idGrupo)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS webhooks_entregas (
+    idEntrega BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    idWebhook INT UNSIGNED NOT NULL,
+    Tipo VARCHAR(32) NOT NULL,
+    Payload MEDIUMTEXT NOT NULL,
+    Estado ENUM('pending', 'delivered', 'failed') NOT NULL DEFAULT 'pending',
+    Intentos INT UNSIGNED NOT NULL DEFAULT 0,
+    ProximoIntento TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    Bloqueo CHAR(32) NULL,
+    UltimoError TEXT,
+    CodigoRespuesta SMALLINT UNSIGNED NULL,
+    FechaCreacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FechaEntrega TIMESTAMP NULL DEFAULT NULL,
+
+    PRIMARY KEY (idEntrega),
+    INDEX idx_estado_proximo (Estado, ProximoIntento),
+    INDEX idx_bloqueo (Bloqueo),
+    INDEX idx_webhook (idWebhook, idEntrega),
+
+    CONSTRAINT fk_entregas_webhook
+        FOREIGN KEY (idWebhook)
+        REFERENCES webhooks(idWebhook)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
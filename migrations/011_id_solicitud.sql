@@ -0,0 +1,10 @@
+-- ============================================================================
+-- Migración: ID de solicitud en los registros de error (correlación con logs)
+-- Motor: MySQL 5.7+
+-- ============================================================================
+
+USE telemetria;
+
+ALTER TABLE equipos_telemetria_errores
+    ADD COLUMN idSolicitud VARCHAR(128) NULL AFTER descripcion,
+    ADD INDEX idx_solicitud (idSolicitud);
@@ -0,0 +1,447 @@
+-- ============================================================================
+-- Schema de Base de Datos para Sistema de Telemetría
+-- Motor: PostgreSQL 12+ (compatible con TimescaleDB, ver timescale.sql)
+-- Descripción: Estructura completa para almacenar datos de telemetría,
+--              dispositivos y errores. Equivalente a migrations/schema.sql;
+--              los identificadores sin comillas se almacenan en minúsculas
+-- Uso: createdb telemetria && psql -d telemetria -f migrations/postgres/schema.sql
+-- ============================================================================
+
+-- ============================================================================
+-- Tabla: grupos_telemetria
+-- Descripción: Grupos de dispositivos (ej. flota o cliente)
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS grupos_telemetria (
+    idGrupo INTEGER GENERATED BY DEFAULT AS IDENTITY,
+    Nombre VARCHAR(255) NOT NULL,
+
+    PRIMARY KEY (idGrupo)
+);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria
+-- Descripción: Almacena información de los dispositivos de telemetría
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria (
+    idTelemetria INTEGER GENERATED BY DEFAULT AS IDENTITY,
+    Identificador VARCHAR(255) NOT NULL,
+    Nombre VARCHAR(255) NOT NULL,
+    UltimaConexion TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    TiempoFueraLinea TIME DEFAULT '00:00:00',
+    Activo BOOLEAN NOT NULL DEFAULT TRUE,
+    FueraLinea BOOLEAN NOT NULL DEFAULT FALSE,
+    FechaFueraLinea TIMESTAMPTZ NULL DEFAULT NULL,
+    idGrupo INTEGER NULL,
+
+    PRIMARY KEY (idTelemetria),
+    CONSTRAINT uk_identificador UNIQUE (Identificador),
+
+    CONSTRAINT fk_telemetria_grupo
+        FOREIGN KEY (idGrupo)
+        REFERENCES grupos_telemetria(idGrupo)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_telemetria_ultima_conexion ON equipos_telemetria (UltimaConexion);
+CREATE INDEX IF NOT EXISTS idx_telemetria_fuera_linea ON equipos_telemetria (FueraLinea, Activo);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_credenciales
+-- Descripción: Credenciales de los dispositivos. Tipo 'token' almacena solo
+--              el hash SHA-256 del token en hexadecimal; tipo 'hmac' almacena
+--              el secreto compartido para verificar firmas HMAC-SHA256
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_credenciales (
+    idCredencial INTEGER GENERATED BY DEFAULT AS IDENTITY,
+    idTelemetria INTEGER NOT NULL,
+    Tipo VARCHAR(8) NOT NULL DEFAULT 'token' CHECK (Tipo IN ('token', 'hmac')),
+    TokenHash CHAR(64) NULL,
+    Secreto VARCHAR(255) NULL,
+    Descripcion VARCHAR(255),
+    Activo BOOLEAN NOT NULL DEFAULT TRUE,
+    FechaCreacion TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FechaExpiracion TIMESTAMPTZ NULL DEFAULT NULL,
+
+    PRIMARY KEY (idCredencial),
+    CONSTRAINT uk_token_hash UNIQUE (TokenHash),
+
+    CONSTRAINT fk_credenciales_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_credenciales_telemetria_activo ON equipos_telemetria_credenciales (idTelemetria, Activo);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_sensores
+-- Descripción: Catálogo de canales de sensores por dispositivo. Slot asocia
+--              el sensor a una columna legacy Sensor_1..Sensor_5 (opcional)
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_sensores (
+    idSensor INTEGER GENERATED BY DEFAULT AS IDENTITY,
+    idTelemetria INTEGER NOT NULL,
+    Nombre VARCHAR(64) NOT NULL,
+    Unidad VARCHAR(32),
+    TipoDato VARCHAR(8) NOT NULL DEFAULT 'float' CHECK (TipoDato IN ('float', 'int', 'bool')),
+    Slot SMALLINT NULL,
+    Activo BOOLEAN NOT NULL DEFAULT TRUE,
+
+    PRIMARY KEY (idSensor),
+    CONSTRAINT uk_telemetria_nombre UNIQUE (idTelemetria, Nombre),
+
+    CONSTRAINT fk_sensores_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_datos
+-- Descripción: Almacena las mediciones de telemetría de cada dispositivo.
+--              timescale.sql la convierte en hypertable particionada por Fecha
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_datos (
+    idMedicion BIGINT GENERATED BY DEFAULT AS IDENTITY,
+    idTelemetria INTEGER NOT NULL,
+    Fecha TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FechaDispositivo TIMESTAMPTZ NULL DEFAULT NULL,
+    FechaRecepcion TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    Latitud NUMERIC(9, 6),
+    Longitud NUMERIC(9, 6),
+    Distancia NUMERIC(9, 6),
+    Sensor_1 NUMERIC(9, 6),
+    Sensor_2 NUMERIC(9, 6),
+    Sensor_3 NUMERIC(9, 6),
+    Sensor_4 NUMERIC(9, 6),
+    Sensor_5 NUMERIC(9, 6),
+
+    PRIMARY KEY (idMedicion),
+
+    CONSTRAINT fk_datos_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_datos_telemetria_fecha ON equipos_telemetria_datos (idTelemetria, Fecha);
+CREATE INDEX IF NOT EXISTS idx_datos_fecha ON equipos_telemetria_datos (Fecha);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_datos_sensores
+-- Descripción: Valores de sensores con nombre de cada medición
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_datos_sensores (
+    idMedicion BIGINT NOT NULL,
+    Nombre VARCHAR(64) NOT NULL,
+    Valor DOUBLE PRECISION NOT NULL,
+
+    PRIMARY KEY (idMedicion, Nombre),
+
+    CONSTRAINT fk_datos_sensores_medicion
+        FOREIGN KEY (idMedicion)
+        REFERENCES equipos_telemetria_datos(idMedicion)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_datos_sensores_nombre ON equipos_telemetria_datos_sensores (Nombre);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_errores
+-- Descripción: Almacena errores y eventos anómalos del sistema
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_errores (
+    idError BIGINT GENERATED BY DEFAULT AS IDENTITY,
+    idTelemetria INTEGER,
+    Identificador VARCHAR(255),
+    Fecha TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    descripcion TEXT,
+    idSolicitud VARCHAR(128),
+
+    PRIMARY KEY (idError),
+
+    CONSTRAINT fk_errores_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_errores_telemetria_fecha ON equipos_telemetria_errores (idTelemetria, Fecha);
+CREATE INDEX IF NOT EXISTS idx_errores_identificador ON equipos_telemetria_errores (Identificador);
+
+-- ID de la solicitud HTTP/MQTT que originó el registro (bases creadas antes de incluirlo)
+ALTER TABLE equipos_telemetria_errores ADD COLUMN IF NOT EXISTS idSolicitud VARCHAR(128);
+CREATE INDEX IF NOT EXISTS idx_errores_solicitud ON equipos_telemetria_errores (idSolicitud);
+CREATE INDEX IF NOT EXISTS idx_errores_fecha ON equipos_telemetria_errores (Fecha);
+
+-- ============================================================================
+-- Tabla: geocercas
+-- Descripción: Geocercas circulares (centro y radio en metros) o poligonales
+--              (Vertices: arreglo JSON de pares [latitud, longitud])
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS geocercas (
+    idGeocerca INTEGER GENERATED BY DEFAULT AS IDENTITY,
+    Nombre VARCHAR(255) NOT NULL,
+    Tipo VARCHAR(8) NOT NULL CHECK (Tipo IN ('circle', 'polygon')),
+    Latitud NUMERIC(9, 6) NULL,
+    Longitud NUMERIC(9, 6) NULL,
+    Radio DOUBLE PRECISION NULL,
+    Vertices TEXT NULL,
+    TiempoPermanencia INTEGER NOT NULL DEFAULT 0 CHECK (TiempoPermanencia >= 0),
+    Activo BOOLEAN NOT NULL DEFAULT TRUE,
+
+    PRIMARY KEY (idGeocerca)
+);
+
+-- ============================================================================
+-- Tabla: geocercas_asignaciones
+-- Descripción: Asignación de geocercas a un dispositivo o a un grupo
+--              (solo uno de idTelemetria / idGrupo por fila)
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS geocercas_asignaciones (
+    idAsignacion INTEGER GENERATED BY DEFAULT AS IDENTITY,
+    idGeocerca INTEGER NOT NULL,
+    idTelemetria INTEGER NULL,
+    idGrupo INTEGER NULL,
+
+    PRIMARY KEY (idAsignacion),
+    CONSTRAINT uk_geocerca_telemetria UNIQUE (idGeocerca, idTelemetria),
+    CONSTRAINT uk_geocerca_grupo UNIQUE (idGeocerca, idGrupo),
+
+    CONSTRAINT fk_asignaciones_geocerca
+        FOREIGN KEY (idGeocerca)
+        REFERENCES geocercas(idGeocerca)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+
+    CONSTRAINT fk_asignaciones_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+
+    CONSTRAINT fk_asignaciones_grupo
+        FOREIGN KEY (idGrupo)
+        REFERENCES grupos_telemetria(idGrupo)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_asignaciones_telemetria ON geocercas_asignaciones (idTelemetria);
+CREATE INDEX IF NOT EXISTS idx_asignaciones_grupo ON geocercas_asignaciones (idGrupo);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_eventos
+-- Descripción: Eventos de dispositivos (entrada, salida y permanencia en geocercas)
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_eventos (
+    idEvento BIGINT GENERATED BY DEFAULT AS IDENTITY,
+    idTelemetria INTEGER NOT NULL,
+    idGeocerca INTEGER NULL,
+    Tipo VARCHAR(32) NOT NULL,
+    Fecha TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    Latitud NUMERIC(9, 6),
+    Longitud NUMERIC(9, 6),
+    Descripcion TEXT,
+
+    PRIMARY KEY (idEvento),
+
+    CONSTRAINT fk_eventos_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+
+    CONSTRAINT fk_eventos_geocerca
+        FOREIGN KEY (idGeocerca)
+        REFERENCES geocercas(idGeocerca)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_eventos_telemetria_fecha ON equipos_telemetria_eventos (idTelemetria, Fecha);
+CREATE INDEX IF NOT EXISTS idx_eventos_tipo ON equipos_telemetria_eventos (Tipo);
+
+-- ============================================================================
+-- Tabla: alertas_reglas
+-- Descripción: Reglas de alerta sobre valores de sensores, asignadas a un
+--              dispositivo o a un grupo (solo uno de idTelemetria / idGrupo)
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS alertas_reglas (
+    idRegla INTEGER GENERATED BY DEFAULT AS IDENTITY,
+    Nombre VARCHAR(255) NOT NULL,
+    idTelemetria INTEGER NULL,
+    idGrupo INTEGER NULL,
+    Sensor VARCHAR(64) NOT NULL,
+    Operador VARCHAR(8) NOT NULL CHECK (Operador IN ('gt', 'lt', 'between', 'outside', 'rate')),
+    Umbral DOUBLE PRECISION NOT NULL,
+    UmbralMax DOUBLE PRECISION NULL,
+    Histeresis DOUBLE PRECISION NOT NULL DEFAULT 0,
+    Duracion INTEGER NOT NULL DEFAULT 0 CHECK (Duracion >= 0),
+    Activo BOOLEAN NOT NULL DEFAULT TRUE,
+
+    PRIMARY KEY (idRegla),
+
+    CONSTRAINT fk_reglas_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+
+    CONSTRAINT fk_reglas_grupo
+        FOREIGN KEY (idGrupo)
+        REFERENCES grupos_telemetria(idGrupo)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reglas_telemetria ON alertas_reglas (idTelemetria);
+CREATE INDEX IF NOT EXISTS idx_reglas_grupo ON alertas_reglas (idGrupo);
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_alertas
+-- Descripción: Historial de alertas; FechaResolucion NULL indica una alerta activa
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_alertas (
+    idAlerta BIGINT GENERATED BY DEFAULT AS IDENTITY,
+    idRegla INTEGER NOT NULL,
+    idTelemetria INTEGER NOT NULL,
+    Sensor VARCHAR(64) NOT NULL,
+    Valor DOUBLE PRECISION NOT NULL,
+    FechaActivacion TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FechaResolucion TIMESTAMPTZ NULL DEFAULT NULL,
+    ValorResolucion DOUBLE PRECISION NULL,
+    Descripcion TEXT,
+
+    PRIMARY KEY (idAlerta),
+
+    CONSTRAINT fk_alertas_regla
+        FOREIGN KEY (idRegla)
+        REFERENCES alertas_reglas(idRegla)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+
+    CONSTRAINT fk_alertas_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_alertas_telemetria_fecha ON equipos_telemetria_alertas (idTelemetria, FechaActivacion);
+CREATE INDEX IF NOT EXISTS idx_alertas_regla_abierta ON equipos_telemetria_alertas (idRegla, idTelemetria, FechaResolucion);
+
+-- ============================================================================
+-- Tabla: webhooks
+-- Descripción: Destinos de notificaciones salientes. Sin idGrupo reciben las
+--              notificaciones de todos los dispositivos; Eventos es un arreglo
+--              JSON de tipos (NULL = todos)
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS webhooks (
+    idWebhook INTEGER GENERATED BY DEFAULT AS IDENTITY,
+    Nombre VARCHAR(255) NOT NULL,
+    URL VARCHAR(2048) NOT NULL,
+    Secreto VARCHAR(255) NOT NULL,
+    idGrupo INTEGER NULL,
+    Eventos TEXT NULL,
+    Activo BOOLEAN NOT NULL DEFAULT TRUE,
+
+    PRIMARY KEY (idWebhook),
+
+    CONSTRAINT fk_webhooks_grupo
+        FOREIGN KEY (idGrupo)
+        REFERENCES grupos_telemetria(idGrupo)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+-- ============================================================================
+-- Tabla: webhooks_entregas
+-- Descripción: Cola persistente y registro de entregas de webhooks. Bloqueo
+--              identifica la reserva de la instancia que está enviando la entrega
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS webhooks_entregas (
+    idEntrega BIGINT GENERATED BY DEFAULT AS IDENTITY,
+    idWebhook INTEGER NOT NULL,
+    Tipo VARCHAR(32) NOT NULL,
+    Payload TEXT NOT NULL,
+    Estado VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (Estado IN ('pending', 'delivered', 'failed')),
+    Intentos INTEGER NOT NULL DEFAULT 0,
+    ProximoIntento TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    Bloqueo CHAR(32) NULL,
+    UltimoError TEXT,
+    CodigoRespuesta SMALLINT NULL,
+    FechaCreacion TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FechaEntrega TIMESTAMPTZ NULL DEFAULT NULL,
+
+    PRIMARY KEY (idEntrega),
+
+    CONSTRAINT fk_entregas_webhook
+        FOREIGN KEY (idWebhook)
+        REFERENCES webhooks(idWebhook)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_entregas_estado_proximo ON webhooks_entregas (Estado, ProximoIntento);
+CREATE INDEX IF NOT EXISTS idx_entregas_bloqueo ON webhooks_entregas (Bloqueo);
+CREATE INDEX IF NOT EXISTS idx_entregas_webhook ON webhooks_entregas (idWebhook, idEntrega);
+
+-- ============================================================================
+-- Vistas útiles
+-- ============================================================================
+
+-- Vista: Última posición de cada dispositivo
+CREATE OR REPLACE VIEW v_ultima_posicion AS
+SELECT
+    et.idTelemetria,
+    et.Identificador,
+    et.Nombre,
+    et.UltimaConexion,
+    etd.Latitud,
+    etd.Longitud,
+    etd.Fecha AS FechaUltimaMedicion
+FROM equipos_telemetria et
+LEFT JOIN LATERAL (
+    SELECT Latitud, Longitud, Fecha
+    FROM equipos_telemetria_datos
+    WHERE idTelemetria = et.idTelemetria
+    ORDER BY Fecha DESC
+    LIMIT 1
+) etd ON TRUE;
+
+-- Vista: Resumen de errores por dispositivo
+CREATE OR REPLACE VIEW v_resumen_errores AS
+SELECT
+    et.idTelemetria,
+    et.Identificador,
+    et.Nombre,
+    COUNT(ete.idError) AS TotalErrores,
+    MAX(ete.Fecha) AS UltimoError
+FROM equipos_telemetria et
+LEFT JOIN equipos_telemetria_errores ete ON et.idTelemetria = ete.idTelemetria
+GROUP BY et.idTelemetria, et.Identificador, et.Nombre;
+
+-- ============================================================================
+-- Comentarios en las tablas
+-- ============================================================================
+COMMENT ON TABLE equipos_telemetria IS 'Catálogo de dispositivos de telemetría registrados';
+COMMENT ON TABLE equipos_telemetria_datos IS 'Mediciones de telemetría recibidas de los dispositivos';
+COMMENT ON TABLE equipos_telemetria_errores IS 'Registro de errores y eventos anómalos del sistema';
+COMMENT ON TABLE equipos_telemetria_credenciales IS 'Credenciales de autenticación de los dispositivos';
+COMMENT ON TABLE equipos_telemetria_sensores IS 'Catálogo de canales de sensores por dispositivo';
+COMMENT ON TABLE equipos_telemetria_datos_sensores IS 'Valores de sensores con nombre de cada medición';
+COMMENT ON TABLE grupos_telemetria IS 'Grupos de dispositivos';
+COMMENT ON TABLE geocercas IS 'Geocercas circulares y poligonales';
+COMMENT ON TABLE geocercas_asignaciones IS 'Asignación de geocercas a dispositivos o grupos';
+COMMENT ON TABLE equipos_telemetria_eventos IS 'Eventos de entrada, salida y permanencia en geocercas';
+COMMENT ON TABLE alertas_reglas IS 'Reglas de alerta sobre valores de sensores';
+COMMENT ON TABLE equipos_telemetria_alertas IS 'Historial de alertas activadas y resueltas';
+COMMENT ON TABLE webhooks IS 'Destinos de notificaciones salientes';
+COMMENT ON TABLE webhooks_entregas IS 'Cola persistente y registro de entregas de webhooks';
@@ -0,0 +1,45 @@
+-- ============================================================================
+-- TimescaleDB (opcional)
+-- Descripción: Convierte equipos_telemetria_datos en una hypertable
+--              particionada por Fecha. Ejecutar después de schema.sql en una
+--              base de datos con la extensión TimescaleDB disponible
+-- Uso: psql -d telemetria -f migrations/postgres/timescale.sql
+-- ============================================================================
+
+CREATE EXTENSION IF NOT EXISTS timescaledb;
+
+-- Una hypertable no admite claves foráneas que la referencien: la limpieza de
+-- equipos_telemetria_datos_sensores la realiza el repositorio al eliminar un
+-- dispositivo
+ALTER TABLE equipos_telemetria_datos_sensores
+    DROP CONSTRAINT IF EXISTS fk_datos_sensores_medicion;
+
+-- Toda restricción única de una hypertable debe incluir la columna de partición
+ALTER TABLE equipos_telemetria_datos
+    DROP CONSTRAINT IF EXISTS equipos_telemetria_datos_pkey;
+
+ALTER TABLE equipos_telemetria_datos
+    ADD PRIMARY KEY (idMedicion, Fecha);
+
+SELECT create_hypertable(
+    'equipos_telemetria_datos',
+    'fecha',
+    chunk_time_interval => INTERVAL '7 days',
+    migrate_data => TRUE,
+    if_not_exists => TRUE
+);
+
+-- ============================================================================
+-- Políticas opcionales (descomentar según necesidad)
+-- ============================================================================
+
+-- Comprimir chunks con más de 30 días
+-- ALTER TABLE equipos_telemetria_datos SET (
+--     timescaledb.compress,
+--     timescaledb.compress_segmentby = 'idtelemetria',
+--     timescaledb.compress_orderby = 'fecha DESC, idmedicion DESC'
+-- );
+-- SELECT add_compression_policy('equipos_telemetria_datos', INTERVAL '30 days');
+
+-- Eliminar mediciones con más de un año
+-- SELECT add_retention_policy('equipos_telemetria_datos', INTERVAL '1 year');
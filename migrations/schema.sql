@@ -12,6 +12,17 @@ COLLATE utf8mb4_unicode_ci;
 
 USE telemetria;
 
+-- ============================================================================
+-- Tabla: grupos_telemetria
+-- Descripción: Grupos de dispositivos (ej. flota o cliente)
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS grupos_telemetria (
+    idGrupo INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    Nombre VARCHAR(255) NOT NULL,
+
+    PRIMARY KEY (idGrupo)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
 -- ============================================================================
 -- Tabla: equipos_telemetria
 -- Descripción: Almacena información de los dispositivos de telemetría
@@ -22,10 +33,73 @@ CREATE TABLE IF NOT EXISTS equipos_telemetria (
     Nombre VARCHAR(255) NOT NULL,
     UltimaConexion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     TiempoFueraLinea TIME DEFAULT '00:00:00',
+    Activo TINYINT(1) NOT NULL DEFAULT 1,
+    FueraLinea TINYINT(1) NOT NULL DEFAULT 0,
+    FechaFueraLinea TIMESTAMP NULL DEFAULT NULL,
+    idGrupo INT UNSIGNED NULL,
 
     PRIMARY KEY (idTelemetria),
     UNIQUE KEY uk_identificador (Identificador),
-    INDEX idx_ultima_conexion (UltimaConexion)
+    INDEX idx_ultima_conexion (UltimaConexion),
+    INDEX idx_fuera_linea (FueraLinea, Activo),
+
+    CONSTRAINT fk_telemetria_grupo
+        FOREIGN KEY (idGrupo)
+        REFERENCES grupos_telemetria(idGrupo)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_credenciales
+-- Descripción: Credenciales de los dispositivos. Tipo 'token' almacena solo
+--              el hash SHA-256 del token en hexadecimal; tipo 'hmac' almacena
+--              el secreto compartido para verificar firmas HMAC-SHA256
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_credenciales (
+    idCredencial INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    idTelemetria INT UNSIGNED NOT NULL,
+    Tipo ENUM('token', 'hmac') NOT NULL DEFAULT 'token',
+    TokenHash CHAR(64) NULL,
+    Secreto VARCHAR(255) NULL,
+    Descripcion VARCHAR(255),
+    Activo TINYINT(1) NOT NULL DEFAULT 1,
+    FechaCreacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FechaExpiracion TIMESTAMP NULL DEFAULT NULL,
+
+    PRIMARY KEY (idCredencial),
+    UNIQUE KEY uk_token_hash (TokenHash),
+    INDEX idx_telemetria_activo (idTelemetria, Activo),
+
+    CONSTRAINT fk_credenciales_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_sensores
+-- Descripción: Catálogo de canales de sensores por dispositivo. Slot asocia
+--              el sensor a una columna legacy Sensor_1..Sensor_5 (opcional)
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_sensores (
+    idSensor INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    idTelemetria INT UNSIGNED NOT NULL,
+    Nombre VARCHAR(64) NOT NULL,
+    Unidad VARCHAR(32),
+    TipoDato ENUM('float', 'int', 'bool') NOT NULL DEFAULT 'float',
+    Slot TINYINT UNSIGNED NULL,
+    Activo TINYINT(1) NOT NULL DEFAULT 1,
+
+    PRIMARY KEY (idSensor),
+    UNIQUE KEY uk_telemetria_nombre (idTelemetria, Nombre),
+
+    CONSTRAINT fk_sensores_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
 -- ============================================================================
@@ -36,6 +110,8 @@ CREATE TABLE IF NOT EXISTS equipos_telemetria_datos (
     idMedicion BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
     idTelemetria INT UNSIGNED NOT NULL,
     Fecha TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FechaDispositivo TIMESTAMP NULL DEFAULT NULL,
+    FechaRecepcion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     Latitud DECIMAL(9, 6),
     Longitud DECIMAL(9, 6),
     Distancia DECIMAL(9, 6),
@@ -56,6 +132,25 @@ CREATE TABLE IF NOT EXISTS equipos_telemetria_datos (
         ON UPDATE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+-- ============================================================================
+-- Tabla: equipos_telemetria_datos_sensores
+-- Descripción: Valores de sensores con nombre de cada medición
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_datos_sensores (
+    idMedicion BIGINT UNSIGNED NOT NULL,
+    Nombre VARCHAR(64) NOT NULL,
+    Valor DOUBLE NOT NULL,
+
+    PRIMARY KEY (idMedicion, Nombre),
+    INDEX idx_nombre (Nombre),
+
+    CONSTRAINT fk_datos_sensores_medicion
+        FOREIGN KEY (idMedicion)
+        REFERENCES equipos_telemetria_datos(idMedicion)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
 -- ============================================================================
 -- Tabla: equipos_telemetria_errores
 -- Descripción: Almacena errores y eventos anómalos del sistema
@@ -66,11 +161,13 @@ CREATE TABLE IF NOT EXISTS equipos_telemetria_errores (
     Identificador VARCHAR(255),
     Fecha TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     descripcion TEXT,
+    idSolicitud VARCHAR(128) NULL,
 
     PRIMARY KEY (idError),
     INDEX idx_telemetria_fecha (idTelemetria, Fecha),
     INDEX idx_identificador (Identificador),
     INDEX idx_fecha (Fecha),
+    INDEX idx_solicitud (idSolicitud),
 
     CONSTRAINT fk_errores_telemetria
         FOREIGN KEY (idTelemetria)
@@ -79,6 +176,214 @@ CREATE TABLE IF NOT EXISTS equipos_telemetria_errores (
         ON UPDATE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+-- ============================================================================
+-- Tabla: geocercas
+-- Descripción: Geocercas circulares (centro y radio en metros) o poligonales
+--              (Vertices: arreglo JSON de pares [latitud, longitud])
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS geocercas (
+    idGeocerca INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    Nombre VARCHAR(255) NOT NULL,
+    Tipo ENUM('circle', 'polygon') NOT NULL,
+    Latitud DECIMAL(9, 6) NULL,
+    Longitud DECIMAL(9, 6) NULL,
+    Radio DOUBLE NULL,
+    Vertices TEXT NULL,
+    TiempoPermanencia INT UNSIGNED NOT NULL DEFAULT 0,
+    Activo TINYINT(1) NOT NULL DEFAULT 1,
+
+    PRIMARY KEY (idGeocerca)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================================================
+-- Tabla: geocercas_asignaciones
+-- Descripción: Asignación de geocercas a un dispositivo o a un grupo
+--              (solo uno de idTelemetria / idGrupo por fila)
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS geocercas_asignaciones (
+    idAsignacion INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    idGeocerca INT UNSIGNED NOT NULL,
+    idTelemetria INT UNSIGNED NULL,
+    idGrupo INT UNSIGNED NULL,
+
+    PRIMARY KEY (idAsignacion),
+    UNIQUE KEY uk_geocerca_telemetria (idGeocerca, idTelemetria),
+    UNIQUE KEY uk_geocerca_grupo (idGeocerca, idGrupo),
+    INDEX idx_telemetria (idTelemetria),
+    INDEX idx_grupo (idGrupo),
+
+    CONSTRAINT fk_asignaciones_geocerca
+        FOREIGN KEY (idGeocerca)
+        REFERENCES geocercas(idGeocerca)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+
+    CONSTRAINT fk_asignaciones_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+
+    CONSTRAINT fk_asignaciones_grupo
+        FOREIGN KEY (idGrupo)
+        REFERENCES grupos_telemetria(idGrupo)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_eventos
+-- Descripción: Eventos de dispositivos (entrada, salida y permanencia en geocercas)
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_eventos (
+    idEvento BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    idTelemetria INT UNSIGNED NOT NULL,
+    idGeocerca INT UNSIGNED NULL,
+    Tipo VARCHAR(32) NOT NULL,
+    Fecha TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    Latitud DECIMAL(9, 6),
+    Longitud DECIMAL(9, 6),
+    Descripcion TEXT,
+
+    PRIMARY KEY (idEvento),
+    INDEX idx_telemetria_fecha (idTelemetria, Fecha),
+    INDEX idx_tipo (Tipo),
+
+    CONSTRAINT fk_eventos_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+
+    CONSTRAINT fk_eventos_geocerca
+        FOREIGN KEY (idGeocerca)
+        REFERENCES geocercas(idGeocerca)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================================================
+-- Tabla: alertas_reglas
+-- Descripción: Reglas de alerta sobre valores de sensores, asignadas a un
+--              dispositivo o a un grupo (solo uno de idTelemetria / idGrupo)
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS alertas_reglas (
+    idRegla INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    Nombre VARCHAR(255) NOT NULL,
+    idTelemetria INT UNSIGNED NULL,
+    idGrupo INT UNSIGNED NULL,
+    Sensor VARCHAR(64) NOT NULL,
+    Operador ENUM('gt', 'lt', 'between', 'outside', 'rate') NOT NULL,
+    Umbral DOUBLE NOT NULL,
+    UmbralMax DOUBLE NULL,
+    Histeresis DOUBLE NOT NULL DEFAULT 0,
+    Duracion INT UNSIGNED NOT NULL DEFAULT 0,
+    Activo TINYINT(1) NOT NULL DEFAULT 1,
+
+    PRIMARY KEY (idRegla),
+    INDEX idx_telemetria (idTelemetria),
+    INDEX idx_grupo (idGrupo),
+
+    CONSTRAINT fk_reglas_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+
+    CONSTRAINT fk_reglas_grupo
+        FOREIGN KEY (idGrupo)
+        REFERENCES grupos_telemetria(idGrupo)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================================================
+-- Tabla: equipos_telemetria_alertas
+-- Descripción: Historial de alertas; FechaResolucion NULL indica una alerta activa
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS equipos_telemetria_alertas (
+    idAlerta BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    idRegla INT UNSIGNED NOT NULL,
+    idTelemetria INT UNSIGNED NOT NULL,
+    Sensor VARCHAR(64) NOT NULL,
+    Valor DOUBLE NOT NULL,
+    FechaActivacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FechaResolucion TIMESTAMP NULL DEFAULT NULL,
+    ValorResolucion DOUBLE NULL,
+    Descripcion TEXT,
+
+    PRIMARY KEY (idAlerta),
+    INDEX idx_telemetria_fecha (idTelemetria, FechaActivacion),
+    INDEX idx_regla_abierta (idRegla, idTelemetria, FechaResolucion),
+
+    CONSTRAINT fk_alertas_regla
+        FOREIGN KEY (idRegla)
+        REFERENCES alertas_reglas(idRegla)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+
+    CONSTRAINT fk_alertas_telemetria
+        FOREIGN KEY (idTelemetria)
+        REFERENCES equipos_telemetria(idTelemetria)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================================================
+-- Tabla: webhooks
+-- Descripción: Destinos de notificaciones salientes. Sin idGrupo reciben las
+--              notificaciones de todos los dispositivos; Eventos es un arreglo
+--              JSON de tipos (NULL = todos)
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS webhooks (
+    idWebhook INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    Nombre VARCHAR(255) NOT NULL,
+    URL VARCHAR(2048) NOT NULL,
+    Secreto VARCHAR(255) NOT NULL,
+    idGrupo INT UNSIGNED NULL,
+    Eventos TEXT NULL,
+    Activo TINYINT(1) NOT NULL DEFAULT 1,
+
+    PRIMARY KEY (idWebhook),
+
+    CONSTRAINT fk_webhooks_grupo
+        FOREIGN KEY (idGrupo)
+        REFERENCES grupos_telemetria(idGrupo)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- ============================================================================
+-- Tabla: webhooks_entregas
+-- Descripción: Cola persistente y registro de entregas de webhooks. Bloqueo
+--              identifica la reserva de la instancia que está enviando la entrega
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS webhooks_entregas (
+    idEntrega BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    idWebhook INT UNSIGNED NOT NULL,
+    Tipo VARCHAR(32) NOT NULL,
+    Payload MEDIUMTEXT NOT NULL,
+    Estado ENUM('pending', 'delivered', 'failed') NOT NULL DEFAULT 'pending',
+    Intentos INT UNSIGNED NOT NULL DEFAULT 0,
+    ProximoIntento TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    Bloqueo CHAR(32) NULL,
+    UltimoError TEXT,
+    CodigoRespuesta SMALLINT UNSIGNED NULL,
+    FechaCreacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FechaEntrega TIMESTAMP NULL DEFAULT NULL,
+
+    PRIMARY KEY (idEntrega),
+    INDEX idx_estado_proximo (Estado, ProximoIntento),
+    INDEX idx_bloqueo (Bloqueo),
+    INDEX idx_webhook (idWebhook, idEntrega),
+
+    CONSTRAINT fk_entregas_webhook
+        FOREIGN KEY (idWebhook)
+        REFERENCES webhooks(idWebhook)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
 -- ============================================================================
 -- Vistas útiles
 -- ============================================================================
@@ -127,3 +432,35 @@ ALTER TABLE equipos_telemetria_datos
 
 ALTER TABLE equipos_telemetria_errores
     COMMENT = 'Registro de errores y eventos anómalos del sistema';
+
+ALTER TABLE equipos_telemetria_credenciales
+    COMMENT = 'Credenciales de autenticación de los dispositivos';
+ALTER TABLE equipos_telemetria_sensores
+    COMMENT = 'Catálogo de canales de sensores por dispositivo';
+
+ALTER TABLE equipos_telemetria_datos_sensores
+    COMMENT = 'Valores de sensores con nombre de cada medición';
+
+ALTER TABLE grupos_telemetria
+    COMMENT = 'Grupos de dispositivos';
+
+ALTER TABLE geocercas
+    COMMENT = 'Geocercas circulares y poligonales';
+
+ALTER TABLE geocercas_asignaciones
+    COMMENT = 'Asignación de geocercas a dispositivos o grupos';
+
+ALTER TABLE equipos_telemetria_eventos
+    COMMENT = 'Eventos de entrada, salida y permanencia en geocercas';
+
+ALTER TABLE alertas_reglas
+    COMMENT = 'Reglas de alerta sobre valores de sensores';
+
+ALTER TABLE equipos_telemetria_alertas
+    COMMENT = 'Historial de alertas activadas y resueltas';
+
+ALTER TABLE webhooks
+    COMMENT = 'Destinos de notificaciones salientes';
+
+ALTER TABLE webhooks_entregas
+    COMMENT = 'Cola persistente y registro de entregas de webhooks';
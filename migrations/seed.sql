@@ -14,6 +14,51 @@ INSERT INTO equipos_telemetria (Identificador, Nombre, UltimaConexion, TiempoFue
 ('DEVICE004', 'Vehículo 4 - Zona Oeste', '2025-11-27 10:45:00', '00:20:00'),
 ('DEVICE005', 'Sensor Fijo 1 - Centro', '2025-11-27 11:00:00', '02:00:00');
 
+-- ============================================================================
+-- Insertar grupos y geocercas de prueba
+-- ============================================================================
+INSERT INTO grupos_telemetria (Nombre) VALUES
+('Flota Norte');
+
+UPDATE equipos_telemetria SET idGrupo = 1 WHERE idTelemetria IN (1, 3);
+
+INSERT INTO geocercas (Nombre, Tipo, Latitud, Longitud, Radio, Vertices, TiempoPermanencia) VALUES
+('Depósito Central', 'circle', -33.448900, -70.669300, 500, NULL, 600),
+('Cliente Norte', 'polygon', NULL, NULL, NULL, '[[-33.40,-70.62],[-33.40,-70.58],[-33.43,-70.58],[-33.43,-70.62]]', 0);
+
+INSERT INTO geocercas_asignaciones (idGeocerca, idTelemetria, idGrupo) VALUES
+(1, NULL, 1),
+(2, 2, NULL);
+
+-- ============================================================================
+-- Insertar reglas de alerta de prueba
+-- ============================================================================
+INSERT INTO alertas_reglas (Nombre, idTelemetria, idGrupo, Sensor, Operador, Umbral, UmbralMax, Histeresis, Duracion) VALUES
+('Cadena de frío', 1, NULL, 'sensor_1', 'gt', 8, NULL, 0.5, 300),
+('Temperatura de motor', 4, NULL, 'temp_engine', 'outside', 70, 105, 2, 60),
+('Variación brusca de presión', NULL, 1, 'sensor_3', 'rate', 5, NULL, 1, 0);
+
+-- ============================================================================
+-- Insertar credenciales de prueba
+-- Tokens en claro: device001-secret, device002-secret
+-- ============================================================================
+INSERT INTO equipos_telemetria_credenciales (idTelemetria, Tipo, TokenHash, Secreto, Descripcion) VALUES
+(1, 'token', '1b88a550d1f7f36129cb41672cf747052c9a8e8edf5ce3a157d09820f496233d', NULL, 'Token de prueba DEVICE001'),
+(2, 'token', '2572d53d23b942991ebc78877af3e49d2d76ee6a049c61972db84b3ae03e0888', NULL, 'Token de prueba DEVICE002'),
+(3, 'hmac', NULL, 'device003-hmac-secret', 'Secreto HMAC de prueba DEVICE003');
+
+-- ============================================================================
+-- Insertar catálogo de sensores de prueba
+-- ============================================================================
+INSERT INTO equipos_telemetria_sensores (idTelemetria, Nombre, Unidad, TipoDato, Slot) VALUES
+(1, 'temperatura', '°C', 'float', 1),
+(1, 'humedad', '%', 'float', 2),
+(1, 'presion', 'hPa', 'float', 3),
+(4, 'temp_engine', '°C', 'float', 1),
+(4, 'fuel_level', '%', 'float', NULL),
+(4, 'door_open', NULL, 'bool', NULL),
+(4, 'rpm', 'rpm', 'int', NULL);
+
 -- ============================================================================
 -- Insertar datos de telemetría de ejemplo
 -- ============================================================================